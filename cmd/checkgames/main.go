@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"t-cubed/internal/service"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+)
+
+// Replays every game in the database and reports the ones whose move events or terminal state are corrupted.
+// With -repair, the move events after the last valid one are deleted and the terminal state is recomputed.
+func main() {
+	repair := flag.Bool("repair", false, "repair corrupted games instead of only reporting them")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		slog.Warn("Could not load .env file", "error", err)
+	}
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		fmt.Fprintln(os.Stderr, "No DATABASE_URL environment variable found")
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, databaseURL)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not connect to database:", err)
+		os.Exit(1)
+	}
+	defer pool.Close()
+
+	verifier := service.NewGameVerifier(pool)
+	gameUuids, err := verifier.ListGames(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not list games:", err)
+		os.Exit(1)
+	}
+
+	corrupted, unrepaired := 0, 0
+	for _, gameUuid := range gameUuids {
+		var verification *service.GameVerification
+		if *repair {
+			verification, err = verifier.Repair(ctx, gameUuid)
+		} else {
+			verification, err = verifier.Verify(ctx, gameUuid)
+		}
+		if verification == nil || verification.OK() {
+			if err != nil {
+				fmt.Printf("FAIL %s\n\t%s\n", gameUuid, err)
+				unrepaired++
+			}
+			continue
+		}
+
+		corrupted++
+		fmt.Printf("BAD  %s (%d move events, last valid move %d)\n", gameUuid, verification.MoveEventCount, verification.LastValidMoveSequence)
+		for _, problem := range verification.Problems {
+			fmt.Printf("\t%s\n", problem)
+		}
+		switch {
+		case !*repair:
+			unrepaired++
+		case err != nil:
+			fmt.Printf("\tnot repaired: %s\n", err)
+			unrepaired++
+		default:
+			fmt.Printf("\trepaired: kept moves 0-%d\n", verification.LastValidMoveSequence)
+		}
+	}
+
+	fmt.Printf("Checked %d games, %d corrupted\n", len(gameUuids), corrupted)
+	if unrepaired > 0 {
+		os.Exit(1)
+	}
+}
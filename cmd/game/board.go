@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"t-cubed/internal/engine"
+)
+
+// Renders the board with column letters and row numbers. Empty cells show their position (1-9).
+func renderBoard(gameState *engine.GameState) string {
+	cells := gameState.GetBoardAsBytes()
+
+	var b strings.Builder
+	b.WriteString("    a   b   c\n")
+	for row := range 3 {
+		fmt.Fprintf(&b, "%d  ", row+1)
+		for col := range 3 {
+			cell := cells[row*3+col]
+			if cell == '_' {
+				cell = byte('1' + row*3 + col)
+			}
+			fmt.Fprintf(&b, " %c ", cell)
+			if col < 2 {
+				b.WriteString("│")
+			}
+		}
+		b.WriteString("\n")
+		if row < 2 {
+			b.WriteString("   ───┼───┼───\n")
+		}
+	}
+	return b.String()
+}
+
+// Returns the piece of the given player
+func pieceOf(gameState *engine.GameState, playerId uint8) byte {
+	if playerId == gameState.Player1.Id {
+		return gameState.Player1.Piece
+	}
+	return gameState.Player2.Piece
+}
+
+// Describes a move, e.g. "X plays b2 (5)"
+func describeMove(gameState *engine.GameState, playerId uint8, position uint8) string {
+	coordinate, err := engine.PositionToCoordinate(position)
+	if err != nil {
+		coordinate = "?"
+	}
+	return fmt.Sprintf("%c plays %s (%d)", pieceOf(gameState, playerId), coordinate, position)
+}
+
+// Describes how the game ended
+func describeResult(gameState *engine.GameState, names map[uint8]string) string {
+	switch gameState.TerminalState {
+	case engine.TERM_WIN_1:
+		return fmt.Sprintf("%s (%c) wins!", names[1], gameState.Player1.Piece)
+	case engine.TERM_WIN_2:
+		return fmt.Sprintf("%s (%c) wins!", names[2], gameState.Player2.Piece)
+	case engine.TERM_DRAW:
+		return "Draw!"
+	default:
+		return "Game in progress"
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+
+	"t-cubed/internal/engine"
+)
+
+type playedMove struct {
+	playerId uint8
+	position uint8
+}
+
+// A game played locally, kept as its list of moves so it can be undone and replayed
+type localGame struct {
+	options *engine.GameStateOptions
+	state   *engine.GameState
+	moves   []playedMove
+	players map[uint8]player
+}
+
+func newLocalGame(options *engine.GameStateOptions, player1, player2 player) (*localGame, error) {
+	state, err := engine.NewGameState(options)
+	if err != nil {
+		return nil, err
+	}
+	return &localGame{
+		options: options,
+		state:   state,
+		players: map[uint8]player{1: player1, 2: player2},
+	}, nil
+}
+
+func (g *localGame) names() map[uint8]string {
+	return map[uint8]string{1: g.players[1].name(), 2: g.players[2].name()}
+}
+
+// Plays a move for the player whose turn it is
+func (g *localGame) play(position uint8) error {
+	playerId := g.state.GetCurrentPlayerId()
+	ok, err := g.state.Move(position)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("Invalid move")
+	}
+	g.moves = append(g.moves, playedMove{playerId: playerId, position: position})
+	return nil
+}
+
+// Takes back moves up to and including the last human move, so that human is to move again.
+// Returns false when no human has moved yet.
+func (g *localGame) undo() (bool, error) {
+	for i := len(g.moves) - 1; i >= 0; i-- {
+		if !g.players[g.moves[i].playerId].isHuman() {
+			continue
+		}
+		state, err := replayMoves(g.options, g.moves[:i])
+		if err != nil {
+			return false, err
+		}
+		g.state, g.moves = state, g.moves[:i]
+		return true, nil
+	}
+	return false, nil
+}
+
+// Returns the game state after playing moves from the start
+func replayMoves(options *engine.GameStateOptions, moves []playedMove) (*engine.GameState, error) {
+	state, err := engine.NewGameState(options)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range moves {
+		if ok, err := state.Move(m.position); !ok || err != nil {
+			return nil, fmt.Errorf("could not replay move %d: %v", m.position, err)
+		}
+	}
+	return state, nil
+}
+
+// Runs the game until it ends or a human quits. Returns false if the game was abandoned.
+func (g *localGame) run() (bool, error) {
+	fmt.Println()
+	fmt.Println(renderBoard(g.state))
+	for !g.state.IsTerminal() {
+		playerId := g.state.GetCurrentPlayerId()
+		current := g.players[playerId]
+
+		position, err := current.move(g.state)
+		switch {
+		case err == errQuit:
+			return false, nil
+		case err == errUndo:
+			undone, err := g.undo()
+			if err != nil {
+				return false, err
+			}
+			if !undone {
+				fmt.Println("Nothing to undo")
+				continue
+			}
+			fmt.Println()
+			fmt.Println(renderBoard(g.state))
+			continue
+		case err != nil:
+			return false, err
+		}
+
+		if err := g.play(position); err != nil {
+			fmt.Println(err)
+			continue
+		}
+		fmt.Printf("\n%s: %s\n", current.name(), describeMove(g.state, playerId, position))
+		fmt.Println(renderBoard(g.state))
+	}
+
+	fmt.Println("Game over!", describeResult(g.state, g.names()))
+	return true, nil
+}
+
+// Prints the game move by move, waiting for wait() between moves
+func (g *localGame) replay(wait func()) error {
+	state, err := engine.NewGameState(g.options)
+	if err != nil {
+		return err
+	}
+	fmt.Println("\nReplay:")
+	fmt.Println(renderBoard(state))
+	for i, m := range g.moves {
+		wait()
+		if ok, err := state.Move(m.position); !ok || err != nil {
+			return fmt.Errorf("could not replay move %d: %v", m.position, err)
+		}
+		fmt.Printf("Move %d, %s: %s\n", i+1, g.players[m.playerId].name(), describeMove(state, m.playerId, m.position))
+		fmt.Println(renderBoard(state))
+	}
+	fmt.Println(describeResult(state, g.names()))
+	return nil
+}
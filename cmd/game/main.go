@@ -2,78 +2,199 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
+	"math/rand"
 	"os"
 	"strconv"
+	"strings"
 
 	"t-cubed/internal/ai"
 	"t-cubed/internal/engine"
 )
 
-func Main() {
-	fmt.Println("Welcome to T-Cubed, the game of Tic-Tac-Toe!")
-	gameStateOptions := &engine.GameStateOptions{
-		Player1Piece: engine.PIECE_X,
-		Player2Piece: engine.PIECE_O,
-		FirstPlayerId: 1,
-	}
-	gameState, err := engine.NewGameState(gameStateOptions)
-	if err != nil {
-		fmt.Println(err)
-		return
+const (
+	DIVIDER = "========================================"
+	HELP    = "Enter a position as 1-9 or a coordinate from a1 (top left) to c3 (bottom right), u to undo or q to quit"
+)
+
+// Plays Tic-Tac-Toe in the terminal against minimax, a neural network, another human, random moves
+// or a running t-cubed server
+func main() {
+	serverURL := flag.String("server", "", "play against a running t-cubed server at this URL, e.g. http://localhost:8080")
+	weightsPath := flag.String("weights", "data/weights.json", "weights file for the neural network opponent and side panel")
+	fullScreen := flag.Bool("tui", false, "play local games in a full-screen UI with the network's and minimax's view of the board")
+	flag.Parse()
+	if *fullScreen && *serverURL != "" {
+		fmt.Println("-tui can only be used for local games")
+		os.Exit(2)
 	}
-	scnr := bufio.NewScanner(os.Stdin)
 
+	fmt.Println(DIVIDER)
+	fmt.Println(" 🎲  t³ — Tic-Tac-Toe in the terminal")
+	fmt.Println(DIVIDER)
+
+	scnr := bufio.NewScanner(os.Stdin)
 	for {
-		if gameState.GetCurrentPlayerId() == 2 {
-			bestMove := ai.BestMove(gameState.Board)
-			ok, err := gameState.Move(bestMove)
-			if err != nil {
-				panic(err)
-			}
-			if !ok {
-				panic("Invalid move")
-			}
+		var err error
+		if *serverURL != "" {
+			err = playRemote(scnr, *serverURL)
 		} else {
-			fmt.Printf("[PLAYER %d] Enter your move (1-9): \n", gameState.GetCurrentPlayerId())
-			scnr.Scan()
-			position, err := strconv.Atoi(scnr.Text())
-			if err != nil {
-				fmt.Println("Invalid input")
-				continue
-			}
-			ok, err := gameState.Move(uint8(position))
-			if err != nil {
-				fmt.Println(err)
-				continue
-			}
-			if !ok {
-				fmt.Println("Invalid move")
-				continue
-			}
+			err = playLocal(scnr, *weightsPath, *fullScreen)
+		}
+		if err != nil {
+			fmt.Println("Error:", err)
+		}
+		if !confirm(scnr, "Play again? (y/N): ") {
+			fmt.Println("Goodbye!")
+			return
 		}
+	}
+}
 
+// Sets up and plays a local game, then offers a replay
+func playLocal(scnr *bufio.Scanner, weightsPath string, fullScreen bool) error {
+	rng := rand.New(rand.NewSource(rand.Int63()))
 
-		boardString := gameState.GetBoardAsString()
-		fmt.Println()
-		for i, c := range boardString {
-			fmt.Printf("%c", c)
-			if i == 2 || i == 5 || i == 8 {
-				fmt.Println()
-			} 
+	opponents := []string{OPPONENT_MINIMAX, OPPONENT_NN, OPPONENT_HUMAN, OPPONENT_RANDOM}
+	opponent := opponents[choose(scnr, "Choose your opponent:", []string{"Minimax", "Neural network", "Another human", "Random moves"})]
+
+	difficulty := DIFFICULTY_HARD
+	if opponent == OPPONENT_MINIMAX || opponent == OPPONENT_NN {
+		difficulties := []string{DIFFICULTY_EASY, DIFFICULTY_MEDIUM, DIFFICULTY_HARD}
+		difficulty = difficulties[choose(scnr, "Choose the difficulty:", []string{"Easy", "Medium", "Hard"})]
+	}
+
+	pieceQuestion := "Choose your piece:"
+	if opponent == OPPONENT_HUMAN {
+		pieceQuestion = "Choose Player 1's piece:"
+	}
+	options := &engine.GameStateOptions{Player1Piece: engine.PIECE_X, Player2Piece: engine.PIECE_O}
+	if choose(scnr, pieceQuestion, []string{"X", "O"}) == 1 {
+		options.Player1Piece, options.Player2Piece = engine.PIECE_O, engine.PIECE_X
+	}
+
+	switch choose(scnr, "Who goes first?", []string{"Player 1", "Player 2", "Random"}) {
+	case 0:
+		options.FirstPlayerId = 1
+	case 1:
+		options.FirstPlayerId = 2
+	default:
+		options.FirstPlayerId = uint8(rng.Intn(2) + 1)
+	}
+
+	human := &humanPlayer{label: "You", prompt: func(gameState *engine.GameState) (uint8, error) {
+		return promptMove(scnr, gameState)
+	}}
+	var player2 player
+	var network *ai.Network
+	switch opponent {
+	case OPPONENT_MINIMAX:
+		player2 = newMinimaxPlayer(difficulty, rng)
+	case OPPONENT_NN:
+		var err error
+		network, err = ai.LoadGameNetwork(weightsPath)
+		if err != nil {
+			return err
 		}
-		fmt.Println()
-
-		if gameState.IsTerminal() {
-			fmt.Println("Game over!")
-			if gameState.TerminalState == engine.TERM_WIN_1 {
-				fmt.Println("Player 1 wins!")
-			} else if gameState.TerminalState == engine.TERM_WIN_2 {
-				fmt.Println("Player 2 wins!")
-			} else if gameState.TerminalState == engine.TERM_DRAW {
-				fmt.Println("Draw!")
+		player2 = newNNPlayer(network, difficulty, rng)
+	case OPPONENT_HUMAN:
+		human.label = "Player 1"
+		player2 = &humanPlayer{label: "Player 2", prompt: human.prompt}
+	default:
+		player2 = newRandomPlayer(rng)
+	}
+
+	game, err := newLocalGame(options, human, player2)
+	if err != nil {
+		return err
+	}
+
+	if fullScreen {
+		// The side panel shows the network's policy whichever opponent is playing
+		noNetwork := ""
+		if network == nil {
+			if network, err = ai.LoadGameNetwork(weightsPath); err != nil {
+				network, noNetwork = nil, err.Error()
 			}
-			break
 		}
+		return playFullScreen(game, network, noNetwork)
+	}
+
+	fmt.Printf("\nPlayer 1: %s (%c), Player 2: %s (%c). Player %d goes first.\n",
+		human.name(), options.Player1Piece, player2.name(), options.Player2Piece, options.FirstPlayerId)
+	fmt.Println(HELP)
+
+	finished, err := game.run()
+	if err != nil || !finished {
+		return err
+	}
+	if confirm(scnr, "Replay the game? (y/N): ") {
+		return game.replay(func() { waitForEnter(scnr) })
+	}
+	return nil
+}
+
+// Asks a numbered multiple-choice question and returns the index of the chosen option
+func choose(scnr *bufio.Scanner, question string, options []string) int {
+	fmt.Println(question)
+	for i, option := range options {
+		fmt.Printf("\t%d. %s\n", i+1, option)
+	}
+	for {
+		fmt.Print("Enter your choice: ")
+		if !scnr.Scan() {
+			os.Exit(0)
+		}
+		choice, err := strconv.Atoi(strings.TrimSpace(scnr.Text()))
+		if err != nil || choice < 1 || choice > len(options) {
+			fmt.Println("Invalid choice")
+			continue
+		}
+		return choice - 1
+	}
+}
+
+func confirm(scnr *bufio.Scanner, question string) bool {
+	fmt.Print(question)
+	if !scnr.Scan() {
+		return false
+	}
+	return strings.EqualFold(strings.TrimSpace(scnr.Text()), "y")
+}
+
+func waitForEnter(scnr *bufio.Scanner) {
+	fmt.Print("Press Enter for the next move...")
+	scnr.Scan()
+}
+
+// Reads a move for the current player. Returns errUndo or errQuit for those commands.
+func promptMove(scnr *bufio.Scanner, gameState *engine.GameState) (uint8, error) {
+	playerId := gameState.GetCurrentPlayerId()
+	for {
+		fmt.Printf("[PLAYER %d %c] Enter your move: ", playerId, pieceOf(gameState, playerId))
+		if !scnr.Scan() {
+			return 0, errQuit
+		}
+		input := strings.ToLower(strings.TrimSpace(scnr.Text()))
+		switch input {
+		case "u", "undo":
+			return 0, errUndo
+		case "q", "quit":
+			return 0, errQuit
+		case "h", "help", "?":
+			fmt.Println(HELP)
+			continue
+		}
+		position, err := engine.ParsePosition(input)
+		if err != nil {
+			fmt.Println(err, "-", HELP)
+			continue
+		}
+		if gameState.Board.AvailableMoves()&(1<<(position-1)) == 0 {
+			fmt.Println("That square is taken")
+			continue
+		}
+		return position, nil
 	}
 }
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"math/rand"
+
+	"t-cubed/internal/ai"
+	"t-cubed/internal/engine"
+)
+
+const (
+	OPPONENT_MINIMAX = "minimax"
+	OPPONENT_NN      = "neural network"
+	OPPONENT_HUMAN   = "human"
+	OPPONENT_RANDOM  = "random"
+)
+
+const (
+	DIFFICULTY_EASY   = "easy"
+	DIFFICULTY_MEDIUM = "medium"
+	DIFFICULTY_HARD   = "hard"
+)
+
+// Chance that an AI opponent plays a random move instead of its best move
+var MISTAKE_RATES = map[string]float64{
+	DIFFICULTY_EASY:   0.5,
+	DIFFICULTY_MEDIUM: 0.2,
+	DIFFICULTY_HARD:   0,
+}
+
+// Returned by a human player's move to undo or leave the game
+var (
+	errUndo = errors.New("undo")
+	errQuit = errors.New("quit")
+)
+
+type player interface {
+	name() string
+	isHuman() bool
+	move(gameState *engine.GameState) (uint8, error)
+}
+
+// Reads moves from the terminal
+type humanPlayer struct {
+	label  string
+	prompt func(gameState *engine.GameState) (uint8, error)
+}
+
+func (p *humanPlayer) name() string  { return p.label }
+func (p *humanPlayer) isHuman() bool { return true }
+
+func (p *humanPlayer) move(gameState *engine.GameState) (uint8, error) {
+	return p.prompt(gameState)
+}
+
+// Plays its best move, except for random mistakes at lower difficulties
+type aiPlayer struct {
+	label       string
+	best        func(gameState *engine.GameState) (uint8, error)
+	mistakeRate float64
+	rng         *rand.Rand
+}
+
+func (p *aiPlayer) name() string  { return p.label }
+func (p *aiPlayer) isHuman() bool { return false }
+
+func (p *aiPlayer) move(gameState *engine.GameState) (uint8, error) {
+	if p.best == nil || p.rng.Float64() < p.mistakeRate {
+		return randomMove(gameState, p.rng), nil
+	}
+	return p.best(gameState)
+}
+
+func newMinimaxPlayer(difficulty string, rng *rand.Rand) *aiPlayer {
+	return &aiPlayer{
+		label: "Minimax",
+		best: func(gameState *engine.GameState) (uint8, error) {
+			return ai.BestMoveFor(gameState.Board, gameState.GetCurrentPlayerId()), nil
+		},
+		mistakeRate: MISTAKE_RATES[difficulty],
+		rng:         rng,
+	}
+}
+
+func newNNPlayer(network *ai.Network, difficulty string, rng *rand.Rand) *aiPlayer {
+	workspace := network.NewWorkspace()
+	return &aiPlayer{
+		label: "Neural network",
+		best: func(gameState *engine.GameState) (uint8, error) {
+			probs, err := workspace.Forward(gameState.GetBoardAsNetworkInputFor(gameState.GetCurrentPlayerId()))
+			if err != nil {
+				return 0, err
+			}
+			return bestLegalMove(probs, gameState.Board.AvailableMoves()), nil
+		},
+		mistakeRate: MISTAKE_RATES[difficulty],
+		rng:         rng,
+	}
+}
+
+func newRandomPlayer(rng *rand.Rand) *aiPlayer {
+	return &aiPlayer{label: "Random", rng: rng}
+}
+
+// Returns the available position (1-9) with the highest score
+func bestLegalMove(scores []float64, available uint16) uint8 {
+	best := -1
+	for i, score := range scores {
+		if available&(1<<i) != 0 && (best == -1 || score > scores[best]) {
+			best = i
+		}
+	}
+	return uint8(best + 1)
+}
+
+// Returns a uniformly random available position (1-9)
+func randomMove(gameState *engine.GameState, rng *rand.Rand) uint8 {
+	available := gameState.Board.AvailableMoves()
+	positions := []uint8{}
+	for i := range uint8(9) {
+		if available&(1<<i) != 0 {
+			positions = append(positions, i+1)
+		}
+	}
+	return positions[rng.Intn(len(positions))]
+}
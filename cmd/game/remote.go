@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"t-cubed/internal/engine"
+)
+
+// Game types and move endpoints of the REST API
+const (
+	API_GAME_TYPE_NN      = "neural_network"
+	API_GAME_TYPE_MINIMAX = "minimax"
+)
+
+var API_MOVE_PATHS = map[string]string{
+	API_GAME_TYPE_NN:      "nn",
+	API_GAME_TYPE_MINIMAX: "mm",
+}
+
+// Talks to a running t-cubed server. The server always plays as Player 2, and either player can move first.
+type apiClient struct {
+	baseURL string
+	client  *http.Client
+}
+
+type apiGame struct {
+	UUID          string `json:"uuid"`
+	Name          string `json:"name"`
+	GameType      string `json:"game_type"`
+	BoardState    string `json:"board_state"`
+	NextPlayerID  int16  `json:"next_player_id"`
+	Player1Piece  string `json:"player_1_piece"`
+	Player2Piece  string `json:"player_2_piece"`
+	TerminalState int16  `json:"terminal_state"`
+	MoveSequence  int16  `json:"move_sequence"`
+	FirstPlayerID int16  `json:"first_player_id"`
+}
+
+type apiMoveEvent struct {
+	MoveEvent struct {
+		MoveSequence  int16  `json:"move_sequence"`
+		PlayerID      int16  `json:"player_id"`
+		PostMoveState string `json:"post_move_state"`
+		Position      *int16 `json:"position"` // Missing from servers that do not store positions
+	} `json:"move_event"`
+}
+
+func newAPIClient(baseURL string) *apiClient {
+	return &apiClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Sends a JSON request and decodes the JSON response into out
+func (c *apiClient) do(method string, path string, body any, out any) error {
+	var reqBody bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reqBody).Encode(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, c.baseURL+path, &reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(res.Body).Decode(&apiErr)
+		if apiErr.Error == "" {
+			apiErr.Error = http.StatusText(res.StatusCode)
+		}
+		return fmt.Errorf("%s %s: %s", method, path, apiErr.Error)
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+// Creates a game against the server. If the server moves first, the game includes its opening move.
+func (c *apiClient) createGame(gameType string, player1Piece string, player2Piece string, firstPlayerID int16) (*apiGame, error) {
+	game := &apiGame{}
+	err := c.do(http.MethodPost, "/api/v1/game", map[string]string{
+		"name":           "Terminal game",
+		"game_type":      gameType,
+		"player_1_piece": player1Piece,
+		"player_2_piece": player2Piece,
+		"next_player_id": strconv.Itoa(int(firstPlayerID)),
+		"ai_player_id":   "2",
+	}, game)
+	return game, err
+}
+
+// Plays the human's move. The response includes the server's reply unless the game ended.
+func (c *apiClient) playMove(game *apiGame, position uint8) (*apiGame, error) {
+	res := struct {
+		Game *apiGame `json:"game"`
+	}{}
+	path := fmt.Sprintf("/api/v1/game/%s/%s", game.UUID, API_MOVE_PATHS[game.GameType])
+	err := c.do(http.MethodPost, path, map[string]string{
+		"player_id":              "1",
+		"position":               strconv.Itoa(int(position)),
+		"expected_move_sequence": strconv.Itoa(int(game.MoveSequence)),
+	}, &res)
+	if err == nil && res.Game == nil {
+		err = fmt.Errorf("POST %s: response has no game", path)
+	}
+	return res.Game, err
+}
+
+func (c *apiClient) history(game *apiGame) ([]apiMoveEvent, error) {
+	events := []apiMoveEvent{}
+	err := c.do(http.MethodGet, fmt.Sprintf("/api/v1/game/%s/history", game.UUID), nil, &events)
+	return events, err
+}
+
+func (g *apiGame) options() *engine.GameStateOptions {
+	firstPlayerId := uint8(g.FirstPlayerID)
+	if firstPlayerId == 0 {
+		firstPlayerId = 1 // Servers without game configs always let the human move first
+	}
+	return &engine.GameStateOptions{
+		Player1Piece:  g.Player1Piece[0],
+		Player2Piece:  g.Player2Piece[0],
+		FirstPlayerId: firstPlayerId,
+	}
+}
+
+// Rebuilds the engine's game state from the API's packed board
+func (g *apiGame) gameState() (*engine.GameState, error) {
+	boardState, err := hex.DecodeString(g.BoardState)
+	if err != nil {
+		return nil, err
+	}
+	return engine.NewGameStateFromBytes(g.options(), boardState)
+}
+
+// Plays a game against the server, then offers a replay from the server's move history
+func playRemote(scnr *bufio.Scanner, baseURL string) error {
+	client := newAPIClient(baseURL)
+
+	gameType := API_GAME_TYPE_MINIMAX
+	if choose(scnr, "Choose your opponent:", []string{"Minimax", "Neural network"}) == 1 {
+		gameType = API_GAME_TYPE_NN
+	}
+	humanPiece, aiPiece := "X", "O"
+	if choose(scnr, "Choose your piece:", []string{"X", "O"}) == 1 {
+		humanPiece, aiPiece = "O", "X"
+	}
+
+	firstPlayerID := int16(1)
+	if choose(scnr, "Who moves first?", []string{"You", "The server"}) == 1 {
+		firstPlayerID = 2
+	}
+
+	game, err := client.createGame(gameType, humanPiece, aiPiece, firstPlayerID)
+	if err != nil {
+		return err
+	}
+	names := map[uint8]string{1: "You", 2: "Server " + strings.ReplaceAll(gameType, "_", " ")}
+	fmt.Printf("Created game %s.\n", game.UUID)
+
+	gameState, err := game.gameState()
+	if err != nil {
+		return err
+	}
+	fmt.Println()
+	if opening, err := engine.MovePosition(make([]byte, 4), gameState.GetBoardAsByteArray()); err == nil {
+		fmt.Printf("%s: %s\n", names[2], describeMove(gameState, 2, opening))
+	}
+	fmt.Println(renderBoard(gameState))
+
+	for !gameState.IsTerminal() {
+		position, err := promptMove(scnr, gameState)
+		if err == errQuit {
+			return nil
+		}
+		if err == errUndo {
+			fmt.Println("Undo is not available when playing against a server")
+			continue
+		}
+
+		// Check the move locally first, so the server's reply can be told apart from it
+		afterHuman, err := game.gameState()
+		if err != nil {
+			return err
+		}
+		if ok, err := afterHuman.Move(position); !ok || err != nil {
+			fmt.Println("Invalid move:", err)
+			continue
+		}
+
+		game, err = client.playMove(game, position)
+		if err != nil {
+			return err
+		}
+		if gameState, err = game.gameState(); err != nil {
+			return err
+		}
+
+		fmt.Printf("\n%s: %s\n", names[1], describeMove(gameState, 1, position))
+		reply, err := engine.MovePosition(afterHuman.GetBoardAsByteArray(), gameState.GetBoardAsByteArray())
+		if err == nil {
+			fmt.Printf("%s: %s\n", names[2], describeMove(gameState, 2, reply))
+		}
+		fmt.Println(renderBoard(gameState))
+	}
+	fmt.Println("Game over!", describeResult(gameState, names))
+
+	if !confirm(scnr, "Replay the game? (y/N): ") {
+		return nil
+	}
+	events, err := client.history(game)
+	if err != nil {
+		return err
+	}
+	return replayHistory(scnr, game, events, names)
+}
+
+// Prints a game from the server's move history
+func replayHistory(scnr *bufio.Scanner, game *apiGame, events []apiMoveEvent, names map[uint8]string) error {
+	state, err := engine.NewGameState(game.options())
+	if err != nil {
+		return err
+	}
+	fmt.Println("\nReplay:")
+	fmt.Println(renderBoard(state))
+
+	previous := state.GetBoardAsByteArray()
+	for _, event := range events {
+		if event.MoveEvent.MoveSequence == 0 {
+			continue // The blank board created with the game
+		}
+		postMoveState, err := hex.DecodeString(event.MoveEvent.PostMoveState)
+		if err != nil {
+			return err
+		}
+		var position uint8
+		if event.MoveEvent.Position != nil {
+			position = uint8(*event.MoveEvent.Position)
+		} else if position, err = engine.MovePosition(previous, postMoveState); err != nil {
+			return fmt.Errorf("move %d: %w", event.MoveEvent.MoveSequence, err)
+		}
+		waitForEnter(scnr)
+		if ok, err := state.Move(position); !ok || err != nil {
+			return fmt.Errorf("could not replay move %d: %v", position, err)
+		}
+		playerId := uint8(event.MoveEvent.PlayerID)
+		fmt.Printf("Move %d, %s: %s\n", event.MoveEvent.MoveSequence, names[playerId], describeMove(state, playerId, position))
+		fmt.Println(renderBoard(state))
+		previous = postMoveState
+	}
+	fmt.Println(describeResult(state, names))
+	return nil
+}
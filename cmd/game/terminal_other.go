@@ -0,0 +1,13 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+func enableRawMode(f *os.File) (func() error, error) {
+	return nil, fmt.Errorf("the full-screen mode is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,443 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"t-cubed/internal/ai"
+	"t-cubed/internal/engine"
+)
+
+// ANSI escape sequences
+const (
+	ENTER_ALT_SCREEN = "\x1b[?1049h"
+	LEAVE_ALT_SCREEN = "\x1b[?1049l"
+	HIDE_CURSOR      = "\x1b[?25l"
+	SHOW_CURSOR      = "\x1b[?25h"
+	CLEAR_SCREEN     = "\x1b[H\x1b[2J"
+	RESET            = "\x1b[0m"
+	BOLD             = "\x1b[1m"
+	DIM              = "\x1b[2m"
+	REVERSE          = "\x1b[7m"
+)
+
+// Keys other than plain bytes
+const (
+	KEY_ESCAPE = iota + 256
+	KEY_UP
+	KEY_DOWN
+	KEY_LEFT
+	KEY_RIGHT
+)
+
+const (
+	KEY_CTRL_C = 3
+	KEY_ENTER  = '\r'
+)
+
+const (
+	BOARD_COLUMN_WIDTH = 34
+	AI_MOVE_DELAY      = 400 * time.Millisecond // So the audience can follow the AI's moves
+	TUI_HELP           = "←↑↓→ select  Enter/Space play  1-9 play  u undo  q quit"
+)
+
+// 256-color backgrounds for the policy heatmap, from 0% to 100%
+var HEAT_COLORS = []int{236, 52, 88, 124, 160, 196}
+
+// 256-color backgrounds for minimax values
+var VALUE_COLORS = map[int]int{1: 28, 0: 136, -1: 124}
+var VALUE_LABELS = map[int]string{1: "win", 0: "draw", -1: "loss"}
+
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[a-zA-Z]`)
+
+// Full-screen terminal UI for a local game. The side panel shows the neural network's policy and the minimax
+// value of each square for the player to move.
+type tui struct {
+	in        *bufio.Reader
+	game      *localGame
+	network   *ai.Network // Nil if no network could be loaded
+	noNetwork string      // Why network is nil
+	cursor    uint8       // Selected square, 0-8
+	status    string
+}
+
+// Plays the game in the full-screen UI, then prints the result in the normal terminal
+func playFullScreen(game *localGame, network *ai.Network, noNetwork string) error {
+	finished, err := runFullScreen(game, network, noNetwork)
+	if err != nil || !finished {
+		return err
+	}
+	fmt.Println("Game over!", describeResult(game.state, game.names()))
+	return nil
+}
+
+func runFullScreen(game *localGame, network *ai.Network, noNetwork string) (bool, error) {
+	restore, err := enableRawMode(os.Stdin)
+	if err != nil {
+		return false, err
+	}
+	fmt.Print(ENTER_ALT_SCREEN + HIDE_CURSOR)
+	defer func() {
+		fmt.Print(SHOW_CURSOR + LEAVE_ALT_SCREEN)
+		restore()
+	}()
+
+	t := &tui{
+		in:        bufio.NewReader(os.Stdin),
+		game:      game,
+		network:   network,
+		noNetwork: noNetwork,
+		cursor:    4,
+	}
+	for _, p := range game.players {
+		if h, ok := p.(*humanPlayer); ok {
+			h.prompt = t.readMove
+		}
+	}
+
+	finished, err := t.run()
+	if err != nil || !finished {
+		return finished, err
+	}
+
+	t.status = fmt.Sprintf("Game over! %s Press r to replay the game or any other key to continue.",
+		describeResult(game.state, game.names()))
+	t.draw(game.state, len(game.moves), false)
+	key, err := t.readKey()
+	if err != nil {
+		return true, err
+	}
+	if key == 'r' {
+		return true, t.review()
+	}
+	return true, nil
+}
+
+// Runs the game until it ends or a human quits. Returns false if the game was abandoned.
+func (t *tui) run() (bool, error) {
+	g := t.game
+	for !g.state.IsTerminal() {
+		playerId := g.state.GetCurrentPlayerId()
+		current := g.players[playerId]
+		if !current.isHuman() {
+			t.status = current.name() + " is thinking..."
+			t.draw(g.state, len(g.moves), false)
+			time.Sleep(AI_MOVE_DELAY)
+		}
+
+		position, err := current.move(g.state)
+		switch {
+		case err == errQuit:
+			return false, nil
+		case err == errUndo:
+			undone, err := g.undo()
+			if err != nil {
+				return false, err
+			}
+			t.status = "Took back your last move"
+			if !undone {
+				t.status = "Nothing to undo"
+			}
+			continue
+		case err != nil:
+			return false, err
+		}
+
+		if err := g.play(position); err != nil {
+			t.status = err.Error()
+			continue
+		}
+		t.status = fmt.Sprintf("%s: %s", current.name(), describeMove(g.state, playerId, position))
+	}
+	return true, nil
+}
+
+// Reads keys until the player to move picks an available square. Returns errUndo or errQuit for those commands.
+func (t *tui) readMove(gameState *engine.GameState) (uint8, error) {
+	for {
+		t.draw(gameState, len(t.game.moves), true)
+		key, err := t.readKey()
+		if err != nil {
+			return 0, err
+		}
+
+		switch {
+		case key == KEY_UP && t.cursor >= 3:
+			t.cursor -= 3
+		case key == KEY_DOWN && t.cursor < 6:
+			t.cursor += 3
+		case key == KEY_LEFT && t.cursor%3 > 0:
+			t.cursor--
+		case key == KEY_RIGHT && t.cursor%3 < 2:
+			t.cursor++
+		case key >= '1' && key <= '9':
+			t.cursor = uint8(key - '1')
+			fallthrough
+		case key == KEY_ENTER || key == '\n' || key == ' ':
+			if gameState.Board.AvailableMoves()&(1<<t.cursor) == 0 {
+				t.status = "That square is taken"
+				continue
+			}
+			return t.cursor + 1, nil
+		case key == 'u':
+			return 0, errUndo
+		case key == 'q' || key == KEY_CTRL_C:
+			return 0, errQuit
+		}
+	}
+}
+
+// Steps through the finished game with the arrow keys
+func (t *tui) review() error {
+	g := t.game
+	shown := len(g.moves)
+	for {
+		state, err := replayMoves(g.options, g.moves[:shown])
+		if err != nil {
+			return err
+		}
+		t.status = fmt.Sprintf("Replay: move %d of %d. ←→ step through the game, q to leave.", shown, len(g.moves))
+		t.draw(state, shown, false)
+
+		key, err := t.readKey()
+		if err != nil {
+			return err
+		}
+		switch key {
+		case KEY_LEFT, KEY_UP:
+			shown = max(shown-1, 0)
+		case KEY_RIGHT, KEY_DOWN, ' ':
+			shown = min(shown+1, len(g.moves))
+		case 'q', KEY_ESCAPE, KEY_ENTER, KEY_CTRL_C:
+			return nil
+		}
+	}
+}
+
+// Reads a key press, decoding arrow key escape sequences
+func (t *tui) readKey() (int, error) {
+	b, err := t.in.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if b != 0x1b {
+		return int(b), nil
+	}
+
+	// An escape sequence arrives in one read, so a lone escape has nothing buffered after it
+	if t.in.Buffered() < 2 {
+		return KEY_ESCAPE, nil
+	}
+	seq := make([]byte, 2)
+	if _, err := t.in.Read(seq); err != nil {
+		return 0, err
+	}
+	if seq[0] != '[' && seq[0] != 'O' {
+		return KEY_ESCAPE, nil
+	}
+	switch seq[1] {
+	case 'A':
+		return KEY_UP, nil
+	case 'B':
+		return KEY_DOWN, nil
+	case 'C':
+		return KEY_RIGHT, nil
+	case 'D':
+		return KEY_LEFT, nil
+	default:
+		return KEY_ESCAPE, nil
+	}
+}
+
+// Redraws the screen for a position reached after the first played moves of the game
+func (t *tui) draw(state *engine.GameState, played int, showCursor bool) {
+	left := []string{BOLD + "t³ — Tic-Tac-Toe" + RESET, ""}
+	left = append(left, t.renderBoard(state, showCursor)...)
+	left = append(left, "")
+	left = append(left, t.renderHistory(played)...)
+
+	right := []string{"", ""}
+	right = append(right, t.renderPolicy(state)...)
+	right = append(right, "")
+	right = append(right, renderMinimax(state)...)
+
+	var b strings.Builder
+	b.WriteString(CLEAR_SCREEN)
+	for i := range max(len(left), len(right)) {
+		line := ""
+		if i < len(left) {
+			line = left[i]
+		}
+		if i < len(right) {
+			line = padRight(line, BOARD_COLUMN_WIDTH) + right[i]
+		}
+		b.WriteString(line + "\r\n")
+	}
+	b.WriteString("\r\n" + t.status + "\r\n")
+	if showCursor {
+		b.WriteString(DIM + TUI_HELP + RESET + "\r\n")
+	}
+	os.Stdout.WriteString(b.String())
+}
+
+// Renders the board with the cursor on the selected square
+func (t *tui) renderBoard(state *engine.GameState, showCursor bool) []string {
+	cells := state.GetBoardAsBytes()
+	lines := []string{
+		"      a     b     c",
+		"   ┌─────┬─────┬─────┐",
+	}
+	for row := range 3 {
+		line := fmt.Sprintf(" %d │", row+1)
+		for col := range 3 {
+			i := row*3 + col
+			cell := fmt.Sprintf("  %s  ", styledPiece(cells[i]))
+			if cells[i] == '_' {
+				cell = fmt.Sprintf("  %s%d%s  ", DIM, i+1, RESET)
+			}
+			if showCursor && uint8(i) == t.cursor {
+				cell = REVERSE + ansiPattern.ReplaceAllString(cell, "") + RESET
+			}
+			line += cell + "│"
+		}
+		lines = append(lines, line)
+		if row < 2 {
+			lines = append(lines, "   ├─────┼─────┼─────┤")
+		}
+	}
+	return append(lines, "   └─────┴─────┴─────┘")
+}
+
+func (t *tui) renderHistory(played int) []string {
+	g := t.game
+	lines := []string{BOLD + "Moves" + RESET}
+	if len(g.moves) == 0 {
+		return append(lines, DIM+"none yet"+RESET)
+	}
+	for i, m := range g.moves {
+		line := fmt.Sprintf("%d. %s: %s", i+1, g.players[m.playerId].name(), describeMove(g.state, m.playerId, m.position))
+		if i >= played {
+			line = DIM + line + RESET
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// Renders the network's softmax output for the player to move as a heatmap, marking its best legal move
+func (t *tui) renderPolicy(state *engine.GameState) []string {
+	lines := []string{BOLD + "Neural network policy" + RESET}
+	switch {
+	case t.network == nil:
+		return append(lines, DIM+"No network: "+t.noNetwork+RESET)
+	case state.IsTerminal():
+		return append(lines, DIM+"Game over"+RESET)
+	}
+
+	trace := &ai.ForwardTrace{}
+	probs, err := t.network.Forward(state.GetBoardAsNetworkInputFor(state.GetCurrentPlayerId()), trace)
+	if err != nil {
+		return append(lines, DIM+err.Error()+RESET)
+	}
+	available := state.Board.AvailableMoves()
+	best := bestLegalMove(probs, available)
+
+	cells := state.GetBoardAsBytes()
+	lines = append(lines, gridHeader())
+	for row := range 3 {
+		line := fmt.Sprintf(" %d ", row+1)
+		for col := range 3 {
+			i := row*3 + col
+			if available&(1<<i) == 0 {
+				line += takenCell(cells[i])
+				continue
+			}
+			mark := " "
+			if uint8(i+1) == best {
+				mark = "*"
+			}
+			color := HEAT_COLORS[min(int(probs[i]*float64(len(HEAT_COLORS)-1)+0.5), len(HEAT_COLORS)-1)]
+			line += fmt.Sprintf("\x1b[48;5;%dm\x1b[97m %3.0f%%%s%s ", color, probs[i]*100, mark, RESET)
+		}
+		lines = append(lines, line)
+	}
+
+	// Share of hidden neurons that fired for this position
+	active := []string{}
+	for _, outputs := range trace.LayerOutputs[1 : len(trace.LayerOutputs)-1] {
+		count := 0
+		for _, a := range outputs {
+			if a > 0 {
+				count++
+			}
+		}
+		active = append(active, fmt.Sprintf("%d/%d", count, len(outputs)))
+	}
+	return append(lines, DIM+"Active hidden neurons: "+strings.Join(active, " · ")+RESET)
+}
+
+// Renders the minimax value of each available square for the player to move, marking ai.BestMove's choice
+func renderMinimax(state *engine.GameState) []string {
+	lines := []string{BOLD + "Minimax evaluation" + RESET}
+	if state.IsTerminal() {
+		return append(lines, DIM+"Game over"+RESET)
+	}
+
+	playerId := state.GetCurrentPlayerId()
+	values := ai.MoveValuesFor(state.Board, playerId)
+	best := ai.BestMoveFor(state.Board, playerId)
+
+	cells := state.GetBoardAsBytes()
+	lines = append(lines, gridHeader())
+	for row := range 3 {
+		line := fmt.Sprintf(" %d ", row+1)
+		for col := range 3 {
+			i := row*3 + col
+			value, ok := values[uint8(i+1)]
+			if !ok {
+				line += takenCell(cells[i])
+				continue
+			}
+			mark := " "
+			if uint8(i+1) == best {
+				mark = "*"
+			}
+			line += fmt.Sprintf("\x1b[48;5;%dm\x1b[97m %-4s%s%s ", VALUE_COLORS[value], VALUE_LABELS[value], mark, RESET)
+		}
+		lines = append(lines, line)
+	}
+	return append(lines, DIM+fmt.Sprintf("For %c with perfect play, * is the best move", pieceOf(state, playerId))+RESET)
+}
+
+func gridHeader() string {
+	return "     a      b      c"
+}
+
+func takenCell(piece byte) string {
+	return fmt.Sprintf("\x1b[48;5;236m  %c   %s ", piece, RESET)
+}
+
+func styledPiece(piece byte) string {
+	switch piece {
+	case engine.PIECE_X:
+		return fmt.Sprintf("%s\x1b[96m%c%s", BOLD, piece, RESET)
+	case engine.PIECE_O:
+		return fmt.Sprintf("%s\x1b[95m%c%s", BOLD, piece, RESET)
+	default:
+		return string(piece)
+	}
+}
+
+// Pads s with spaces to the given width, not counting escape sequences
+func padRight(s string, width int) string {
+	visible := utf8.RuneCountInString(ansiPattern.ReplaceAllString(s, ""))
+	if visible >= width {
+		return s
+	}
+	return s + strings.Repeat(" ", width-visible)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"t-cubed/internal/service"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+)
+
+// Rewrites trace_cache rows stored with an older trace schema to the current version.
+// Run after `goose -env .env.db up` has added the schema_version column.
+func main() {
+	if err := godotenv.Load(); err != nil {
+		slog.Warn("Could not load .env file", "error", err)
+	}
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		fmt.Fprintln(os.Stderr, "No DATABASE_URL environment variable found")
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, databaseURL)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not connect to database:", err)
+		os.Exit(1)
+	}
+	defer pool.Close()
+
+	migrated, err := service.MigrateTraces(ctx, pool)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Migration stopped after %d traces: %s\n", migrated, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Migrated %d traces to schema version %d\n", migrated, service.TRACE_SCHEMA_VERSION)
+}
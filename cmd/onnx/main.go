@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"t-cubed/internal/ai"
+)
+
+const (
+	MSG_USAGE = "Usage: onnx export <weights.json> <model.onnx>\n       onnx import <model.onnx> <weights.json>"
+)
+
+// Converts neural network weights between the JSON format served by the API and ONNX
+func main() {
+	if len(os.Args) != 4 {
+		fmt.Fprintln(os.Stderr, MSG_USAGE)
+		os.Exit(1)
+	}
+	command, from, to := os.Args[1], os.Args[2], os.Args[3]
+
+	var err error
+	switch command {
+	case "export":
+		err = exportONNX(from, to)
+	case "import":
+		err = importONNX(from, to)
+	default:
+		fmt.Fprintln(os.Stderr, MSG_USAGE)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "FAIL", err)
+		os.Exit(1)
+	}
+}
+
+func exportONNX(weightsPath, onnxPath string) error {
+	network, err := ai.LoadNetwork(weightsPath)
+	if err != nil {
+		return err
+	}
+	if err := ai.SaveONNX(onnxPath, network); err != nil {
+		return err
+	}
+	fmt.Printf("OK   %s -> %s\n", weightsPath, onnxPath)
+	return nil
+}
+
+// Imports an ONNX model and checks that it can be served before writing the weights
+func importONNX(onnxPath, weightsPath string) error {
+	network, err := ai.LoadONNX(onnxPath)
+	if err != nil {
+		return err
+	}
+	if err := network.ValidateForGame(); err != nil {
+		return fmt.Errorf("%s: %w", onnxPath, err)
+	}
+	if err := ai.SaveNetwork(weightsPath, network); err != nil {
+		return err
+	}
+	fmt.Printf("OK   %s -> %s\n\tchecksum: %s\n", onnxPath, weightsPath, network.Checksum())
+	return nil
+}
@@ -2,15 +2,19 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"math"
 	"math/rand"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	"t-cubed/internal/ai"
 	"t-cubed/internal/engine"
@@ -28,8 +32,10 @@ func main() {
 	fmt.Println("Choose an option:")
 	fmt.Println("\t1. ✨ Generate training data")
 	fmt.Println("\t2. 🧠 Train a neural network")
-	fmt.Println("\t3. 🧪 Test a neural network")
-	fmt.Println("\t4. 🚪 Exit")
+	fmt.Println("\t3. ♻️  Self-play train a neural network")
+	fmt.Println("\t4. 🔬 Sweep training hyperparameters")
+	fmt.Println("\t5. 🧪 Test a neural network")
+	fmt.Println("\t6. 🚪 Exit")
 
 	scnr := bufio.NewScanner(os.Stdin)
 	for {
@@ -46,8 +52,12 @@ func main() {
 		case 2:
 			trainNeuralNetwork()
 		case 3:
-			testNeuralNetwork()
+			selfPlayNeuralNetwork()
 		case 4:
+			sweepHyperparameters()
+		case 5:
+			testNeuralNetwork()
+		case 6:
 			fmt.Println("Exiting...")
 			return
 		default:
@@ -90,7 +100,10 @@ func generateTrainingData() {
 		break
 	}
 
-	fmt.Printf("%d examples will be saved to %s\n", numExamples, outDir)
+	seed := readSeed(scnr)
+	rng := rand.New(rand.NewSource(seed))
+
+	fmt.Printf("%d examples will be saved to %s (seed %d)\n", numExamples, outDir, seed)
 
 	exampleHashes := make(map[string]bool)
 	breakThreshold := 90.0
@@ -110,7 +123,7 @@ func generateTrainingData() {
 			breakThreshold -= breakThresholdDecay * (float64(i) / float64(numExamples))
 		}
 
-		example, movesPlayed, err := createExample(int(math.Round(breakThreshold)), 0)
+		example, movesPlayed, err := createExample(rng, int(math.Round(breakThreshold)), 0)
 		if err != nil {
 			fmt.Println("Failed to generate example:", err)
 			break
@@ -146,13 +159,13 @@ func generateTrainingData() {
 // Note: the AI package expects Player 2 to be the AI player.
 // 	breakThresdhold: Requires range [0, 100], where a higher value means a higher chance of breaking early in the game.
 // 	iterations: Used to prevent stack overflow. If maxIterations is reached, an error is returned.
-func createExample(breakThreshold int, iterations int) (ai.TrainingExample, int, error) {
+func createExample(rng *rand.Rand, breakThreshold int, iterations int) (ai.TrainingExample, int, error) {
 	maxIterations := 100
 	maxRandNum := 100
 	outputLen := 9
 
 	AIPlayerId := uint8(2)
-	firstPlayerId := uint8(rand.Intn(2) + 1)
+	firstPlayerId := uint8(rng.Intn(2) + 1)
 
 	movesPlayed := 0
 
@@ -170,25 +183,28 @@ func createExample(breakThreshold int, iterations int) (ai.TrainingExample, int,
 	for {
 		// Probabilistically break to generate different depths of game states
 		if movesPlayed > 0 && gameState.GetCurrentPlayerId() == AIPlayerId {
-			randNum := rand.Intn(maxRandNum)
+			randNum := rng.Intn(maxRandNum)
 			// If below threshold, return game state with AI's best move
 			if randNum < breakThreshold {
 				input := gameState.GetBoardAsNetworkInput()
 				// Output vector is a vector of zeros except for 1 at the best move index
-				bestMove := ai.BestMove(gameState.Board)
+				bestMove, bestValue := ai.BestMoveWithValue(gameState.Board)
 				output := make([]float64, outputLen)
 				output[bestMove-1] = 1
+				// Expected outcome for the AI player, used by networks with a value head
+				value := float64(bestValue)
 
 				return ai.TrainingExample{
 					Input:  input,
 					Target: output,
+					Value:  &value,
 				}, movesPlayed, nil
 			}
 		}
 
 		// Try to play a random move until it works
 		for {
-			nextMove := uint8(rand.Intn(9) + 1)
+			nextMove := uint8(rng.Intn(9) + 1)
 			ok, _ := gameState.Move(nextMove)
 			if ok {
 				break
@@ -205,7 +221,7 @@ func createExample(breakThreshold int, iterations int) (ai.TrainingExample, int,
 			if breakThreshold < maxRandNum - 1 {
 				breakThreshold += 1
 			}
-			return createExample(breakThreshold, iterations+1)
+			return createExample(rng, breakThreshold, iterations+1)
 		}
 		movesPlayed++
 	}
@@ -219,39 +235,165 @@ func trainNeuralNetwork() {
 	scnr.Scan()
 	outDir := filepath.Clean(scnr.Text())
 
-	fmt.Println("Creating neural network...")
+	fmt.Print("Resume from checkpoint (leave blank to start a new network): ")
+	scnr.Scan()
+	resumeFrom := strings.TrimSpace(scnr.Text())
+
+	var network *ai.Network
+	var err error
+	var seed int64
+	if resumeFrom != "" {
+		// The checkpoint's network replaces this one when training starts
+		network = &ai.Network{}
+	} else {
+		fmt.Print("Add a value head for position evaluation? (y/N): ")
+		scnr.Scan()
+		withValueHead := strings.EqualFold(strings.TrimSpace(scnr.Text()), "y")
+		seed = readSeed(scnr)
+
+		fmt.Printf("Creating neural network (seed %d)...\n", seed)
+
+		if withValueHead {
+			network, err = ai.NewSeededPolicyValueNetwork(seed, []int{18, 32, 32}, []int{32, 9}, []int{16, 1})
+		} else {
+			network, err = ai.NewSeededNetwork(seed, 18, 32, 32, 32, 9)
+		}
+		if err != nil {
+			fmt.Println("Failed to create network:", err)
+			return
+		}
+	}
+
+	fmt.Println("Training neural network... (Ctrl+C saves a checkpoint and stops)")
 
-	network, err := ai.NewNetwork(18, 32, 32, 32, 9)
+	trainingConfig := ai.TrainingConfig{
+		LearningRate:       0.0001,
+		CostThreshold:      0.1,
+		Epochs:             10_000,
+		BatchSize:          10,
+		ExamplesDir:        outDir,
+		ValueWeight:        1,
+		ValidationSplit:    0.1,
+		MetricsFile:        "metrics.csv",
+		CheckpointDir:      "checkpoints",
+		CheckpointInterval: 500,
+		ResumeFrom:         resumeFrom,
+		Seed:               seed, // Ignored when resuming, the checkpoint holds the RNG state
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err = network.TrainContext(ctx, &trainingConfig)
+	if errors.Is(err, context.Canceled) {
+		fmt.Printf("\nTraining interrupted, checkpoint saved to %s\n", trainingConfig.CheckpointDir)
+		return
+	}
+	if err != nil {
+		fmt.Println("Failed to train network:", err)
+		return
+	}
+
+	ai.SaveNetwork(filepath.Join(savedName), network)
+
+	fmt.Printf("\n🎉 All done! Weights written to %s and metrics to %s", savedName, trainingConfig.MetricsFile)
+	fmt.Println("   Go forth and let the AI play Tic-Tac-Toe 🧠🤖")
+}
+
+func selfPlayNeuralNetwork() {
+	savedName := "weights.json"
+	scnr := bufio.NewScanner(os.Stdin)
+
+	fmt.Print("Enter starting weights file (leave blank for a new network): ")
+	scnr.Scan()
+	startPath := scnr.Text()
+
+	seed := readSeed(scnr)
+
+	var network *ai.Network
+	var err error
+	if startPath == "" {
+		fmt.Printf("Creating neural network (seed %d)...\n", seed)
+		network, err = ai.NewSeededNetwork(seed, 18, 32, 32, 32, 9)
+	} else {
+		network, err = ai.LoadGameNetwork(startPath)
+	}
 	if err != nil {
 		fmt.Println("Failed to create network:", err)
 		return
 	}
 
-	fmt.Println("Training neural network...")
+	var episodes int
+	for {
+		fmt.Print("Enter number of self-play games: ")
+		scnr.Scan()
+		episodes, err = strconv.Atoi(scnr.Text())
+		if err != nil || episodes < 1 {
+			fmt.Println("Invalid number")
+			continue
+		}
+		break
+	}
 
-	trainingConfig := ai.TrainingConfig{
-		LearningRate:  0.0001,
-		CostThreshold: 0.1,
-		Epochs:        10_000,
-		BatchSize:     10,
-		ExamplesDir:   outDir,
+	fmt.Println("Training neural network with self-play...")
+
+	selfPlayConfig := ai.SelfPlayConfig{
+		LearningRate:     0.001,
+		Episodes:         episodes,
+		BatchSize:        32,
+		Discount:         0.9,
+		PoolSize:         10,
+		SnapshotInterval: 1_000,
+		PoolRatio:        0.5,
+		EvalInterval:     1_000,
+		EvalGames:        100,
+		Seed:             seed,
 	}
 
-	err = network.Train(&trainingConfig)
+	err = network.SelfPlay(&selfPlayConfig)
 	if err != nil {
 		fmt.Println("Failed to train network:", err)
 		return
 	}
 
+	result, err := network.EvaluateAgainstMinimax(selfPlayConfig.EvalGames)
+	if err != nil {
+		fmt.Println("Failed to evaluate network:", err)
+		return
+	}
+	fmt.Printf("Against minimax: %d wins, %d draws, %d losses\n", result.Wins, result.Draws, result.Losses)
+
 	ai.SaveNetwork(filepath.Join(savedName), network)
 
 	fmt.Printf("\n🎉 All done! Weights written to %s", savedName)
 	fmt.Println("   Go forth and let the AI play Tic-Tac-Toe 🧠🤖")
 }
 
+// Asks for a seed so runs can be reproduced. A blank answer picks a random seed.
+func readSeed(scnr *bufio.Scanner) int64 {
+	for {
+		fmt.Print("Enter random seed (leave blank for a random seed): ")
+		scnr.Scan()
+		text := strings.TrimSpace(scnr.Text())
+		if text == "" {
+			seed := rand.Int63()
+			for seed == 0 {
+				seed = rand.Int63()
+			}
+			return seed
+		}
+		seed, err := strconv.ParseInt(text, 10, 64)
+		if err != nil || seed == 0 {
+			fmt.Println("Seed must be a non-zero integer")
+			continue
+		}
+		return seed
+	}
+}
+
 func testNeuralNetwork() {
 	savedName := "data/weights.json"
-	network, err := ai.LoadNetwork(savedName)
+	network, err := ai.LoadGameNetwork(savedName)
 	if err != nil {
 		fmt.Println("Failed to load network:", err)
 		return
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"t-cubed/internal/ai"
+	"t-cubed/internal/engine"
+)
+
+const (
+	SWEEP_GRID   = "grid"
+	SWEEP_RANDOM = "random"
+)
+
+// Search space and settings for a hyperparameter sweep, read from a JSON file
+type sweepSpec struct {
+	ExamplesDir     string    `json:"examplesDir"`
+	OutDir          string    `json:"outDir"`
+	Search          string    `json:"search"`  // SWEEP_GRID tries every combination, SWEEP_RANDOM samples Trials of them
+	Trials          int       `json:"trials"`  // Random search only
+	Workers         int       `json:"workers"` // Trials trained at the same time, 0 uses the number of CPUs
+	Seed            int64     `json:"seed"`    // Seeds random search, weights and validation splits, 0 picks a random seed
+	Epochs          int       `json:"epochs"`
+	ValidationSplit float64   `json:"validationSplit"`
+	HiddenLayers    [][]int   `json:"hiddenLayers"` // Hidden layer sizes between the 18 inputs and 9 outputs
+	LearningRates   []float64 `json:"learningRates"`
+	BatchSizes      []int     `json:"batchSizes"`
+	Optimizers      []string  `json:"optimizers"`
+	WeightDecays    []float64 `json:"weightDecays"`
+}
+
+// One combination of hyperparameters and, once trained, its results
+type sweepTrial struct {
+	Id           int     `json:"id"`
+	HiddenLayers []int   `json:"hiddenLayers"`
+	LearningRate float64 `json:"learningRate"`
+	BatchSize    int     `json:"batchSize"`
+	Optimizer    string  `json:"optimizer"`
+	WeightDecay  float64 `json:"weightDecay"`
+
+	ValidationLoss     float64 `json:"validationLoss"`
+	ValidationAccuracy float64 `json:"validationAccuracy"`
+	MinimaxAgreement   float64 `json:"minimaxAgreement"`
+	Error              string  `json:"error,omitempty"`
+
+	network *ai.Network
+}
+
+func sweepHyperparameters() {
+	scnr := bufio.NewScanner(os.Stdin)
+
+	fmt.Print("Enter sweep spec file: ")
+	scnr.Scan()
+	specPath := filepath.Clean(strings.TrimSpace(scnr.Text()))
+
+	spec, err := loadSweepSpec(specPath)
+	if err != nil {
+		fmt.Println("Failed to load sweep spec:", err)
+		return
+	}
+	trials := spec.trials()
+	if err := os.MkdirAll(spec.OutDir, 0755); err != nil {
+		fmt.Println("Failed to create output directory:", err)
+		return
+	}
+
+	fmt.Printf("Running %d trials with %d workers (seed %d)...\n", len(trials), spec.Workers, spec.Seed)
+
+	// Per-epoch logs from parallel trials would interleave, so only warnings are shown during the sweep
+	logger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})))
+	runSweep(spec, trials)
+	slog.SetDefault(logger)
+
+	rankTrials(trials)
+	leaderboardPath := filepath.Join(spec.OutDir, "leaderboard.csv")
+	if err := writeLeaderboard(leaderboardPath, trials); err != nil {
+		fmt.Println("Failed to write leaderboard:", err)
+		return
+	}
+
+	best := trials[0]
+	if best.Error != "" {
+		fmt.Println("All trials failed, see", leaderboardPath)
+		return
+	}
+	bestPath := filepath.Join(spec.OutDir, "best_weights.json")
+	if err := ai.SaveNetwork(bestPath, best.network); err != nil {
+		fmt.Println("Failed to save best weights:", err)
+		return
+	}
+
+	fmt.Printf("\n🏆 Best trial %d: hidden %v, learning rate %g, batch size %d, %s, weight decay %g\n",
+		best.Id, best.HiddenLayers, best.LearningRate, best.BatchSize, best.Optimizer, best.WeightDecay)
+	fmt.Printf("   Validation loss %.4f, minimax agreement %.1f%%\n", best.ValidationLoss, best.MinimaxAgreement*100)
+	fmt.Printf("   Leaderboard written to %s and weights to %s\n", leaderboardPath, bestPath)
+}
+
+// Loads a sweep spec and fills in defaults
+func loadSweepSpec(fpath string) (*sweepSpec, error) {
+	data, err := os.ReadFile(fpath)
+	if err != nil {
+		return nil, err
+	}
+	spec := &sweepSpec{}
+	if err := json.Unmarshal(data, spec); err != nil {
+		return nil, err
+	}
+
+	if spec.ExamplesDir == "" {
+		return nil, errors.New("examplesDir is required")
+	}
+	if spec.OutDir == "" {
+		spec.OutDir = "sweep"
+	}
+	if spec.Search == "" {
+		spec.Search = SWEEP_GRID
+	}
+	if spec.Search != SWEEP_GRID && spec.Search != SWEEP_RANDOM {
+		return nil, fmt.Errorf("unknown search %q", spec.Search)
+	}
+	if spec.Search == SWEEP_RANDOM && spec.Trials < 1 {
+		return nil, errors.New("random search needs at least 1 trial")
+	}
+	for spec.Seed == 0 {
+		spec.Seed = rand.Int63()
+	}
+	if spec.Workers < 1 {
+		spec.Workers = runtime.NumCPU()
+	}
+	if spec.Epochs < 1 {
+		spec.Epochs = 100
+	}
+	if spec.ValidationSplit <= 0 || spec.ValidationSplit >= 1 {
+		spec.ValidationSplit = 0.2
+	}
+	if len(spec.HiddenLayers) == 0 {
+		spec.HiddenLayers = [][]int{{32, 32, 32}}
+	}
+	if len(spec.LearningRates) == 0 {
+		spec.LearningRates = []float64{0.0001}
+	}
+	if len(spec.BatchSizes) == 0 {
+		spec.BatchSizes = []int{10}
+	}
+	if len(spec.Optimizers) == 0 {
+		spec.Optimizers = []string{ai.OPTIMIZER_SGD}
+	}
+	if len(spec.WeightDecays) == 0 {
+		spec.WeightDecays = []float64{0}
+	}
+	return spec, nil
+}
+
+// Returns every combination for grid search, or Trials random combinations
+func (s *sweepSpec) trials() []*sweepTrial {
+	trials := []*sweepTrial{}
+	add := func(hidden []int, learningRate float64, batchSize int, optimizer string, weightDecay float64) {
+		trials = append(trials, &sweepTrial{
+			Id:           len(trials),
+			HiddenLayers: hidden,
+			LearningRate: learningRate,
+			BatchSize:    batchSize,
+			Optimizer:    optimizer,
+			WeightDecay:  weightDecay,
+		})
+	}
+
+	if s.Search == SWEEP_RANDOM {
+		rng := rand.New(rand.NewSource(s.Seed))
+		for range s.Trials {
+			add(
+				s.HiddenLayers[rng.Intn(len(s.HiddenLayers))],
+				s.LearningRates[rng.Intn(len(s.LearningRates))],
+				s.BatchSizes[rng.Intn(len(s.BatchSizes))],
+				s.Optimizers[rng.Intn(len(s.Optimizers))],
+				s.WeightDecays[rng.Intn(len(s.WeightDecays))],
+			)
+		}
+		return trials
+	}
+
+	for _, hidden := range s.HiddenLayers {
+		for _, learningRate := range s.LearningRates {
+			for _, batchSize := range s.BatchSizes {
+				for _, optimizer := range s.Optimizers {
+					for _, weightDecay := range s.WeightDecays {
+						add(hidden, learningRate, batchSize, optimizer, weightDecay)
+					}
+				}
+			}
+		}
+	}
+	return trials
+}
+
+// Trains the trials with at most spec.Workers running at once
+func runSweep(spec *sweepSpec, trials []*sweepTrial) {
+	queue := make(chan *sweepTrial)
+	var wg sync.WaitGroup
+	for range spec.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for trial := range queue {
+				if err := runTrial(spec, trial); err != nil {
+					trial.Error = err.Error()
+					fmt.Printf("\tTrial %d failed: %v\n", trial.Id, err)
+					continue
+				}
+				fmt.Printf("\tTrial %d: validation loss %.4f, minimax agreement %.1f%%\n",
+					trial.Id, trial.ValidationLoss, trial.MinimaxAgreement*100)
+			}
+		}()
+	}
+	for _, trial := range trials {
+		queue <- trial
+	}
+	close(queue)
+	wg.Wait()
+}
+
+func runTrial(spec *sweepSpec, trial *sweepTrial) error {
+	sizes := append([]int{engine.NETWORK_INPUT_LEN}, trial.HiddenLayers...)
+	sizes = append(sizes, engine.NETWORK_OUTPUT_LEN)
+	// Every trial shares the seed, so trials are compared on the same validation examples
+	network, err := ai.NewSeededNetwork(spec.Seed, sizes...)
+	if err != nil {
+		return err
+	}
+
+	var last *ai.EpochMetrics
+	trainingConfig := ai.TrainingConfig{
+		LearningRate:    trial.LearningRate,
+		Epochs:          spec.Epochs,
+		BatchSize:       trial.BatchSize,
+		ExamplesDir:     spec.ExamplesDir,
+		Optimizer:       trial.Optimizer,
+		WeightDecay:     trial.WeightDecay,
+		ValidationSplit: spec.ValidationSplit,
+		MetricsFile:     filepath.Join(spec.OutDir, fmt.Sprintf("trial_%03d_metrics.csv", trial.Id)),
+		OnEpoch:         func(m *ai.EpochMetrics) { last = m },
+		Seed:            spec.Seed,
+	}
+	if err := network.Train(&trainingConfig); err != nil {
+		return err
+	}
+	if last == nil || last.ValidationLoss == nil {
+		return errors.New("no validation metrics, add more examples")
+	}
+	if math.IsNaN(*last.ValidationLoss) {
+		return errors.New("training diverged")
+	}
+
+	trial.ValidationLoss = *last.ValidationLoss
+	trial.ValidationAccuracy = *last.ValidationAccuracy
+	trial.MinimaxAgreement, err = network.MinimaxAgreement()
+	if err != nil {
+		return err
+	}
+	trial.network = network
+	return nil
+}
+
+// Sorts trials by validation loss, breaking ties by minimax agreement. Failed trials go last.
+func rankTrials(trials []*sweepTrial) {
+	slices.SortStableFunc(trials, func(a, b *sweepTrial) int {
+		if (a.Error == "") != (b.Error == "") {
+			if a.Error == "" {
+				return -1
+			}
+			return 1
+		}
+		if c := cmp.Compare(a.ValidationLoss, b.ValidationLoss); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.MinimaxAgreement, a.MinimaxAgreement)
+	})
+}
+
+func writeLeaderboard(fpath string, trials []*sweepTrial) error {
+	file, err := os.Create(fpath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	w.Write([]string{
+		"rank", "trial", "hidden_layers", "learning_rate", "batch_size", "optimizer", "weight_decay",
+		"validation_loss", "validation_accuracy", "minimax_agreement", "error",
+	})
+	for rank, trial := range trials {
+		hidden := make([]string, len(trial.HiddenLayers))
+		for i, size := range trial.HiddenLayers {
+			hidden[i] = strconv.Itoa(size)
+		}
+		w.Write([]string{
+			strconv.Itoa(rank + 1),
+			strconv.Itoa(trial.Id),
+			strings.Join(hidden, "-"),
+			strconv.FormatFloat(trial.LearningRate, 'g', -1, 64),
+			strconv.Itoa(trial.BatchSize),
+			trial.Optimizer,
+			strconv.FormatFloat(trial.WeightDecay, 'g', -1, 64),
+			strconv.FormatFloat(trial.ValidationLoss, 'f', 6, 64),
+			strconv.FormatFloat(trial.ValidationAccuracy, 'f', 4, 64),
+			strconv.FormatFloat(trial.MinimaxAgreement, 'f', 4, 64),
+			trial.Error,
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"t-cubed/internal/ai"
+)
+
+const (
+	MSG_USAGE = "Usage: verify <weights.json> [<weights.json> ...]"
+)
+
+// Verifies neural network weight files offline and prints their shape and checksum
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, MSG_USAGE)
+		os.Exit(1)
+	}
+
+	failed := 0
+	for _, fpath := range os.Args[1:] {
+		network, err := ai.LoadGameNetwork(fpath)
+		if err != nil {
+			fmt.Printf("FAIL %s\n\t%s\n", fpath, err)
+			failed++
+			continue
+		}
+
+		shape := []string{fmt.Sprint(network.Layers[0].Input)}
+		for _, l := range network.Layers {
+			shape = append(shape, fmt.Sprint(l.Output))
+		}
+		fmt.Printf("OK   %s\n\tshape:    %s\n\tchecksum: %s\n", fpath, strings.Join(shape, " -> "), network.Checksum())
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "%d of %d weight files failed verification\n", failed, len(os.Args)-1)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,7 @@
+-- +goose Up
+-- Existing rows hold version 1 traces with fixed layer1-layer5 fields.
+-- They are rewritten to the current schema by `go run ./cmd/migratetraces`.
+ALTER TABLE trace_cache ADD COLUMN schema_version SMALLINT NOT NULL DEFAULT 1;
+
+-- +goose Down
+ALTER TABLE trace_cache DROP COLUMN IF EXISTS schema_version;
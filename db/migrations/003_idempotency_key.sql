@@ -0,0 +1,22 @@
+-- +goose Up
+-- Responses to POST requests sent with an Idempotency-Key header, so a retried request is answered with the
+-- original response instead of running again. Rows are deleted once they expire.
+CREATE TABLE idempotency_key (
+    key VARCHAR(255) NOT NULL,
+    -- Method and path of the request. A key is only replayed for the same route.
+    route VARCHAR(255) NOT NULL,
+    -- SHA256 of the request body, to reject a key that is reused for a different request
+    request_hash BYTEA NOT NULL,
+    -- 0 while the first request is still running
+    status_code SMALLINT NOT NULL DEFAULT 0,
+    response_headers JSONB,
+    response_body BYTEA,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (key, route)
+);
+
+CREATE INDEX idempotency_key_expires_at_idx ON idempotency_key (expires_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS idempotency_key;
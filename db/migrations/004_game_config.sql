@@ -0,0 +1,31 @@
+-- +goose Up
+-- The full configuration of a game, so the engine can reconstruct it without assumptions about who moves first
+ALTER TABLE game ADD COLUMN first_player_id SMALLINT NOT NULL DEFAULT 1 CHECK (first_player_id=1 OR first_player_id=2);
+ALTER TABLE game ADD COLUMN variant VARCHAR(32) NOT NULL DEFAULT 'standard';
+-- Checksum of the neural network that plays the game, empty for other game types
+ALTER TABLE game ADD COLUMN model_id VARCHAR(64) NOT NULL DEFAULT '';
+-- Clock for each player and the time added after each of their moves, 0 for untimed games
+ALTER TABLE game ADD COLUMN time_control_seconds INT NOT NULL DEFAULT 0 CHECK (time_control_seconds >= 0);
+ALTER TABLE game ADD COLUMN time_increment_seconds INT NOT NULL DEFAULT 0 CHECK (time_increment_seconds >= 0);
+
+-- The blank board event of existing games was saved for the player opposite the first player
+UPDATE game g
+SET first_player_id = CASE WHEN me.player_id = 1 THEN 2 ELSE 1 END
+FROM move_event me
+WHERE me.game_uuid = g.uuid AND me.move_sequence = 0;
+
+-- Games between humans have no AI player
+UPDATE game
+SET ai_player_id = 0
+WHERE game_type_id = (SELECT id FROM game_type WHERE label = 'humans');
+
+-- +goose Down
+UPDATE game
+SET ai_player_id = 2
+WHERE game_type_id = (SELECT id FROM game_type WHERE label = 'humans');
+
+ALTER TABLE game DROP COLUMN IF EXISTS time_increment_seconds;
+ALTER TABLE game DROP COLUMN IF EXISTS time_control_seconds;
+ALTER TABLE game DROP COLUMN IF EXISTS model_id;
+ALTER TABLE game DROP COLUMN IF EXISTS variant;
+ALTER TABLE game DROP COLUMN IF EXISTS first_player_id;
@@ -0,0 +1,36 @@
+-- +goose Up
+-- The square played (1-9), NULL for the blank board a game starts with
+ALTER TABLE move_event ADD COLUMN position SMALLINT CHECK (position BETWEEN 1 AND 9);
+-- When the server received the move, or started choosing it for AI moves
+ALTER TABLE move_event ADD COLUMN received_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP;
+-- How long the AI took to choose its move, NULL for human moves
+ALTER TABLE move_event ADD COLUMN think_time_ms INT CHECK (think_time_ms >= 0);
+
+UPDATE move_event SET received_at = created_at;
+
+-- The square played is the single cell occupied after the move but not before it
+WITH occupied AS (
+    SELECT
+        uuid,
+        ((get_byte(post_move_state, 0) << 8) | get_byte(post_move_state, 1))
+            | ((get_byte(post_move_state, 2) << 8) | get_byte(post_move_state, 3)) AS cells,
+        LAG(((get_byte(post_move_state, 0) << 8) | get_byte(post_move_state, 1))
+            | ((get_byte(post_move_state, 2) << 8) | get_byte(post_move_state, 3)))
+            OVER (PARTITION BY game_uuid ORDER BY move_sequence) AS previous_cells
+    FROM move_event
+),
+played AS (
+    SELECT o.uuid, cell.i + 1 AS position
+    FROM occupied o
+    JOIN generate_series(0, 8) AS cell(i) ON (o.cells & ~o.previous_cells) = (1 << cell.i)
+    WHERE o.previous_cells & ~o.cells = 0
+)
+UPDATE move_event me
+SET position = played.position
+FROM played
+WHERE me.uuid = played.uuid;
+
+-- +goose Down
+ALTER TABLE move_event DROP COLUMN IF EXISTS think_time_ms;
+ALTER TABLE move_event DROP COLUMN IF EXISTS received_at;
+ALTER TABLE move_event DROP COLUMN IF EXISTS position;
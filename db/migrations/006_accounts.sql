@@ -0,0 +1,43 @@
+-- +goose Up
+-- Player accounts. Passwords are stored as bcrypt hashes.
+CREATE TABLE account (
+    uuid UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    username VARCHAR(32) NOT NULL,
+    password_hash BYTEA NOT NULL
+);
+
+-- Usernames are unique regardless of case
+CREATE UNIQUE INDEX account_username_idx ON account (lower(username));
+
+-- Sessions of logged in accounts. Only the SHA256 of the session token is stored. Rows are deleted once they expire.
+CREATE TABLE account_session (
+    token_hash BYTEA PRIMARY KEY,
+    account_uuid UUID NOT NULL REFERENCES account(uuid) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX account_session_account_uuid_idx ON account_session (account_uuid);
+CREATE INDEX account_session_expires_at_idx ON account_session (expires_at);
+
+-- The account that created a game and the accounts seated as each player. Games created without an account have
+-- no owners, and anyone with their UUID can move in them.
+ALTER TABLE game ADD COLUMN creator_uuid UUID REFERENCES account(uuid) ON DELETE SET NULL;
+ALTER TABLE game ADD COLUMN player_1_uuid UUID REFERENCES account(uuid) ON DELETE SET NULL;
+ALTER TABLE game ADD COLUMN player_2_uuid UUID REFERENCES account(uuid) ON DELETE SET NULL;
+
+CREATE TRIGGER update_account_updated_at
+    BEFORE UPDATE ON account
+    FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS update_account_updated_at ON account;
+
+ALTER TABLE game DROP COLUMN IF EXISTS player_2_uuid;
+ALTER TABLE game DROP COLUMN IF EXISTS player_1_uuid;
+ALTER TABLE game DROP COLUMN IF EXISTS creator_uuid;
+
+DROP TABLE IF EXISTS account_session;
+DROP TABLE IF EXISTS account;
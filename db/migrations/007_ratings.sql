@@ -0,0 +1,49 @@
+-- +goose Up
+-- Glicko-2 ratings of accounts and AI opponents. An AI is identified by its game type and settings, e.g. "minimax" or
+-- "neural_network:<model checksum>", so each model version is rated on its own.
+CREATE TABLE rating (
+    uuid UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    account_uuid UUID UNIQUE REFERENCES account(uuid) ON DELETE CASCADE,
+    ai_identity VARCHAR(128) UNIQUE,
+    rating DOUBLE PRECISION NOT NULL DEFAULT 1500,
+    rating_deviation DOUBLE PRECISION NOT NULL DEFAULT 350,
+    volatility DOUBLE PRECISION NOT NULL DEFAULT 0.06,
+    games INT NOT NULL DEFAULT 0,
+    wins INT NOT NULL DEFAULT 0,
+    losses INT NOT NULL DEFAULT 0,
+    draws INT NOT NULL DEFAULT 0,
+    -- A rating belongs to either an account or an AI
+    CHECK ((account_uuid IS NULL) <> (ai_identity IS NULL))
+);
+
+CREATE INDEX rating_rating_idx ON rating (rating DESC);
+
+-- The rating of a player after each rated game
+CREATE TABLE rating_history (
+    uuid UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    rating_uuid UUID NOT NULL REFERENCES rating(uuid) ON DELETE CASCADE,
+    game_uuid UUID NOT NULL REFERENCES game(uuid) ON DELETE CASCADE,
+    opponent_rating_uuid UUID NOT NULL REFERENCES rating(uuid) ON DELETE CASCADE,
+    -- 1 for a win, 0.5 for a draw and 0 for a loss
+    score DOUBLE PRECISION NOT NULL CHECK (score IN (0, 0.5, 1)),
+    rating DOUBLE PRECISION NOT NULL,
+    rating_deviation DOUBLE PRECISION NOT NULL,
+    volatility DOUBLE PRECISION NOT NULL,
+    rating_change DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (rating_uuid, game_uuid)
+);
+
+CREATE INDEX rating_history_game_uuid_idx ON rating_history (game_uuid);
+
+CREATE TRIGGER update_rating_updated_at
+    BEFORE UPDATE ON rating
+    FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS update_rating_updated_at ON rating;
+
+DROP TABLE IF EXISTS rating_history;
+DROP TABLE IF EXISTS rating;
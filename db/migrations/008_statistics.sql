@@ -0,0 +1,36 @@
+-- +goose Up
+-- How often each square is played at each move number in finished games, for the square usage heatmap.
+-- The square played is the single cell occupied after a move but not before it. Refreshed periodically by the server.
+CREATE MATERIALIZED VIEW square_usage AS
+WITH occupied AS (
+    SELECT
+        me.game_uuid,
+        me.move_sequence,
+        me.player_id,
+        ((get_byte(me.post_move_state, 0) << 8) | get_byte(me.post_move_state, 1))
+            | ((get_byte(me.post_move_state, 2) << 8) | get_byte(me.post_move_state, 3)) AS cells,
+        LAG(((get_byte(me.post_move_state, 0) << 8) | get_byte(me.post_move_state, 1))
+            | ((get_byte(me.post_move_state, 2) << 8) | get_byte(me.post_move_state, 3)))
+            OVER (PARTITION BY me.game_uuid ORDER BY me.move_sequence) AS previous_cells
+    FROM move_event me
+    JOIN game g ON g.uuid = me.game_uuid
+    WHERE g.terminal_state <> 0
+)
+SELECT
+    gt.label AS game_type,
+    o.move_sequence AS move_number,
+    (cell.i + 1)::SMALLINT AS position,
+    o.player_id = g.ai_player_id AS ai_move,
+    count(*) AS moves
+FROM occupied o
+JOIN generate_series(0, 8) AS cell(i) ON (o.cells & ~o.previous_cells) = (1 << cell.i)
+JOIN game g ON g.uuid = o.game_uuid
+JOIN game_type gt ON gt.id = g.game_type_id
+WHERE o.previous_cells & ~o.cells = 0
+GROUP BY gt.label, o.move_sequence, cell.i, o.player_id = g.ai_player_id;
+
+-- Needed to refresh the view concurrently, without blocking reads
+CREATE UNIQUE INDEX square_usage_idx ON square_usage (game_type, move_number, position, ai_move);
+
+-- +goose Down
+DROP MATERIALIZED VIEW IF EXISTS square_usage;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Checksum of the neural network that made the traced move, empty for traces saved before it was recorded
+ALTER TABLE trace_cache ADD COLUMN model_id TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE trace_cache DROP COLUMN IF EXISTS model_id;
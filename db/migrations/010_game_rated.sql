@@ -0,0 +1,7 @@
+-- +goose Up
+-- Whether finishing the game updates its players' ratings. Imported games are not rated, since they were not played
+-- on this server.
+ALTER TABLE game ADD COLUMN rated BOOLEAN NOT NULL DEFAULT true;
+
+-- +goose Down
+ALTER TABLE game DROP COLUMN IF EXISTS rated;
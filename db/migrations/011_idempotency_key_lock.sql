@@ -0,0 +1,7 @@
+-- +goose Up
+-- Until when the request running with the key holds it. A key still in progress after that, e.g. because its server
+-- crashed, is taken over by the next request with the key instead of blocking it until the key expires.
+ALTER TABLE idempotency_key ADD COLUMN locked_until TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE idempotency_key DROP COLUMN IF EXISTS locked_until;
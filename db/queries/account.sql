@@ -0,0 +1,33 @@
+-- name: CreateAccount :one
+INSERT INTO account (uuid, username, password_hash)
+VALUES (uuid_generate_v4(), $1, $2)
+RETURNING *;
+
+-- Usernames are matched regardless of case
+-- name: GetAccountByUsername :one
+SELECT * FROM account
+WHERE lower(username) = lower(sqlc.arg(username));
+
+-- name: CreateAccountSession :one
+INSERT INTO account_session (token_hash, account_uuid, expires_at)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- Returns the account of a session that has not expired
+-- name: GetAccountBySessionToken :one
+SELECT sqlc.embed(a)
+FROM account_session s
+JOIN account a ON a.uuid = s.account_uuid
+WHERE s.token_hash = $1 AND s.expires_at > CURRENT_TIMESTAMP;
+
+-- name: DeleteAccountSession :exec
+DELETE FROM account_session
+WHERE token_hash = $1;
+
+-- name: DeleteExpiredAccountSessions :execrows
+DELETE FROM account_session
+WHERE expires_at < CURRENT_TIMESTAMP;
+
+-- name: DeleteAccount :exec
+DELETE FROM account
+WHERE uuid = $1;
@@ -11,17 +11,56 @@ LEFT JOIN move_event me
   )
 WHERE g.uuid = $1;
 
+-- Locks the game row until the end of the transaction, so moves on a game are played one at a time
+-- name: GetGameByUUIDForUpdate :one
+SELECT sqlc.embed(g), sqlc.embed(me)
+FROM game g
+LEFT JOIN move_event me
+  ON me.uuid = (
+      SELECT uuid
+      FROM move_event
+      WHERE game_uuid = g.uuid
+      ORDER BY move_sequence DESC
+      LIMIT 1
+  )
+WHERE g.uuid = $1
+FOR UPDATE OF g;
+
 -- name: CreateGame :one
-INSERT INTO game (uuid, name, game_type_id, ai_player_id, player_1_piece, player_2_piece)
-VALUES (uuid_generate_v4(), $1, $2, $3, $4, $5)
+INSERT INTO game (
+    uuid, name, game_type_id, ai_player_id, player_1_piece, player_2_piece,
+    first_player_id, variant, model_id, time_control_seconds, time_increment_seconds,
+    creator_uuid, player_1_uuid, player_2_uuid, rated
+)
+VALUES (uuid_generate_v4(), $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 RETURNING *;
 
+-- name: GetGameSeats :one
+SELECT player_1_uuid, player_2_uuid FROM game
+WHERE uuid = $1;
+
+-- name: ListGameUUIDs :many
+SELECT uuid FROM game
+ORDER BY created_at;
+
 -- name: UpdateGame :one
 UPDATE game
 SET name = $1, terminal_state = $2
 WHERE uuid = $3
 RETURNING *;
 
+-- name: UpdateGameModel :one
+UPDATE game
+SET model_id = $1
+WHERE uuid = $2
+RETURNING *;
+
+-- name: UpdateGameSeats :one
+UPDATE game
+SET player_1_uuid = $1, player_2_uuid = $2
+WHERE uuid = $3
+RETURNING *;
+
 -- name: DeleteGame :exec
 DELETE FROM game
 WHERE uuid = $1;
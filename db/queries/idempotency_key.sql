@@ -0,0 +1,33 @@
+-- Claims a key for a request, taking over an expired one or one whose request is still in progress past its lock.
+-- Returns no rows if the key is already claimed.
+-- name: ReserveIdempotencyKey :one
+INSERT INTO idempotency_key (key, route, request_hash, expires_at, locked_until)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (key, route) DO UPDATE
+SET request_hash = EXCLUDED.request_hash,
+    status_code = 0,
+    response_headers = NULL,
+    response_body = NULL,
+    created_at = CURRENT_TIMESTAMP,
+    expires_at = EXCLUDED.expires_at,
+    locked_until = EXCLUDED.locked_until
+WHERE idempotency_key.expires_at < CURRENT_TIMESTAMP
+   OR (idempotency_key.status_code = 0 AND idempotency_key.locked_until < CURRENT_TIMESTAMP)
+RETURNING *;
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_key
+WHERE key = $1 AND route = $2;
+
+-- name: CompleteIdempotencyKey :exec
+UPDATE idempotency_key
+SET status_code = $1, response_headers = $2, response_body = $3
+WHERE key = $4 AND route = $5;
+
+-- name: DeleteIdempotencyKey :exec
+DELETE FROM idempotency_key
+WHERE key = $1 AND route = $2;
+
+-- name: DeleteExpiredIdempotencyKeys :execrows
+DELETE FROM idempotency_key
+WHERE expires_at < CURRENT_TIMESTAMP;
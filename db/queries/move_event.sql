@@ -1,11 +1,16 @@
 -- name: CreateMoveEvent :one
-INSERT INTO move_event (uuid, game_uuid, trace_uuid, move_sequence, player_id, post_move_state)
-VALUES (uuid_generate_v4(), $1, $2, $3, $4, $5)
+INSERT INTO move_event (uuid, game_uuid, trace_uuid, move_sequence, player_id, post_move_state, position, received_at, think_time_ms)
+VALUES (uuid_generate_v4(), $1, $2, $3, $4, $5, $6, $7, $8)
 RETURNING *;
 
+-- name: DeleteMoveEventsAfter :execrows
+DELETE FROM move_event
+WHERE game_uuid = $1 AND move_sequence > $2;
+
 -- name: ListGameMoveEvents :many
 SELECT * FROM move_event
-WHERE game_uuid = $1;
+WHERE game_uuid = $1
+ORDER BY move_sequence;
 
 -- name: ListGameMoveEventsWithTrace :many
 SELECT * FROM move_event
@@ -0,0 +1,73 @@
+-- Returns an account's rating, creating it if the account has none. The row is locked until the end of the transaction.
+-- name: GetOrCreateAccountRating :one
+INSERT INTO rating (account_uuid)
+VALUES ($1)
+ON CONFLICT (account_uuid) DO UPDATE
+SET account_uuid = EXCLUDED.account_uuid
+RETURNING *;
+
+-- Returns an AI's rating, creating it if the AI has none. The row is locked until the end of the transaction.
+-- name: GetOrCreateAIRating :one
+INSERT INTO rating (ai_identity)
+VALUES ($1)
+ON CONFLICT (ai_identity) DO UPDATE
+SET ai_identity = EXCLUDED.ai_identity
+RETURNING *;
+
+-- name: UpdateRating :one
+UPDATE rating
+SET rating = $1, rating_deviation = $2, volatility = $3, games = $4, wins = $5, losses = $6, draws = $7
+WHERE uuid = $8
+RETURNING *;
+
+-- name: CreateRatingHistory :one
+INSERT INTO rating_history (
+    uuid, rating_uuid, game_uuid, opponent_rating_uuid, score, rating, rating_deviation, volatility, rating_change
+)
+VALUES (uuid_generate_v4(), $1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: GameHasRatingHistory :one
+SELECT EXISTS (SELECT 1 FROM rating_history WHERE game_uuid = $1);
+
+-- Deletes a game's rating history and takes its rating changes and results back out of its players' ratings. The
+-- deviation and volatility are kept, as their values from before the game are not stored.
+-- name: UndoGameRatings :execrows
+WITH undone AS (
+    DELETE FROM rating_history
+    WHERE game_uuid = $1
+    RETURNING rating_uuid, score, rating_change
+)
+UPDATE rating r
+SET rating = r.rating - u.rating_change,
+    games = r.games - 1,
+    wins = r.wins - (u.score = 1)::int,
+    losses = r.losses - (u.score = 0)::int,
+    draws = r.draws - (u.score = 0.5)::int
+FROM undone u
+WHERE r.uuid = u.rating_uuid;
+
+-- name: GetRating :one
+SELECT sqlc.embed(r), a.username
+FROM rating r
+LEFT JOIN account a ON a.uuid = r.account_uuid
+WHERE r.uuid = $1;
+
+-- Ratings of players with at least one rated game, best first. kind is "all", "account" or "ai".
+-- name: ListRatings :many
+SELECT sqlc.embed(r), a.username
+FROM rating r
+LEFT JOIN account a ON a.uuid = r.account_uuid
+WHERE r.games > 0
+  AND (sqlc.arg(kind)::text = 'all' OR (sqlc.arg(kind)::text = 'ai') = (r.ai_identity IS NOT NULL))
+ORDER BY r.rating DESC, r.uuid
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- Rated games of a player, oldest first, with their opponents
+-- name: ListRatingHistory :many
+SELECT sqlc.embed(h), o.ai_identity AS opponent_ai_identity, a.username AS opponent_username
+FROM rating_history h
+JOIN rating o ON o.uuid = h.opponent_rating_uuid
+LEFT JOIN account a ON a.uuid = o.account_uuid
+WHERE h.rating_uuid = $1
+ORDER BY h.created_at, h.uuid;
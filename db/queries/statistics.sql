@@ -0,0 +1,58 @@
+-- Results of finished games per game type and neural network model, with their average length in moves.
+-- terminal_state is 1 or 2 when that player won, and 3 for a draw.
+-- name: ListGameOutcomes :many
+SELECT
+    gt.label AS game_type,
+    g.model_id,
+    count(*) AS games,
+    count(*) FILTER (WHERE g.terminal_state = 1) AS player_1_wins,
+    count(*) FILTER (WHERE g.terminal_state = 2) AS player_2_wins,
+    count(*) FILTER (WHERE g.terminal_state = 3) AS draws,
+    count(*) FILTER (WHERE g.ai_player_id <> 0 AND g.terminal_state = g.ai_player_id) AS ai_wins,
+    count(*) FILTER (WHERE g.ai_player_id <> 0 AND g.terminal_state IN (1, 2) AND g.terminal_state <> g.ai_player_id) AS human_wins,
+    avg(moves.last_move_sequence)::DOUBLE PRECISION AS average_moves
+FROM game g
+JOIN game_type gt ON gt.id = g.game_type_id
+JOIN (
+    SELECT game_uuid, max(move_sequence) AS last_move_sequence
+    FROM move_event
+    GROUP BY game_uuid
+) moves ON moves.game_uuid = g.uuid
+WHERE g.terminal_state <> 0
+GROUP BY gt.label, g.model_id
+ORDER BY gt.label, g.model_id;
+
+-- Results of humans against each kind of AI in finished games
+-- name: ListHumanResultsAgainstAI :many
+SELECT
+    gt.label AS game_type,
+    count(*) AS games,
+    count(*) FILTER (WHERE g.terminal_state IN (1, 2) AND g.terminal_state <> g.ai_player_id) AS human_wins,
+    count(*) FILTER (WHERE g.terminal_state = 3) AS draws,
+    count(*) FILTER (WHERE g.terminal_state = g.ai_player_id) AS ai_wins
+FROM game g
+JOIN game_type gt ON gt.id = g.game_type_id
+WHERE g.terminal_state <> 0 AND g.ai_player_id <> 0
+GROUP BY gt.label
+ORDER BY gt.label;
+
+-- Squares humans opened finished games with, most common first
+-- name: ListOpeningSquares :many
+SELECT gt.label AS game_type, me.position, count(*) AS games
+FROM move_event me
+JOIN game g ON g.uuid = me.game_uuid
+JOIN game_type gt ON gt.id = g.game_type_id
+WHERE me.move_sequence = 1 AND me.player_id <> g.ai_player_id AND me.position IS NOT NULL AND g.terminal_state <> 0
+GROUP BY gt.label, me.position
+ORDER BY gt.label, games DESC, me.position;
+
+-- Moves per move number and square, for all game types if game_type is empty
+-- name: ListSquareUsage :many
+SELECT move_number, position, ai_move, sum(moves)::BIGINT AS moves
+FROM square_usage
+WHERE sqlc.arg(game_type)::text = '' OR game_type = sqlc.arg(game_type)::text
+GROUP BY move_number, position, ai_move
+ORDER BY move_number, position, ai_move;
+
+-- name: RefreshSquareUsage :exec
+REFRESH MATERIALIZED VIEW CONCURRENTLY square_usage;
@@ -10,6 +10,17 @@ WHERE pre_post_move_state_hash = $1;
 SELECT * FROM trace_cache;
 
 -- name: CreateTraceCache :one
-INSERT INTO trace_cache (uuid, pre_post_move_state_hash, trace)
-VALUES (uuid_generate_v4(), $1, $2)
+INSERT INTO trace_cache (uuid, pre_post_move_state_hash, trace, schema_version, model_id)
+VALUES (uuid_generate_v4(), $1, $2, $3, $4)
+RETURNING *;
+
+-- name: ListTraceCachesBelowSchemaVersion :many
+SELECT * FROM trace_cache
+WHERE schema_version < $1
+ORDER BY created_at;
+
+-- name: UpdateTraceCache :one
+UPDATE trace_cache
+SET trace = $1, schema_version = $2
+WHERE uuid = $3
 RETURNING *;
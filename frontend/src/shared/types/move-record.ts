@@ -11,4 +11,7 @@ type MoveEvent = {
 
 type Trace = {
   layerOutputs: number[][];
+  activations?: string[];
+  preActivations?: (number[] | null)[];
+  logits?: number[];
 }
@@ -0,0 +1,124 @@
+package ai
+
+import (
+	"errors"
+	"sync"
+
+	"t-cubed/internal/engine"
+)
+
+// A reachable position with Player 2 to move and the moves (bit i for cell i) that keep its minimax value
+type optimalPosition struct {
+	board engine.Board
+	moves uint16
+}
+
+var (
+	optimalPositionsOnce sync.Once
+	optimalPositions     []optimalPosition
+)
+
+// Returns the fraction of reachable positions where the network's top legal move is optimal under minimax.
+// Positions are taken from Player 2's point of view with either player moving first.
+func (n *Network) MinimaxAgreement() (float64, error) {
+	if err := n.ValidateForGame(); err != nil {
+		return 0, err
+	}
+	optimalPositionsOnce.Do(func() {
+		optimalPositions = solveOptimalPositions()
+	})
+	if len(optimalPositions) == 0 {
+		return 0, errors.New("no positions to evaluate")
+	}
+
+	workspace := n.NewWorkspace()
+	input := make([]float64, engine.NETWORK_INPUT_LEN)
+	agreed := 0
+	for _, p := range optimalPositions {
+		for i := range engine.NETWORK_OUTPUT_LEN {
+			input[i] = float64((p.board.P1Board >> i) & 1)
+			input[i+engine.NETWORK_OUTPUT_LEN] = float64((p.board.P2Board >> i) & 1)
+		}
+		probs, err := workspace.Forward(input)
+		if err != nil {
+			return 0, err
+		}
+		if p.moves&(1<<greedyLegalMove(probs, p.board.AvailableMoves())) != 0 {
+			agreed++
+		}
+	}
+	return float64(agreed) / float64(len(optimalPositions)), nil
+}
+
+type solvedKey struct {
+	board engine.Board
+	mover uint8
+}
+
+// Walks every reachable position and records the optimal moves wherever Player 2 is to move
+func solveOptimalPositions() []optimalPosition {
+	values := map[solvedKey]int{}
+	seen := map[solvedKey]bool{}
+	positions := []optimalPosition{}
+
+	var walk func(board engine.Board, mover uint8)
+	walk = func(board engine.Board, mover uint8) {
+		key := solvedKey{board, mover}
+		if seen[key] || engine.IsTerminal(&board) != engine.TERM_NOT {
+			return
+		}
+		seen[key] = true
+
+		best, moves := 0, uint16(0)
+		for i := range engine.NETWORK_OUTPUT_LEN {
+			if board.AvailableMoves()&(1<<i) == 0 {
+				continue
+			}
+			next := board
+			next.Move(mover, uint8(i+1))
+			if mover == 2 {
+				value := solvedValue(values, next, 1)
+				if moves == 0 || value > best {
+					best, moves = value, 0
+				}
+				if value == best {
+					moves |= 1 << i
+				}
+			}
+			walk(next, 3-mover)
+		}
+		if mover == 2 {
+			positions = append(positions, optimalPosition{board: board, moves: moves})
+		}
+	}
+	walk(engine.Board{}, 1)
+	walk(engine.Board{}, 2)
+	return positions
+}
+
+// Returns the memoized minimax value for Player 2 with mover to play
+func solvedValue(values map[solvedKey]int, board engine.Board, mover uint8) int {
+	if engine.IsTerminal(&board) != engine.TERM_NOT {
+		return heuristic(&board)
+	}
+	key := solvedKey{board, mover}
+	if value, ok := values[key]; ok {
+		return value
+	}
+
+	value := 0
+	first := true
+	for i := range engine.NETWORK_OUTPUT_LEN {
+		if board.AvailableMoves()&(1<<i) == 0 {
+			continue
+		}
+		next := board
+		next.Move(mover, uint8(i+1))
+		v := solvedValue(values, next, 3-mover)
+		if first || mover == 2 && v > value || mover == 1 && v < value {
+			value, first = v, false
+		}
+	}
+	values[key] = value
+	return value
+}
@@ -0,0 +1,52 @@
+package ai
+
+import (
+	"testing"
+
+	"t-cubed/internal/engine"
+)
+
+func TestSolveOptimalPositions(t *testing.T) {
+	positions := solveOptimalPositions()
+	byBoard := map[engine.Board]uint16{}
+	for _, p := range positions {
+		if p.moves == 0 || p.moves&^p.board.AvailableMoves() != 0 {
+			t.Fatalf("position %+v has optimal moves %09b outside its available moves", p.board, p.moves)
+		}
+		byBoard[p.board] = p.moves
+	}
+	if len(byBoard) != len(positions) {
+		t.Errorf("%d positions but %d distinct boards", len(positions), len(byBoard))
+	}
+
+	// Every first move draws
+	if got := byBoard[engine.Board{}]; got != engine.BOARD_FULL {
+		t.Errorf("empty board optimal moves = %09b, want all", got)
+	}
+
+	// X X _      Player 2 (O) wins at cell 5, and blocking at cell 2 creates a fork.
+	// O O _      Cell 6 loses to X at cell 2.
+	// _ _ _
+	moves := byBoard[engine.Board{P1Board: 0b000000011, P2Board: 0b000011000}]
+	if moves&(1<<5) == 0 || moves&(1<<2) == 0 || moves&(1<<6) != 0 {
+		t.Errorf("optimal moves = %09b, want 5 and 2 but not 6", moves)
+	}
+}
+
+func TestNetwork_MinimaxAgreement(t *testing.T) {
+	n, err := NewNetwork(18, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agreement, err := n.MinimaxAgreement()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if agreement <= 0 || agreement >= 1 {
+		t.Errorf("agreement of a random network = %v, want between 0 and 1", agreement)
+	}
+
+	if _, err := (&Network{}).MinimaxAgreement(); err == nil {
+		t.Error("expected error for an invalid network")
+	}
+}
@@ -0,0 +1,139 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+
+	"t-cubed/internal/engine"
+)
+
+// Method used to attribute a network's choice to its input features
+type AttributionMethod string
+
+const (
+	ATTRIBUTION_NONE                 AttributionMethod = ""
+	ATTRIBUTION_GRADIENT_X_INPUT     AttributionMethod = "gradient_x_input"
+	ATTRIBUTION_INTEGRATED_GRADIENTS AttributionMethod = "integrated_gradients"
+)
+
+// Number of interpolation steps between the baseline and the input for integrated gradients
+const INTEGRATED_GRADIENTS_STEPS = 32
+
+// Importance of each input feature for the log-probability of the chosen move.
+// Positive scores pushed the network towards the move and negative scores pushed it away.
+type Attribution struct {
+	Method  AttributionMethod `json:"method"`
+	Move    uint8             `json:"move"`     // Chosen position, 1-9
+	Scores  []float64         `json:"scores"`   // One score per input feature
+	Player1 []float64         `json:"player_1"` // Scores mapped onto the 9 board cells for Player 1's pieces
+	Player2 []float64         `json:"player_2"` // Scores mapped onto the 9 board cells for Player 2's pieces
+}
+
+// Parses an attribution method name. An empty name returns ATTRIBUTION_NONE.
+func ParseAttributionMethod(name string) (AttributionMethod, error) {
+	switch method := AttributionMethod(name); method {
+	case ATTRIBUTION_NONE, ATTRIBUTION_GRADIENT_X_INPUT, ATTRIBUTION_INTEGRATED_GRADIENTS:
+		return method, nil
+	default:
+		return ATTRIBUTION_NONE, fmt.Errorf("unknown attribution method %q", name)
+	}
+}
+
+// Attributes the network's choice of move (1-9) for the board input x to each of the input features
+func (n *Network) Attribute(x []float64, move uint8, method AttributionMethod) (*Attribution, error) {
+	if len(x) != engine.NETWORK_INPUT_LEN {
+		return nil, fmt.Errorf("attribution requires %d inputs, got %d", engine.NETWORK_INPUT_LEN, len(x))
+	}
+	if move < 1 || int(move) > n.Layers[len(n.Layers)-1].Output {
+		return nil, errors.New("move is out of range for the network output")
+	}
+
+	var scores []float64
+	var err error
+	switch method {
+	case ATTRIBUTION_GRADIENT_X_INPUT:
+		scores, err = n.gradientXInput(x, int(move-1))
+	case ATTRIBUTION_INTEGRATED_GRADIENTS:
+		scores, err = n.integratedGradients(x, int(move-1), INTEGRATED_GRADIENTS_STEPS)
+	default:
+		return nil, fmt.Errorf("unsupported attribution method %q", method)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cells := engine.NETWORK_INPUT_LEN / 2
+	return &Attribution{
+		Method:  method,
+		Move:    move,
+		Scores:  scores,
+		Player1: copySlice(scores[:cells]),
+		Player2: copySlice(scores[cells:]),
+	}, nil
+}
+
+// Returns the gradient of log(softmax(x)[target]) with respect to the input,
+// using the training backward pass with a one-hot target
+func (n *Network) logProbGradient(x []float64, target int) ([]float64, error) {
+	tn := newTrainingNetwork(n)
+	tn.inputGradient = make([]float64, len(x))
+
+	example := &TrainingExample{
+		Input:  x,
+		Target: make([]float64, n.Layers[len(n.Layers)-1].Output),
+	}
+	example.Target[target] = 1
+
+	if err := tn.forwardWithCache(x); err != nil {
+		return nil, err
+	}
+	if err := tn.backward(example); err != nil {
+		return nil, err
+	}
+
+	// The cross-entropy cost is -log(p[target]), so its gradient is the negated gradient of the log-probability
+	gradient := tn.inputGradient
+	for i := range gradient {
+		gradient[i] = -gradient[i]
+	}
+	return gradient, nil
+}
+
+// Gradient x input: the first-order estimate of each feature's effect on the log-probability
+func (n *Network) gradientXInput(x []float64, target int) ([]float64, error) {
+	gradient, err := n.logProbGradient(x, target)
+	if err != nil {
+		return nil, err
+	}
+	scores := make([]float64, len(x))
+	for i := range x {
+		scores[i] = gradient[i] * x[i]
+	}
+	return scores, nil
+}
+
+// Integrated gradients from the empty board baseline (all zeros), using the midpoint Riemann sum.
+// The scores approximately sum to the change in log-probability between the baseline and x.
+func (n *Network) integratedGradients(x []float64, target int, steps int) ([]float64, error) {
+	totals := make([]float64, len(x))
+	scaled := make([]float64, len(x))
+	for step := range steps {
+		alpha := (float64(step) + 0.5) / float64(steps)
+		for i := range x {
+			scaled[i] = alpha * x[i]
+		}
+		gradient, err := n.logProbGradient(scaled, target)
+		if err != nil {
+			return nil, err
+		}
+		for i := range totals {
+			totals[i] += gradient[i]
+		}
+	}
+
+	scores := make([]float64, len(x))
+	for i := range x {
+		scores[i] = x[i] * totals[i] / float64(steps)
+	}
+	return scores, nil
+}
@@ -0,0 +1,131 @@
+package ai
+
+import (
+	"math"
+	"testing"
+)
+
+// Returns log(softmax(x)[target]) from the network's forward pass
+func logProb(t *testing.T, n *Network, x []float64, target int) float64 {
+	t.Helper()
+	probs, err := n.Forward(x, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return math.Log(probs[target])
+}
+
+func TestLogProbGradient_MatchesFiniteDifferences(t *testing.T) {
+	n, err := NewNetwork(18, 12, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Small centered weights keep ReLUs away from their kink
+	for _, l := range n.Layers {
+		for i := range l.Weights {
+			for j := range l.Weights[i] {
+				l.Weights[i][j] -= 0.5
+			}
+		}
+	}
+
+	x := make([]float64, 18)
+	x[0], x[4], x[11], x[15] = 1, 1, 1, 1
+	target := 2
+
+	gradient, err := n.logProbGradient(x, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := 1e-6
+	for i := range x {
+		xPlus, xMinus := copySlice(x), copySlice(x)
+		xPlus[i] += h
+		xMinus[i] -= h
+		numeric := (logProb(t, n, xPlus, target) - logProb(t, n, xMinus, target)) / (2 * h)
+		if !almostEqual(gradient[i], numeric, 1e-5) {
+			t.Fatalf("input %d: analytic gradient %v, numeric %v", i, gradient[i], numeric)
+		}
+	}
+}
+
+func TestAttribute_GradientXInput(t *testing.T) {
+	n, err := NewNetwork(18, 8, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := make([]float64, 18)
+	x[0], x[13] = 1, 1
+
+	attribution, err := n.Attribute(x, 5, ATTRIBUTION_GRADIENT_X_INPUT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attribution.Move != 5 || attribution.Method != ATTRIBUTION_GRADIENT_X_INPUT {
+		t.Fatalf("unexpected attribution header: %+v", attribution)
+	}
+	if len(attribution.Scores) != 18 || len(attribution.Player1) != 9 || len(attribution.Player2) != 9 {
+		t.Fatalf("unexpected attribution lengths: %+v", attribution)
+	}
+	// Empty cells have no input, so their gradient x input is zero
+	for i, score := range attribution.Scores {
+		if x[i] == 0 && score != 0 {
+			t.Fatalf("expected zero score for empty feature %d, got %v", i, score)
+		}
+	}
+	if attribution.Player1[0] != attribution.Scores[0] || attribution.Player2[4] != attribution.Scores[13] {
+		t.Fatalf("scores not mapped onto board cells: %+v", attribution)
+	}
+}
+
+func TestAttribute_IntegratedGradientsCompleteness(t *testing.T) {
+	n, err := NewNetwork(18, 8, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := make([]float64, 18)
+	x[2], x[6], x[9] = 1, 1, 1
+	move := uint8(5)
+
+	attribution, err := n.Attribute(x, move, ATTRIBUTION_INTEGRATED_GRADIENTS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Integrated gradients should add up to the change in log-probability from the empty board
+	sum := 0.0
+	for _, score := range attribution.Scores {
+		sum += score
+	}
+	want := logProb(t, n, x, int(move-1)) - logProb(t, n, make([]float64, 18), int(move-1))
+	if !almostEqual(sum, want, 0.05*math.Max(1, math.Abs(want))) {
+		t.Fatalf("integrated gradients sum %v, expected about %v", sum, want)
+	}
+}
+
+func TestAttribute_Errors(t *testing.T) {
+	n, err := NewNetwork(18, 8, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := make([]float64, 18)
+	if _, err := n.Attribute(x, 0, ATTRIBUTION_GRADIENT_X_INPUT); err == nil {
+		t.Fatalf("expected error for move 0")
+	}
+	if _, err := n.Attribute(x, 10, ATTRIBUTION_GRADIENT_X_INPUT); err == nil {
+		t.Fatalf("expected error for move 10")
+	}
+	if _, err := n.Attribute(x[:9], 1, ATTRIBUTION_GRADIENT_X_INPUT); err == nil {
+		t.Fatalf("expected error for short input")
+	}
+	if _, err := n.Attribute(x, 1, "saliency"); err == nil {
+		t.Fatalf("expected error for unknown method")
+	}
+	if _, err := ParseAttributionMethod("saliency"); err == nil {
+		t.Fatalf("expected error parsing unknown method")
+	}
+	if method, err := ParseAttributionMethod(""); err != nil || method != ATTRIBUTION_NONE {
+		t.Fatalf("expected empty name to parse as ATTRIBUTION_NONE, got %q %v", method, err)
+	}
+}
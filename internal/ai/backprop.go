@@ -1,14 +1,17 @@
 package ai
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
 	"math"
-	"math/rand"
+	"math/rand/v2"
 	"os"
 	"path/filepath"
+	"slices"
+	"time"
 )
 
 type TrainingConfig struct {
@@ -17,97 +20,265 @@ type TrainingConfig struct {
 	Epochs        int     `json:"epochs"`
 	BatchSize     int     `json:"batchSize"`
 	ExamplesDir   string  `json:"examplesDir"`
+	ValueWeight   float64 `json:"valueWeight"` // Scales the value head's loss in the combined loss, 0 uses 1
+
+	Optimizer   string  `json:"optimizer"`   // OPTIMIZER_SGD (default), OPTIMIZER_MOMENTUM or OPTIMIZER_ADAM
+	Momentum    float64 `json:"momentum"`    // Momentum coefficient, 0 uses DEFAULT_MOMENTUM
+	WeightDecay float64 `json:"weightDecay"` // L2 regularization coefficient for the weights
+
+	ValidationSplit    float64 `json:"validationSplit"`    // Fraction of the examples held out to compute validation metrics
+	MetricsFile        string  `json:"metricsFile"`        // Per-epoch metrics are appended here as CSV (.csv) or JSON lines
+	CheckpointDir      string  `json:"checkpointDir"`      // Where checkpoints are saved, none are saved when empty
+	CheckpointInterval int     `json:"checkpointInterval"` // Save a checkpoint every N epochs, 0 only saves on interruption
+	ResumeFrom         string  `json:"resumeFrom"`         // Checkpoint to continue training from
+	Seed               int64   `json:"seed"`               // Seeds the validation split and example order, 0 picks a random seed
+
+	OnEpoch func(*EpochMetrics) `json:"-"` // Called with each epoch's metrics, optional
 }
 
 type TrainingExample struct {
 	Input  []float64 `json:"input"`
 	Target []float64 `json:"target"`
+	Value  *float64  `json:"value,omitempty"` // Expected outcome in [-1, 1] for the value head, optional
+}
+
+// Per-layer activations, deltas and gradients for a chain of layers.
+// Activations and deltas hold a row-major matrix per layer with one row per example in the batch.
+type trainingBuffers struct {
+	layerCaches []*layerCache // First row of zs and as, for single-example passes
+	deltaCache  [][]float64   // First row of deltas
+	wGradients  [][][]float64 // accumulates weight gradients
+	bGradients  [][]float64   // accumulates bias gradients
+
+	rows   int // Number of batch rows the buffers hold
+	zs     [][]float64
+	as     [][]float64
+	deltas [][]float64
 }
 
 type trainingNetwork struct {
 	network *Network
 
-	layerCaches []*layerCache
-	deltaCache  [][]float64
-	wGradients  [][][]float64 // accumulates weight gradients
-	bGradients  [][]float64   // accumulates bias gradients
+	trainingBuffers                  // Trunk and policy head (network.Layers)
+	value           *trainingBuffers // Value head, nil if the network has none
+	valueWeight     float64
+
+	// Batch inputs and targets, one row per example
+	x             []float64
+	targets       []float64
+	valueTargets  []float64
+	valueMask     []float64 // 1 for rows with a value target, 0 otherwise
+	trunkGradient []float64 // Value head's gradient with respect to the trunk output
+
+	inputGradient []float64 // dJ/dx of the last single-example backward pass, only computed when non-nil
 
 	forwardCalled bool
 }
 
 func (n *Network) Train(trainingConfig *TrainingConfig) error {
+	return n.TrainContext(context.Background(), trainingConfig)
+}
+
+// Same as Train, but stops after the current batch when ctx is cancelled.
+// An interrupted run saves a checkpoint (when CheckpointDir is set) with the network, optimizer and RNG state from
+// the start of the interrupted epoch, so resuming restarts that epoch. It returns an error wrapping ctx.Err().
+func (n *Network) TrainContext(ctx context.Context, trainingConfig *TrainingConfig) error {
 	path := filepath.Clean(trainingConfig.ExamplesDir)
-	files, err := os.ReadDir(path)
+	files, err := exampleFiles(path)
 	if err != nil {
 		return err
 	}
 
-	// Remove directories
-	cleanFiles := []os.DirEntry{}
-	for _, f := range files {
-		if !f.IsDir() {
-			cleanFiles = append(cleanFiles, f)
+	// Shuffling uses its own PCG source so its state can be checkpointed
+	seed := trainingConfig.Seed
+	for seed == 0 {
+		seed = rand.Int64()
+	}
+	source := rand.NewPCG(uint64(seed), 0)
+	rng := rand.New(source)
+	var optimizerState *OptimizerState
+	startEpoch := 0
+	var validationFiles []string
+
+	if trainingConfig.ResumeFrom != "" {
+		checkpoint, err := LoadCheckpoint(trainingConfig.ResumeFrom)
+		if err != nil {
+			return err
+		}
+		if err := source.UnmarshalBinary(checkpoint.RNGState); err != nil {
+			return fmt.Errorf("%s: %w", trainingConfig.ResumeFrom, err)
+		}
+		*n = *checkpoint.Network
+		optimizerState = &checkpoint.Optimizer
+		startEpoch = checkpoint.Epoch
+		validationFiles = checkpoint.ValidationFiles
+		files = slices.DeleteFunc(files, func(f string) bool { return slices.Contains(validationFiles, f) })
+		slog.Info(fmt.Sprintf("Resuming from %s at epoch %d", trainingConfig.ResumeFrom, startEpoch))
+	} else {
+		if n.Metadata == nil {
+			n.Metadata = &NetworkMetadata{}
+		}
+		n.Metadata.TrainingSeed = &seed
+	}
+	if trainingConfig.ResumeFrom == "" && trainingConfig.ValidationSplit > 0 {
+		// Hold out a random subset of the examples
+		rng.Shuffle(len(files), func(i, j int) {
+			files[i], files[j] = files[j], files[i]
+		})
+		held := int(float64(len(files)) * trainingConfig.ValidationSplit)
+		validationFiles = slices.Clone(files[:held])
+		files = files[held:]
+		slices.Sort(validationFiles)
+		slices.Sort(files)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("%s: no training examples", path)
+	}
+
+	validation := make([]*TrainingExample, len(validationFiles))
+	for i, file := range validationFiles {
+		if validation[i], err = readTrainingExample(filepath.Join(path, file)); err != nil {
+			return err
 		}
 	}
-	files = cleanFiles
 
+	var metrics *metricsWriter
+	if trainingConfig.MetricsFile != "" {
+		if metrics, err = openMetricsWriter(trainingConfig.MetricsFile); err != nil {
+			return err
+		}
+		defer metrics.Close()
+	}
+
+	optimizer, err := newOptimizer(trainingConfig, n, optimizerState)
+	if err != nil {
+		return err
+	}
 	tn := newTrainingNetwork(n)
+	if trainingConfig.ValueWeight > 0 {
+		tn.valueWeight = trainingConfig.ValueWeight
+	}
 
-	for epoch := range trainingConfig.Epochs {
-		// Shuffle to ensure training does not fit data ordering
-		rand.Shuffle(len(files), func(i, j int) {
+	saveCheckpoint := func(fpath string, epoch int, network *Network, optimizerState OptimizerState, rngState []byte) error {
+		err := SaveCheckpoint(fpath, &TrainingCheckpoint{
+			Version:         TRAINING_CHECKPOINT_VERSION,
+			Epoch:           epoch,
+			Network:         network,
+			RNGState:        rngState,
+			Optimizer:       optimizerState,
+			ValidationFiles: validationFiles,
+		})
+		if err == nil {
+			slog.Info(fmt.Sprintf("Saved checkpoint %s", fpath))
+		}
+		return err
+	}
+
+	for epoch := startEpoch; epoch < trainingConfig.Epochs; epoch++ {
+		started := time.Now()
+		rngState, err := source.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		// Kept so an interruption can checkpoint the start of the epoch, before any of its batches were applied
+		var epochNetwork *Network
+		var epochOptimizer OptimizerState
+		if trainingConfig.CheckpointDir != "" {
+			epochNetwork = n.clone()
+			epochOptimizer = optimizer.OptimizerState.clone()
+		}
+
+		// Shuffle to ensure training does not fit data ordering. Sorting first makes the order
+		// depend only on the RNG state, so a resumed run sees the same order.
+		slices.Sort(files)
+		rng.Shuffle(len(files), func(i, j int) {
 			files[i], files[j] = files[j], files[i]
 		})
 
-		batchIndex := 0
+		batch := make([]*TrainingExample, 0, trainingConfig.BatchSize)
 		examplesProcessed := 0
 		totalCost := 0.0
+		correct := 0
+		gradientNorms := 0.0
+		batches := 0
 
-		for _, file := range files {
-			data, err := os.ReadFile(filepath.Join(path, file.Name()))
+		for i, file := range files {
+			example, err := readTrainingExample(filepath.Join(path, file))
 			if err != nil {
 				return err
 			}
-			var example TrainingExample
-			if err := json.Unmarshal(data, &example); err != nil {
-				return err
-			}
+			batch = append(batch, example)
 
-			// Forward propogate
-			if err := tn.forwardWithCache(example.Input); err != nil {
-				return err
+			if len(batch) < trainingConfig.BatchSize && i < len(files)-1 {
+				continue
 			}
 
-			totalCost += crossEntropyLoss(tn.layerCaches[len(n.Layers)-1].As, example.Target)
+			if ctx.Err() != nil {
+				if trainingConfig.CheckpointDir != "" {
+					fpath := InterruptedCheckpointPath(trainingConfig.CheckpointDir, epoch)
+					if err := saveCheckpoint(fpath, epoch, epochNetwork, epochOptimizer, rngState); err != nil {
+						return err
+					}
+				}
+				return fmt.Errorf("training interrupted during epoch %d: %w", epoch, ctx.Err())
+			}
 
-			// Backpropagate
-			if err := tn.backward(&example); err != nil {
+			// Forward and backpropagate the whole batch, then average its gradients
+			cost, err := tn.trainBatch(batch)
+			if err != nil {
 				return err
 			}
+			totalCost += cost
+			correct += tn.batchCorrect(len(batch))
+			examplesProcessed += len(batch)
+			gradientNorms += tn.gradientNorm(len(batch))
+			batches++
+			optimizer.update(tn, len(batch))
+			batch = batch[:0]
+		}
 
-			examplesProcessed++
-			batchIndex++
-
-			if batchIndex == trainingConfig.BatchSize {
-				tn.updateWeights(trainingConfig.LearningRate, trainingConfig.BatchSize)
-
-				// message := fmt.Sprintf("Completed batch of %d examples!\n\tEpoch %d: %d examples processed", batchIndex, epoch, examplesProcessed)
-				// slog.Info(message)
-
-				batchIndex = 0
+		avgCost := totalCost / float64(examplesProcessed)
+		epochMetrics := &EpochMetrics{
+			Epoch:        epoch,
+			Examples:     examplesProcessed,
+			Loss:         avgCost,
+			Accuracy:     float64(correct) / float64(examplesProcessed),
+			GradientNorm: gradientNorms / float64(batches),
+			WeightNorm:   n.weightNorm(),
+			LearningRate: optimizer.LearningRate,
+		}
+		if len(validation) > 0 {
+			cost, correct, err := tn.evaluateBatch(validation)
+			if err != nil {
+				return err
 			}
+			loss := cost / float64(len(validation))
+			accuracy := float64(correct) / float64(len(validation))
+			epochMetrics.ValidationLoss, epochMetrics.ValidationAccuracy = &loss, &accuracy
 		}
+		epochMetrics.Seconds = time.Since(started).Seconds()
 
-		if batchIndex > 0 {
-			tn.updateWeights(trainingConfig.LearningRate, batchIndex)
-
-			// message := fmt.Sprintf("Completed batch of %d examples!\n\tEpoch %d: %d examples processed", batchIndex, epoch, examplesProcessed)
-			// slog.Info(message)
+		message := fmt.Sprintf("Epoch %d: Average cost: %f", epoch, avgCost)
+		slog.Info(message, "accuracy", epochMetrics.Accuracy, "gradientNorm", epochMetrics.GradientNorm, "weightNorm", epochMetrics.WeightNorm)
+		if metrics != nil {
+			if err := metrics.Write(epochMetrics); err != nil {
+				return err
+			}
+		}
+		if trainingConfig.OnEpoch != nil {
+			trainingConfig.OnEpoch(epochMetrics)
 		}
 
-		avgCost := totalCost / float64(examplesProcessed)
-		message := fmt.Sprintf("Epoch %d: Average cost: %f", epoch, avgCost)
-		slog.Info(message)
+		if trainingConfig.CheckpointDir != "" && trainingConfig.CheckpointInterval > 0 && (epoch+1)%trainingConfig.CheckpointInterval == 0 {
+			rngState, err := source.MarshalBinary()
+			if err != nil {
+				return err
+			}
+			fpath := CheckpointPath(trainingConfig.CheckpointDir, epoch+1)
+			if err := saveCheckpoint(fpath, epoch+1, n, optimizer.OptimizerState, rngState); err != nil {
+				return err
+			}
+		}
 
 		if avgCost < trainingConfig.CostThreshold {
 			message := fmt.Sprintf("Training complete: cost threshold reached (%f < %f)", avgCost, trainingConfig.CostThreshold)
@@ -122,6 +293,33 @@ func (n *Network) Train(trainingConfig *TrainingConfig) error {
 	return nil
 }
 
+// Returns the sorted names of the example files in dir, skipping directories
+func exampleFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, f := range entries {
+		if !f.IsDir() {
+			files = append(files, f.Name())
+		}
+	}
+	return files, nil
+}
+
+func readTrainingExample(fpath string) (*TrainingExample, error) {
+	data, err := os.ReadFile(fpath)
+	if err != nil {
+		return nil, err
+	}
+	var example TrainingExample
+	if err := json.Unmarshal(data, &example); err != nil {
+		return nil, fmt.Errorf("%s: %w", fpath, err)
+	}
+	return &example, nil
+}
+
 func crossEntropyLoss(predicted, target []float64) float64 {
 	eps := 1e-15
 	loss := 0.0
@@ -131,47 +329,78 @@ func crossEntropyLoss(predicted, target []float64) float64 {
 	return -loss
 }
 
-func newTrainingNetwork(network *Network) *trainingNetwork {
-	numLayers := len(network.Layers)
-
-	layerCaches := make([]*layerCache, numLayers)
-	deltaCache := make([][]float64, numLayers)
-	wGradients := make([][][]float64, numLayers)
-	bGradients := make([][]float64, numLayers)
-
-	// Initialize caches
-	for i := range numLayers {
-		layerInputs := network.Layers[i].Input
-		layerOutputs := network.Layers[i].Output
-
-		// Initialize pre/post activation caches for each layer
-		layerCaches[i] = &layerCache{
-			As: make([]float64, layerOutputs),
-			Zs: make([]float64, layerOutputs),
-		}
-
-		// Initialize delta caches for each layer
-		deltaCache[i] = make([]float64, layerOutputs)
+func squaredError(predicted, target float64) float64 {
+	return (predicted - target) * (predicted - target)
+}
 
-		// Initialize weight gradients for each layer
-		wGradients[i] = make([][]float64, layerInputs)
-		for j := range layerInputs {
-			wGradients[i][j] = make([]float64, layerOutputs)
-		}
+func newTrainingNetwork(network *Network) *trainingNetwork {
+	n := &trainingNetwork{
+		network:         network,
+		trainingBuffers: *newTrainingBuffers(network.Layers),
+		valueWeight:     1,
+	}
+	if network.HasValueHead() {
+		n.value = newTrainingBuffers(network.ValueHead)
+	}
+	n.ensureRows(1)
+	return n
+}
 
-		// Initialize bias gradients for each layer
-		bGradients[i] = make([]float64, network.Layers[i].Output)
+func newTrainingBuffers(layers []*layer) *trainingBuffers {
+	numLayers := len(layers)
+
+	b := &trainingBuffers{
+		layerCaches: make([]*layerCache, numLayers),
+		deltaCache:  make([][]float64, numLayers),
+		wGradients:  make([][][]float64, numLayers),
+		bGradients:  make([][]float64, numLayers),
+		zs:          make([][]float64, numLayers),
+		as:          make([][]float64, numLayers),
+		deltas:      make([][]float64, numLayers),
 	}
+	for i, l := range layers {
+		b.layerCaches[i] = &layerCache{}
+		b.wGradients[i] = contiguousRows(l.Input, l.Output)
+		b.bGradients[i] = make([]float64, l.Output)
+	}
+	b.ensureRows(layers, 1)
+	return b
+}
 
-	n := &trainingNetwork{
-		network:     network,
-		layerCaches: layerCaches,
-		deltaCache:  deltaCache,
-		wGradients:  wGradients,
-		bGradients:  bGradients,
+// Grows the activation and delta buffers to hold at least rows examples
+func (b *trainingBuffers) ensureRows(layers []*layer, rows int) {
+	if rows <= b.rows {
+		return
+	}
+	b.rows = rows
+	for i, l := range layers {
+		b.zs[i] = make([]float64, rows*l.Output)
+		b.as[i] = make([]float64, rows*l.Output)
+		b.deltas[i] = make([]float64, rows*l.Output)
+
+		b.layerCaches[i].Zs = b.zs[i][:l.Output]
+		b.layerCaches[i].As = b.as[i][:l.Output]
+		b.deltaCache[i] = b.deltas[i][:l.Output]
 	}
+}
 
-	return n
+// Grows all buffers to hold at least rows examples
+func (tn *trainingNetwork) ensureRows(rows int) {
+	tn.trainingBuffers.ensureRows(tn.network.Layers, rows)
+	if tn.value != nil {
+		tn.value.ensureRows(tn.network.ValueHead, rows)
+	}
+	if rows <= len(tn.valueMask) {
+		return
+	}
+	layers := tn.network.Layers
+	tn.x = make([]float64, rows*layers[0].Input)
+	tn.targets = make([]float64, rows*layers[len(layers)-1].Output)
+	tn.valueTargets = make([]float64, rows)
+	tn.valueMask = make([]float64, rows)
+	if tn.value != nil {
+		tn.trunkGradient = make([]float64, rows*layers[tn.network.Trunk-1].Output)
+	}
 }
 
 // Forward propagates the input through the network and saves pre/post activations in layerCaches
@@ -179,87 +408,223 @@ func (tn *trainingNetwork) forwardWithCache(x []float64) error {
 	if tn.forwardCalled {
 		return errors.New("forwardWithCache() already called")
 	}
+	if len(x) != tn.network.Layers[0].Input {
+		return errors.New("Input length does not match layer input length")
+	}
+
+	tn.forwardRows(x, 1)
+
+	// Set flag so backward() can be called
+	tn.forwardCalled = true
+	return nil
+}
 
-	var err error
-	out := x
+// Forward propagates rows examples stored row-major in x, saving pre/post activations for every row
+func (tn *trainingNetwork) forwardRows(x []float64, rows int) {
+	layers := tn.network.Layers
+	lli := len(layers) - 1
 
-	for i, layer := range tn.network.Layers {
+	in := x
+	for i, l := range layers {
 		act := reLU
-		if i == len(tn.network.Layers)-1 {
+		if i == lli {
 			act = identity // last layer emits logits
 		}
-		out, err = layer.feedForward(out, act, tn.layerCaches[i])
-		if err != nil {
-			return err
-		}
+		l.forwardBatch(in, tn.zs[i], tn.as[i], act, rows)
+		in = tn.as[i]
+	}
+	// Normalize each row of the last layer with softmax
+	out := layers[lli].Output
+	for r := range rows {
+		row := tn.as[lli][r*out : (r+1)*out]
+		softmaxInto(row, row)
 	}
-	// Update last layer cache with softmax output
-	out = softmax(out)
-	tn.layerCaches[len(tn.network.Layers)-1].As = copySlice(out)
 
-	// Set flag so backward() can be called
-	tn.forwardCalled = true
-	return nil
+	// The value head continues from the trunk's output
+	if tn.value != nil {
+		in = tn.as[tn.network.Trunk-1]
+		for i, l := range tn.network.ValueHead {
+			act := reLU
+			if i == len(tn.network.ValueHead)-1 {
+				act = math.Tanh // last layer emits the expected outcome
+			}
+			l.forwardBatch(in, tn.value.zs[i], tn.value.as[i], act, rows)
+			in = tn.value.as[i]
+		}
+	}
 }
 
 // Backpropagate the error from the last layer to the first layer.
 // Requires forwardWithCache to have been called first.
 func (tn *trainingNetwork) backward(trainingExample *TrainingExample) error {
+	return tn.backwardWeighted(trainingExample, 1)
+}
+
+// Same as backward, but scales the example's policy gradients by weight.
+// Used by policy gradient training, where the weight is the return of the chosen action.
+// The value head's gradients are not scaled.
+func (tn *trainingNetwork) backwardWeighted(trainingExample *TrainingExample, weight float64) error {
 	if !tn.forwardCalled {
 		return errors.New("forwardWithCache() must be called before backward()")
 	}
+	if len(trainingExample.Target) != len(tn.deltaCache[len(tn.deltaCache)-1]) {
+		return errors.New("Target length does not match network output length")
+	}
+
+	tn.valueTargets[0], tn.valueMask[0] = 0, 0
+	if trainingExample.Value != nil {
+		tn.valueTargets[0], tn.valueMask[0] = *trainingExample.Value, 1
+	}
+	tn.backwardRows(trainingExample.Input, trainingExample.Target, weight, 1)
+
+	// Reset flag so forwardWithCache() can be called again
+	tn.forwardCalled = false
+
+	tn.resetCaches()
+	return nil
+}
+
+// Forward propagates and backpropagates a batch of examples as row-major matrices,
+// accumulating their gradients. Returns the batch's total combined cost.
+func (tn *trainingNetwork) trainBatch(examples []*TrainingExample) (float64, error) {
+	if err := tn.loadBatch(examples); err != nil {
+		return 0, err
+	}
+	rows := len(examples)
+	tn.forwardRows(tn.x, rows)
+	cost := tn.batchCost(rows)
+	tn.backwardRows(tn.x, tn.targets, 1, rows)
+	return cost, nil
+}
 
-	x := trainingExample.Input
-	y := trainingExample.Target
-	lli := len(tn.network.Layers) - 1 // last layer index
+// Forward propagates a batch of examples without accumulating gradients.
+// Returns the batch's total combined cost and the number of examples whose top move matches the target.
+func (tn *trainingNetwork) evaluateBatch(examples []*TrainingExample) (float64, int, error) {
+	if err := tn.loadBatch(examples); err != nil {
+		return 0, 0, err
+	}
+	rows := len(examples)
+	tn.forwardRows(tn.x, rows)
+	return tn.batchCost(rows), tn.batchCorrect(rows), nil
+}
+
+// Copies a batch of examples into the row-major input and target buffers
+func (tn *trainingNetwork) loadBatch(examples []*TrainingExample) error {
+	tn.ensureRows(len(examples))
+
+	layers := tn.network.Layers
+	in := layers[0].Input
+	out := layers[len(layers)-1].Output
+	for r, example := range examples {
+		if len(example.Input) != in || len(example.Target) != out {
+			return fmt.Errorf("example %d has %d inputs and %d targets, expected %d and %d", r, len(example.Input), len(example.Target), in, out)
+		}
+		copy(tn.x[r*in:], example.Input)
+		copy(tn.targets[r*out:], example.Target)
+		tn.valueTargets[r], tn.valueMask[r] = 0, 0
+		if example.Value != nil {
+			tn.valueTargets[r], tn.valueMask[r] = *example.Value, 1
+		}
+	}
+	return nil
+}
 
-	// Compute deltas and gradients for output layer (derivative of J = softmax + cross-entropy)
-	for i := range tn.layerCaches[lli].As {
-		delta := tn.layerCaches[lli].As[i] - y[i]
-		tn.deltaCache[lli][i] = delta
-		for j := range tn.network.Layers[lli].Weights {
-			tn.wGradients[lli][j][i] += delta * tn.layerCaches[lli-1].As[j]
+// Returns the total combined cost of the rows forward propagated last
+func (tn *trainingNetwork) batchCost(rows int) float64 {
+	layers := tn.network.Layers
+	out := layers[len(layers)-1].Output
+
+	cost := 0.0
+	probs := tn.as[len(layers)-1]
+	for r := range rows {
+		cost += crossEntropyLoss(probs[r*out:(r+1)*out], tn.targets[r*out:(r+1)*out])
+		if tn.value != nil {
+			values := tn.value.as[len(tn.value.as)-1]
+			cost += tn.valueMask[r] * tn.valueWeight * squaredError(values[r], tn.valueTargets[r])
 		}
-		tn.bGradients[lli][i] += delta
 	}
+	return cost
+}
 
-	// Compute deltas and gradients for hidden layers (derivative of J = ReLU(W*x + b))
-	for i := lli - 1; i >= 0; i-- {
-		currentLayerCache := tn.layerCaches[i]
-		currentLayerDeltas := tn.deltaCache[i]
-		nextLayerDeltas := tn.deltaCache[i+1]
-		nextLayerWeights := tn.network.Layers[i+1].Weights
+// Returns how many of the rows forward propagated last predict the target's most likely move
+func (tn *trainingNetwork) batchCorrect(rows int) int {
+	layers := tn.network.Layers
+	out := layers[len(layers)-1].Output
 
-		var previousLayerAs []float64
-		if i == 0 {
-			previousLayerAs = x // input layer
-		} else {
-			previousLayerAs = tn.layerCaches[i-1].As
+	correct := 0
+	probs := tn.as[len(layers)-1]
+	for r := range rows {
+		if argmax(probs[r*out:(r+1)*out]) == argmax(tn.targets[r*out:(r+1)*out]) {
+			correct++
 		}
+	}
+	return correct
+}
 
-		// Compute deltas for each neuron in current layer
-		for j := range currentLayerCache.As {
-			sum := 0.0
-			for k := range nextLayerDeltas {
-				sum += nextLayerDeltas[k] * nextLayerWeights[j][k]
-			}
-			currentLayerDeltas[j] = sum * reLUDerivative(currentLayerCache.Zs[j])
+func argmax(values []float64) int {
+	best := 0
+	for i, v := range values {
+		if v > values[best] {
+			best = i
+		}
+	}
+	return best
+}
+
+// Backpropagates rows examples after forwardRows. y holds the policy targets row-major, weight scales
+// the policy gradients and the value targets are read from valueTargets and valueMask.
+func (tn *trainingNetwork) backwardRows(x, y []float64, weight float64, rows int) {
+	layers := tn.network.Layers
+	lli := len(layers) - 1 // last layer index
+
+	// Deltas for output layer (derivative of J = softmax + cross-entropy)
+	probs := tn.as[lli]
+	outputDeltas := tn.deltas[lli]
+	for k := range rows * layers[lli].Output {
+		outputDeltas[k] = (probs[k] - y[k]) * weight
+	}
+
+	// The value head's error flows back into the trunk's last layer
+	var trunkGradient []float64
+	if tn.value != nil && hasValueTarget(tn.valueMask[:rows]) {
+		trunkGradient = tn.backwardValue(rows)
+	}
+
+	// Gradients for each layer, then deltas for the layer below it (derivative of J = ReLU(W*x + b))
+	for i := lli; i >= 0; i-- {
+		in := x
+		if i > 0 {
+			in = tn.as[i-1]
 		}
 
-		// Compute gradients for each neuron in current layer
-		for j := range currentLayerDeltas {
-			for k := range previousLayerAs {
-				tn.wGradients[i][k][j] += currentLayerDeltas[j] * previousLayerAs[k]
+		var dIn []float64
+		if i > 0 {
+			dIn = tn.deltas[i-1]
+		} else if rows == 1 {
+			dIn = tn.inputGradient // propagated to the input for attribution when non-nil
+		}
+		layers[i].backwardBatch(in, tn.deltas[i], tn.wGradients[i], tn.bGradients[i], dIn, rows)
+
+		if i > 0 {
+			dIn = dIn[:rows*layers[i-1].Output]
+			if trunkGradient != nil && i-1 == tn.network.Trunk-1 {
+				axpy(1, trunkGradient, dIn)
+			}
+			zs := tn.zs[i-1]
+			for k := range dIn {
+				dIn[k] *= reLUDerivative(zs[k])
 			}
-			tn.bGradients[i][j] += currentLayerDeltas[j]
 		}
 	}
+}
 
-	// Reset flag so forwardWithCache() can be called again
-	tn.forwardCalled = false
-
-	tn.resetCaches()
-	return nil
+func hasValueTarget(mask []float64) bool {
+	for _, m := range mask {
+		if m != 0 {
+			return true
+		}
+	}
+	return false
 }
 
 func reLUDerivative(z float64) float64 {
@@ -271,17 +636,24 @@ func reLUDerivative(z float64) float64 {
 
 // Resets layerCaches and deltaCache. Should be used after each training example
 func (tn *trainingNetwork) resetCaches() {
-	for i := range tn.network.Layers {
-		if len(tn.layerCaches[i].As) != len(tn.layerCaches[i].Zs) {
+	tn.trainingBuffers.resetCaches()
+	if tn.value != nil {
+		tn.value.resetCaches()
+	}
+}
+
+func (b *trainingBuffers) resetCaches() {
+	for i := range b.layerCaches {
+		if len(b.layerCaches[i].As) != len(b.layerCaches[i].Zs) {
 			panic("layerCaches length mismatch for As and Zs")
 		}
-		for j := range tn.layerCaches[i].As {
-			tn.layerCaches[i].As[j] = 0
-			tn.layerCaches[i].Zs[j] = 0
+		for j := range b.layerCaches[i].As {
+			b.layerCaches[i].As[j] = 0
+			b.layerCaches[i].Zs[j] = 0
 		}
 
-		for j := range tn.deltaCache[i] {
-			tn.deltaCache[i][j] = 0
+		for j := range b.deltaCache[i] {
+			b.deltaCache[i][j] = 0
 		}
 	}
 }
@@ -291,33 +663,40 @@ func (tn *trainingNetwork) resetCaches() {
 func (tn *trainingNetwork) updateWeights(learningRate float64, batchSize int) {
 	scale := learningRate / float64(batchSize)
 
-	for i := range tn.network.Layers {
+	tn.trainingBuffers.updateWeights(tn.network.Layers, scale)
+	if tn.value != nil {
+		tn.value.updateWeights(tn.network.ValueHead, scale)
+	}
+}
+
+func (b *trainingBuffers) updateWeights(layers []*layer, scale float64) {
+	for i := range layers {
 		// Update weights
-		for j := range tn.network.Layers[i].Weights {
-			for k := range tn.network.Layers[i].Weights[j] {
-				tn.network.Layers[i].Weights[j][k] -= scale * tn.wGradients[i][j][k]
+		for j := range layers[i].Weights {
+			for k := range layers[i].Weights[j] {
+				layers[i].Weights[j][k] -= scale * b.wGradients[i][j][k]
 			}
 		}
 		// Update biases
-		for j := range tn.network.Layers[i].Biases {
-			tn.network.Layers[i].Biases[j] -= scale * tn.bGradients[i][j]
+		for j := range layers[i].Biases {
+			layers[i].Biases[j] -= scale * b.bGradients[i][j]
 		}
 	}
 
-	tn.resetGradients()
+	b.resetGradients()
 }
 
 // Used to reset gradients after each training batch
-func (tn *trainingNetwork) resetGradients() {
-	for i := range tn.network.Layers {
-		for j := range tn.wGradients[i] {
-			for k := range tn.wGradients[i][j] {
-				tn.wGradients[i][j][k] = 0
+func (b *trainingBuffers) resetGradients() {
+	for i := range b.wGradients {
+		for j := range b.wGradients[i] {
+			for k := range b.wGradients[i][j] {
+				b.wGradients[i][j][k] = 0
 			}
 		}
 
-		for j := range tn.bGradients[i] {
-			tn.bGradients[i][j] = 0
+		for j := range b.bGradients[i] {
+			b.bGradients[i][j] = 0
 		}
 	}
 }
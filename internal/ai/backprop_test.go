@@ -1,6 +1,7 @@
 package ai
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -287,3 +288,57 @@ func almostEqualSlices(a, b []float64, eps float64) bool {
 	}
 	return true
 }
+
+func TestTrain_SameSeedsProduceIdenticalWeights(t *testing.T) {
+	examplesDir := writeTrainingExamples(t, 30, 4, 3)
+	dir := t.TempDir()
+
+	train := func(name string, networkSeed, trainingSeed int64) []byte {
+		n, err := NewSeededPolicyValueNetwork(networkSeed, []int{4, 8}, []int{6, 3}, []int{4, 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		config := &TrainingConfig{
+			LearningRate:    0.05,
+			Epochs:          3,
+			BatchSize:       4,
+			ExamplesDir:     examplesDir,
+			ValidationSplit: 0.2,
+			Optimizer:       OPTIMIZER_ADAM,
+			Seed:            trainingSeed,
+		}
+		if err := n.Train(config); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, name)
+		if err := SaveNetwork(path, n); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	first := train("first.json", 42, 7)
+	second := train("second.json", 42, 7)
+	if !bytes.Equal(first, second) {
+		t.Fatal("two runs with the same seeds saved different weights")
+	}
+	if bytes.Equal(first, train("network.json", 43, 7)) {
+		t.Error("a different network seed saved the same weights")
+	}
+	if bytes.Equal(first, train("training.json", 42, 8)) {
+		t.Error("a different training seed saved the same weights")
+	}
+
+	n, err := ParseNetwork(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Metadata == nil || n.Metadata.Seed == nil || *n.Metadata.Seed != 42 ||
+		n.Metadata.TrainingSeed == nil || *n.Metadata.TrainingSeed != 7 {
+		t.Errorf("metadata = %+v, want seed 42 and training seed 7", n.Metadata)
+	}
+}
@@ -0,0 +1,99 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const TRAINING_CHECKPOINT_VERSION = 1
+
+// Optimizer settings and progress. Momentum and Adam keep per-parameter moments, laid out as in optimizer.update.
+type OptimizerState struct {
+	Name         string      `json:"name,omitempty"` // Empty for checkpoints saved before optimizers could be chosen (SGD)
+	LearningRate float64     `json:"learningRate"`
+	Step         int         `json:"step"`              // Number of weight updates applied
+	Moments      [][]float64 `json:"moments,omitempty"` // Velocity (momentum) or first moment (Adam)
+	Squares      [][]float64 `json:"squares,omitempty"` // Second moment (Adam)
+}
+
+// Everything needed to resume training where it stopped
+type TrainingCheckpoint struct {
+	Version         int            `json:"version"`
+	Epoch           int            `json:"epoch"` // Completed epochs, training resumes with this epoch
+	Network         *Network       `json:"network"`
+	RNGState        []byte         `json:"rngState"` // Shuffling RNG (PCG) at the start of the next epoch
+	Optimizer       OptimizerState `json:"optimizer"`
+	ValidationFiles []string       `json:"validationFiles"` // Example files held out for validation
+}
+
+// Returns the path of the checkpoint for the given number of completed epochs
+func CheckpointPath(dir string, epoch int) string {
+	return filepath.Join(filepath.Clean(dir), fmt.Sprintf("checkpoint_%05d.json", epoch))
+}
+
+// Returns the path of the checkpoint saved when training is interrupted during the given epoch.
+// It restarts that epoch, and has its own name so it never replaces the interval checkpoint of the same epoch.
+func InterruptedCheckpointPath(dir string, epoch int) string {
+	return filepath.Join(filepath.Clean(dir), fmt.Sprintf("checkpoint_%05d_interrupted.json", epoch))
+}
+
+// Returns a copy of the state that does not share moments with s
+func (s *OptimizerState) clone() OptimizerState {
+	c := *s
+	c.Moments = cloneRows(s.Moments)
+	c.Squares = cloneRows(s.Squares)
+	return c
+}
+
+func cloneRows(rows [][]float64) [][]float64 {
+	if rows == nil {
+		return nil
+	}
+	c := make([][]float64, len(rows))
+	for i, row := range rows {
+		c[i] = copySlice(row)
+	}
+	return c
+}
+
+// Saves a checkpoint atomically so an interrupted write never replaces a good checkpoint
+func SaveCheckpoint(fpath string, checkpoint *TrainingCheckpoint) error {
+	fpath = filepath.Clean(fpath)
+	if err := os.MkdirAll(filepath.Dir(fpath), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	tmp := fpath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fpath)
+}
+
+// Loads and validates a checkpoint
+func LoadCheckpoint(fpath string) (*TrainingCheckpoint, error) {
+	fpath = filepath.Clean(fpath)
+	data, err := os.ReadFile(fpath)
+	if err != nil {
+		return nil, err
+	}
+	var checkpoint TrainingCheckpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, fmt.Errorf("%s: %w", fpath, err)
+	}
+	if checkpoint.Version != TRAINING_CHECKPOINT_VERSION {
+		return nil, fmt.Errorf("%s: unsupported checkpoint version %d", fpath, checkpoint.Version)
+	}
+	if checkpoint.Network == nil {
+		return nil, fmt.Errorf("%s: checkpoint has no network", fpath)
+	}
+	if err := checkpoint.Network.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", fpath, err)
+	}
+	return &checkpoint, nil
+}
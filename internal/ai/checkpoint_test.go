@@ -0,0 +1,314 @@
+package ai
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Writes count random examples for a network with the given input and output sizes
+func writeTrainingExamples(t *testing.T, count, inputs, outputs int) string {
+	t.Helper()
+	dir := t.TempDir()
+	for i := range count {
+		example := TrainingExample{Input: make([]float64, inputs), Target: make([]float64, outputs)}
+		for j := range example.Input {
+			example.Input[j] = float64((i >> j) & 1)
+		}
+		example.Target[i%outputs] = 1
+		data, err := json.Marshal(example)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("example_%d.json", i)), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestTrain_MetricsFile(t *testing.T) {
+	examplesDir := writeTrainingExamples(t, 20, 4, 3)
+	for _, ext := range []string{".csv", ".jsonl"} {
+		t.Run(ext, func(t *testing.T) {
+			n, err := NewNetwork(4, 5, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			metricsFile := filepath.Join(t.TempDir(), "metrics"+ext)
+			config := &TrainingConfig{
+				LearningRate:    0.1,
+				Epochs:          3,
+				BatchSize:       4,
+				ExamplesDir:     examplesDir,
+				ValidationSplit: 0.25,
+				MetricsFile:     metricsFile,
+			}
+			if err := n.Train(config); err != nil {
+				t.Fatal(err)
+			}
+
+			file, err := os.Open(metricsFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+
+			if ext == ".csv" {
+				records, err := csv.NewReader(file).ReadAll()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(records) != 4 || !reflect.DeepEqual(records[0], metricsCSVHeader) {
+					t.Fatalf("got %d records starting with %v, want a header and 3 epochs", len(records), records[0])
+				}
+				if records[3][0] != "2" || records[3][1] != "15" || records[3][4] == "" {
+					t.Errorf("last record = %v", records[3])
+				}
+				return
+			}
+
+			var lines []EpochMetrics
+			scanner := bufio.NewScanner(file)
+			for scanner.Scan() {
+				var m EpochMetrics
+				if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+					t.Fatal(err)
+				}
+				lines = append(lines, m)
+			}
+			if len(lines) != 3 {
+				t.Fatalf("got %d lines, want 3", len(lines))
+			}
+			for i, m := range lines {
+				if m.Epoch != i || m.Examples != 15 || m.LearningRate != 0.1 || m.GradientNorm <= 0 || m.WeightNorm <= 0 {
+					t.Errorf("epoch %d metrics = %+v", i, m)
+				}
+				if m.Accuracy < 0 || m.Accuracy > 1 || m.ValidationLoss == nil || m.ValidationAccuracy == nil {
+					t.Errorf("epoch %d metrics = %+v", i, m)
+				}
+			}
+		})
+	}
+}
+
+func TestTrain_ResumeMatchesUninterruptedRun(t *testing.T) {
+	examplesDir := writeTrainingExamples(t, 24, 4, 3)
+
+	tests := []struct {
+		optimizer   string
+		weightDecay float64
+	}{
+		{OPTIMIZER_SGD, 0},
+		{OPTIMIZER_MOMENTUM, 0},
+		{OPTIMIZER_ADAM, 0.001},
+	}
+	for _, tc := range tests {
+		t.Run(tc.optimizer, func(t *testing.T) {
+			checkpointDir := t.TempDir()
+			n, err := NewPolicyValueNetwork([]int{4, 6}, []int{6, 3}, []int{4, 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			config := TrainingConfig{
+				LearningRate:       0.05,
+				Epochs:             4,
+				BatchSize:          5,
+				ExamplesDir:        examplesDir,
+				ValidationSplit:    0.2,
+				CheckpointDir:      checkpointDir,
+				CheckpointInterval: 2,
+				Optimizer:          tc.optimizer,
+				WeightDecay:        tc.weightDecay,
+			}
+			if err := n.Train(&config); err != nil {
+				t.Fatal(err)
+			}
+
+			// Resuming from the halfway checkpoint must reproduce the final weights exactly
+			resumed := &Network{}
+			resumeConfig := config
+			resumeConfig.CheckpointDir = ""
+			resumeConfig.ResumeFrom = CheckpointPath(checkpointDir, 2)
+			if err := resumed.Train(&resumeConfig); err != nil {
+				t.Fatal(err)
+			}
+			if resumed.Checksum() != n.Checksum() {
+				t.Error("resumed training does not match the uninterrupted run")
+			}
+
+			final, err := LoadCheckpoint(CheckpointPath(checkpointDir, 4))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if final.Epoch != 4 || final.Optimizer.Step != 4*4 || len(final.ValidationFiles) != 4 {
+				t.Errorf("final checkpoint = epoch %d, step %d, %d validation files", final.Epoch, final.Optimizer.Step, len(final.ValidationFiles))
+			}
+			if final.Optimizer.Name != tc.optimizer || (tc.optimizer != OPTIMIZER_SGD) != (final.Optimizer.Moments != nil) {
+				t.Errorf("final checkpoint optimizer = %s with moments %v", final.Optimizer.Name, final.Optimizer.Moments != nil)
+			}
+			if final.Network.Checksum() != n.Checksum() {
+				t.Error("final checkpoint does not hold the trained network")
+			}
+
+			// A checkpoint cannot continue with a different optimizer
+			resumeConfig.Optimizer = OPTIMIZER_MOMENTUM
+			if tc.optimizer == OPTIMIZER_MOMENTUM {
+				resumeConfig.Optimizer = OPTIMIZER_ADAM
+			}
+			if err := (&Network{}).Train(&resumeConfig); err == nil {
+				t.Error("expected error resuming with a different optimizer")
+			}
+		})
+	}
+}
+
+func TestTrainContext_InterruptSavesCheckpoint(t *testing.T) {
+	examplesDir := writeTrainingExamples(t, 8, 3, 2)
+	checkpointDir := t.TempDir()
+
+	n, err := NewNetwork(3, 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	initial := n.Checksum()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	config := &TrainingConfig{
+		LearningRate:  0.1,
+		Epochs:        10,
+		BatchSize:     2,
+		ExamplesDir:   examplesDir,
+		CheckpointDir: checkpointDir,
+	}
+	err = n.TrainContext(ctx, config)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("TrainContext() = %v, want context.Canceled", err)
+	}
+
+	checkpoint, err := LoadCheckpoint(InterruptedCheckpointPath(checkpointDir, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkpoint.Epoch != 0 || checkpoint.Optimizer.Step != 0 || checkpoint.Network.Checksum() != initial {
+		t.Errorf("checkpoint = epoch %d, step %d", checkpoint.Epoch, checkpoint.Optimizer.Step)
+	}
+
+	config.ResumeFrom = InterruptedCheckpointPath(checkpointDir, 0)
+	config.CheckpointDir = ""
+	if err := n.Train(config); err != nil {
+		t.Fatalf("resuming from the interrupted checkpoint failed: %v", err)
+	}
+}
+
+// A context that is cancelled once Err has been called more than after times, to interrupt training mid-epoch
+type cancelAfterContext struct {
+	context.Context
+	calls, after int
+}
+
+func (c *cancelAfterContext) Err() error {
+	c.calls++
+	if c.calls > c.after {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestTrainContext_InterruptMidEpochRestartsTheEpoch(t *testing.T) {
+	examplesDir := writeTrainingExamples(t, 8, 3, 2)
+	train := func(ctx context.Context, checkpointDir string) (*Network, error) {
+		n, err := NewSeededNetwork(7, 3, 4, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config := &TrainingConfig{
+			LearningRate:       0.1,
+			Epochs:             3,
+			BatchSize:          2,
+			ExamplesDir:        examplesDir,
+			Optimizer:          OPTIMIZER_ADAM,
+			CheckpointDir:      checkpointDir,
+			CheckpointInterval: 1,
+			Seed:               5,
+		}
+		return n, n.TrainContext(ctx, config)
+	}
+	cleanDir, interruptedDir := t.TempDir(), t.TempDir()
+	uninterrupted, err := train(context.Background(), cleanDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 4 batches per epoch: stop after the second batch of epoch 1
+	if _, err := train(&cancelAfterContext{Context: context.Background(), after: 6}, interruptedDir); !errors.Is(err, context.Canceled) {
+		t.Fatalf("TrainContext() = %v, want context.Canceled", err)
+	}
+	clean, err := LoadCheckpoint(CheckpointPath(cleanDir, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	intervalCheckpoint, err := LoadCheckpoint(CheckpointPath(interruptedDir, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if intervalCheckpoint.Network.Checksum() != clean.Network.Checksum() {
+		t.Error("the interruption replaced the interval checkpoint")
+	}
+	interrupted, err := LoadCheckpoint(InterruptedCheckpointPath(interruptedDir, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if interrupted.Epoch != 1 || interrupted.Optimizer.Step != clean.Optimizer.Step || interrupted.Network.Checksum() != clean.Network.Checksum() {
+		t.Errorf("interrupted checkpoint = epoch %d, step %d, want the start of epoch 1 (step %d)", interrupted.Epoch, interrupted.Optimizer.Step, clean.Optimizer.Step)
+	}
+
+	// Resuming trains every batch of the interrupted epoch exactly once
+	resumed := &Network{}
+	config := &TrainingConfig{
+		LearningRate: 0.1,
+		Epochs:       3,
+		BatchSize:    2,
+		ExamplesDir:  examplesDir,
+		Optimizer:    OPTIMIZER_ADAM,
+		ResumeFrom:   InterruptedCheckpointPath(interruptedDir, 1),
+	}
+	if err := resumed.Train(config); err != nil {
+		t.Fatal(err)
+	}
+	if resumed.Checksum() != uninterrupted.Checksum() {
+		t.Error("resuming from the interrupted checkpoint does not match the uninterrupted run")
+	}
+}
+
+func TestLoadCheckpoint_Errors(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := map[string]string{
+		"missing":     filepath.Join(dir, "missing.json"),
+		"malformed":   write("malformed.json", "{"),
+		"version":     write("version.json", `{"version":99}`),
+		"no network":  write("nonetwork.json", `{"version":1}`),
+		"bad network": write("badnetwork.json", `{"version":1,"network":{"layers":[]}}`),
+	}
+	for name, path := range tests {
+		if _, err := LoadCheckpoint(path); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
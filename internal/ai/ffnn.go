@@ -3,7 +3,7 @@ package ai
 import (
 	"encoding/json"
 	"errors"
-	"math"
+	"fmt"
 	"math/rand"
 	"os"
 	"path/filepath"
@@ -23,16 +23,58 @@ type layerCache struct {
 }
 
 type Network struct {
-	Layers []*layer `json:"layers"`
+	Layers []*layer `json:"layers"` // Trunk followed by the policy head
+
+	// Optional value head. It reads the output of the first Trunk layers and emits tanh(expected outcome).
+	Trunk     int      `json:"trunk,omitempty"`
+	ValueHead []*layer `json:"valueHead,omitempty"`
+
+	Metadata *NetworkMetadata `json:"metadata,omitempty"`
 }
 
+// Seeds that produced a network's weights, so a run can be reproduced. Unknown seeds are nil.
+type NetworkMetadata struct {
+	Seed         *int64 `json:"seed,omitempty"`         // Initial weights
+	TrainingSeed *int64 `json:"trainingSeed,omitempty"` // Validation split and example order of the last Train run
+	SelfPlaySeed *int64 `json:"selfPlaySeed,omitempty"` // Games of the last SelfPlay run
+}
+
+const (
+	ACTIVATION_NONE     = "none" // Input layer
+	ACTIVATION_RELU     = "relu"
+	ACTIVATION_IDENTITY = "identity"
+)
+
 type ForwardTrace struct {
+	Level TraceLevel `json:"-"` // Set before calling Forward to choose how much is recorded
+	TopK  int        `json:"-"` // Number of contributions kept per neuron at TRACE_LEVEL_DETAILED
+
 	LayerOutputs [][]float64 `json:"layerOutputs"`
+	Activations  []string    `json:"activations,omitempty"` // Activation function name for each entry in LayerOutputs
+
+	// Only recorded at TRACE_LEVEL_DETAILED
+	PreActivations [][]float64        `json:"preActivations,omitempty"` // Zs for each entry in LayerOutputs, nil for the input layer
+	Logits         []float64          `json:"logits,omitempty"`         // Output layer values before softmax
+	Contributions  [][][]Contribution `json:"contributions,omitempty"`  // Top-k incoming contributions for each neuron, nil for the input layer
 }
 
 // Creates a new feed-forward neural network where x1, x2, ..., xn are the neuron counts for each layer.
 // The first layer is the input layer, and the last layer is the output layer.
 func NewNetwork(neurons ...int) (*Network, error) {
+	return NewSeededNetwork(rand.Int63(), neurons...)
+}
+
+// Same as NewNetwork, but the initial weights are drawn from seed, which is recorded in the metadata
+func NewSeededNetwork(seed int64, neurons ...int) (*Network, error) {
+	n, err := newNetwork(rand.New(rand.NewSource(seed)), neurons...)
+	if err != nil {
+		return nil, err
+	}
+	n.Metadata = &NetworkMetadata{Seed: &seed}
+	return n, nil
+}
+
+func newNetwork(rng *rand.Rand, neurons ...int) (*Network, error) {
 	if len(neurons) < 2 {
 		return nil, errors.New("At least 2 neurons required")
 	}
@@ -48,31 +90,80 @@ func NewNetwork(neurons ...int) (*Network, error) {
 	}
 
 	n := &Network{Layers: layers}
-	n.randomizeWeights()
+	randomizeLayers(n.Layers, rng)
 
 	return n, nil
 }
 
-// Randomizes the weights of the network.
-func (n *Network) randomizeWeights() {
-	for _, layer := range n.Layers {
+func randomizeLayers(layers []*layer, rng *rand.Rand) {
+	for _, layer := range layers {
 		for i := 0; i < layer.Input; i++ {
 			for j := 0; j < layer.Output; j++ {
-				layer.Weights[i][j] = rand.Float64()
+				layer.Weights[i][j] = rng.Float64()
 			}
 		}
 	}
 }
 
-// Loads a FFNN config from a JSON file
+// Returns a deep copy of the network
+func (n *Network) clone() *Network {
+	return &Network{
+		Layers:    cloneLayers(n.Layers),
+		Trunk:     n.Trunk,
+		ValueHead: cloneLayers(n.ValueHead),
+		Metadata:  n.Metadata,
+	}
+}
+
+func cloneLayers(layers []*layer) []*layer {
+	if layers == nil {
+		return nil
+	}
+	clones := make([]*layer, len(layers))
+	for i, l := range layers {
+		c := newLayer(l.Input, l.Output)
+		for j := range l.Weights {
+			copy(c.Weights[j], l.Weights[j])
+		}
+		copy(c.Biases, l.Biases)
+		clones[i] = c
+	}
+	return clones
+}
+
+// Loads a FFNN config from a JSON file and validates its structure
 func LoadNetwork(fpath string) (*Network, error) {
 	fpath = filepath.Clean(fpath)
 	data, err := os.ReadFile(fpath)
 	if err != nil {
 		return nil, err
 	}
+	network, err := ParseNetwork(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fpath, err)
+	}
+	return network, nil
+}
+
+// Loads a FFNN config from a JSON file and checks that it can play on the engine's board encoding
+func LoadGameNetwork(fpath string) (*Network, error) {
+	network, err := LoadNetwork(fpath)
+	if err != nil {
+		return nil, err
+	}
+	if err := network.ValidateForGame(); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Clean(fpath), err)
+	}
+	return network, nil
+}
+
+// Parses a FFNN config from JSON and validates its structure
+func ParseNetwork(data []byte) (*Network, error) {
 	var network Network
 	if err := json.Unmarshal(data, &network); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidNetwork, err)
+	}
+	if err := network.Validate(); err != nil {
 		return nil, err
 	}
 	return &network, nil
@@ -94,24 +185,50 @@ func (n *Network) Forward(x []float64, trace *ForwardTrace) ([]float64, error) {
 	out := x
 	record := trace != nil
 
+	detailed := record && trace.Level >= TRACE_LEVEL_DETAILED
+
 	// If recording, initialize to have length of first + hidden + output layers
 	if record {
 		trace.LayerOutputs = make([][]float64, len(n.Layers)+1)
 		trace.LayerOutputs[0] = copySlice(x)
+		trace.Activations = make([]string, len(n.Layers)+1)
+		trace.Activations[0] = ACTIVATION_NONE
+		trace.PreActivations, trace.Logits, trace.Contributions = nil, nil, nil
+	}
+	if detailed {
+		trace.PreActivations = make([][]float64, len(n.Layers)+1)
+		trace.Contributions = make([][][]Contribution, len(n.Layers)+1)
 	}
 
 	for i, l := range n.Layers {
-		act := reLU
+		act, actName := reLU, ACTIVATION_RELU
 		if i == len(n.Layers)-1 {
-			act = identity // last layer emits logits
+			act, actName = identity, ACTIVATION_IDENTITY // last layer emits logits
+		}
+
+		var cache *layerCache
+		if detailed {
+			cache = &layerCache{
+				As: make([]float64, l.Output),
+				Zs: make([]float64, l.Output),
+			}
+			trace.Contributions[i+1] = l.topContributions(out, trace.topK())
 		}
-		out, err = l.feedForward(out, act, nil)
+
+		out, err = l.feedForward(out, act, cache)
 		if record {
 			trace.LayerOutputs[i+1] = copySlice(out)
+			trace.Activations[i+1] = actName
 		}
 		if err != nil {
 			return nil, err
 		}
+		if detailed {
+			trace.PreActivations[i+1] = cache.Zs
+		}
+	}
+	if detailed {
+		trace.Logits = copySlice(out)
 	}
 	return softmax(out), nil
 }
@@ -127,42 +244,60 @@ func newLayer(input, output int) *layer {
 	l := &layer{
 		Input:   input,
 		Output:  output,
-		Weights: make([][]float64, input),
+		Weights: contiguousRows(input, output),
 		Biases:  make([]float64, output),
 	}
-	for i := range l.Weights {
-		l.Weights[i] = make([]float64, output)
-	}
 	return l
 }
 
-// Feed forwards the input through the network and returns the output.
-func (l *layer) feedForward(input []float64, activationFunc func(float64) float64, cache *layerCache) ([]float64, error) {
-	if len(input) != l.Input {
-		return nil, errors.New("Input length does not match layer input length")
+// Returns rows x cols rows backed by a single row-major allocation
+func contiguousRows(rows, cols int) [][]float64 {
+	data := make([]float64, rows*cols)
+	m := make([][]float64, rows)
+	for i := range m {
+		m[i] = data[i*cols : (i+1)*cols : (i+1)*cols]
 	}
+	return m
+}
 
-	doCache := cache != nil
+// Decodes a layer and packs its weight rows into a single row-major allocation.
+// The JSON format is unchanged: weights are still an array of input rows.
+func (l *layer) UnmarshalJSON(data []byte) error {
+	type jsonLayer layer // drops the method so decoding does not recurse
+	var decoded jsonLayer
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*l = layer(decoded)
 
-	output := make([]float64, l.Output)
-	for j := 0; j < l.Output; j++ {
-		sum := l.Biases[j]
-		for i := 0; i < l.Input; i++ {
-			sum += l.Weights[i][j] * input[i]
-		}
-		// Cache pre-activation values
-		if doCache {
-			cache.Zs[j] = sum
+	// Leave ragged weights as decoded so Validate can report them
+	for _, row := range l.Weights {
+		if len(row) != l.Output {
+			return nil
 		}
+	}
+	packed := contiguousRows(len(l.Weights), l.Output)
+	for i, row := range l.Weights {
+		copy(packed[i], row)
+	}
+	l.Weights = packed
+	return nil
+}
 
-		output[j] = activationFunc(sum)
+// Feed forwards the input through the layer and returns the output.
+// When cache is non-nil the output is cache.As.
+func (l *layer) feedForward(input []float64, activationFunc func(float64) float64, cache *layerCache) ([]float64, error) {
+	if len(input) != l.Input {
+		return nil, errors.New("Input length does not match layer input length")
+	}
 
-		// Cache post-activation values
-		if doCache {
-			cache.As[j] = output[j]
-		}
+	if cache != nil {
+		l.forwardBatch(input, cache.Zs, cache.As, activationFunc, 1)
+		return cache.As, nil
 	}
 
+	output := make([]float64, l.Output)
+	l.forwardBatch(input, output, output, activationFunc, 1)
 	return output, nil
 }
 
@@ -178,30 +313,8 @@ func identity(x float64) float64 { return x }
 
 // Normalizes the input to probability distribution
 func softmax(input []float64) []float64 {
-	// Subtract max value to prevent overflow
-	max := input[0]
-	for _, value := range input {
-		if value > max {
-			max = value
-		}
-	}
-
 	// New slice to avoid overwriting input
 	values := make([]float64, len(input))
-	for i := range values {
-		// Exponentiate all values once for reuse
-		values[i] = math.Exp(input[i] - max)
-	}
-
-	// Compute denominator once for reuse
-	denominator := 0.0
-	for j := range values {
-		denominator += values[j]
-	}
-
-	for i := range values {
-		numerator := values[i]
-		values[i] = numerator / denominator
-	}
+	softmaxInto(values, input)
 	return values
 }
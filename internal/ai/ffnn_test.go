@@ -325,3 +325,35 @@ func TestForward_TraceRecording(t *testing.T) {
 		t.Fatalf("softmax outputs do not sum to ~1: sum=%v out=%v", sum, out)
 	}
 }
+
+func TestNewSeededNetwork(t *testing.T) {
+	a, err := NewSeededNetwork(5, 4, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewSeededNetwork(5, 4, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Checksum() != b.Checksum() {
+		t.Error("networks with the same seed have different weights")
+	}
+	if a.Metadata == nil || a.Metadata.Seed == nil || *a.Metadata.Seed != 5 {
+		t.Errorf("metadata = %+v, want seed 5", a.Metadata)
+	}
+
+	c, err := NewNetwork(4, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Metadata == nil || c.Metadata.Seed == nil {
+		t.Fatal("NewNetwork did not record its seed")
+	}
+	d, err := NewSeededNetwork(*c.Metadata.Seed, 4, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Checksum() != d.Checksum() {
+		t.Error("the recorded seed does not reproduce the network")
+	}
+}
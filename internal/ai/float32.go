@@ -0,0 +1,88 @@
+package ai
+
+import "errors"
+
+// Inference-only copy of a network's policy path with float32 parameters.
+// Halves the memory of the weights at the cost of precision. The value head is not included.
+type Network32 struct {
+	layers []layer32
+}
+
+type layer32 struct {
+	input   int
+	output  int
+	weights []float32 // input x output, row-major
+	biases  []float32
+}
+
+// Preallocated buffers for forward passes through a Network32. Not safe for concurrent use.
+type Workspace32 struct {
+	network *Network32
+	outputs [][]float32
+}
+
+// Returns a float32 copy of the network for inference
+func (n *Network) Float32() *Network32 {
+	n32 := &Network32{layers: make([]layer32, len(n.Layers))}
+	for i, l := range n.Layers {
+		l32 := layer32{
+			input:   l.Input,
+			output:  l.Output,
+			weights: make([]float32, l.Input*l.Output),
+			biases:  make([]float32, l.Output),
+		}
+		for j, row := range l.Weights {
+			for k, w := range row {
+				l32.weights[j*l.Output+k] = float32(w)
+			}
+		}
+		for j, b := range l.Biases {
+			l32.biases[j] = float32(b)
+		}
+		n32.layers[i] = l32
+	}
+	return n32
+}
+
+// Creates a workspace sized for the network
+func (n *Network32) NewWorkspace() *Workspace32 {
+	w := &Workspace32{
+		network: n,
+		outputs: make([][]float32, len(n.layers)),
+	}
+	for i, l := range n.layers {
+		w.outputs[i] = make([]float32, l.output)
+	}
+	return w
+}
+
+// Forward propagates the input and returns the softmax output. The returned slice belongs to the
+// workspace and is overwritten by the next call.
+func (w *Workspace32) Forward(x []float32) ([]float32, error) {
+	layers := w.network.layers
+	if len(x) != layers[0].input {
+		return nil, errors.New("Input length does not match layer input length")
+	}
+
+	in := x
+	for i, l := range layers {
+		out := w.outputs[i]
+		copy(out, l.biases)
+		for j, xj := range in {
+			if xj != 0 {
+				axpy(xj, l.weights[j*l.output:(j+1)*l.output], out)
+			}
+		}
+		// Hidden layers use ReLU, the last layer emits logits
+		if i < len(layers)-1 {
+			for k, v := range out {
+				if v < 0 {
+					out[k] = 0
+				}
+			}
+		}
+		in = out
+	}
+	softmaxInto(in, in)
+	return in, nil
+}
@@ -0,0 +1,163 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	DEFAULT_GRADIENT_CHECK_EPSILON   = 1e-6
+	DEFAULT_GRADIENT_CHECK_TOLERANCE = 1e-5
+)
+
+type GradientCheckConfig struct {
+	Epsilon      float64 // Central difference step, 0 uses DEFAULT_GRADIENT_CHECK_EPSILON
+	Tolerance    float64 // Maximum error per parameter, 0 uses DEFAULT_GRADIENT_CHECK_TOLERANCE
+	PolicyWeight float64 // Scales the policy loss like backwardWeighted, 0 uses 1
+	ValueWeight  float64 // Scales the value loss like TrainingConfig.ValueWeight, 0 uses 1
+	Batched      bool    // Compute analytic gradients with the batched training pass instead of per example
+}
+
+// A parameter whose analytic gradient disagrees with the numerical estimate
+type GradientMismatch struct {
+	Parameter string  `json:"parameter"` // e.g. "layer 1 weight [3][2]" or "value layer 0 bias [0]"
+	Analytic  float64 `json:"analytic"`
+	Numeric   float64 `json:"numeric"`
+	Error     float64 `json:"error"`
+}
+
+type GradientCheckResult struct {
+	Checked    int                `json:"checked"`  // Number of parameters compared
+	MaxError   float64            `json:"maxError"` // Largest error over all parameters
+	Mismatches []GradientMismatch `json:"mismatches"`
+}
+
+// Compares the gradients accumulated by the training backward pass over the examples with central
+// finite differences of the training loss, computed independently through Forward and Evaluate.
+// The loss is PolicyWeight * cross-entropy plus ValueWeight * squared error for examples with a value target.
+// The error is |analytic - numeric| / max(1, |analytic|, |numeric|), so it is absolute for small gradients
+// and relative for large ones.
+func (n *Network) GradientCheck(examples []*TrainingExample, config GradientCheckConfig) (*GradientCheckResult, error) {
+	if len(examples) == 0 {
+		return nil, errors.New("Gradient check requires at least one example")
+	}
+	if err := n.Validate(); err != nil {
+		return nil, err
+	}
+	epsilon := defaultIfZero(config.Epsilon, DEFAULT_GRADIENT_CHECK_EPSILON)
+	tolerance := defaultIfZero(config.Tolerance, DEFAULT_GRADIENT_CHECK_TOLERANCE)
+	policyWeight := defaultIfZero(config.PolicyWeight, 1)
+	valueWeight := defaultIfZero(config.ValueWeight, 1)
+	if config.Batched && policyWeight != 1 {
+		return nil, errors.New("The batched training pass does not support a policy weight")
+	}
+
+	// Analytic gradients
+	tn := newTrainingNetwork(n)
+	tn.valueWeight = valueWeight
+	if config.Batched {
+		if _, err := tn.trainBatch(examples); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, example := range examples {
+			if err := tn.forwardWithCache(example.Input); err != nil {
+				return nil, err
+			}
+			if err := tn.backwardWeighted(example, policyWeight); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	loss := func() (float64, error) {
+		total := 0.0
+		for _, example := range examples {
+			if !n.HasValueHead() {
+				policy, err := n.Forward(example.Input, nil)
+				if err != nil {
+					return 0, err
+				}
+				total += policyWeight * crossEntropyLoss(policy, example.Target)
+				continue
+			}
+			policy, value, err := n.Evaluate(example.Input, nil)
+			if err != nil {
+				return 0, err
+			}
+			total += policyWeight * crossEntropyLoss(policy, example.Target)
+			if example.Value != nil {
+				total += valueWeight * squaredError(value, *example.Value)
+			}
+		}
+		return total, nil
+	}
+
+	result := &GradientCheckResult{}
+	check := func(name string, parameter *float64, analytic float64) error {
+		original := *parameter
+		*parameter = original + epsilon
+		plus, err := loss()
+		if err != nil {
+			return err
+		}
+		*parameter = original - epsilon
+		minus, err := loss()
+		*parameter = original
+		if err != nil {
+			return err
+		}
+
+		numeric := (plus - minus) / (2 * epsilon)
+		gradientError := math.Abs(analytic-numeric) / max(1, math.Abs(analytic), math.Abs(numeric))
+		result.Checked++
+		result.MaxError = max(result.MaxError, gradientError)
+		if gradientError > tolerance || math.IsNaN(gradientError) {
+			result.Mismatches = append(result.Mismatches, GradientMismatch{
+				Parameter: name,
+				Analytic:  analytic,
+				Numeric:   numeric,
+				Error:     gradientError,
+			})
+		}
+		return nil
+	}
+
+	checkLayers := func(layers []*layer, buffers *trainingBuffers, name string) error {
+		for i, l := range layers {
+			for j := range l.Weights {
+				for k := range l.Weights[j] {
+					parameter := fmt.Sprintf("%s %d weight [%d][%d]", name, i, j, k)
+					if err := check(parameter, &l.Weights[j][k], buffers.wGradients[i][j][k]); err != nil {
+						return err
+					}
+				}
+			}
+			for j := range l.Biases {
+				parameter := fmt.Sprintf("%s %d bias [%d]", name, i, j)
+				if err := check(parameter, &l.Biases[j], buffers.bGradients[i][j]); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	if err := checkLayers(n.Layers, &tn.trainingBuffers, "layer"); err != nil {
+		return nil, err
+	}
+	if tn.value != nil {
+		if err := checkLayers(n.ValueHead, tn.value, "value layer"); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func defaultIfZero(value, fallback float64) float64 {
+	if value == 0 {
+		return fallback
+	}
+	return value
+}
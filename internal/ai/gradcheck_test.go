@@ -0,0 +1,147 @@
+package ai
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Centers the network's weights around 0 and gives every bias a small value so the
+// hidden ReLUs see both signs and the finite differences stay away from their kink
+func centerParameters(n *Network, rng *rand.Rand) {
+	for _, layers := range [][]*layer{n.Layers, n.ValueHead} {
+		for _, l := range layers {
+			for i := range l.Weights {
+				for j := range l.Weights[i] {
+					l.Weights[i][j] = rng.Float64() - 0.5
+				}
+			}
+			for j := range l.Biases {
+				l.Biases[j] = (rng.Float64() - 0.5) * 0.2
+			}
+		}
+	}
+}
+
+// Examples with random inputs, a one-hot or soft policy target and, for every other example, a value target
+func gradientCheckExamples(n *Network, count int, rng *rand.Rand) []*TrainingExample {
+	in := n.Layers[0].Input
+	out := n.Layers[len(n.Layers)-1].Output
+	examples := make([]*TrainingExample, count)
+	for e := range examples {
+		example := &TrainingExample{
+			Input:  make([]float64, in),
+			Target: make([]float64, out),
+		}
+		for i := range example.Input {
+			example.Input[i] = rng.Float64()*2 - 1
+		}
+		if e%2 == 0 {
+			example.Target[rng.Intn(out)] = 1
+		} else {
+			sum := 0.0
+			for i := range example.Target {
+				example.Target[i] = rng.Float64()
+				sum += example.Target[i]
+			}
+			for i := range example.Target {
+				example.Target[i] /= sum
+			}
+			value := rng.Float64()*2 - 1
+			example.Value = &value
+		}
+		examples[e] = example
+	}
+	return examples
+}
+
+func TestGradientCheck(t *testing.T) {
+	architectures := []struct {
+		name  string
+		build func() (*Network, error)
+	}{
+		{"single layer", func() (*Network, error) { return NewNetwork(4, 3) }},
+		{"one hidden layer", func() (*Network, error) { return NewNetwork(5, 7, 4) }},
+		{"deep", func() (*Network, error) { return NewNetwork(6, 8, 7, 5, 3) }},
+		{"board shape", func() (*Network, error) { return NewNetwork(18, 12, 9) }},
+		{"policy/value", func() (*Network, error) {
+			return NewPolicyValueNetwork([]int{4, 6}, []int{5, 3}, []int{4, 1})
+		}},
+		{"policy/value deep heads", func() (*Network, error) {
+			return NewPolicyValueNetwork([]int{5, 8, 6}, []int{3}, []int{5, 4, 1})
+		}},
+	}
+	configs := []struct {
+		name   string
+		config GradientCheckConfig
+	}{
+		{"per example", GradientCheckConfig{}},
+		{"batched", GradientCheckConfig{Batched: true}},
+		{"policy weight", GradientCheckConfig{PolicyWeight: -0.7}},
+		{"value weight", GradientCheckConfig{ValueWeight: 0.3}},
+	}
+
+	for a, architecture := range architectures {
+		for _, tc := range configs {
+			t.Run(architecture.name+"/"+tc.name, func(t *testing.T) {
+				rng := rand.New(rand.NewSource(int64(a + 1)))
+				n, err := architecture.build()
+				if err != nil {
+					t.Fatal(err)
+				}
+				centerParameters(n, rng)
+				examples := gradientCheckExamples(n, 4, rng)
+
+				result, err := n.GradientCheck(examples, tc.config)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result.Checked == 0 {
+					t.Fatal("no parameters were checked")
+				}
+				for _, mismatch := range result.Mismatches {
+					t.Errorf("%s: analytic %v, numeric %v (error %v)", mismatch.Parameter, mismatch.Analytic, mismatch.Numeric, mismatch.Error)
+				}
+			})
+		}
+	}
+}
+
+func TestGradientCheck_ReportsMismatches(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	n, err := NewNetwork(3, 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	centerParameters(n, rng)
+	examples := gradientCheckExamples(n, 2, rng)
+
+	// A tolerance below the finite difference error flags every parameter with a non-zero gradient
+	result, err := n.GradientCheck(examples, GradientCheckConfig{Tolerance: 1e-300})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Mismatches) == 0 {
+		t.Error("expected mismatches with an unreachable tolerance")
+	}
+	if result.Checked != 3*4+4+4*2+2 {
+		t.Errorf("checked %d parameters, want %d", result.Checked, 3*4+4+4*2+2)
+	}
+}
+
+func TestGradientCheck_Errors(t *testing.T) {
+	n, err := NewNetwork(2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := n.GradientCheck(nil, GradientCheckConfig{}); err == nil {
+		t.Error("expected error without examples")
+	}
+	examples := []*TrainingExample{{Input: []float64{1, 0}, Target: []float64{1, 0}}}
+	if _, err := n.GradientCheck(examples, GradientCheckConfig{Batched: true, PolicyWeight: 2}); err == nil {
+		t.Error("expected error for a policy weight with the batched pass")
+	}
+	bad := []*TrainingExample{{Input: []float64{1}, Target: []float64{1, 0}}}
+	if _, err := n.GradientCheck(bad, GradientCheckConfig{}); err == nil {
+		t.Error("expected error for a mismatched input")
+	}
+}
@@ -0,0 +1,102 @@
+package ai
+
+import "math"
+
+// Element types supported by the dense kernels
+type Float interface {
+	~float32 | ~float64
+}
+
+// y += a * x
+func axpy[T Float](a T, x, y []T) {
+	y = y[:len(x)] // lets the compiler drop bounds checks in the loop
+	i := 0
+	for ; i+4 <= len(x); i += 4 {
+		y[i] += a * x[i]
+		y[i+1] += a * x[i+1]
+		y[i+2] += a * x[i+2]
+		y[i+3] += a * x[i+3]
+	}
+	for ; i < len(x); i++ {
+		y[i] += a * x[i]
+	}
+}
+
+// Returns the dot product of x and y
+func dot[T Float](x, y []T) T {
+	y = y[:len(x)]
+	var s0, s1, s2, s3 T
+	i := 0
+	for ; i+4 <= len(x); i += 4 {
+		s0 += x[i] * y[i]
+		s1 += x[i+1] * y[i+1]
+		s2 += x[i+2] * y[i+2]
+		s3 += x[i+3] * y[i+3]
+	}
+	for ; i < len(x); i++ {
+		s0 += x[i] * y[i]
+	}
+	return (s0 + s1) + (s2 + s3)
+}
+
+// Writes softmax(src) to dst. dst and src may be the same slice.
+func softmaxInto[T Float](dst, src []T) {
+	max := src[0]
+	for _, v := range src {
+		if v > max {
+			max = v
+		}
+	}
+	var denominator T
+	for i, v := range src {
+		e := T(math.Exp(float64(v - max)))
+		dst[i] = e
+		denominator += e
+	}
+	for i := range dst[:len(src)] {
+		dst[i] /= denominator
+	}
+}
+
+// Computes z = in·W + b and a = act(z) for a batch of rows stored row-major (rows x Input and rows x Output).
+// Weight rows are read contiguously, so each input feature scales a whole row of the output.
+func (l *layer) forwardBatch(in, z, a []float64, act func(float64) float64, rows int) {
+	for r := range rows {
+		x := in[r*l.Input : (r+1)*l.Input]
+		zr := z[r*l.Output : (r+1)*l.Output]
+		copy(zr, l.Biases)
+		for i, xi := range x {
+			if xi != 0 {
+				axpy(xi, l.Weights[i], zr)
+			}
+		}
+		if a == nil {
+			continue
+		}
+		ar := a[r*l.Output : (r+1)*l.Output]
+		for j, v := range zr {
+			ar[j] = act(v)
+		}
+	}
+}
+
+// Accumulates the gradients dW += inᵀ·delta and db += Σ delta over a batch of rows.
+// If dIn is non-nil it is set to delta·Wᵀ, the gradient with respect to the layer's input.
+func (l *layer) backwardBatch(in, delta []float64, wGradients [][]float64, bGradients []float64, dIn []float64, rows int) {
+	for r := range rows {
+		x := in[r*l.Input : (r+1)*l.Input]
+		d := delta[r*l.Output : (r+1)*l.Output]
+		for i, xi := range x {
+			if xi != 0 {
+				axpy(xi, d, wGradients[i])
+			}
+		}
+		axpy(1, d, bGradients)
+		if dIn != nil {
+			dr := dIn[r*l.Input : (r+1)*l.Input]
+			for i := range dr {
+				dr[i] = dot(d, l.Weights[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package ai
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Training metrics for one epoch
+type EpochMetrics struct {
+	Epoch              int      `json:"epoch"`
+	Examples           int      `json:"examples"`
+	Loss               float64  `json:"loss"`     // Average combined cost per training example
+	Accuracy           float64  `json:"accuracy"` // Fraction of training examples whose top move matches the target
+	ValidationLoss     *float64 `json:"validationLoss,omitempty"`
+	ValidationAccuracy *float64 `json:"validationAccuracy,omitempty"`
+	GradientNorm       float64  `json:"gradientNorm"` // Average L2 norm of the batch gradients
+	WeightNorm         float64  `json:"weightNorm"`   // L2 norm of all weights and biases after the epoch
+	LearningRate       float64  `json:"learningRate"`
+	Seconds            float64  `json:"seconds"`
+}
+
+var metricsCSVHeader = []string{
+	"epoch", "examples", "loss", "accuracy", "validation_loss", "validation_accuracy",
+	"gradient_norm", "weight_norm", "learning_rate", "seconds",
+}
+
+// Appends epoch metrics to a file as CSV (.csv) or JSON lines (any other extension)
+type metricsWriter struct {
+	file *os.File
+	csv  *csv.Writer
+}
+
+// Opens the metrics file for appending, so resumed training continues the same file.
+// A CSV header is written when the file is empty.
+func openMetricsWriter(fpath string) (*metricsWriter, error) {
+	fpath = filepath.Clean(fpath)
+	file, err := os.OpenFile(fpath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	w := &metricsWriter{file: file}
+	if strings.EqualFold(filepath.Ext(fpath), ".csv") {
+		w.csv = csv.NewWriter(file)
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		if info.Size() == 0 {
+			w.csv.Write(metricsCSVHeader)
+		}
+	}
+	return w, nil
+}
+
+func (w *metricsWriter) Write(metrics *EpochMetrics) error {
+	if w.csv == nil {
+		data, err := json.Marshal(metrics)
+		if err != nil {
+			return err
+		}
+		_, err = w.file.Write(append(data, '\n'))
+		return err
+	}
+
+	formatFloat := func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+	formatOptional := func(v *float64) string {
+		if v == nil {
+			return ""
+		}
+		return formatFloat(*v)
+	}
+	w.csv.Write([]string{
+		strconv.Itoa(metrics.Epoch),
+		strconv.Itoa(metrics.Examples),
+		formatFloat(metrics.Loss),
+		formatFloat(metrics.Accuracy),
+		formatOptional(metrics.ValidationLoss),
+		formatOptional(metrics.ValidationAccuracy),
+		formatFloat(metrics.GradientNorm),
+		formatFloat(metrics.WeightNorm),
+		formatFloat(metrics.LearningRate),
+		formatFloat(metrics.Seconds),
+	})
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+func (w *metricsWriter) Close() error {
+	return w.file.Close()
+}
+
+// Returns the L2 norm of the mean gradients of a batch
+func (tn *trainingNetwork) gradientNorm(batchSize int) float64 {
+	sum := tn.trainingBuffers.squaredGradientSum()
+	if tn.value != nil {
+		sum += tn.value.squaredGradientSum()
+	}
+	return math.Sqrt(sum) / float64(batchSize)
+}
+
+func (b *trainingBuffers) squaredGradientSum() float64 {
+	sum := 0.0
+	for i := range b.wGradients {
+		for _, row := range b.wGradients[i] {
+			sum += dot(row, row)
+		}
+		sum += dot(b.bGradients[i], b.bGradients[i])
+	}
+	return sum
+}
+
+// Returns the L2 norm of all weights and biases
+func (n *Network) weightNorm() float64 {
+	sum := 0.0
+	for _, layers := range [][]*layer{n.Layers, n.ValueHead} {
+		for _, l := range layers {
+			for _, row := range l.Weights {
+				sum += dot(row, row)
+			}
+			sum += dot(l.Biases, l.Biases)
+		}
+	}
+	return math.Sqrt(sum)
+}
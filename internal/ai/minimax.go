@@ -12,29 +12,55 @@ Returns the best move for the AI player.
 Assumes that the AI player is Player 2 and the human player is Player 1.
 */
 func BestMove(gameBoard *engine.Board) uint8 {
+	pos, _ := BestMoveWithValue(gameBoard)
+	return pos
+}
+
+/*
+Returns the best move for the AI player and its minimax value (1 win, 0 draw, -1 loss) with perfect play.
+Assumes that the AI player is Player 2 and the human player is Player 1.
+*/
+func BestMoveWithValue(gameBoard *engine.Board) (uint8, int) {
 	if engine.IsTerminal(gameBoard) != engine.TERM_NOT {
-		return 0
+		return 0, heuristic(gameBoard)
 	}
 
 	bestValue := math.MinInt
 	bestPos := uint8(0)
-	// Run minimax on all available moves for Player 2 (AI)
+	// Run minimax on all available moves for Player 2 (AI), keeping the lowest position on ties
 	nextMoves := getNextMoves(gameBoard, uint8(2))
-	for pos, nextBoard := range nextMoves {
+	for i, nextBoard := range nextMoves {
+		if nextBoard == nil {
+			continue
+		}
 		value := abminimax(nextBoard, math.MinInt, math.MaxInt, false)
 		if value > bestValue {
 			bestValue = value
-			bestPos = pos
+			bestPos = uint8(i + 1)
 		}
 	}
 
-	return bestPos
+	return bestPos, bestValue
+}
+
+// Returns the best move for the given player.
+// Minimax always maximizes for Player 2, so the boards are swapped when playing as Player 1.
+func BestMoveFor(gameBoard *engine.Board, playerId uint8) uint8 {
+	if playerId == 2 {
+		return BestMove(gameBoard)
+	}
+	swapped := &engine.Board{
+		P1Board: gameBoard.P2Board,
+		P2Board: gameBoard.P1Board,
+	}
+	return BestMove(swapped)
 }
 
-// Takes a board state and returns all possible next boards for the given player
-func getNextMoves(gameBoard *engine.Board, playerId uint8) map[uint8]*engine.Board {
+// Takes a board state and returns all possible next boards for the given player.
+// The board after playing position p is at index p-1, and nil if p is taken, so callers visit moves in a fixed order.
+func getNextMoves(gameBoard *engine.Board, playerId uint8) [9]*engine.Board {
 	moves := gameBoard.AvailableMoves()
-	nextMoves := make(map[uint8]*engine.Board) // [position]board
+	var nextMoves [9]*engine.Board // [position-1]board
 	for bitpos := uint8(0); bitpos < 9; bitpos++ {
 		bit := uint16(1 << bitpos)
 		// If the bit is 0, then move on, no move available
@@ -48,7 +74,7 @@ func getNextMoves(gameBoard *engine.Board, playerId uint8) map[uint8]*engine.Boa
 		if err != nil || !ok {
 			panic(err)
 		}
-		nextMoves[bitpos+1] = nextBoard
+		nextMoves[bitpos] = nextBoard
 	}
 
 	return nextMoves
@@ -66,6 +92,9 @@ func abminimax(gameBoard *engine.Board, alpha int, beta int, isMax bool) int {
 		value := math.MinInt
 		nextMoves := getNextMoves(gameBoard, 2) // Get AI's moves
 		for _, nextBoard := range nextMoves {
+			if nextBoard == nil {
+				continue
+			}
 			value = max(value, abminimax(nextBoard, alpha, beta, false))
 			if value >= beta {
 				break
@@ -77,6 +106,9 @@ func abminimax(gameBoard *engine.Board, alpha int, beta int, isMax bool) int {
 		value := math.MaxInt
 		nextMoves := getNextMoves(gameBoard, 1) // Get Human's moves
 		for _, nextBoard := range nextMoves {
+			if nextBoard == nil {
+				continue
+			}
 			value = min(value, abminimax(nextBoard, alpha, beta, true))
 			if value <= alpha {
 				break
@@ -101,3 +133,26 @@ func heuristic(gameBoard *engine.Board) int {
 		panic("Invalid terminal state")
 	}
 }
+
+// Returns the minimax value (1 win, 0 draw, -1 loss) of each available position (1-9) for the given player,
+// assuming perfect play afterwards
+func MoveValuesFor(gameBoard *engine.Board, playerId uint8) map[uint8]int {
+	board := gameBoard
+	if playerId == 1 {
+		board = &engine.Board{
+			P1Board: gameBoard.P2Board,
+			P2Board: gameBoard.P1Board,
+		}
+	}
+
+	values := make(map[uint8]int)
+	if engine.IsTerminal(board) != engine.TERM_NOT {
+		return values
+	}
+	for i, nextBoard := range getNextMoves(board, 2) {
+		if nextBoard != nil {
+			values[uint8(i+1)] = abminimax(nextBoard, math.MinInt, math.MaxInt, false)
+		}
+	}
+	return values
+}
@@ -0,0 +1,61 @@
+package ai
+
+import (
+	"testing"
+
+	"t-cubed/internal/engine"
+)
+
+func TestMoveValuesFor(t *testing.T) {
+	// X | X | _
+	// O | O | _
+	// _ | _ | _
+	board := &engine.Board{P1Board: 0b000_000_011, P2Board: 0b000_011_000}
+
+	values := MoveValuesFor(board, 1)
+	if len(values) != 5 {
+		t.Fatalf("Expected 5 available moves, got %d", len(values))
+	}
+	if values[3] != 1 {
+		t.Errorf("Expected completing the top row to win for Player 1, got %d", values[3])
+	}
+	if values[7] != -1 {
+		t.Errorf("Expected leaving the middle row open to lose for Player 1, got %d", values[7])
+	}
+
+	values = MoveValuesFor(board, 2)
+	if values[6] != 1 {
+		t.Errorf("Expected completing the middle row to win for Player 2, got %d", values[6])
+	}
+	if values[7] != -1 {
+		t.Errorf("Expected leaving the top row open to lose for Player 2, got %d", values[7])
+	}
+}
+
+func TestMoveValuesFor_EmptyBoardIsDraw(t *testing.T) {
+	values := MoveValuesFor(&engine.Board{}, 1)
+	if len(values) != 9 {
+		t.Fatalf("Expected 9 available moves, got %d", len(values))
+	}
+	for pos, value := range values {
+		if value != 0 {
+			t.Errorf("Expected position %d to draw with perfect play, got %d", pos, value)
+		}
+	}
+}
+
+func TestMoveValuesFor_TerminalBoard(t *testing.T) {
+	board := &engine.Board{P1Board: 0b000_000_111, P2Board: 0b000_011_000}
+	if values := MoveValuesFor(board, 2); len(values) != 0 {
+		t.Errorf("Expected no moves on a finished board, got %v", values)
+	}
+}
+
+func TestBestMove_TiesPickTheLowestPosition(t *testing.T) {
+	// Every square draws on the empty board, so the first one is chosen every time
+	for range 50 {
+		if move := BestMove(&engine.Board{}); move != 1 {
+			t.Fatalf("Expected position 1 on the empty board, got %d", move)
+		}
+	}
+}
@@ -0,0 +1,403 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Tensor names of the exported graph
+const (
+	ONNX_INPUT_NAME        = "input"
+	ONNX_OUTPUT_NAME       = "output" // Move probabilities (Softmax)
+	ONNX_VALUE_OUTPUT_NAME = "value"  // Value head estimate (Tanh), only for networks with a value head
+)
+
+var ErrUnsupportedOnnx = errors.New("unsupported ONNX graph")
+
+// Encodes the network as an ONNX model: each layer is a Gemm followed by Relu, and the policy head
+// ends with Softmax. A value head branches from the trunk and ends with Tanh.
+// Weights are stored as float32, the precision used by most ONNX runtimes.
+func (n *Network) MarshalONNX() ([]byte, error) {
+	if err := n.Validate(); err != nil {
+		return nil, err
+	}
+
+	var graph []byte
+	emit := func(layers []*layer, prefix, input, lastOp, lastOutput string, attributes ...onnxIntAttribute) []string {
+		outputs := make([]string, len(layers))
+		x := input
+		for i, l := range layers {
+			weight := fmt.Sprintf("%s%d.weight", prefix, i)
+			bias := fmt.Sprintf("%s%d.bias", prefix, i)
+			weights := make([]float64, 0, l.Input*l.Output)
+			for _, row := range l.Weights {
+				weights = append(weights, row...)
+			}
+			graph = appendMessage(graph, onnxGraphInitializer, encodeOnnxTensor(weight, []int64{int64(l.Input), int64(l.Output)}, weights))
+			graph = appendMessage(graph, onnxGraphInitializer, encodeOnnxTensor(bias, []int64{int64(l.Output)}, l.Biases))
+
+			gemm := fmt.Sprintf("%s%d.gemm", prefix, i)
+			graph = appendMessage(graph, onnxGraphNode, encodeOnnxNode(gemm, "Gemm", []string{x, weight, bias}, []string{gemm}))
+
+			op, output := "Relu", fmt.Sprintf("%s%d.relu", prefix, i)
+			var opAttributes []onnxIntAttribute
+			if i == len(layers)-1 {
+				op, output, opAttributes = lastOp, lastOutput, attributes
+			}
+			graph = appendMessage(graph, onnxGraphNode, encodeOnnxNode(output, op, []string{gemm}, []string{output}, opAttributes...))
+			outputs[i] = output
+			x = output
+		}
+		return outputs
+	}
+
+	outputs := emit(n.Layers, "layer", ONNX_INPUT_NAME, "Softmax", ONNX_OUTPUT_NAME, onnxIntAttribute{name: "axis", value: 1})
+	if n.HasValueHead() {
+		emit(n.ValueHead, "value", outputs[n.Trunk-1], "Tanh", ONNX_VALUE_OUTPUT_NAME)
+	}
+
+	graph = appendString(graph, onnxGraphName, "t-cubed")
+	graph = appendMessage(graph, onnxGraphInput, encodeOnnxValueInfo(ONNX_INPUT_NAME, n.Layers[0].Input))
+	graph = appendMessage(graph, onnxGraphOutput, encodeOnnxValueInfo(ONNX_OUTPUT_NAME, n.Layers[len(n.Layers)-1].Output))
+	if n.HasValueHead() {
+		graph = appendMessage(graph, onnxGraphOutput, encodeOnnxValueInfo(ONNX_VALUE_OUTPUT_NAME, 1))
+	}
+
+	var opset []byte
+	opset = appendString(opset, onnxOpsetIdDomain, "")
+	opset = appendVarint(opset, onnxOpsetIdVersion, onnxOpsetVersion)
+
+	var model []byte
+	model = appendVarint(model, onnxModelIrVersion, onnxIrVersion)
+	model = appendString(model, onnxModelProducerName, "t-cubed")
+	model = appendString(model, onnxModelProducerVersion, "1")
+	model = appendMessage(model, onnxModelGraph, graph)
+	model = appendMessage(model, onnxModelOpsetImport, opset)
+	return model, nil
+}
+
+// Decodes an ONNX MLP into a network. Supported graphs are chains of Gemm (or MatMul + Add) layers
+// with Relu between them, optionally ending with Softmax, such as a PyTorch nn.Sequential of Linear
+// and ReLU modules. A branch ending with Tanh and a single output is imported as the value head.
+func ParseONNX(data []byte) (*Network, error) {
+	graph, err := decodeOnnxModel(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Older exporters also list initializers as graph inputs
+	input := ""
+	for _, name := range graph.inputs {
+		if _, ok := graph.initializers[name]; !ok {
+			if input != "" {
+				return nil, fmt.Errorf("%w: more than one input", ErrUnsupportedOnnx)
+			}
+			input = name
+		}
+	}
+	if input == "" {
+		return nil, fmt.Errorf("%w: no input", ErrUnsupportedOnnx)
+	}
+
+	consumers := map[string][]*onnxNode{}
+	for _, node := range graph.nodes {
+		for _, name := range node.inputs {
+			consumers[name] = append(consumers[name], node)
+		}
+	}
+	reader := &onnxChainReader{graph: graph, consumers: consumers}
+
+	trunk, err := reader.read(input)
+	if err != nil {
+		return nil, err
+	}
+	network := &Network{}
+	if trunk.branch == "" {
+		if err := trunk.checkHead(false); err != nil {
+			return nil, err
+		}
+		network.Layers = trunk.layers
+	} else {
+		// Two heads share the trunk, which must end with Relu
+		if len(trunk.layers) == 0 || !trunk.relu[len(trunk.layers)-1] {
+			return nil, fmt.Errorf("%w: heads must branch after a Relu", ErrUnsupportedOnnx)
+		}
+		branches := consumers[trunk.branch]
+		if len(branches) != 2 {
+			return nil, fmt.Errorf("%w: %d branches from %q, expected a policy and a value head", ErrUnsupportedOnnx, len(branches), trunk.branch)
+		}
+		var policy, value *onnxChain
+		for _, node := range branches {
+			head, err := reader.readFrom(node)
+			if err != nil {
+				return nil, err
+			}
+			if head.finalOp == "Tanh" {
+				value = head
+			} else {
+				policy = head
+			}
+		}
+		if policy == nil || value == nil {
+			return nil, fmt.Errorf("%w: expected a policy head and a Tanh value head", ErrUnsupportedOnnx)
+		}
+		if err := policy.checkHead(false); err != nil {
+			return nil, err
+		}
+		if err := value.checkHead(true); err != nil {
+			return nil, err
+		}
+		network.Layers = append(trunk.layers, policy.layers...)
+		network.Trunk = len(trunk.layers)
+		network.ValueHead = value.layers
+	}
+
+	if err := network.Validate(); err != nil {
+		return nil, err
+	}
+	return network, nil
+}
+
+// Saves a network to an ONNX file
+func SaveONNX(fpath string, n *Network) error {
+	data, err := n.MarshalONNX()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Clean(fpath), data, 0644)
+}
+
+// Loads a network from an ONNX file
+func LoadONNX(fpath string) (*Network, error) {
+	fpath = filepath.Clean(fpath)
+	data, err := os.ReadFile(fpath)
+	if err != nil {
+		return nil, err
+	}
+	network, err := ParseONNX(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fpath, err)
+	}
+	return network, nil
+}
+
+// Layers read from a chain of ONNX nodes
+type onnxChain struct {
+	layers  []*layer
+	relu    []bool // Whether each layer is followed by Relu
+	finalOp string // "Softmax", "Tanh" or "" if the chain ends with a layer or Relu
+	branch  string // Tensor the chain split at, "" if it ended
+}
+
+// Checks that a head's hidden layers use Relu and that its last layer feeds the final activation directly
+func (c *onnxChain) checkHead(value bool) error {
+	if c.branch != "" {
+		return fmt.Errorf("%w: nested branches", ErrUnsupportedOnnx)
+	}
+	if len(c.layers) == 0 {
+		return fmt.Errorf("%w: head without layers", ErrUnsupportedOnnx)
+	}
+	for i, relu := range c.relu[:len(c.relu)-1] {
+		if !relu {
+			return fmt.Errorf("%w: layer %d is not followed by Relu", ErrUnsupportedOnnx, i)
+		}
+	}
+	if c.relu[len(c.relu)-1] {
+		return fmt.Errorf("%w: Relu after the output layer", ErrUnsupportedOnnx)
+	}
+	if value {
+		if out := c.layers[len(c.layers)-1].Output; out != 1 {
+			return fmt.Errorf("%w: value head has %d outputs", ErrUnsupportedOnnx, out)
+		}
+	} else if c.finalOp == "Tanh" {
+		return fmt.Errorf("%w: policy head ends with Tanh", ErrUnsupportedOnnx)
+	}
+	return nil
+}
+
+type onnxChainReader struct {
+	graph     *onnxGraph
+	consumers map[string][]*onnxNode
+}
+
+// Reads the chain of nodes consuming the tensor
+func (r *onnxChainReader) read(tensor string) (*onnxChain, error) {
+	next := r.consumers[tensor]
+	if len(next) != 1 {
+		return nil, fmt.Errorf("%w: input %q has %d consumers", ErrUnsupportedOnnx, tensor, len(next))
+	}
+	return r.readFrom(next[0])
+}
+
+// Reads the chain starting at node until it ends or branches
+func (r *onnxChainReader) readFrom(node *onnxNode) (*onnxChain, error) {
+	chain := &onnxChain{}
+	for node != nil {
+		if len(node.outputs) != 1 {
+			return nil, fmt.Errorf("%w: node %q has %d outputs", ErrUnsupportedOnnx, node.name, len(node.outputs))
+		}
+		output := node.outputs[0]
+
+		switch node.opType {
+		case "Gemm":
+			l, err := r.gemmLayer(node)
+			if err != nil {
+				return nil, err
+			}
+			chain.layers = append(chain.layers, l)
+			chain.relu = append(chain.relu, false)
+		case "MatMul":
+			add := r.consumers[output]
+			if len(add) != 1 || add[0].opType != "Add" {
+				return nil, fmt.Errorf("%w: MatMul %q is not followed by a bias Add", ErrUnsupportedOnnx, node.name)
+			}
+			l, err := r.matMulLayer(node, add[0])
+			if err != nil {
+				return nil, err
+			}
+			chain.layers = append(chain.layers, l)
+			chain.relu = append(chain.relu, false)
+			node, output = add[0], add[0].outputs[0]
+		case "Relu":
+			if len(chain.layers) == 0 || chain.relu[len(chain.relu)-1] {
+				return nil, fmt.Errorf("%w: Relu %q does not follow a layer", ErrUnsupportedOnnx, node.name)
+			}
+			chain.relu[len(chain.relu)-1] = true
+		case "Softmax", "Tanh":
+			if len(chain.layers) == 0 {
+				return nil, fmt.Errorf("%w: %s %q does not follow a layer", ErrUnsupportedOnnx, node.opType, node.name)
+			}
+			if len(r.consumers[output]) > 0 {
+				return nil, fmt.Errorf("%w: %s %q is not the last node", ErrUnsupportedOnnx, node.opType, node.name)
+			}
+			chain.finalOp = node.opType
+		case "Identity", "Dropout":
+			// No effect at inference
+		default:
+			return nil, fmt.Errorf("%w: operator %s (%q)", ErrUnsupportedOnnx, node.opType, node.name)
+		}
+
+		next := r.consumers[output]
+		switch len(next) {
+		case 0:
+			node = nil
+		case 1:
+			node = next[0]
+		default:
+			chain.branch = output
+			node = nil
+		}
+	}
+	return chain, nil
+}
+
+// Returns the values and dims of an initializer
+func (r *onnxChainReader) initializer(name string) ([]float64, []int64, error) {
+	tensor, ok := r.graph.initializers[name]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %q is not an initializer", ErrUnsupportedOnnx, name)
+	}
+	values, err := tensor.values()
+	if err != nil {
+		return nil, nil, err
+	}
+	size := int64(1)
+	for _, d := range tensor.dims {
+		size *= d
+	}
+	if size != int64(len(values)) {
+		return nil, nil, fmt.Errorf("%w: initializer %q has %d values for dims %v", ErrUnsupportedOnnx, name, len(values), tensor.dims)
+	}
+	return values, tensor.dims, nil
+}
+
+// Builds a layer from Gemm(x, B, C) = alpha * x·B' + beta * C, where B' is B or its transpose
+func (r *onnxChainReader) gemmLayer(node *onnxNode) (*layer, error) {
+	if len(node.inputs) < 2 {
+		return nil, fmt.Errorf("%w: Gemm %q has %d inputs", ErrUnsupportedOnnx, node.name, len(node.inputs))
+	}
+	if node.attributes["transA"].i != 0 {
+		return nil, fmt.Errorf("%w: Gemm %q uses transA", ErrUnsupportedOnnx, node.name)
+	}
+	alpha, beta := 1.0, 1.0
+	if a, ok := node.attributes["alpha"]; ok {
+		alpha = float64(a.f)
+	}
+	if b, ok := node.attributes["beta"]; ok {
+		beta = float64(b.f)
+	}
+
+	weights, dims, err := r.initializer(node.inputs[1])
+	if err != nil {
+		return nil, err
+	}
+	if len(dims) != 2 {
+		return nil, fmt.Errorf("%w: Gemm %q weights have dims %v", ErrUnsupportedOnnx, node.name, dims)
+	}
+	transB := node.attributes["transB"].i != 0
+	in, out := int(dims[0]), int(dims[1])
+	if transB {
+		in, out = out, in // PyTorch Linear stores weights as output x input
+	}
+
+	l := newLayer(in, out)
+	for i := range in {
+		for j := range out {
+			w := weights[i*out+j]
+			if transB {
+				w = weights[j*in+i]
+			}
+			l.Weights[i][j] = alpha * w
+		}
+	}
+
+	if len(node.inputs) > 2 && node.inputs[2] != "" {
+		biases, _, err := r.initializer(node.inputs[2])
+		if err != nil {
+			return nil, err
+		}
+		if len(biases) != out {
+			return nil, fmt.Errorf("%w: Gemm %q has %d biases for %d outputs", ErrUnsupportedOnnx, node.name, len(biases), out)
+		}
+		for j, b := range biases {
+			l.Biases[j] = beta * b
+		}
+	}
+	return l, nil
+}
+
+// Builds a layer from Add(MatMul(x, W), b)
+func (r *onnxChainReader) matMulLayer(matMul, add *onnxNode) (*layer, error) {
+	if len(matMul.inputs) != 2 || len(add.inputs) != 2 {
+		return nil, fmt.Errorf("%w: MatMul %q must have two inputs and one bias", ErrUnsupportedOnnx, matMul.name)
+	}
+	weights, dims, err := r.initializer(matMul.inputs[1])
+	if err != nil {
+		return nil, err
+	}
+	if len(dims) != 2 {
+		return nil, fmt.Errorf("%w: MatMul %q weights have dims %v", ErrUnsupportedOnnx, matMul.name, dims)
+	}
+	in, out := int(dims[0]), int(dims[1])
+
+	biasName := add.inputs[1]
+	if biasName == matMul.outputs[0] {
+		biasName = add.inputs[0]
+	}
+	biases, _, err := r.initializer(biasName)
+	if err != nil {
+		return nil, err
+	}
+	if len(biases) != out {
+		return nil, fmt.Errorf("%w: Add %q has %d biases for %d outputs", ErrUnsupportedOnnx, add.name, len(biases), out)
+	}
+
+	l := newLayer(in, out)
+	for i := range in {
+		copy(l.Weights[i], weights[i*out:(i+1)*out])
+	}
+	copy(l.Biases, biases)
+	return l, nil
+}
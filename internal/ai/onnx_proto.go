@@ -0,0 +1,412 @@
+package ai
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Minimal wire encoding of the ONNX protobuf messages used by MLPs (see onnx/onnx.proto).
+// Only the fields needed for Gemm/MatMul/Add/Relu/Tanh/Softmax graphs are read or written.
+
+// Field numbers from onnx.proto
+const (
+	onnxModelIrVersion       = 1
+	onnxModelProducerName    = 2
+	onnxModelProducerVersion = 3
+	onnxModelGraph           = 7
+	onnxModelOpsetImport     = 8
+
+	onnxOpsetIdDomain  = 1
+	onnxOpsetIdVersion = 2
+
+	onnxGraphNode        = 1
+	onnxGraphName        = 2
+	onnxGraphInitializer = 5
+	onnxGraphInput       = 11
+	onnxGraphOutput      = 12
+
+	onnxNodeInput     = 1
+	onnxNodeOutput    = 2
+	onnxNodeName      = 3
+	onnxNodeOpType    = 4
+	onnxNodeAttribute = 5
+
+	onnxAttributeName = 1
+	onnxAttributeF    = 2
+	onnxAttributeI    = 3
+	onnxAttributeType = 20
+
+	onnxTensorDims       = 1
+	onnxTensorDataType   = 2
+	onnxTensorFloatData  = 4
+	onnxTensorName       = 8
+	onnxTensorRawData    = 9
+	onnxTensorDoubleData = 10
+
+	onnxValueInfoName = 1
+	onnxValueInfoType = 2
+
+	onnxTypeTensorType = 1
+	onnxTensorElemType = 1
+	onnxTensorShape    = 2
+	onnxShapeDim       = 1
+	onnxDimensionValue = 1
+	onnxDimensionParam = 2
+
+	// AttributeProto.AttributeType and TensorProto.DataType values
+	onnxAttributeInt   = 2
+	onnxDataTypeFloat  = 1
+	onnxDataTypeDouble = 11
+
+	// Versions written on export
+	onnxIrVersion    = 7
+	onnxOpsetVersion = 13
+)
+
+type onnxTensor struct {
+	name       string
+	dims       []int64
+	dataType   int32
+	floatData  []float32
+	doubleData []float64
+	rawData    []byte
+}
+
+type onnxAttribute struct {
+	f float32
+	i int64
+}
+
+type onnxNode struct {
+	name       string
+	opType     string
+	inputs     []string
+	outputs    []string
+	attributes map[string]onnxAttribute
+}
+
+type onnxGraph struct {
+	nodes        []*onnxNode
+	initializers map[string]*onnxTensor
+	inputs       []string
+	outputs      []string
+}
+
+// Returns the tensor's values as float64, whatever its storage
+func (t *onnxTensor) values() ([]float64, error) {
+	switch t.dataType {
+	case onnxDataTypeFloat:
+		if t.rawData != nil {
+			if len(t.rawData)%4 != 0 {
+				return nil, fmt.Errorf("tensor %q has truncated raw data", t.name)
+			}
+			values := make([]float64, len(t.rawData)/4)
+			for i := range values {
+				values[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(t.rawData[i*4:])))
+			}
+			return values, nil
+		}
+		values := make([]float64, len(t.floatData))
+		for i, v := range t.floatData {
+			values[i] = float64(v)
+		}
+		return values, nil
+	case onnxDataTypeDouble:
+		if t.rawData != nil {
+			if len(t.rawData)%8 != 0 {
+				return nil, fmt.Errorf("tensor %q has truncated raw data", t.name)
+			}
+			values := make([]float64, len(t.rawData)/8)
+			for i := range values {
+				values[i] = math.Float64frombits(binary.LittleEndian.Uint64(t.rawData[i*8:]))
+			}
+			return values, nil
+		}
+		return t.doubleData, nil
+	default:
+		return nil, fmt.Errorf("tensor %q has unsupported data type %d", t.name, t.dataType)
+	}
+}
+
+// Encoding
+
+func appendMessage(b []byte, num protowire.Number, message []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, message)
+}
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+// Encodes a float32 tensor stored as little-endian raw data
+func encodeOnnxTensor(name string, dims []int64, values []float64) []byte {
+	var b []byte
+	for _, d := range dims {
+		b = appendVarint(b, onnxTensorDims, uint64(d))
+	}
+	b = appendVarint(b, onnxTensorDataType, onnxDataTypeFloat)
+	b = appendString(b, onnxTensorName, name)
+	raw := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(raw[i*4:], math.Float32bits(float32(v)))
+	}
+	b = protowire.AppendTag(b, onnxTensorRawData, protowire.BytesType)
+	return protowire.AppendBytes(b, raw)
+}
+
+type onnxIntAttribute struct {
+	name  string
+	value int64
+}
+
+func encodeOnnxNode(name, opType string, inputs, outputs []string, attributes ...onnxIntAttribute) []byte {
+	var b []byte
+	for _, input := range inputs {
+		b = appendString(b, onnxNodeInput, input)
+	}
+	for _, output := range outputs {
+		b = appendString(b, onnxNodeOutput, output)
+	}
+	b = appendString(b, onnxNodeName, name)
+	b = appendString(b, onnxNodeOpType, opType)
+	for _, attribute := range attributes {
+		var a []byte
+		a = appendString(a, onnxAttributeName, attribute.name)
+		a = appendVarint(a, onnxAttributeI, uint64(attribute.value))
+		a = appendVarint(a, onnxAttributeType, onnxAttributeInt)
+		b = appendMessage(b, onnxNodeAttribute, a)
+	}
+	return b
+}
+
+// Encodes a float tensor value info with a symbolic batch dimension, [batch, size]
+func encodeOnnxValueInfo(name string, size int) []byte {
+	var batch, features, shape, tensorType, typeProto []byte
+	batch = appendString(batch, onnxDimensionParam, "batch")
+	features = appendVarint(features, onnxDimensionValue, uint64(size))
+	shape = appendMessage(shape, onnxShapeDim, batch)
+	shape = appendMessage(shape, onnxShapeDim, features)
+	tensorType = appendVarint(tensorType, onnxTensorElemType, onnxDataTypeFloat)
+	tensorType = appendMessage(tensorType, onnxTensorShape, shape)
+	typeProto = appendMessage(typeProto, onnxTypeTensorType, tensorType)
+
+	var b []byte
+	b = appendString(b, onnxValueInfoName, name)
+	return appendMessage(b, onnxValueInfoType, typeProto)
+}
+
+// Decoding
+
+var errOnnxMalformed = errors.New("malformed ONNX protobuf")
+
+// Calls fn for each field of a message. For varint and fixed fields v holds the value,
+// for length-delimited fields data holds the bytes.
+func walkOnnxFields(b []byte, fn func(num protowire.Number, typ protowire.Type, v uint64, data []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return errOnnxMalformed
+		}
+		b = b[n:]
+
+		var v uint64
+		var data []byte
+		switch typ {
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed32Type:
+			var v32 uint32
+			v32, n = protowire.ConsumeFixed32(b)
+			v = uint64(v32)
+		case protowire.Fixed64Type:
+			v, n = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			data, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return errOnnxMalformed
+		}
+		b = b[n:]
+		if err := fn(num, typ, v, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Appends a repeated scalar field that may be packed or unpacked
+func appendRepeated(values []uint64, typ protowire.Type, v uint64, data []byte, elemType protowire.Type) ([]uint64, error) {
+	if typ != protowire.BytesType {
+		return append(values, v), nil
+	}
+	for len(data) > 0 {
+		var n int
+		switch elemType {
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(data)
+		case protowire.Fixed32Type:
+			var v32 uint32
+			v32, n = protowire.ConsumeFixed32(data)
+			v = uint64(v32)
+		case protowire.Fixed64Type:
+			v, n = protowire.ConsumeFixed64(data)
+		}
+		if n < 0 {
+			return nil, errOnnxMalformed
+		}
+		values = append(values, v)
+		data = data[n:]
+	}
+	return values, nil
+}
+
+// Returns the graph of an ONNX model
+func decodeOnnxModel(b []byte) (*onnxGraph, error) {
+	var graph *onnxGraph
+	err := walkOnnxFields(b, func(num protowire.Number, typ protowire.Type, v uint64, data []byte) error {
+		if num != onnxModelGraph || typ != protowire.BytesType {
+			return nil
+		}
+		var err error
+		graph, err = decodeOnnxGraph(data)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if graph == nil {
+		return nil, errors.New("ONNX model has no graph")
+	}
+	return graph, nil
+}
+
+func decodeOnnxGraph(b []byte) (*onnxGraph, error) {
+	graph := &onnxGraph{initializers: map[string]*onnxTensor{}}
+	err := walkOnnxFields(b, func(num protowire.Number, typ protowire.Type, v uint64, data []byte) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case onnxGraphNode:
+			node, err := decodeOnnxNode(data)
+			if err != nil {
+				return err
+			}
+			graph.nodes = append(graph.nodes, node)
+		case onnxGraphInitializer:
+			tensor, err := decodeOnnxTensor(data)
+			if err != nil {
+				return err
+			}
+			graph.initializers[tensor.name] = tensor
+		case onnxGraphInput, onnxGraphOutput:
+			name, err := decodeOnnxValueInfoName(data)
+			if err != nil {
+				return err
+			}
+			if num == onnxGraphInput {
+				graph.inputs = append(graph.inputs, name)
+			} else {
+				graph.outputs = append(graph.outputs, name)
+			}
+		}
+		return nil
+	})
+	return graph, err
+}
+
+func decodeOnnxNode(b []byte) (*onnxNode, error) {
+	node := &onnxNode{attributes: map[string]onnxAttribute{}}
+	err := walkOnnxFields(b, func(num protowire.Number, typ protowire.Type, v uint64, data []byte) error {
+		switch num {
+		case onnxNodeInput:
+			node.inputs = append(node.inputs, string(data))
+		case onnxNodeOutput:
+			node.outputs = append(node.outputs, string(data))
+		case onnxNodeName:
+			node.name = string(data)
+		case onnxNodeOpType:
+			node.opType = string(data)
+		case onnxNodeAttribute:
+			var name string
+			var attribute onnxAttribute
+			err := walkOnnxFields(data, func(num protowire.Number, typ protowire.Type, v uint64, data []byte) error {
+				switch num {
+				case onnxAttributeName:
+					name = string(data)
+				case onnxAttributeF:
+					attribute.f = math.Float32frombits(uint32(v))
+				case onnxAttributeI:
+					attribute.i = int64(v)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			node.attributes[name] = attribute
+		}
+		return nil
+	})
+	return node, err
+}
+
+func decodeOnnxTensor(b []byte) (*onnxTensor, error) {
+	tensor := &onnxTensor{}
+	var dims, floats, doubles []uint64
+	err := walkOnnxFields(b, func(num protowire.Number, typ protowire.Type, v uint64, data []byte) error {
+		var err error
+		switch num {
+		case onnxTensorDims:
+			dims, err = appendRepeated(dims, typ, v, data, protowire.VarintType)
+		case onnxTensorDataType:
+			tensor.dataType = int32(v)
+		case onnxTensorFloatData:
+			floats, err = appendRepeated(floats, typ, v, data, protowire.Fixed32Type)
+		case onnxTensorDoubleData:
+			doubles, err = appendRepeated(doubles, typ, v, data, protowire.Fixed64Type)
+		case onnxTensorName:
+			tensor.name = string(data)
+		case onnxTensorRawData:
+			tensor.rawData = data
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range dims {
+		tensor.dims = append(tensor.dims, int64(d))
+	}
+	for _, f := range floats {
+		tensor.floatData = append(tensor.floatData, math.Float32frombits(uint32(f)))
+	}
+	for _, d := range doubles {
+		tensor.doubleData = append(tensor.doubleData, math.Float64frombits(d))
+	}
+	return tensor, nil
+}
+
+func decodeOnnxValueInfoName(b []byte) (string, error) {
+	var name string
+	err := walkOnnxFields(b, func(num protowire.Number, typ protowire.Type, v uint64, data []byte) error {
+		if num == onnxValueInfoName {
+			name = string(data)
+		}
+		return nil
+	})
+	return name, err
+}
@@ -0,0 +1,249 @@
+package ai
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Builds an ONNX model from encoded nodes and initializers
+func testOnnxModel(nodes, initializers [][]byte, inputs, outputs []string) []byte {
+	var graph []byte
+	for _, node := range nodes {
+		graph = appendMessage(graph, onnxGraphNode, node)
+	}
+	for _, initializer := range initializers {
+		graph = appendMessage(graph, onnxGraphInitializer, initializer)
+	}
+	for _, input := range inputs {
+		graph = appendMessage(graph, onnxGraphInput, encodeOnnxValueInfo(input, 1))
+	}
+	for _, output := range outputs {
+		graph = appendMessage(graph, onnxGraphOutput, encodeOnnxValueInfo(output, 1))
+	}
+	return appendMessage(nil, onnxModelGraph, graph)
+}
+
+// Encodes a float tensor using packed float_data, as some exporters do
+func testOnnxFloatDataTensor(name string, dims []int64, values []float64) []byte {
+	var b []byte
+	for _, d := range dims {
+		b = appendVarint(b, onnxTensorDims, uint64(d))
+	}
+	b = appendVarint(b, onnxTensorDataType, onnxDataTypeFloat)
+	b = appendString(b, onnxTensorName, name)
+	var packed []byte
+	for _, v := range values {
+		packed = protowire.AppendFixed32(packed, math.Float32bits(float32(v)))
+	}
+	return appendMessage(b, onnxTensorFloatData, packed)
+}
+
+// Encodes a double tensor using unpacked double_data
+func testOnnxDoubleDataTensor(name string, dims []int64, values []float64) []byte {
+	var b []byte
+	for _, d := range dims {
+		b = appendVarint(b, onnxTensorDims, uint64(d))
+	}
+	b = appendVarint(b, onnxTensorDataType, onnxDataTypeDouble)
+	b = appendString(b, onnxTensorName, name)
+	for _, v := range values {
+		b = protowire.AppendTag(b, onnxTensorDoubleData, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, math.Float64bits(v))
+	}
+	return b
+}
+
+// Appends a float attribute to an encoded node
+func testOnnxFloatAttribute(node []byte, name string, f float32) []byte {
+	var a []byte
+	a = appendString(a, onnxAttributeName, name)
+	a = protowire.AppendTag(a, onnxAttributeF, protowire.Fixed32Type)
+	a = protowire.AppendFixed32(a, math.Float32bits(f))
+	return appendMessage(node, onnxNodeAttribute, a)
+}
+
+func assertSameOutputs(t *testing.T, want, got *Network, eps float64) {
+	t.Helper()
+	rng := rand.New(rand.NewSource(1))
+	for range 10 {
+		x := make([]float64, want.Layers[0].Input)
+		for i := range x {
+			x[i] = float64(rng.Intn(2))
+		}
+		wantOut, err := want.Forward(x, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotOut, err := got.Forward(x, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !almostEqualSlices(gotOut, wantOut, eps) {
+			t.Fatalf("outputs for %v = %v, want %v", x, gotOut, wantOut)
+		}
+		if want.HasValueHead() {
+			_, wantValue, _ := want.Evaluate(x, nil)
+			_, gotValue, err := got.Evaluate(x, nil)
+			if err != nil || !almostEqual(gotValue, wantValue, eps) {
+				t.Fatalf("value for %v = %v (%v), want %v", x, gotValue, err, wantValue)
+			}
+		}
+	}
+}
+
+func TestONNX_RoundTrip(t *testing.T) {
+	n := newBenchmarkNetwork(t)
+	data, err := n.MarshalONNX()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseONNX(data)
+	if err != nil {
+		t.Fatalf("ParseONNX() = %v", err)
+	}
+	if len(parsed.Layers) != len(n.Layers) || parsed.HasValueHead() {
+		t.Fatalf("parsed %d layers (value head %v), want %d", len(parsed.Layers), parsed.HasValueHead(), len(n.Layers))
+	}
+	if err := parsed.ValidateForGame(); err != nil {
+		t.Fatal(err)
+	}
+	// Weights are exported as float32
+	assertSameOutputs(t, n, parsed, 1e-5)
+}
+
+func TestONNX_RoundTripPolicyValue(t *testing.T) {
+	n, err := NewPolicyValueNetwork([]int{18, 16, 12}, []int{10, 9}, []int{6, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	centerParameters(n, rand.New(rand.NewSource(2)))
+
+	path := filepath.Join(t.TempDir(), "model.onnx")
+	if err := SaveONNX(path, n); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := LoadONNX(path)
+	if err != nil {
+		t.Fatalf("LoadONNX() = %v", err)
+	}
+	if parsed.Trunk != n.Trunk || len(parsed.Layers) != len(n.Layers) || len(parsed.ValueHead) != len(n.ValueHead) {
+		t.Fatalf("parsed trunk %d with %d policy and %d value layers, want %d, %d and %d",
+			parsed.Trunk, len(parsed.Layers), len(parsed.ValueHead), n.Trunk, len(n.Layers), len(n.ValueHead))
+	}
+	assertSameOutputs(t, n, parsed, 1e-5)
+}
+
+func TestParseONNX_PyTorchLinear(t *testing.T) {
+	// nn.Sequential(nn.Linear(2, 3), nn.ReLU(), nn.Linear(3, 2)) stores weights as output x input with transB=1
+	w0 := []float64{1, -1, 0.5, 2, 0, -0.5} // 3x2
+	b0 := []float64{0.1, 0.2, 0.3}
+	w1 := []float64{1, 0, -1, 0.5, 0.5, 0.5} // 2x3
+	b1 := []float64{0, 1}
+
+	gemm0 := encodeOnnxNode("gemm0", "Gemm", []string{"x", "0.weight", "0.bias"}, []string{"h0"}, onnxIntAttribute{name: "transB", value: 1})
+	gemm0 = testOnnxFloatAttribute(gemm0, "alpha", 1)
+	gemm1 := encodeOnnxNode("gemm1", "Gemm", []string{"h1", "2.weight", "2.bias"}, []string{"logits"}, onnxIntAttribute{name: "transB", value: 1})
+	gemm1 = testOnnxFloatAttribute(gemm1, "beta", 2)
+	model := testOnnxModel(
+		[][]byte{
+			gemm0,
+			encodeOnnxNode("relu0", "Relu", []string{"h0"}, []string{"h1"}),
+			gemm1,
+		},
+		[][]byte{
+			testOnnxFloatDataTensor("0.weight", []int64{3, 2}, w0),
+			testOnnxFloatDataTensor("0.bias", []int64{3}, b0),
+			encodeOnnxTensor("2.weight", []int64{2, 3}, w1),
+			encodeOnnxTensor("2.bias", []int64{2}, b1),
+		},
+		// Initializers listed as inputs, as older exporters do
+		[]string{"x", "0.weight", "0.bias", "2.weight", "2.bias"},
+		[]string{"logits"},
+	)
+
+	n, err := ParseONNX(model)
+	if err != nil {
+		t.Fatalf("ParseONNX() = %v", err)
+	}
+	want := &Network{Layers: []*layer{newLayer(2, 3), newLayer(3, 2)}}
+	for i := range 2 {
+		for j := range 3 {
+			want.Layers[0].Weights[i][j] = w0[j*2+i]
+			want.Layers[1].Weights[j][i] = w1[i*3+j]
+		}
+	}
+	copy(want.Layers[0].Biases, b0)
+	want.Layers[1].Biases = []float64{2 * b1[0], 2 * b1[1]} // beta scales the bias
+
+	assertSameOutputs(t, want, n, 1e-6)
+}
+
+func TestParseONNX_MatMulAdd(t *testing.T) {
+	model := testOnnxModel(
+		[][]byte{
+			encodeOnnxNode("matmul", "MatMul", []string{"x", "w"}, []string{"xw"}),
+			encodeOnnxNode("add", "Add", []string{"xw", "b"}, []string{"z"}),
+			encodeOnnxNode("softmax", "Softmax", []string{"z"}, []string{"y"}),
+		},
+		[][]byte{
+			testOnnxDoubleDataTensor("w", []int64{2, 2}, []float64{1, 2, 3, 4}),
+			testOnnxDoubleDataTensor("b", []int64{2}, []float64{0.5, -0.5}),
+		},
+		[]string{"x"},
+		[]string{"y"},
+	)
+	n, err := ParseONNX(model)
+	if err != nil {
+		t.Fatalf("ParseONNX() = %v", err)
+	}
+	if len(n.Layers) != 1 || n.Layers[0].Weights[1][0] != 3 || n.Layers[0].Biases[1] != -0.5 {
+		t.Errorf("unexpected layer: %+v", n.Layers[0])
+	}
+}
+
+func TestParseONNX_Unsupported(t *testing.T) {
+	weights := [][]byte{
+		encodeOnnxTensor("w", []int64{2, 2}, []float64{1, 0, 0, 1}),
+		encodeOnnxTensor("b", []int64{2}, []float64{0, 0}),
+	}
+	tests := []struct {
+		name  string
+		nodes [][]byte
+	}{
+		{"unknown operator", [][]byte{
+			encodeOnnxNode("gemm", "Gemm", []string{"x", "w", "b"}, []string{"z"}),
+			encodeOnnxNode("sigmoid", "Sigmoid", []string{"z"}, []string{"y"}),
+		}},
+		{"missing relu", [][]byte{
+			encodeOnnxNode("gemm0", "Gemm", []string{"x", "w", "b"}, []string{"h"}),
+			encodeOnnxNode("gemm1", "Gemm", []string{"h", "w", "b"}, []string{"y"}),
+		}},
+		{"relu on output", [][]byte{
+			encodeOnnxNode("gemm", "Gemm", []string{"x", "w", "b"}, []string{"z"}),
+			encodeOnnxNode("relu", "Relu", []string{"z"}, []string{"y"}),
+		}},
+		{"transA", [][]byte{
+			encodeOnnxNode("gemm", "Gemm", []string{"x", "w", "b"}, []string{"y"}, onnxIntAttribute{name: "transA", value: 1}),
+		}},
+		{"weights not an initializer", [][]byte{
+			encodeOnnxNode("gemm", "Gemm", []string{"x", "x", "b"}, []string{"y"}),
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseONNX(testOnnxModel(tc.nodes, weights, []string{"x"}, []string{"y"}))
+			if !errors.Is(err, ErrUnsupportedOnnx) {
+				t.Errorf("ParseONNX() = %v, want ErrUnsupportedOnnx", err)
+			}
+		})
+	}
+
+	if _, err := ParseONNX([]byte{0xff, 0xff}); err == nil {
+		t.Error("expected error for malformed data")
+	}
+}
@@ -0,0 +1,160 @@
+package ai
+
+import (
+	"fmt"
+	"math"
+)
+
+// Optimizers supported by Train
+const (
+	OPTIMIZER_SGD      = "sgd"
+	OPTIMIZER_MOMENTUM = "momentum"
+	OPTIMIZER_ADAM     = "adam"
+)
+
+const (
+	DEFAULT_MOMENTUM = 0.9
+	ADAM_BETA1       = 0.9
+	ADAM_BETA2       = 0.999
+	ADAM_EPSILON     = 1e-8
+)
+
+// Applies averaged batch gradients to the network's weights
+type optimizer struct {
+	OptimizerState
+	momentum    float64
+	weightDecay float64
+}
+
+// Creates the optimizer named by the config. A non-nil state continues from a checkpoint.
+func newOptimizer(config *TrainingConfig, n *Network, state *OptimizerState) (*optimizer, error) {
+	name := config.Optimizer
+	if name == "" {
+		name = OPTIMIZER_SGD
+	}
+	if name != OPTIMIZER_SGD && name != OPTIMIZER_MOMENTUM && name != OPTIMIZER_ADAM {
+		return nil, fmt.Errorf("unknown optimizer %q", config.Optimizer)
+	}
+	if config.WeightDecay < 0 {
+		return nil, fmt.Errorf("weight decay must not be negative, got %v", config.WeightDecay)
+	}
+
+	o := &optimizer{
+		OptimizerState: OptimizerState{Name: name, LearningRate: config.LearningRate},
+		momentum:       config.Momentum,
+		weightDecay:    config.WeightDecay,
+	}
+	if o.momentum == 0 {
+		o.momentum = DEFAULT_MOMENTUM
+	}
+
+	sizes := []int{}
+	for _, l := range append(n.Layers[:len(n.Layers):len(n.Layers)], n.ValueHead...) {
+		sizes = append(sizes, l.Input*l.Output+l.Output)
+	}
+
+	if state != nil {
+		if state.Name == "" {
+			state.Name = OPTIMIZER_SGD
+		}
+		if state.Name != name {
+			return nil, fmt.Errorf("checkpoint was trained with %s, not %s", state.Name, name)
+		}
+		if name != OPTIMIZER_SGD && !momentsMatch(state.Moments, sizes) ||
+			name == OPTIMIZER_ADAM && !momentsMatch(state.Squares, sizes) {
+			return nil, fmt.Errorf("checkpoint %s state does not match the network", name)
+		}
+		o.OptimizerState = *state
+		return o, nil
+	}
+
+	if name != OPTIMIZER_SGD {
+		o.Moments = make([][]float64, len(sizes))
+		for i, size := range sizes {
+			o.Moments[i] = make([]float64, size)
+		}
+	}
+	if name == OPTIMIZER_ADAM {
+		o.Squares = make([][]float64, len(sizes))
+		for i, size := range sizes {
+			o.Squares[i] = make([]float64, size)
+		}
+	}
+	return o, nil
+}
+
+func momentsMatch(moments [][]float64, sizes []int) bool {
+	if len(moments) != len(sizes) {
+		return false
+	}
+	for i := range sizes {
+		if len(moments[i]) != sizes[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Updates the weights with the gradients accumulated over a batch, then resets the gradients.
+// Per-layer state is indexed over the policy layers followed by the value head,
+// with each layer's weights (row by row) followed by its biases.
+func (o *optimizer) update(tn *trainingNetwork, batchSize int) {
+	o.Step++
+	if o.Name == OPTIMIZER_SGD && o.weightDecay == 0 {
+		tn.updateWeights(o.LearningRate, batchSize)
+		return
+	}
+
+	scale := 1 / float64(batchSize)
+	// Adam's bias corrections for moments that start at zero
+	c1 := 1 - math.Pow(ADAM_BETA1, float64(o.Step))
+	c2 := 1 - math.Pow(ADAM_BETA2, float64(o.Step))
+
+	chains := []struct {
+		layers  []*layer
+		buffers *trainingBuffers
+	}{
+		{tn.network.Layers, &tn.trainingBuffers},
+		{tn.network.ValueHead, tn.value},
+	}
+	li := 0
+	for _, chain := range chains {
+		if chain.buffers == nil {
+			continue
+		}
+		for i, l := range chain.layers {
+			k := 0
+			for j, row := range l.Weights {
+				for c := range row {
+					// L2 regularization applies to weights only
+					g := chain.buffers.wGradients[i][j][c]*scale + o.weightDecay*row[c]
+					row[c] -= o.step(li, k, g, c1, c2)
+					k++
+				}
+			}
+			for j := range l.Biases {
+				l.Biases[j] -= o.step(li, k, chain.buffers.bGradients[i][j]*scale, c1, c2)
+				k++
+			}
+			li++
+		}
+		chain.buffers.resetGradients()
+	}
+}
+
+// Returns the change to subtract from parameter k of layer li given its gradient
+func (o *optimizer) step(li, k int, g, c1, c2 float64) float64 {
+	switch o.Name {
+	case OPTIMIZER_MOMENTUM:
+		m := o.Moments[li]
+		m[k] = o.momentum*m[k] + g
+		return o.LearningRate * m[k]
+	case OPTIMIZER_ADAM:
+		m, s := o.Moments[li], o.Squares[li]
+		m[k] = ADAM_BETA1*m[k] + (1-ADAM_BETA1)*g
+		s[k] = ADAM_BETA2*s[k] + (1-ADAM_BETA2)*g*g
+		return o.LearningRate * (m[k] / c1) / (math.Sqrt(s[k]/c2) + ADAM_EPSILON)
+	default:
+		return o.LearningRate * g
+	}
+}
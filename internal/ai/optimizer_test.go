@@ -0,0 +1,105 @@
+package ai
+
+import (
+	"math"
+	"testing"
+)
+
+// Builds a 2-1 network and a training network whose accumulated gradients are grads (weights then bias)
+func newOptimizerTestNetwork(t *testing.T, weights []float64, grads []float64) *trainingNetwork {
+	t.Helper()
+	n, err := NewNetwork(2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Layers[0].Weights[0][0], n.Layers[0].Weights[1][0] = weights[0], weights[1]
+	n.Layers[0].Biases[0] = weights[2]
+	tn := newTrainingNetwork(n)
+	tn.wGradients[0][0][0], tn.wGradients[0][1][0] = grads[0], grads[1]
+	tn.bGradients[0][0] = grads[2]
+	return tn
+}
+
+func parameters(n *Network) []float64 {
+	l := n.Layers[0]
+	return []float64{l.Weights[0][0], l.Weights[1][0], l.Biases[0]}
+}
+
+func TestOptimizer_Update(t *testing.T) {
+	weights := []float64{1, -2, 0.5}
+	grads := []float64{0.4, -0.2, 0} // Summed over a batch of 2
+
+	tests := []struct {
+		name   string
+		config TrainingConfig
+		steps  int
+		want   []float64
+	}{
+		{"sgd", TrainingConfig{LearningRate: 0.1}, 1, []float64{0.98, -1.99, 0.5}},
+		{"sgd weight decay", TrainingConfig{LearningRate: 0.1, WeightDecay: 0.5}, 1, []float64{0.93, -1.89, 0.5}},
+		// v = 0.9v + g, twice with the same gradient: v = 1.9g
+		{"momentum", TrainingConfig{LearningRate: 0.1, Optimizer: OPTIMIZER_MOMENTUM}, 2, []float64{1 - 0.1*(0.2+0.38), -2 + 0.1*(0.1+0.19), 0.5}},
+		// Adam's first step moves every parameter with a gradient by about the learning rate
+		{"adam", TrainingConfig{LearningRate: 0.1, Optimizer: OPTIMIZER_ADAM}, 1, []float64{0.9, -1.9, 0.5}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tn := newOptimizerTestNetwork(t, weights, grads)
+			o, err := newOptimizer(&tc.config, tn.network, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for step := range tc.steps {
+				if step > 0 {
+					tn.wGradients[0][0][0], tn.wGradients[0][1][0] = grads[0], grads[1]
+				}
+				o.update(tn, 2)
+			}
+			if got := parameters(tn.network); !almostEqualSlices(got, tc.want, 1e-6) {
+				t.Errorf("parameters = %v, want %v", got, tc.want)
+			}
+			if o.Step != tc.steps {
+				t.Errorf("step = %d, want %d", o.Step, tc.steps)
+			}
+			if tn.wGradients[0][0][0] != 0 || tn.bGradients[0][0] != 0 {
+				t.Error("gradients were not reset")
+			}
+		})
+	}
+}
+
+func TestOptimizer_AdamBiasCorrection(t *testing.T) {
+	tn := newOptimizerTestNetwork(t, []float64{0, 0, 0}, []float64{1, 0, 0})
+	o, err := newOptimizer(&TrainingConfig{LearningRate: 1, Optimizer: OPTIMIZER_ADAM}, tn.network, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.update(tn, 1)
+	tn.wGradients[0][0][0] = 1
+	o.update(tn, 1)
+
+	// With a constant gradient the bias-corrected moments equal the gradient and its square
+	if got := tn.network.Layers[0].Weights[0][0]; math.Abs(got+2) > 1e-6 {
+		t.Errorf("weight after two steps = %v, want -2", got)
+	}
+}
+
+func TestNewOptimizer_Errors(t *testing.T) {
+	n, err := NewNetwork(2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newOptimizer(&TrainingConfig{Optimizer: "rmsprop"}, n, nil); err == nil {
+		t.Error("expected error for unknown optimizer")
+	}
+	if _, err := newOptimizer(&TrainingConfig{WeightDecay: -1}, n, nil); err == nil {
+		t.Error("expected error for negative weight decay")
+	}
+	state := &OptimizerState{Name: OPTIMIZER_ADAM, Moments: [][]float64{{0}}, Squares: [][]float64{{0}}}
+	if _, err := newOptimizer(&TrainingConfig{Optimizer: OPTIMIZER_ADAM}, n, state); err == nil {
+		t.Error("expected error for moments that do not match the network")
+	}
+	if _, err := newOptimizer(&TrainingConfig{}, n, &OptimizerState{}); err != nil {
+		t.Errorf("state without a name should resume as SGD: %v", err)
+	}
+}
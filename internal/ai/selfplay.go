@@ -0,0 +1,306 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand"
+
+	"t-cubed/internal/engine"
+)
+
+type SelfPlayConfig struct {
+	LearningRate     float64 `json:"learningRate"`
+	Episodes         int     `json:"episodes"`         // Number of games to play
+	BatchSize        int     `json:"batchSize"`        // Games per weight update
+	Discount         float64 `json:"discount"`         // Return multiplier per move away from the end of the game
+	PoolSize         int     `json:"poolSize"`         // Maximum number of past checkpoints kept as opponents
+	SnapshotInterval int     `json:"snapshotInterval"` // Games between adding the current network to the pool
+	PoolRatio        float64 `json:"poolRatio"`        // Probability of playing a past checkpoint instead of itself
+	EvalInterval     int     `json:"evalInterval"`     // Games between evaluations against minimax, 0 to disable
+	EvalGames        int     `json:"evalGames"`        // Games played per evaluation
+	Seed             int64   `json:"seed"`             // Seeds game play and evaluation, 0 picks a random seed
+}
+
+// Outcome of games played against minimax, from the network's point of view
+type EvalResult struct {
+	Wins   int `json:"wins"`
+	Draws  int `json:"draws"`
+	Losses int `json:"losses"`
+}
+
+func (r EvalResult) Games() int {
+	return r.Wins + r.Draws + r.Losses
+}
+
+// Rewards for the final position of a game, from the mover's point of view
+const (
+	REWARD_WIN  = 1.0
+	REWARD_DRAW = 0.0
+	REWARD_LOSS = -1.0
+)
+
+// Chance that the minimax opponent plays a random move during evaluation, so games vary
+const EVAL_OPPONENT_RANDOMNESS = 0.2
+
+// A move played by the learning network, kept until the game's outcome is known
+type selfPlayStep struct {
+	input    []float64
+	action   int // 0-8
+	playerId uint8
+}
+
+// Trains the network with REINFORCE by playing against itself and past checkpoints of itself.
+// Only moves played by the current network are learned from.
+func (n *Network) SelfPlay(config *SelfPlayConfig) error {
+	if config.Episodes < 1 || config.BatchSize < 1 {
+		return errors.New("Episodes and batch size must be at least 1")
+	}
+	if err := n.ValidateForGame(); err != nil {
+		return err
+	}
+
+	seed := config.Seed
+	for seed == 0 {
+		seed = rand.Int63()
+	}
+	rng := rand.New(rand.NewSource(seed))
+	if n.Metadata == nil {
+		n.Metadata = &NetworkMetadata{}
+	}
+	n.Metadata.SelfPlaySeed = &seed
+
+	tn := newTrainingNetwork(n)
+	pool := []*Network{}
+	batchIndex := 0
+	batchSteps := 0
+	totalReward := 0.0
+
+	for episode := 1; episode <= config.Episodes; episode++ {
+		// Pick an opponent: the current network or a past checkpoint
+		opponent := n
+		if len(pool) > 0 && rng.Float64() < config.PoolRatio {
+			opponent = pool[rng.Intn(len(pool))]
+		}
+		learnerId := uint8(rng.Intn(2) + 1)
+		firstPlayerId := uint8(rng.Intn(2) + 1)
+
+		steps, terminalState, err := n.playSelfPlayGame(opponent, learnerId, firstPlayerId, rng)
+		if err != nil {
+			return err
+		}
+
+		// Learn from every move played by the current network, weighted by its discounted return
+		for i, step := range steps {
+			reward := selfPlayReward(terminalState, step.playerId)
+			ret := reward * math.Pow(config.Discount, float64(len(steps)-1-i))
+			example := &TrainingExample{
+				Input:  step.input,
+				Target: make([]float64, engine.NETWORK_OUTPUT_LEN),
+				Value:  &reward, // The value head learns the game's outcome
+			}
+			example.Target[step.action] = 1
+
+			if err := tn.forwardWithCache(step.input); err != nil {
+				return err
+			}
+			// Gradient descent on -return * log(p[action]) is gradient ascent on the expected return
+			if err := tn.backwardWeighted(example, ret); err != nil {
+				return err
+			}
+		}
+		batchSteps += len(steps)
+		totalReward += selfPlayReward(terminalState, learnerId)
+		batchIndex++
+
+		if batchIndex == config.BatchSize || episode == config.Episodes {
+			if batchSteps > 0 {
+				tn.updateWeights(config.LearningRate, batchSteps)
+			}
+			batchIndex = 0
+			batchSteps = 0
+		}
+
+		if config.SnapshotInterval > 0 && episode%config.SnapshotInterval == 0 && config.PoolSize > 0 {
+			pool = append(pool, n.clone())
+			if len(pool) > config.PoolSize {
+				pool = pool[1:]
+			}
+		}
+
+		if config.EvalInterval > 0 && episode%config.EvalInterval == 0 {
+			result, err := n.evaluateAgainstMinimax(config.EvalGames, rng)
+			if err != nil {
+				return err
+			}
+			message := fmt.Sprintf("Episode %d: Average reward: %f", episode, totalReward/float64(config.EvalInterval))
+			slog.Info(message, "wins", result.Wins, "draws", result.Draws, "losses", result.Losses, "pool_size", len(pool))
+			totalReward = 0
+		}
+	}
+
+	slog.Info("Self-play training complete", "episodes", config.Episodes)
+	return nil
+}
+
+// Plays one game between the network (as learnerId) and the opponent.
+// Returns the learner's moves (or every move when playing itself) and the terminal state.
+func (n *Network) playSelfPlayGame(opponent *Network, learnerId uint8, firstPlayerId uint8, rng *rand.Rand) ([]selfPlayStep, uint8, error) {
+	gameState, err := engine.NewGameState(&engine.GameStateOptions{
+		Player1Piece:  engine.PIECE_X,
+		Player2Piece:  engine.PIECE_O,
+		FirstPlayerId: firstPlayerId,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Workspaces avoid allocating on every move
+	learnerWorkspace, opponentWorkspace := n.NewWorkspace(), opponent.NewWorkspace()
+
+	steps := []selfPlayStep{}
+	for !gameState.IsTerminal() {
+		playerId := gameState.GetCurrentPlayerId()
+		mover, workspace := n, learnerWorkspace
+		if playerId != learnerId {
+			mover, workspace = opponent, opponentWorkspace
+		}
+
+		input := gameState.GetBoardAsNetworkInputFor(playerId)
+		probs, err := workspace.Forward(input)
+		if err != nil {
+			return nil, 0, err
+		}
+		action := sampleLegalMove(probs, gameState.Board.AvailableMoves(), rng)
+		if ok, err := gameState.Move(uint8(action + 1)); !ok || err != nil {
+			return nil, 0, fmt.Errorf("self-play move %d failed: %v", action+1, err)
+		}
+
+		if mover == n {
+			steps = append(steps, selfPlayStep{input: input, action: action, playerId: playerId})
+		}
+	}
+	return steps, gameState.TerminalState, nil
+}
+
+// Samples a move (0-8) from the network's probabilities, restricted to the available moves
+func sampleLegalMove(probs []float64, available uint16, rng *rand.Rand) int {
+	total := 0.0
+	for i, p := range probs {
+		if available&(1<<i) != 0 {
+			total += p
+		}
+	}
+
+	r := rng.Float64() * total
+	last := -1
+	for i, p := range probs {
+		if available&(1<<i) == 0 {
+			continue
+		}
+		last = i
+		r -= p
+		if r <= 0 {
+			return i
+		}
+	}
+	return last // Rounding can leave a tiny remainder
+}
+
+// Returns a uniformly random available move (0-8)
+func randomLegalMove(available uint16, rng *rand.Rand) int {
+	moves := []int{}
+	for i := range engine.NETWORK_OUTPUT_LEN {
+		if available&(1<<i) != 0 {
+			moves = append(moves, i)
+		}
+	}
+	return moves[rng.Intn(len(moves))]
+}
+
+// Returns the highest-probability available move (0-8)
+func greedyLegalMove(probs []float64, available uint16) int {
+	best := -1
+	for i, p := range probs {
+		if available&(1<<i) == 0 {
+			continue
+		}
+		if best == -1 || p > probs[best] {
+			best = i
+		}
+	}
+	return best
+}
+
+// Returns the reward of the terminal state for the given player
+func selfPlayReward(terminalState uint8, playerId uint8) float64 {
+	switch terminalState {
+	case engine.TERM_WIN_1:
+		if playerId == 1 {
+			return REWARD_WIN
+		}
+		return REWARD_LOSS
+	case engine.TERM_WIN_2:
+		if playerId == 2 {
+			return REWARD_WIN
+		}
+		return REWARD_LOSS
+	default:
+		return REWARD_DRAW
+	}
+}
+
+// Plays the network greedily against minimax. The network plays both seats and both turn orders,
+// and minimax plays a random move with probability EVAL_OPPONENT_RANDOMNESS so that games vary.
+func (n *Network) EvaluateAgainstMinimax(games int) (EvalResult, error) {
+	return n.evaluateAgainstMinimax(games, rand.New(rand.NewSource(rand.Int63())))
+}
+
+func (n *Network) evaluateAgainstMinimax(games int, rng *rand.Rand) (EvalResult, error) {
+	result := EvalResult{}
+	workspace := n.NewWorkspace()
+	for game := range games {
+		networkId := uint8(game%2 + 1)
+		firstPlayerId := uint8((game/2)%2 + 1)
+
+		gameState, err := engine.NewGameState(&engine.GameStateOptions{
+			Player1Piece:  engine.PIECE_X,
+			Player2Piece:  engine.PIECE_O,
+			FirstPlayerId: firstPlayerId,
+		})
+		if err != nil {
+			return result, err
+		}
+
+		for !gameState.IsTerminal() {
+			playerId := gameState.GetCurrentPlayerId()
+			available := gameState.Board.AvailableMoves()
+			var position uint8
+			if playerId == networkId {
+				probs, err := workspace.Forward(gameState.GetBoardAsNetworkInputFor(playerId))
+				if err != nil {
+					return result, err
+				}
+				position = uint8(greedyLegalMove(probs, available) + 1)
+			} else if rng.Float64() < EVAL_OPPONENT_RANDOMNESS {
+				position = uint8(randomLegalMove(available, rng) + 1)
+			} else {
+				position = BestMoveFor(gameState.Board, playerId)
+			}
+			if ok, err := gameState.Move(position); !ok || err != nil {
+				return result, fmt.Errorf("evaluation move %d failed: %v", position, err)
+			}
+		}
+
+		switch selfPlayReward(gameState.TerminalState, networkId) {
+		case REWARD_WIN:
+			result.Wins++
+		case REWARD_LOSS:
+			result.Losses++
+		default:
+			result.Draws++
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,171 @@
+package ai
+
+import (
+	"math/rand"
+	"testing"
+
+	"t-cubed/internal/engine"
+)
+
+func TestSelfPlayReward(t *testing.T) {
+	tests := []struct {
+		terminalState uint8
+		playerId      uint8
+		want          float64
+	}{
+		{engine.TERM_WIN_1, 1, REWARD_WIN},
+		{engine.TERM_WIN_1, 2, REWARD_LOSS},
+		{engine.TERM_WIN_2, 2, REWARD_WIN},
+		{engine.TERM_WIN_2, 1, REWARD_LOSS},
+		{engine.TERM_DRAW, 1, REWARD_DRAW},
+	}
+	for _, tt := range tests {
+		if got := selfPlayReward(tt.terminalState, tt.playerId); got != tt.want {
+			t.Fatalf("selfPlayReward(%d, %d) = %v, want %v", tt.terminalState, tt.playerId, got, tt.want)
+		}
+	}
+}
+
+func TestSampleLegalMove_OnlyAvailable(t *testing.T) {
+	// Most of the probability is on taken cells
+	probs := []float64{0.5, 0.3, 0.1, 0.05, 0.05, 0, 0, 0, 0}
+	available := uint16(0b000011000) // cells 3 and 4 (0-indexed)
+	rng := rand.New(rand.NewSource(1))
+	for range 100 {
+		move := sampleLegalMove(probs, available, rng)
+		if move != 3 && move != 4 {
+			t.Fatalf("sampled unavailable move %d", move)
+		}
+	}
+	if move := greedyLegalMove(probs, available); move != 3 {
+		t.Fatalf("expected greedy move 3, got %d", move)
+	}
+	for range 100 {
+		if move := randomLegalMove(available, rng); move != 3 && move != 4 {
+			t.Fatalf("random move %d is unavailable", move)
+		}
+	}
+}
+
+func TestPlaySelfPlayGame(t *testing.T) {
+	n, err := NewNetwork(18, 16, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Playing itself, every move is the learner's
+	rng := rand.New(rand.NewSource(1))
+	steps, terminalState, err := n.playSelfPlayGame(n, 1, 1, rng)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if terminalState == engine.TERM_NOT {
+		t.Fatalf("game did not finish")
+	}
+	if len(steps) < 5 || len(steps) > 9 {
+		t.Fatalf("unexpected number of moves: %d", len(steps))
+	}
+
+	// Playing a checkpoint, only the learner's moves are kept
+	steps, _, err = n.playSelfPlayGame(n.clone(), 2, 1, rng)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range steps {
+		if step.playerId != 2 {
+			t.Fatalf("kept a move played by the opponent: %+v", step)
+		}
+	}
+}
+
+func TestSelfPlay_UpdatesWeights(t *testing.T) {
+	n, err := NewNetwork(18, 16, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := n.Checksum()
+
+	config := &SelfPlayConfig{
+		LearningRate:     0.01,
+		Episodes:         20,
+		BatchSize:        5,
+		Discount:         0.9,
+		PoolSize:         2,
+		SnapshotInterval: 5,
+		PoolRatio:        0.5,
+		EvalInterval:     10,
+		EvalGames:        4,
+	}
+	if err := n.SelfPlay(config); err != nil {
+		t.Fatal(err)
+	}
+	if n.Checksum() == before {
+		t.Fatalf("expected self-play to change the weights")
+	}
+	if err := n.Validate(); err != nil {
+		t.Fatalf("network invalid after self-play: %v", err)
+	}
+}
+
+func TestSelfPlay_SameSeedsProduceIdenticalWeights(t *testing.T) {
+	play := func(seed int64) *Network {
+		n, err := NewSeededNetwork(3, 18, 16, 9)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config := &SelfPlayConfig{
+			LearningRate:     0.01,
+			Episodes:         20,
+			BatchSize:        5,
+			Discount:         0.9,
+			PoolSize:         2,
+			SnapshotInterval: 5,
+			PoolRatio:        0.5,
+			EvalInterval:     5, // Evaluation plays minimax, whose ties must not depend on map order
+			EvalGames:        2,
+			Seed:             seed,
+		}
+		if err := n.SelfPlay(config); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	first, second := play(11), play(11)
+	if first.Checksum() != second.Checksum() {
+		t.Error("two self-play runs with the same seed produced different weights")
+	}
+	if first.Checksum() == play(12).Checksum() {
+		t.Error("a different self-play seed produced the same weights")
+	}
+	if first.Metadata.SelfPlaySeed == nil || *first.Metadata.SelfPlaySeed != 11 {
+		t.Errorf("metadata = %+v, want self-play seed 11", first.Metadata)
+	}
+}
+
+func TestEvaluateAgainstMinimax(t *testing.T) {
+	n, err := NewNetwork(18, 16, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := n.EvaluateAgainstMinimax(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Games() != 8 {
+		t.Fatalf("expected 8 games, got %+v", result)
+	}
+}
+
+func TestBestMoveFor_Player1(t *testing.T) {
+	// Player 2 has 4 and 5, so Player 1 must block at 6
+	board := &engine.Board{P1Board: 0b100000001, P2Board: 0b000011000}
+	if move := BestMoveFor(board, 1); move != 6 {
+		t.Fatalf("expected Player 1 to block at 6, got %d", move)
+	}
+	// Player 1 has 1 and 2, so Player 2 must block at 3
+	board = &engine.Board{P1Board: 0b000000011, P2Board: 0b000010000}
+	if move := BestMoveFor(board, 2); move != 3 {
+		t.Fatalf("expected Player 2 to block at 3, got %d", move)
+	}
+}
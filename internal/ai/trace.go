@@ -0,0 +1,80 @@
+package ai
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Controls how much of a forward pass is recorded in a ForwardTrace
+type TraceLevel int
+
+const (
+	TRACE_LEVEL_OUTPUTS  TraceLevel = iota // Post-activation outputs only (cheap)
+	TRACE_LEVEL_DETAILED                   // Adds pre-activations, logits and top-k weighted contributions
+)
+
+const DEFAULT_TRACE_TOP_K = 3
+
+// Parses a trace level name, "outputs" or "detailed". An empty name returns TRACE_LEVEL_OUTPUTS.
+func ParseTraceLevel(name string) (TraceLevel, error) {
+	switch name {
+	case "", "outputs":
+		return TRACE_LEVEL_OUTPUTS, nil
+	case "detailed":
+		return TRACE_LEVEL_DETAILED, nil
+	default:
+		return TRACE_LEVEL_OUTPUTS, fmt.Errorf("unknown trace level %q", name)
+	}
+}
+
+// The weighted input a neuron received from a single neuron of the previous layer
+type Contribution struct {
+	From   int     `json:"from"`   // Index of the neuron in the previous layer
+	Weight float64 `json:"weight"` // Weight of the connection
+	Input  float64 `json:"input"`  // Value of the neuron in the previous layer
+	Value  float64 `json:"value"`  // Weight * Input
+}
+
+// Creates a trace recording at the given level. A topK of 0 uses DEFAULT_TRACE_TOP_K.
+func NewForwardTrace(level TraceLevel, topK int) *ForwardTrace {
+	return &ForwardTrace{
+		Level: level,
+		TopK:  topK,
+	}
+}
+
+func (t *ForwardTrace) topK() int {
+	if t.TopK > 0 {
+		return t.TopK
+	}
+	return DEFAULT_TRACE_TOP_K
+}
+
+// Returns the k largest (by magnitude) incoming contributions for each output neuron of the layer
+func (l *layer) topContributions(input []float64, k int) [][]Contribution {
+	if len(input) != l.Input {
+		return nil // feedForward reports the mismatch
+	}
+	k = min(k, l.Input)
+
+	contributions := make([][]Contribution, l.Output)
+	candidates := make([]Contribution, l.Input)
+	for j := 0; j < l.Output; j++ {
+		for i := 0; i < l.Input; i++ {
+			candidates[i] = Contribution{
+				From:   i,
+				Weight: l.Weights[i][j],
+				Input:  input[i],
+				Value:  l.Weights[i][j] * input[i],
+			}
+		}
+		// Stable so ties keep the lower neuron index first
+		sort.SliceStable(candidates, func(a, b int) bool {
+			return math.Abs(candidates[a].Value) > math.Abs(candidates[b].Value)
+		})
+		contributions[j] = make([]Contribution, k)
+		copy(contributions[j], candidates[:k])
+	}
+	return contributions
+}
@@ -0,0 +1,112 @@
+package ai
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Builds a deterministic network: 3 inputs -> 2 hidden -> 2 outputs
+func newTraceTestNetwork() *Network {
+	return &Network{
+		Layers: []*layer{
+			{
+				Input:   3,
+				Output:  2,
+				Weights: [][]float64{{1, -1}, {2, 0.5}, {-3, 1}},
+				Biases:  []float64{0.5, -4},
+			},
+			{
+				Input:   2,
+				Output:  2,
+				Weights: [][]float64{{1, -2}, {3, 1}},
+				Biases:  []float64{0, 1},
+			},
+		},
+	}
+}
+
+func TestParseTraceLevel(t *testing.T) {
+	for name, want := range map[string]TraceLevel{"": TRACE_LEVEL_OUTPUTS, "outputs": TRACE_LEVEL_OUTPUTS, "detailed": TRACE_LEVEL_DETAILED} {
+		if level, err := ParseTraceLevel(name); err != nil || level != want {
+			t.Errorf("expected %q to parse as %d, got %d %v", name, want, level, err)
+		}
+	}
+	if _, err := ParseTraceLevel("everything"); err == nil {
+		t.Fatalf("expected error parsing unknown level")
+	}
+}
+
+func TestForward_OutputsLevelRecordsNoDetails(t *testing.T) {
+	n := newTraceTestNetwork()
+	trace := &ForwardTrace{}
+	if _, err := n.Forward([]float64{1, 1, 1}, trace); err != nil {
+		t.Fatal(err)
+	}
+	if trace.PreActivations != nil || trace.Logits != nil || trace.Contributions != nil {
+		t.Fatalf("expected no detailed values at TRACE_LEVEL_OUTPUTS, got %+v", trace)
+	}
+}
+
+func TestForward_DetailedTrace(t *testing.T) {
+	n := newTraceTestNetwork()
+	x := []float64{1, 2, 1}
+	trace := NewForwardTrace(TRACE_LEVEL_DETAILED, 2)
+	probs, err := n.Forward(x, trace)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Layer 0: z = [0.5 + 1 + 4 - 3, -4 - 1 + 1 + 1] = [2.5, -3], a = [2.5, 0]
+	// Layer 1: z = [2.5*1 + 0*3, 1 + 2.5*(-2) + 0*1] = [2.5, -4]
+	wantZs := [][]float64{nil, {2.5, -3}, {2.5, -4}}
+	if !reflect.DeepEqual(trace.PreActivations, wantZs) {
+		t.Fatalf("pre-activations mismatch: got %v want %v", trace.PreActivations, wantZs)
+	}
+	if !reflect.DeepEqual(trace.LayerOutputs[1], []float64{2.5, 0}) {
+		t.Fatalf("layer 1 outputs mismatch: got %v", trace.LayerOutputs[1])
+	}
+	if !reflect.DeepEqual(trace.Logits, []float64{2.5, -4}) {
+		t.Fatalf("logits mismatch: got %v", trace.Logits)
+	}
+	if !reflect.DeepEqual(softmax(trace.Logits), probs) {
+		t.Fatalf("softmax of logits does not match output: %v vs %v", softmax(trace.Logits), probs)
+	}
+
+	if trace.Contributions[0] != nil {
+		t.Fatalf("expected no contributions for the input layer")
+	}
+	// Hidden neuron 0 receives 1*1, 2*2, -3*1 = [1, 4, -3], top 2 by magnitude are inputs 1 and 2
+	want := []Contribution{
+		{From: 1, Weight: 2, Input: 2, Value: 4},
+		{From: 2, Weight: -3, Input: 1, Value: -3},
+	}
+	if !reflect.DeepEqual(trace.Contributions[1][0], want) {
+		t.Fatalf("hidden neuron 0 contributions mismatch: got %+v want %+v", trace.Contributions[1][0], want)
+	}
+	// Output neuron 1 receives 2.5*(-2), 0*1 = [-5, 0]
+	want = []Contribution{
+		{From: 0, Weight: -2, Input: 2.5, Value: -5},
+		{From: 1, Weight: 1, Input: 0, Value: 0},
+	}
+	if !reflect.DeepEqual(trace.Contributions[2][1], want) {
+		t.Fatalf("output neuron 1 contributions mismatch: got %+v want %+v", trace.Contributions[2][1], want)
+	}
+}
+
+func TestForward_DetailedTraceDefaultTopK(t *testing.T) {
+	n, err := NewNetwork(18, 8, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trace := NewForwardTrace(TRACE_LEVEL_DETAILED, 0)
+	if _, err := n.Forward(make([]float64, 18), trace); err != nil {
+		t.Fatal(err)
+	}
+	for li := 1; li < len(trace.Contributions); li++ {
+		for j, c := range trace.Contributions[li] {
+			if len(c) != DEFAULT_TRACE_TOP_K {
+				t.Fatalf("layer %d neuron %d: expected %d contributions, got %d", li, j, DEFAULT_TRACE_TOP_K, len(c))
+			}
+		}
+	}
+}
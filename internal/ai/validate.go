@@ -0,0 +1,116 @@
+package ai
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+
+	"t-cubed/internal/engine"
+)
+
+var ErrInvalidNetwork = errors.New("invalid network")
+
+// Checks that the network is structurally sound: each layer's weights are Input x Output,
+// biases match the output size, consecutive layers chain, and every value is finite.
+func (n *Network) Validate() error {
+	if len(n.Layers) == 0 {
+		return fmt.Errorf("%w: no layers", ErrInvalidNetwork)
+	}
+	if err := validateLayers(n.Layers, "layer"); err != nil {
+		return err
+	}
+	return n.validateValueHead()
+}
+
+// Checks a chain of layers, naming them "<name> <index>" in errors
+func validateLayers(layers []*layer, name string) error {
+	for i, l := range layers {
+		if l == nil {
+			return fmt.Errorf("%w: %s %d is null", ErrInvalidNetwork, name, i)
+		}
+		if l.Input < 1 || l.Output < 1 {
+			return fmt.Errorf("%w: %s %d has invalid size %dx%d", ErrInvalidNetwork, name, i, l.Input, l.Output)
+		}
+		if len(l.Weights) != l.Input {
+			return fmt.Errorf("%w: %s %d has %d weight rows, expected %d (input)", ErrInvalidNetwork, name, i, len(l.Weights), l.Input)
+		}
+		for j, row := range l.Weights {
+			if len(row) != l.Output {
+				return fmt.Errorf("%w: %s %d weight row %d has %d columns, expected %d (output)", ErrInvalidNetwork, name, i, j, len(row), l.Output)
+			}
+			for k, w := range row {
+				if math.IsNaN(w) || math.IsInf(w, 0) {
+					return fmt.Errorf("%w: %s %d weight [%d][%d] is not finite (%v)", ErrInvalidNetwork, name, i, j, k, w)
+				}
+			}
+		}
+		if len(l.Biases) != l.Output {
+			return fmt.Errorf("%w: %s %d has %d biases, expected %d (output)", ErrInvalidNetwork, name, i, len(l.Biases), l.Output)
+		}
+		for j, b := range l.Biases {
+			if math.IsNaN(b) || math.IsInf(b, 0) {
+				return fmt.Errorf("%w: %s %d bias [%d] is not finite (%v)", ErrInvalidNetwork, name, i, j, b)
+			}
+		}
+		if i > 0 && layers[i-1].Output != l.Input {
+			return fmt.Errorf("%w: %s %d input %d does not match %s %d output %d", ErrInvalidNetwork, name, i, l.Input, name, i-1, layers[i-1].Output)
+		}
+	}
+	return nil
+}
+
+// Checks that the network is valid and can play on the engine's board encoding
+func (n *Network) ValidateForGame() error {
+	if err := n.Validate(); err != nil {
+		return err
+	}
+	if in := n.Layers[0].Input; in != engine.NETWORK_INPUT_LEN {
+		return fmt.Errorf("%w: input size %d does not match the engine's %d-float board encoding", ErrInvalidNetwork, in, engine.NETWORK_INPUT_LEN)
+	}
+	if out := n.Layers[len(n.Layers)-1].Output; out != engine.NETWORK_OUTPUT_LEN {
+		return fmt.Errorf("%w: output size %d does not match the %d board cells", ErrInvalidNetwork, out, engine.NETWORK_OUTPUT_LEN)
+	}
+	return nil
+}
+
+// Returns a SHA-256 checksum of the network's shape and parameters.
+// The checksum does not depend on JSON formatting, so equivalent weight files produce the same value.
+func (n *Network) Checksum() string {
+	h := sha256.New()
+	buf := make([]byte, 8)
+	writeUint := func(v int) {
+		binary.LittleEndian.PutUint64(buf, uint64(v))
+		h.Write(buf)
+	}
+	writeFloat := func(v float64) {
+		binary.LittleEndian.PutUint64(buf, math.Float64bits(v))
+		h.Write(buf)
+	}
+
+	writeLayers := func(layers []*layer) {
+		writeUint(len(layers))
+		for _, l := range layers {
+			writeUint(l.Input)
+			writeUint(l.Output)
+			for _, row := range l.Weights {
+				for _, w := range row {
+					writeFloat(w)
+				}
+			}
+			for _, b := range l.Biases {
+				writeFloat(b)
+			}
+		}
+	}
+
+	writeLayers(n.Layers)
+	// Only included when present so policy-only checksums are unchanged
+	if n.HasValueHead() {
+		writeUint(n.Trunk)
+		writeLayers(n.ValueHead)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
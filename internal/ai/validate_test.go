@@ -0,0 +1,153 @@
+package ai
+
+import (
+	"errors"
+	"math"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Builds a small valid network: 2 inputs -> 3 hidden -> 2 outputs
+func newValidTestNetwork() *Network {
+	return &Network{
+		Layers: []*layer{
+			{
+				Input:   2,
+				Output:  3,
+				Weights: [][]float64{{0.1, -0.2, 0.3}, {0.4, 0.5, -0.6}},
+				Biases:  []float64{0.01, -0.02, 0.03},
+			},
+			{
+				Input:   3,
+				Output:  2,
+				Weights: [][]float64{{0.7, -0.8}, {-0.1, 0.2}, {0.3, 0.4}},
+				Biases:  []float64{0.05, -0.05},
+			},
+		},
+	}
+}
+
+func TestValidate_Valid(t *testing.T) {
+	if err := newValidTestNetwork().Validate(); err != nil {
+		t.Fatalf("expected valid network, got %v", err)
+	}
+}
+
+func TestValidate_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(n *Network)
+		wantMsg string
+	}{
+		{"no layers", func(n *Network) { n.Layers = nil }, "no layers"},
+		{"null layer", func(n *Network) { n.Layers[1] = nil }, "layer 1 is null"},
+		{"missing weight row", func(n *Network) { n.Layers[0].Weights = n.Layers[0].Weights[:1] }, "layer 0 has 1 weight rows, expected 2"},
+		{"short weight row", func(n *Network) { n.Layers[1].Weights[2] = []float64{0.3} }, "layer 1 weight row 2 has 1 columns, expected 2"},
+		{"bias length", func(n *Network) { n.Layers[0].Biases = []float64{0} }, "layer 0 has 1 biases, expected 3"},
+		{"layers do not chain", func(n *Network) {
+			n.Layers[1].Input = 2
+			n.Layers[1].Weights = n.Layers[1].Weights[:2]
+		}, "layer 1 input 2 does not match layer 0 output 3"},
+		{"NaN weight", func(n *Network) { n.Layers[0].Weights[1][2] = math.NaN() }, "layer 0 weight [1][2] is not finite"},
+		{"Inf bias", func(n *Network) { n.Layers[1].Biases[0] = math.Inf(1) }, "layer 1 bias [0] is not finite"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := newValidTestNetwork()
+			tt.mutate(n)
+			err := n.Validate()
+			if err == nil {
+				t.Fatalf("expected error")
+			}
+			if !errors.Is(err, ErrInvalidNetwork) {
+				t.Fatalf("expected ErrInvalidNetwork, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Fatalf("expected error to contain %q, got %q", tt.wantMsg, err.Error())
+			}
+		})
+	}
+}
+
+func TestValidateForGame(t *testing.T) {
+	n, err := NewNetwork(18, 4, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.ValidateForGame(); err != nil {
+		t.Fatalf("expected valid game network, got %v", err)
+	}
+
+	if err := newValidTestNetwork().ValidateForGame(); err == nil || !strings.Contains(err.Error(), "18-float board encoding") {
+		t.Fatalf("expected input size error, got %v", err)
+	}
+
+	n, err = NewNetwork(18, 4, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.ValidateForGame(); err == nil || !strings.Contains(err.Error(), "output size 8") {
+		t.Fatalf("expected output size error, got %v", err)
+	}
+}
+
+func TestLoadNetwork_Truncated(t *testing.T) {
+	fpath := "./ffnn_truncated.json"
+	data := `{ "layers": [ { "input": 2, "output": 3, "weights": [ [0.1, -0.2, 0.3], [0.4, 0.5`
+	if err := os.WriteFile(fpath, []byte(data), 0644); err != nil {
+		t.Fatalf("write temp json: %v", err)
+	}
+	defer func() { _ = os.Remove(fpath) }()
+
+	_, err := LoadNetwork(fpath)
+	if !errors.Is(err, ErrInvalidNetwork) {
+		t.Fatalf("expected ErrInvalidNetwork, got %v", err)
+	}
+}
+
+func TestLoadNetwork_Mismatched(t *testing.T) {
+	fpath := "./ffnn_mismatched.json"
+	data := `{ "layers": [ { "input": 2, "output": 3, "weights": [ [0.1, -0.2, 0.3], [0.4, 0.5, -0.6] ], "biases": [0.01, -0.02, 0.03] },
+{ "input": 2, "output": 2, "weights": [ [0.7, -0.8], [-0.1, 0.2] ], "biases": [0.05, -0.05] } ] }`
+	if err := os.WriteFile(fpath, []byte(data), 0644); err != nil {
+		t.Fatalf("write temp json: %v", err)
+	}
+	defer func() { _ = os.Remove(fpath) }()
+
+	_, err := LoadNetwork(fpath)
+	if err == nil || !strings.Contains(err.Error(), "layer 1 input 2 does not match layer 0 output 3") {
+		t.Fatalf("expected chaining error, got %v", err)
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	a := newValidTestNetwork()
+	b := newValidTestNetwork()
+	if a.Checksum() != b.Checksum() {
+		t.Fatalf("expected equal checksums for identical networks")
+	}
+	if len(a.Checksum()) != 64 {
+		t.Fatalf("expected hex encoded SHA-256, got %q", a.Checksum())
+	}
+
+	b.Layers[1].Biases[1] += 1e-12
+	if a.Checksum() == b.Checksum() {
+		t.Fatalf("expected checksums to differ after changing a bias")
+	}
+
+	// Round-tripping through JSON must not change the checksum
+	fname := "./ffnn_checksum_test.json"
+	if err := SaveNetwork(fname, a); err != nil {
+		t.Fatalf("SaveNetwork returned error: %v", err)
+	}
+	defer func() { _ = os.Remove(fname) }()
+	loaded, err := LoadNetwork(fname)
+	if err != nil {
+		t.Fatalf("LoadNetwork returned error: %v", err)
+	}
+	if loaded.Checksum() != a.Checksum() {
+		t.Fatalf("checksum changed after save/load round trip")
+	}
+}
@@ -0,0 +1,154 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+var ErrNoValueHead = errors.New("network has no value head")
+
+// Creates a policy/value network with a shared trunk.
+// trunk holds the neuron counts of the input layer and the shared hidden layers, policy holds the neuron counts
+// of the policy head's layers (the last is the number of moves) and value holds the neuron counts of the value
+// head's layers (the last must be 1).
+func NewPolicyValueNetwork(trunk []int, policy []int, value []int) (*Network, error) {
+	return NewSeededPolicyValueNetwork(rand.Int63(), trunk, policy, value)
+}
+
+// Same as NewPolicyValueNetwork, but the initial weights are drawn from seed, which is recorded in the metadata
+func NewSeededPolicyValueNetwork(seed int64, trunk []int, policy []int, value []int) (*Network, error) {
+	if len(trunk) < 2 {
+		return nil, errors.New("Trunk requires an input layer and at least one hidden layer")
+	}
+	if len(policy) < 1 || len(value) < 1 {
+		return nil, errors.New("Policy and value heads require at least one layer")
+	}
+	if value[len(value)-1] != 1 {
+		return nil, errors.New("Value head must have a single output")
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	n, err := newNetwork(rng, append(append([]int{}, trunk...), policy...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	valueNeurons := append([]int{trunk[len(trunk)-1]}, value...)
+	n.Trunk = len(trunk) - 1
+	n.ValueHead = make([]*layer, len(value))
+	for i := range n.ValueHead {
+		in, out := valueNeurons[i], valueNeurons[i+1]
+		if out == 0 {
+			return nil, errors.New("Neurons cannot be 0")
+		}
+		n.ValueHead[i] = newLayer(in, out)
+	}
+	randomizeLayers(n.ValueHead, rng)
+	n.Metadata = &NetworkMetadata{Seed: &seed}
+
+	// Scale the value head down so tanh does not start out saturated
+	for _, l := range n.ValueHead {
+		for i := range l.Weights {
+			for j := range l.Weights[i] {
+				l.Weights[i][j] /= float64(l.Input)
+			}
+		}
+	}
+
+	return n, nil
+}
+
+// Reports whether the network has a value head
+func (n *Network) HasValueHead() bool {
+	return len(n.ValueHead) > 0
+}
+
+// Forward propagates the input and returns the policy and the value head's estimate of the expected
+// outcome in [-1, 1] for the player to move. The trace only records the policy path.
+func (n *Network) Evaluate(x []float64, trace *ForwardTrace) ([]float64, float64, error) {
+	if !n.HasValueHead() {
+		return nil, 0, ErrNoValueHead
+	}
+	policy, err := n.Forward(x, trace)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Trunk layers are hidden layers, so they always use ReLU
+	out := x
+	for _, l := range n.Layers[:n.Trunk] {
+		out, err = l.feedForward(out, reLU, nil)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	for i, l := range n.ValueHead {
+		act := reLU
+		if i == len(n.ValueHead)-1 {
+			act = math.Tanh
+		}
+		out, err = l.feedForward(out, act, nil)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	return policy, out[0], nil
+}
+
+// Checks the value head's structure. Networks without a value head are valid.
+func (n *Network) validateValueHead() error {
+	if !n.HasValueHead() {
+		if n.Trunk != 0 {
+			return fmt.Errorf("%w: trunk is set without a value head", ErrInvalidNetwork)
+		}
+		return nil
+	}
+	if n.Trunk < 1 || n.Trunk > len(n.Layers)-1 {
+		return fmt.Errorf("%w: trunk %d must be between 1 and %d", ErrInvalidNetwork, n.Trunk, len(n.Layers)-1)
+	}
+	if err := validateLayers(n.ValueHead, "value layer"); err != nil {
+		return err
+	}
+	if in, out := n.ValueHead[0].Input, n.Layers[n.Trunk-1].Output; in != out {
+		return fmt.Errorf("%w: value layer 0 input %d does not match trunk output %d", ErrInvalidNetwork, in, out)
+	}
+	if out := n.ValueHead[len(n.ValueHead)-1].Output; out != 1 {
+		return fmt.Errorf("%w: value head output size %d, expected 1", ErrInvalidNetwork, out)
+	}
+	return nil
+}
+
+// Backpropagates the value head's squared error (J = valueWeight * (v - z)^2) for rows examples,
+// skipping rows whose valueMask is 0. Accumulates the head's gradients and returns dJ/da for the trunk's output.
+func (tn *trainingNetwork) backwardValue(rows int) []float64 {
+	head := tn.network.ValueHead
+	lli := len(head) - 1
+
+	// Derivative of the squared error through tanh
+	values := tn.value.as[lli]
+	for r := range rows {
+		v := values[r]
+		tn.value.deltas[lli][r] = tn.valueMask[r] * tn.valueWeight * 2 * (v - tn.valueTargets[r]) * (1 - v*v)
+	}
+
+	for i := lli; i >= 0; i-- {
+		in := tn.as[tn.network.Trunk-1]
+		dIn := tn.trunkGradient
+		if i > 0 {
+			in = tn.value.as[i-1]
+			dIn = tn.value.deltas[i-1]
+		}
+		head[i].backwardBatch(in, tn.value.deltas[i], tn.value.wGradients[i], tn.value.bGradients[i], dIn, rows)
+
+		if i > 0 {
+			zs := tn.value.zs[i-1]
+			dIn = dIn[:rows*head[i-1].Output]
+			for k := range dIn {
+				dIn[k] *= reLUDerivative(zs[k])
+			}
+		}
+	}
+	return tn.trunkGradient[:rows*head[0].Input]
+}
@@ -0,0 +1,228 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+
+	"t-cubed/internal/engine"
+)
+
+func newValueTestNetwork(t *testing.T) *Network {
+	t.Helper()
+	n, err := NewPolicyValueNetwork([]int{4, 6}, []int{5, 3}, []int{4, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Small centered weights keep ReLUs away from their kink and tanh away from saturation
+	for _, layers := range [][]*layer{n.Layers, n.ValueHead} {
+		for _, l := range layers {
+			for i := range l.Weights {
+				for j := range l.Weights[i] {
+					l.Weights[i][j] = (l.Weights[i][j] - 0.5) * 0.8
+				}
+			}
+			for j := range l.Biases {
+				l.Biases[j] = 0.05 * float64(j+1)
+			}
+		}
+	}
+	return n
+}
+
+// Combined loss: policy cross-entropy plus weighted value squared error
+func combinedLoss(t *testing.T, n *Network, example *TrainingExample, valueWeight float64) float64 {
+	t.Helper()
+	policy, value, err := n.Evaluate(example.Input, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return crossEntropyLoss(policy, example.Target) + valueWeight*squaredError(value, *example.Value)
+}
+
+func TestNewPolicyValueNetwork(t *testing.T) {
+	n := newValueTestNetwork(t)
+	if err := n.Validate(); err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+	if n.Trunk != 1 || len(n.Layers) != 3 || len(n.ValueHead) != 2 {
+		t.Fatalf("unexpected shape: trunk %d, %d policy layers, %d value layers", n.Trunk, len(n.Layers), len(n.ValueHead))
+	}
+
+	if _, err := NewPolicyValueNetwork([]int{4}, []int{3}, []int{1}); err == nil {
+		t.Error("expected error for a trunk without hidden layers")
+	}
+	if _, err := NewPolicyValueNetwork([]int{4, 6}, []int{3}, []int{2}); err == nil {
+		t.Error("expected error for a value head with more than one output")
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	n := newValueTestNetwork(t)
+	x := []float64{1, 0, 0.5, 1}
+
+	policy, value, err := n.Evaluate(x, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forward, err := n.Forward(x, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !almostEqualSlices(policy, forward, 1e-12) {
+		t.Errorf("Evaluate() policy %v does not match Forward() %v", policy, forward)
+	}
+	if value < -1 || value > 1 {
+		t.Errorf("value %v out of range [-1, 1]", value)
+	}
+
+	policyOnly, err := NewNetwork(4, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := policyOnly.Evaluate(x, nil); !errors.Is(err, ErrNoValueHead) {
+		t.Errorf("Evaluate() without value head error = %v, want ErrNoValueHead", err)
+	}
+}
+
+func TestPolicyValueNetwork_JSONRoundTrip(t *testing.T) {
+	n := newValueTestNetwork(t)
+	data, err := json.Marshal(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseNetwork(data)
+	if err != nil {
+		t.Fatalf("ParseNetwork() = %v", err)
+	}
+	if parsed.Checksum() != n.Checksum() {
+		t.Error("checksum changed after JSON round trip")
+	}
+
+	x := []float64{0, 1, 1, 0}
+	_, want, _ := n.Evaluate(x, nil)
+	_, got, err := parsed.Evaluate(x, nil)
+	if err != nil || got != want {
+		t.Errorf("parsed value = %v (%v), want %v", got, err, want)
+	}
+
+	// Policy-only networks do not serialize value head fields
+	policyOnly, _ := NewNetwork(2, 2)
+	data, _ = json.Marshal(policyOnly)
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fields["valueHead"]; ok {
+		t.Error("policy-only network serialized valueHead")
+	}
+}
+
+func TestValidate_ValueHead(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(n *Network)
+	}{
+		{"trunk zero", func(n *Network) { n.Trunk = 0 }},
+		{"trunk covers policy output", func(n *Network) { n.Trunk = len(n.Layers) }},
+		{"value output size", func(n *Network) { n.ValueHead[1] = newLayer(4, 2) }},
+		{"value input mismatch", func(n *Network) { n.ValueHead[0] = newLayer(5, 4) }},
+		{"non-finite value weight", func(n *Network) { n.ValueHead[0].Weights[0][0] = math.NaN() }},
+		{"trunk without value head", func(n *Network) { n.ValueHead = nil }},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			n := newValueTestNetwork(t)
+			tc.modify(n)
+			if err := n.Validate(); !errors.Is(err, ErrInvalidNetwork) {
+				t.Errorf("Validate() = %v, want ErrInvalidNetwork", err)
+			}
+		})
+	}
+}
+
+func TestBackward_CombinedLossMatchesFiniteDifferences(t *testing.T) {
+	n := newValueTestNetwork(t)
+	valueTarget := -0.5
+	example := &TrainingExample{
+		Input:  []float64{1, 0.5, 0, 1},
+		Target: []float64{0, 1, 0},
+		Value:  &valueTarget,
+	}
+	valueWeight := 0.7
+
+	tn := newTrainingNetwork(n)
+	tn.valueWeight = valueWeight
+	if err := tn.forwardWithCache(example.Input); err != nil {
+		t.Fatal(err)
+	}
+	if err := tn.backward(example); err != nil {
+		t.Fatal(err)
+	}
+
+	h := 1e-6
+	check := func(name string, w *float64, analytic float64) {
+		original := *w
+		*w = original + h
+		plus := combinedLoss(t, n, example, valueWeight)
+		*w = original - h
+		minus := combinedLoss(t, n, example, valueWeight)
+		*w = original
+		numeric := (plus - minus) / (2 * h)
+		if !almostEqual(analytic, numeric, 1e-5) {
+			t.Errorf("%s: analytic gradient %v, numeric %v", name, analytic, numeric)
+		}
+	}
+
+	// Shared trunk weights receive gradients from both heads
+	for i := range n.Layers[0].Weights {
+		for j := range n.Layers[0].Weights[i] {
+			check("trunk weight", &n.Layers[0].Weights[i][j], tn.wGradients[0][i][j])
+		}
+	}
+	for j := range n.Layers[0].Biases {
+		check("trunk bias", &n.Layers[0].Biases[j], tn.bGradients[0][j])
+	}
+	for l := range n.ValueHead {
+		for i := range n.ValueHead[l].Weights {
+			for j := range n.ValueHead[l].Weights[i] {
+				check("value weight", &n.ValueHead[l].Weights[i][j], tn.value.wGradients[l][i][j])
+			}
+		}
+		for j := range n.ValueHead[l].Biases {
+			check("value bias", &n.ValueHead[l].Biases[j], tn.value.bGradients[l][j])
+		}
+	}
+}
+
+func TestBackward_NoValueTargetLeavesValueHead(t *testing.T) {
+	n := newValueTestNetwork(t)
+	tn := newTrainingNetwork(n)
+	example := &TrainingExample{
+		Input:  []float64{1, 0, 0, 1},
+		Target: []float64{1, 0, 0},
+	}
+	if err := tn.forwardWithCache(example.Input); err != nil {
+		t.Fatal(err)
+	}
+	if err := tn.backward(example); err != nil {
+		t.Fatal(err)
+	}
+	for l := range tn.value.bGradients {
+		for j, g := range tn.value.bGradients[l] {
+			if g != 0 {
+				t.Fatalf("value layer %d bias %d gradient = %v, want 0 without a value target", l, j, g)
+			}
+		}
+	}
+}
+
+func TestBestMoveWithValue(t *testing.T) {
+	// Player 2 can only win by completing the top row at position 3, otherwise Player 1 wins at position 6
+	board := &engine.Board{P1Board: 0b001011000, P2Board: 0b000000011}
+	pos, value := BestMoveWithValue(board)
+	if pos != 3 || value != 1 {
+		t.Errorf("BestMoveWithValue() = %d, %d, want 3, 1", pos, value)
+	}
+}
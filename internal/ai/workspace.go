@@ -0,0 +1,94 @@
+package ai
+
+import (
+	"errors"
+	"math"
+)
+
+// Preallocated buffers for forward passes through one network without allocating.
+// A workspace is not safe for concurrent use; create one per goroutine.
+type Workspace struct {
+	network *Network
+	outputs [][]float64 // Output of each layer in network.Layers, with logits from the last layer
+	policy  []float64   // Softmax of the logits
+	values  [][]float64 // Output of each layer in network.ValueHead
+}
+
+// Creates a workspace sized for the network's current shape
+func (n *Network) NewWorkspace() *Workspace {
+	w := &Workspace{
+		network: n,
+		outputs: make([][]float64, len(n.Layers)),
+		policy:  make([]float64, n.Layers[len(n.Layers)-1].Output),
+		values:  make([][]float64, len(n.ValueHead)),
+	}
+	for i, l := range n.Layers {
+		w.outputs[i] = make([]float64, l.Output)
+	}
+	for i, l := range n.ValueHead {
+		w.values[i] = make([]float64, l.Output)
+	}
+	return w
+}
+
+// Same as Network.Forward without a trace. The returned slice belongs to the workspace
+// and is overwritten by the next call.
+func (w *Workspace) Forward(x []float64) ([]float64, error) {
+	n := w.network
+	if len(x) != n.Layers[0].Input {
+		return nil, errors.New("Input length does not match layer input length")
+	}
+
+	in := x
+	for i, l := range n.Layers {
+		act := reLU
+		if i == len(n.Layers)-1 {
+			act = identity // last layer emits logits
+		}
+		l.forwardBatch(in, w.outputs[i], w.outputs[i], act, 1)
+		in = w.outputs[i]
+	}
+	softmaxInto(w.policy, in)
+	return w.policy, nil
+}
+
+// Records the last forward pass in trace at TRACE_LEVEL_OUTPUTS, as Network.Forward would have.
+// x must be the input of that pass. The trace gets copies of the workspace's buffers.
+func (w *Workspace) TraceOutputs(x []float64, trace *ForwardTrace) {
+	n := w.network
+	trace.LayerOutputs = make([][]float64, len(n.Layers)+1)
+	trace.LayerOutputs[0] = copySlice(x)
+	trace.Activations = make([]string, len(n.Layers)+1)
+	trace.Activations[0] = ACTIVATION_NONE
+	trace.PreActivations, trace.Logits, trace.Contributions = nil, nil, nil
+	for i, out := range w.outputs {
+		trace.LayerOutputs[i+1] = copySlice(out)
+		trace.Activations[i+1] = ACTIVATION_RELU
+	}
+	trace.Activations[len(n.Layers)] = ACTIVATION_IDENTITY // last layer emits logits
+}
+
+// Same as Network.Evaluate without a trace. The returned policy belongs to the workspace
+// and is overwritten by the next call.
+func (w *Workspace) Evaluate(x []float64) ([]float64, float64, error) {
+	n := w.network
+	if !n.HasValueHead() {
+		return nil, 0, ErrNoValueHead
+	}
+	// The trunk's outputs are left in place by Forward
+	policy, err := w.Forward(x)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	in := w.outputs[n.Trunk-1]
+	for i, l := range n.ValueHead {
+		act := reLU
+		if i == len(n.ValueHead)-1 {
+			act = math.Tanh
+		}
+		l.forwardBatch(in, w.values[i], w.values[i], act, 1)
+		in = w.values[i]
+	}
+	return policy, in[0], nil
+}
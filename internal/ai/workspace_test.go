@@ -0,0 +1,332 @@
+package ai
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+	"unsafe"
+)
+
+// Straightforward forward pass with column access and an allocation per layer.
+// Kept as a correctness oracle and as the baseline for the benchmarks.
+func referenceForward(n *Network, x []float64) []float64 {
+	out := x
+	for li, l := range n.Layers {
+		next := make([]float64, l.Output)
+		for j := 0; j < l.Output; j++ {
+			sum := l.Biases[j]
+			for i := 0; i < l.Input; i++ {
+				sum += l.Weights[i][j] * out[i]
+			}
+			if li < len(n.Layers)-1 {
+				sum = reLU(sum)
+			}
+			next[j] = sum
+		}
+		out = next
+	}
+	return softmax(out)
+}
+
+func newBenchmarkNetwork(tb testing.TB) *Network {
+	tb.Helper()
+	n, err := NewNetwork(18, 32, 32, 32, 9)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	// Centered weights so the hidden layers are not all positive
+	for _, l := range n.Layers {
+		for i := range l.Weights {
+			for j := range l.Weights[i] {
+				l.Weights[i][j] = (l.Weights[i][j] - 0.5) * 0.5
+			}
+		}
+	}
+	return n
+}
+
+// Mid-game board: X on 0, 4 and O on 2, 8
+func benchmarkBoardInput() []float64 {
+	x := make([]float64, 18)
+	x[0], x[4], x[11], x[17] = 1, 1, 1, 1
+	return x
+}
+
+func TestWorkspaceForward_MatchesReference(t *testing.T) {
+	n := newBenchmarkNetwork(t)
+	w := n.NewWorkspace()
+	inputs := [][]float64{benchmarkBoardInput(), make([]float64, 18)}
+	for _, x := range inputs {
+		want := referenceForward(n, x)
+		got, err := w.Forward(x)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !almostEqualSlices(got, want, 1e-12) {
+			t.Errorf("Workspace.Forward() = %v, want %v", got, want)
+		}
+		forward, err := n.Forward(x, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !almostEqualSlices(forward, want, 1e-12) {
+			t.Errorf("Network.Forward() = %v, want %v", forward, want)
+		}
+	}
+
+	if _, err := w.Forward(make([]float64, 3)); err == nil {
+		t.Error("expected error for wrong input length")
+	}
+}
+
+func TestWorkspaceTraceOutputs_MatchesNetwork(t *testing.T) {
+	n := newBenchmarkNetwork(t)
+	w := n.NewWorkspace()
+	x := benchmarkBoardInput()
+
+	want := NewForwardTrace(TRACE_LEVEL_OUTPUTS, 0)
+	if _, err := n.Forward(x, want); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Forward(x); err != nil {
+		t.Fatal(err)
+	}
+	got := NewForwardTrace(TRACE_LEVEL_OUTPUTS, 0)
+	w.TraceOutputs(x, got)
+
+	if !reflect.DeepEqual(got.Activations, want.Activations) {
+		t.Errorf("activations = %v, want %v", got.Activations, want.Activations)
+	}
+	if len(got.LayerOutputs) != len(want.LayerOutputs) {
+		t.Fatalf("got %d layer outputs, want %d", len(got.LayerOutputs), len(want.LayerOutputs))
+	}
+	for i := range want.LayerOutputs {
+		if !almostEqualSlices(got.LayerOutputs[i], want.LayerOutputs[i], 1e-12) {
+			t.Errorf("layer %d outputs = %v, want %v", i, got.LayerOutputs[i], want.LayerOutputs[i])
+		}
+	}
+
+	// The trace keeps its values when the workspace is reused
+	recorded := copySlice(got.LayerOutputs[len(got.LayerOutputs)-1])
+	if _, err := w.Forward(make([]float64, 18)); err != nil {
+		t.Fatal(err)
+	}
+	if !almostEqualSlices(got.LayerOutputs[len(got.LayerOutputs)-1], recorded, 0) {
+		t.Error("trace outputs changed when the workspace was reused")
+	}
+}
+
+func TestWorkspaceEvaluate_MatchesNetwork(t *testing.T) {
+	n := newValueTestNetwork(t)
+	w := n.NewWorkspace()
+	x := []float64{1, 0.5, 0, 1}
+
+	wantPolicy, wantValue, err := n.Evaluate(x, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy, value, err := w.Evaluate(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !almostEqualSlices(policy, wantPolicy, 1e-12) || !almostEqual(value, wantValue, 1e-12) {
+		t.Errorf("Workspace.Evaluate() = %v, %v, want %v, %v", policy, value, wantPolicy, wantValue)
+	}
+}
+
+func TestWorkspaceForward_DoesNotAllocate(t *testing.T) {
+	n := newBenchmarkNetwork(t)
+	w := n.NewWorkspace()
+	x := benchmarkBoardInput()
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := w.Forward(x); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Workspace.Forward() allocated %v times per run, want 0", allocs)
+	}
+}
+
+func TestWorkspace32Forward(t *testing.T) {
+	n := newBenchmarkNetwork(t)
+	w := n.Float32().NewWorkspace()
+	x := benchmarkBoardInput()
+	x32 := make([]float32, len(x))
+	for i, v := range x {
+		x32[i] = float32(v)
+	}
+
+	want := referenceForward(n, x)
+	got, err := w.Forward(x32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range want {
+		if math.Abs(float64(got[i])-want[i]) > 1e-5 {
+			t.Fatalf("output[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestTrainBatch_MatchesPerExampleGradients(t *testing.T) {
+	n := newValueTestNetwork(t)
+	value := 0.5
+	examples := []*TrainingExample{
+		{Input: []float64{1, 0, 0.5, 1}, Target: []float64{0, 1, 0}, Value: &value},
+		{Input: []float64{0, 1, 1, 0}, Target: []float64{1, 0, 0}},
+		{Input: []float64{0.5, 0.5, 0, 1}, Target: []float64{0, 0, 1}, Value: &value},
+	}
+
+	single := newTrainingNetwork(n)
+	singleCost := 0.0
+	for _, example := range examples {
+		if err := single.forwardWithCache(example.Input); err != nil {
+			t.Fatal(err)
+		}
+		singleCost += crossEntropyLoss(single.layerCaches[len(n.Layers)-1].As, example.Target)
+		if example.Value != nil {
+			singleCost += squaredError(single.value.layerCaches[len(n.ValueHead)-1].As[0], *example.Value)
+		}
+		if err := single.backward(example); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	batched := newTrainingNetwork(n)
+	batchCost, err := batched.trainBatch(examples)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !almostEqual(batchCost, singleCost, 1e-12) {
+		t.Errorf("batch cost = %v, want %v", batchCost, singleCost)
+	}
+
+	compare := func(name string, want, got *trainingBuffers) {
+		for i := range want.wGradients {
+			for j := range want.wGradients[i] {
+				if !almostEqualSlices(got.wGradients[i][j], want.wGradients[i][j], 1e-12) {
+					t.Errorf("%s layer %d weight gradient row %d = %v, want %v", name, i, j, got.wGradients[i][j], want.wGradients[i][j])
+				}
+			}
+			if !almostEqualSlices(got.bGradients[i], want.bGradients[i], 1e-12) {
+				t.Errorf("%s layer %d bias gradients = %v, want %v", name, i, got.bGradients[i], want.bGradients[i])
+			}
+		}
+	}
+	compare("policy", &single.trainingBuffers, &batched.trainingBuffers)
+	compare("value", single.value, batched.value)
+}
+
+func TestLayerUnmarshalJSON_ContiguousWeights(t *testing.T) {
+	var l layer
+	if err := json.Unmarshal([]byte(`{"input":3,"output":2,"weights":[[1,2],[3,4],[5,6]],"biases":[0,0]}`), &l); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < len(l.Weights); i++ {
+		previousEnd := unsafe.Add(unsafe.Pointer(&l.Weights[i-1][0]), 2*unsafe.Sizeof(float64(0)))
+		if unsafe.Pointer(&l.Weights[i][0]) != previousEnd {
+			t.Fatalf("weight row %d is not stored directly after row %d", i, i-1)
+		}
+	}
+	if l.Weights[2][1] != 6 {
+		t.Errorf("weights = %v", l.Weights)
+	}
+
+	// Ragged rows are left for Validate to report
+	if err := json.Unmarshal([]byte(`{"input":2,"output":2,"weights":[[1,2],[3]],"biases":[0,0]}`), &l); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.Weights[1]) != 1 {
+		t.Errorf("ragged weights were changed: %v", l.Weights)
+	}
+}
+
+func BenchmarkForward_Reference(b *testing.B) {
+	n := newBenchmarkNetwork(b)
+	x := benchmarkBoardInput()
+	b.ReportAllocs()
+	for b.Loop() {
+		referenceForward(n, x)
+	}
+}
+
+func BenchmarkForward(b *testing.B) {
+	n := newBenchmarkNetwork(b)
+	x := benchmarkBoardInput()
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := n.Forward(x, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWorkspaceForward(b *testing.B) {
+	n := newBenchmarkNetwork(b)
+	w := n.NewWorkspace()
+	x := benchmarkBoardInput()
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := w.Forward(x); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWorkspace32Forward(b *testing.B) {
+	n := newBenchmarkNetwork(b)
+	w := n.Float32().NewWorkspace()
+	x := make([]float32, 18)
+	for i, v := range benchmarkBoardInput() {
+		x[i] = float32(v)
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := w.Forward(x); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkExamples(count int) []*TrainingExample {
+	examples := make([]*TrainingExample, count)
+	for i := range examples {
+		example := &TrainingExample{Input: benchmarkBoardInput(), Target: make([]float64, 9)}
+		example.Target[i%9] = 1
+		examples[i] = example
+	}
+	return examples
+}
+
+func BenchmarkTrain_PerExample(b *testing.B) {
+	n := newBenchmarkNetwork(b)
+	tn := newTrainingNetwork(n)
+	examples := benchmarkExamples(32)
+	b.ReportAllocs()
+	for b.Loop() {
+		for _, example := range examples {
+			if err := tn.forwardWithCache(example.Input); err != nil {
+				b.Fatal(err)
+			}
+			if err := tn.backward(example); err != nil {
+				b.Fatal(err)
+			}
+		}
+		tn.resetGradients()
+	}
+}
+
+func BenchmarkTrain_Batch(b *testing.B) {
+	n := newBenchmarkNetwork(b)
+	tn := newTrainingNetwork(n)
+	examples := benchmarkExamples(32)
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := tn.trainBatch(examples); err != nil {
+			b.Fatal(err)
+		}
+		tn.resetGradients()
+	}
+}
@@ -5,6 +5,11 @@ const (
 	PIECE_O = 'O'
 )
 
+// Rule sets a game can be played with. Only the standard 3x3 game is supported so far.
+const (
+	VARIANT_STANDARD = "standard"
+)
+
 const (
 	TERM_NOT = iota
 	TERM_WIN_1
@@ -20,3 +25,8 @@ const (
     DIAG2_PATTERN = 0x0054
 )
 
+// Sizes of the neural network encoding of a board
+const (
+	NETWORK_INPUT_LEN  = 18 // 9 cells for Player 1 followed by 9 cells for Player 2
+	NETWORK_OUTPUT_LEN = 9  // One score per cell
+)
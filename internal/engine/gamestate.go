@@ -5,6 +5,7 @@ package engine
 import (
 	"encoding/binary"
 	"fmt"
+	"math/bits"
 	"t-cubed/internal/util"
 )
 
@@ -20,6 +21,7 @@ type GameStateOptions struct {
 	Player1Piece byte
 	Player2Piece byte
 	FirstPlayerId uint8
+	Variant string // Empty for VARIANT_STANDARD
 }
 
 func NewGameState(gameStateOptions *GameStateOptions) (*GameState, error) {
@@ -35,6 +37,9 @@ func NewGameState(gameStateOptions *GameStateOptions) (*GameState, error) {
 	if gameStateOptions.FirstPlayerId > 2 || gameStateOptions.FirstPlayerId < 1 {
 		return nil, fmt.Errorf("Invalid first player ID")
 	}
+	if gameStateOptions.Variant != "" && gameStateOptions.Variant != VARIANT_STANDARD {
+		return nil, fmt.Errorf("Unsupported variant %q", gameStateOptions.Variant)
+	}
 
 	gameState := &GameState{
 		Board: newBoard(),
@@ -47,6 +52,8 @@ func NewGameState(gameStateOptions *GameStateOptions) (*GameState, error) {
 	return gameState, nil
 }
 
+// Reconstructs a game from its options and a packed board.
+// The player to move and the terminal state are derived from the board and the first player.
 func NewGameStateFromBytes(gameStateOptions *GameStateOptions, boardState []byte) (*GameState, error) {
 	if len(boardState) != 4 {
 		return nil, fmt.Errorf("Invalid board state length")
@@ -56,8 +63,26 @@ func NewGameStateFromBytes(gameStateOptions *GameStateOptions, boardState []byte
 		return nil, err
 	}
 	p1Board, p2Board := unpackBoardBigEndian(boardState)
+	if (p1Board|p2Board)&^BOARD_FULL != 0 || p1Board&p2Board != 0 {
+		return nil, fmt.Errorf("Invalid board state")
+	}
 	gameState.Board.P1Board = p1Board
 	gameState.Board.P2Board = p2Board
+
+	// The first player has made as many moves as the other player, or one more
+	firstBoard, secondBoard := p1Board, p2Board
+	if gameStateOptions.FirstPlayerId == 2 {
+		firstBoard, secondBoard = p2Board, p1Board
+	}
+	switch bits.OnesCount16(firstBoard) - bits.OnesCount16(secondBoard) {
+	case 0:
+		gameState.TurnId = gameStateOptions.FirstPlayerId
+	case 1:
+		gameState.TurnId = 3 - gameStateOptions.FirstPlayerId
+	default:
+		return nil, fmt.Errorf("Invalid number of pieces for the first player")
+	}
+	gameState.TerminalState = IsTerminal(gameState.Board)
 	return gameState, nil
 }
 
@@ -94,7 +119,7 @@ func (g *GameState) GetBoardAsBytes() []byte {
 // The first 9 elements are 1 for Player 1's pieces or 0, the next 9 are 1 for Player 2's pieces or 0
 func (g *GameState) GetBoardAsNetworkInput() []float64 {
 	boardAsBytes := g.GetBoardAsBytes()
-	input := make([]float64, 18)
+	input := make([]float64, NETWORK_INPUT_LEN)
 	for i, b := range boardAsBytes {
 		switch b {
 			case g.Player1.Piece:
@@ -108,6 +133,20 @@ func (g *GameState) GetBoardAsNetworkInput() []float64 {
 	return input
 }
 
+// Returns the network input from the perspective of the given player.
+// The network always moves for Player 2, so for Player 1 the two halves of the input are swapped.
+func (g *GameState) GetBoardAsNetworkInputFor(playerId uint8) []float64 {
+	input := g.GetBoardAsNetworkInput()
+	if playerId == g.Player1.Id {
+		cells := NETWORK_INPUT_LEN / 2
+		swapped := make([]float64, NETWORK_INPUT_LEN)
+		copy(swapped[:cells], input[cells:])
+		copy(swapped[cells:], input[:cells])
+		return swapped
+	}
+	return input
+}
+
 func (g *GameState) GetBoardAsString() string {
 	return string(g.GetBoardAsBytes())
 }
@@ -120,6 +159,18 @@ func (g *GameState) IsTerminal() bool {
 	return g.TerminalState != TERM_NOT
 }
 
+// Returns the ID of the player who won, or 0 for a draw or a game in progress
+func (g *GameState) Winner() uint8 {
+	switch g.TerminalState {
+	case TERM_WIN_1:
+		return g.Player1.Id
+	case TERM_WIN_2:
+		return g.Player2.Id
+	default:
+		return 0
+	}
+}
+
 func (g *GameState) Move(position uint8) (bool, error) {
 	if g.IsTerminal() {
 		return false, nil
@@ -165,3 +216,29 @@ func unpackBoardBigEndian(b []byte) (uint16, uint16) {
 func UnpackBoard(b []byte) (uint16, uint16) {
 	return unpackBoardBigEndian(b)
 }
+
+// Returns the position (1-9) that was played between two packed board states.
+// Exactly one cell must have been added by one player.
+func MovePosition(preMoveState []byte, postMoveState []byte) (uint8, error) {
+	if len(preMoveState) != 4 || len(postMoveState) != 4 {
+		return 0, fmt.Errorf("Invalid board state length")
+	}
+	preP1, preP2 := unpackBoardBigEndian(preMoveState)
+	postP1, postP2 := unpackBoardBigEndian(postMoveState)
+	if preP1&^postP1 != 0 || preP2&^postP2 != 0 {
+		return 0, fmt.Errorf("Pieces were removed between board states")
+	}
+	added := (preP1 ^ postP1) | (preP2 ^ postP2)
+	if added == 0 || added&(added-1) != 0 || added&^BOARD_FULL != 0 {
+		return 0, fmt.Errorf("Board states do not differ by exactly one move")
+	}
+	if (preP1^postP1)&(preP2^postP2) != 0 {
+		return 0, fmt.Errorf("Both players played the same position")
+	}
+	for position := uint8(0); position < 9; position++ {
+		if added&(1<<position) != 0 {
+			return position + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("Board states do not differ by exactly one move")
+}
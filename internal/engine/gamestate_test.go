@@ -29,3 +29,95 @@ func TestNewGameState(t *testing.T) {
 	}
 }
 
+
+// Test that the played position is derived from two packed board states.
+func TestMovePosition(t *testing.T) {
+	pre := packBoardBigEndian(0x0001, 0x0010)
+	post := packBoardBigEndian(0x0001, 0x0110)
+	position, err := MovePosition(pre, post)
+	if err != nil {
+		t.Errorf("Error deriving move position: %s", err)
+	}
+	if position != 9 {
+		t.Errorf("Position is not 9, got %d", position)
+	}
+
+	// No move played
+	if _, err := MovePosition(pre, pre); err == nil {
+		t.Errorf("Expected error when no move was played")
+	}
+	// Two moves played
+	if _, err := MovePosition(pre, packBoardBigEndian(0x0003, 0x0110)); err == nil {
+		t.Errorf("Expected error when two moves were played")
+	}
+	// Piece removed
+	if _, err := MovePosition(pre, packBoardBigEndian(0x0000, 0x0030)); err == nil {
+		t.Errorf("Expected error when a piece was removed")
+	}
+	// Invalid length
+	if _, err := MovePosition(pre[:2], post); err == nil {
+		t.Errorf("Expected error for invalid board state length")
+	}
+}
+
+// Test that the network input is mirrored for Player 1.
+func TestGetBoardAsNetworkInputFor(t *testing.T) {
+	gameState, err := NewGameState(&GameStateOptions{
+		Player1Piece:  PIECE_X,
+		Player2Piece:  PIECE_O,
+		FirstPlayerId: 1,
+	})
+	if err != nil {
+		t.Fatalf("Error creating game state: %s", err)
+	}
+	gameState.Move(1) // Player 1
+	gameState.Move(5) // Player 2
+
+	p2Input := gameState.GetBoardAsNetworkInputFor(2)
+	if p2Input[0] != 1 || p2Input[13] != 1 {
+		t.Errorf("Player 2 input is not the standard encoding: %v", p2Input)
+	}
+	p1Input := gameState.GetBoardAsNetworkInputFor(1)
+	if p1Input[4] != 1 || p1Input[9] != 1 {
+		t.Errorf("Player 1 input is not mirrored: %v", p1Input)
+	}
+}
+
+// Test that the player to move and the terminal state are derived when a game is reconstructed.
+func TestNewGameStateFromBytes(t *testing.T) {
+	options := &GameStateOptions{
+		Player1Piece: PIECE_X,
+		Player2Piece: PIECE_O,
+		FirstPlayerId: 2,
+		Variant: VARIANT_STANDARD,
+	}
+	// Player 2 moved first, so Player 1 is to move
+	gameState, err := NewGameStateFromBytes(options, packBoardBigEndian(0x0000, 0x0010))
+	if err != nil {
+		t.Fatalf("Error reconstructing game state: %s", err)
+	}
+	if gameState.TurnId != 1 {
+		t.Errorf("Turn ID is not 1, got %d", gameState.TurnId)
+	}
+	// Player 2 completed the top row
+	gameState, err = NewGameStateFromBytes(options, packBoardBigEndian(0x0018, 0x0007))
+	if err != nil {
+		t.Fatalf("Error reconstructing game state: %s", err)
+	}
+	if gameState.TerminalState != TERM_WIN_2 {
+		t.Errorf("Terminal state is not TERM_WIN_2, got %d", gameState.TerminalState)
+	}
+	// Player 1 cannot have moved more often than Player 2 when Player 2 moved first
+	if _, err := NewGameStateFromBytes(options, packBoardBigEndian(0x0001, 0x0000)); err == nil {
+		t.Errorf("Expected error for too many Player 1 pieces")
+	}
+	// Both players on the same cell
+	if _, err := NewGameStateFromBytes(options, packBoardBigEndian(0x0001, 0x0001)); err == nil {
+		t.Errorf("Expected error for overlapping pieces")
+	}
+
+	options.Variant = "4x4"
+	if _, err := NewGameState(options); err == nil {
+		t.Errorf("Expected error for an unsupported variant")
+	}
+}
@@ -0,0 +1,35 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Board coordinates name columns a-c from left to right and rows 1-3 from top to bottom,
+// so a1 is position 1, c1 is position 3 and c3 is position 9.
+
+// Returns the coordinate (a1-c3) of a position (1-9)
+func PositionToCoordinate(position uint8) (string, error) {
+	if position < 1 || position > 9 {
+		return "", fmt.Errorf("Invalid position")
+	}
+	index := position - 1
+	return fmt.Sprintf("%c%d", 'a'+index%3, index/3+1), nil
+}
+
+// Parses a position given as a number (1-9) or a coordinate (a1-c3, case-insensitive)
+func ParsePosition(s string) (uint8, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if len(s) == 1 {
+		position, err := strconv.Atoi(s)
+		if err != nil || position < 1 || position > 9 {
+			return 0, fmt.Errorf("Invalid position %q", s)
+		}
+		return uint8(position), nil
+	}
+	if len(s) != 2 || s[0] < 'a' || s[0] > 'c' || s[1] < '1' || s[1] > '3' {
+		return 0, fmt.Errorf("Invalid position %q", s)
+	}
+	return (s[1]-'1')*3 + (s[0] - 'a') + 1, nil
+}
@@ -0,0 +1,39 @@
+package engine
+
+import (
+	"testing"
+)
+
+func TestPositionToCoordinate(t *testing.T) {
+	want := []string{"a1", "b1", "c1", "a2", "b2", "c2", "a3", "b3", "c3"}
+	for i, coordinate := range want {
+		position := uint8(i + 1)
+		got, err := PositionToCoordinate(position)
+		if err != nil || got != coordinate {
+			t.Errorf("PositionToCoordinate(%d) = %q, %v, want %q", position, got, err, coordinate)
+		}
+		// Coordinates round trip through ParsePosition
+		if parsed, err := ParsePosition(coordinate); err != nil || parsed != position {
+			t.Errorf("ParsePosition(%q) = %d, %v, want %d", coordinate, parsed, err, position)
+		}
+	}
+	for _, position := range []uint8{0, 10} {
+		if _, err := PositionToCoordinate(position); err == nil {
+			t.Errorf("PositionToCoordinate(%d) expected error", position)
+		}
+	}
+}
+
+func TestParsePosition(t *testing.T) {
+	valid := map[string]uint8{"1": 1, "9": 9, " 5 ": 5, "A1": 1, "C2": 6, "b3": 8}
+	for s, want := range valid {
+		if got, err := ParsePosition(s); err != nil || got != want {
+			t.Errorf("ParsePosition(%q) = %d, %v, want %d", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "0", "10", "d1", "a0", "a4", "1a", "b22", "x"} {
+		if _, err := ParsePosition(s); err == nil {
+			t.Errorf("ParsePosition(%q) expected error", s)
+		}
+	}
+}
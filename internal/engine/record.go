@@ -0,0 +1,254 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A game record is a portable text notation for games, in the spirit of chess PGN. A header of tags is followed by
+// a blank line and the moves, numbered in pairs and ended by the result:
+//
+//	[GameType "minimax"]
+//	[Player1Piece "X"]
+//	[Player2Piece "O"]
+//	[FirstPlayer "1"]
+//	[Result "1/2-1/2"]
+//
+//	1. b2 a1 2. c3 a3 3. a2 c2 4. b1 b3 5. c1 1/2-1/2
+//
+// Squares are written as coordinates (a1-c3) or as positions (1-9).
+
+// Tags read by the engine. Other tags are kept as they are.
+const (
+	TAG_PLAYER_1_PIECE = "Player1Piece"
+	TAG_PLAYER_2_PIECE = "Player2Piece"
+	TAG_FIRST_PLAYER   = "FirstPlayer"
+	TAG_VARIANT        = "Variant"
+	TAG_RESULT         = "Result"
+)
+
+// Results, from Player 1's point of view
+const (
+	RESULT_WIN_1       = "1-0"
+	RESULT_WIN_2       = "0-1"
+	RESULT_DRAW        = "1/2-1/2"
+	RESULT_IN_PROGRESS = "*"
+)
+
+type RecordTag struct {
+	Name  string
+	Value string
+}
+
+type GameRecord struct {
+	Tags           []RecordTag // In the order they are written
+	Moves          []uint8     // Positions (1-9) in the order they were played
+	Result         string
+	NumericSquares bool // Write squares as 1-9 instead of a1-c3
+}
+
+// Returns the value of the tag, or an empty string if the record does not have it
+func (r *GameRecord) Tag(name string) string {
+	for _, tag := range r.Tags {
+		if tag.Name == name {
+			return tag.Value
+		}
+	}
+	return ""
+}
+
+// Sets the value of the tag, adding it after the other tags if the record does not have it
+func (r *GameRecord) SetTag(name string, value string) {
+	for i, tag := range r.Tags {
+		if tag.Name == name {
+			r.Tags[i].Value = value
+			return
+		}
+	}
+	r.Tags = append(r.Tags, RecordTag{Name: name, Value: value})
+}
+
+// Returns the result for a terminal state
+func ResultForTerminalState(terminalState uint8) string {
+	switch terminalState {
+	case TERM_WIN_1:
+		return RESULT_WIN_1
+	case TERM_WIN_2:
+		return RESULT_WIN_2
+	case TERM_DRAW:
+		return RESULT_DRAW
+	default:
+		return RESULT_IN_PROGRESS
+	}
+}
+
+// Writes the record in the game record notation. ParseGameRecord reads it back to the same record.
+func FormatGameRecord(r *GameRecord) (string, error) {
+	var b strings.Builder
+	for _, tag := range r.Tags {
+		if !isValidTagName(tag.Name) {
+			return "", fmt.Errorf("Invalid tag name %q", tag.Name)
+		}
+		fmt.Fprintf(&b, "[%s %s]\n", tag.Name, strconv.Quote(tag.Value))
+	}
+	b.WriteString("\n")
+
+	for i, position := range r.Moves {
+		if i%2 == 0 {
+			fmt.Fprintf(&b, "%d. ", i/2+1)
+		}
+		square := strconv.Itoa(int(position))
+		if !r.NumericSquares {
+			coordinate, err := PositionToCoordinate(position)
+			if err != nil {
+				return "", err
+			}
+			square = coordinate
+		}
+		b.WriteString(square + " ")
+	}
+	if !isValidResult(r.Result) {
+		return "", fmt.Errorf("Invalid result %q", r.Result)
+	}
+	b.WriteString(r.Result + "\n")
+	return b.String(), nil
+}
+
+// Reads a record written in the game record notation
+func ParseGameRecord(text string) (*GameRecord, error) {
+	record := &GameRecord{}
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	// Tags run until the first line that is not a tag
+	line := 0
+	for ; line < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[line]), "["); line++ {
+		tag := strings.TrimSpace(lines[line])
+		name, value, ok := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(tag, "["), "]"), " ")
+		if !ok || !strings.HasSuffix(tag, "]") || !isValidTagName(name) {
+			return nil, fmt.Errorf("Line %d: invalid tag %q", line+1, tag)
+		}
+		unquoted, err := strconv.Unquote(value)
+		if err != nil {
+			return nil, fmt.Errorf("Line %d: invalid value for tag %s", line+1, name)
+		}
+		for _, existing := range record.Tags {
+			if existing.Name == name {
+				return nil, fmt.Errorf("Line %d: duplicate tag %s", line+1, name)
+			}
+		}
+		record.Tags = append(record.Tags, RecordTag{Name: name, Value: unquoted})
+	}
+
+	tokens := strings.Fields(strings.Join(lines[line:], " "))
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("Missing result")
+	}
+	record.Result = tokens[len(tokens)-1]
+	if !isValidResult(record.Result) {
+		return nil, fmt.Errorf("Invalid result %q", record.Result)
+	}
+	if tagResult := record.Tag(TAG_RESULT); tagResult != "" && tagResult != record.Result {
+		return nil, fmt.Errorf("Result %s does not match the Result tag %s", record.Result, tagResult)
+	}
+
+	// Each pair of moves starts with its number
+	numbered := false
+	for _, token := range tokens[:len(tokens)-1] {
+		if number, ok := strings.CutSuffix(token, "."); ok {
+			if numbered || number != strconv.Itoa(len(record.Moves)/2+1) {
+				return nil, fmt.Errorf("Unexpected move number %q", token)
+			}
+			numbered = true
+			continue
+		}
+		if !numbered {
+			return nil, fmt.Errorf("Missing move number before %q", token)
+		}
+		position, err := ParsePosition(token)
+		if err != nil {
+			return nil, err
+		}
+		if len(record.Moves) == 0 {
+			record.NumericSquares = len(token) == 1
+		}
+		record.Moves = append(record.Moves, position)
+		numbered = len(record.Moves)%2 == 1
+	}
+	if numbered && len(record.Moves)%2 == 0 {
+		return nil, fmt.Errorf("Move number without a move")
+	}
+	return record, nil
+}
+
+// Returns the engine options from the record's tags. Player 1 is X and moves first unless the tags say otherwise.
+func (r *GameRecord) Options() (*GameStateOptions, error) {
+	options := &GameStateOptions{
+		Player1Piece:  PIECE_X,
+		Player2Piece:  PIECE_O,
+		FirstPlayerId: 1,
+		Variant:       r.Tag(TAG_VARIANT),
+	}
+	if len(r.Tag(TAG_PLAYER_1_PIECE)) > 1 || len(r.Tag(TAG_PLAYER_2_PIECE)) > 1 {
+		return nil, fmt.Errorf("Invalid player pieces")
+	}
+	if piece := r.Tag(TAG_PLAYER_1_PIECE); piece != "" {
+		options.Player1Piece = piece[0]
+	}
+	if piece := r.Tag(TAG_PLAYER_2_PIECE); piece != "" {
+		options.Player2Piece = piece[0]
+	}
+	if firstPlayer := r.Tag(TAG_FIRST_PLAYER); firstPlayer != "" {
+		id, err := strconv.ParseUint(firstPlayer, 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid first player ID")
+		}
+		options.FirstPlayerId = uint8(id)
+	}
+	return options, nil
+}
+
+// Plays the record's moves and checks that they are legal and end with its result.
+// Returns the packed board after each move, starting with the blank board.
+func (r *GameRecord) Boards() ([][]byte, error) {
+	options, err := r.Options()
+	if err != nil {
+		return nil, err
+	}
+	gameState, err := NewGameState(options)
+	if err != nil {
+		return nil, err
+	}
+
+	boards := [][]byte{gameState.GetBoardAsByteArray()}
+	for i, position := range r.Moves {
+		ok, err := gameState.Move(position)
+		if err != nil {
+			return nil, fmt.Errorf("Move %d: %s", i+1, err)
+		}
+		if !ok {
+			return nil, fmt.Errorf("Move %d: played after the game ended", i+1)
+		}
+		boards = append(boards, gameState.GetBoardAsByteArray())
+	}
+	if result := ResultForTerminalState(gameState.TerminalState); result != r.Result {
+		return nil, fmt.Errorf("The moves end in %s, not %s", result, r.Result)
+	}
+	return boards, nil
+}
+
+func isValidTagName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+func isValidResult(result string) bool {
+	return result == RESULT_WIN_1 || result == RESULT_WIN_2 || result == RESULT_DRAW || result == RESULT_IN_PROGRESS
+}
@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testRecord = `[Name "Saturday \"rematch\""]
+[GameType "minimax"]
+[Player1Piece "O"]
+[Player2Piece "X"]
+[FirstPlayer "2"]
+[Result "1/2-1/2"]
+
+1. b2 a1 2. c3 a3 3. a2 c2 4. b1 b3 5. c1 1/2-1/2
+`
+
+// Test that records are parsed and written back exactly.
+func TestGameRecordRoundTrip(t *testing.T) {
+	record, err := ParseGameRecord(testRecord)
+	if err != nil {
+		t.Fatalf("Error parsing record: %s", err)
+	}
+	if record.Tag("Name") != `Saturday "rematch"` || record.Tag(TAG_FIRST_PLAYER) != "2" {
+		t.Errorf("Unexpected tags: %v", record.Tags)
+	}
+	if !reflect.DeepEqual(record.Moves, []uint8{5, 1, 9, 7, 4, 6, 2, 8, 3}) {
+		t.Errorf("Unexpected moves: %v", record.Moves)
+	}
+	formatted, err := FormatGameRecord(record)
+	if err != nil {
+		t.Fatalf("Error formatting record: %s", err)
+	}
+	if formatted != testRecord {
+		t.Errorf("Record did not round trip:\n%s", formatted)
+	}
+
+	// Numeric squares are kept numeric
+	numeric := strings.NewReplacer("b2", "5", "a1", "1", "c3", "9", "a3", "7", "a2", "4", "c2", "6", "b1", "2", "b3", "8", "c1", "3").Replace(testRecord)
+	record, err = ParseGameRecord(numeric)
+	if err != nil {
+		t.Fatalf("Error parsing numeric record: %s", err)
+	}
+	formatted, err = FormatGameRecord(record)
+	if err != nil || formatted != numeric {
+		t.Errorf("Numeric record did not round trip:\n%s", formatted)
+	}
+	parsed, err := ParseGameRecord(formatted)
+	if err != nil || !reflect.DeepEqual(parsed, record) {
+		t.Errorf("Formatted record parsed to %v, %v", parsed, err)
+	}
+}
+
+// Test that the moves are replayed with the record's options and checked against its result.
+func TestGameRecordBoards(t *testing.T) {
+	record, err := ParseGameRecord(testRecord)
+	if err != nil {
+		t.Fatalf("Error parsing record: %s", err)
+	}
+	boards, err := record.Boards()
+	if err != nil {
+		t.Fatalf("Error replaying record: %s", err)
+	}
+	// Player 2 moved first and took the center
+	if len(boards) != 10 || boards[1][3] != 0x10 {
+		t.Errorf("Unexpected boards: %v", boards)
+	}
+
+	record.Result = RESULT_WIN_1
+	if _, err := record.Boards(); err == nil {
+		t.Errorf("Expected error for a result that does not match the moves")
+	}
+}
+
+func TestParseGameRecord_Invalid(t *testing.T) {
+	invalid := map[string]string{
+		"missing result":   "[Result \"*\"]\n\n1. b2\n",
+		"unknown result":   "1. b2 2-0\n",
+		"result mismatch":  "[Result \"1-0\"]\n\n*\n",
+		"move number":      "2. b2 *\n",
+		"no move number":   "b2 *\n",
+		"dangling number":  "1. b2 a1 2. *\n",
+		"invalid square":   "1. d4 *\n",
+		"unquoted value":   "[Name Game]\n\n*\n",
+		"duplicate tag":    "[Name \"a\"]\n[Name \"b\"]\n\n*\n",
+		"invalid tag name": "[Na-me \"a\"]\n\n*\n",
+	}
+	for name, text := range invalid {
+		if _, err := ParseGameRecord(text); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
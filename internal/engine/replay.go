@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// A move recovered from two consecutive boards
+type ReplayedMove struct {
+	PlayerId uint8
+	Position uint8
+}
+
+// Result of replaying a game from the boards after each of its moves
+type Replay struct {
+	GameState *GameState     // Position and terminal state after the last valid board
+	Moves     []ReplayedMove // Moves that replayed correctly, in order
+	Winner    uint8          // 1 or 2, or 0 for a draw or a game in progress
+}
+
+// Returned by ReplayBoards for the first board that does not follow from the ones before it
+type ReplayError struct {
+	Move   int // Index of the board, 0 for the blank board
+	Reason string
+}
+
+func (e *ReplayError) Error() string {
+	return fmt.Sprintf("Move %d: %s", e.Move, e.Reason)
+}
+
+// Replays a game from its boards, starting with the blank board.
+// Each board must follow from the previous one by a legal move of the player whose turn it was, and no board may
+// follow a finished game. On a *ReplayError, the returned Replay holds the game up to the board before the invalid one.
+func ReplayBoards(gameStateOptions *GameStateOptions, boardStates [][]byte) (*Replay, error) {
+	gameState, err := NewGameState(gameStateOptions)
+	if err != nil {
+		return nil, err
+	}
+	replay := &Replay{GameState: gameState}
+	fail := func(move int, reason string) (*Replay, error) {
+		replay.Winner = gameState.Winner()
+		return replay, &ReplayError{Move: move, Reason: reason}
+	}
+
+	if len(boardStates) == 0 {
+		return fail(0, "Missing blank board")
+	}
+	if !bytes.Equal(boardStates[0], gameState.GetBoardAsByteArray()) {
+		return fail(0, "First board is not blank")
+	}
+
+	for i := 1; i < len(boardStates); i++ {
+		if gameState.IsTerminal() {
+			return fail(i, "Move played after the game ended")
+		}
+		preMoveState := gameState.GetBoardAsByteArray()
+		position, err := MovePosition(preMoveState, boardStates[i])
+		if err != nil {
+			return fail(i, err.Error())
+		}
+
+		// The player whose board changed made the move
+		playerId := uint8(2)
+		if postP1, _ := unpackBoardBigEndian(boardStates[i]); postP1 != gameState.Board.P1Board {
+			playerId = 1
+		}
+		if playerId != gameState.GetCurrentPlayerId() {
+			return fail(i, fmt.Sprintf("Player %d moved out of turn", playerId))
+		}
+		if _, err := gameState.Move(position); err != nil {
+			return fail(i, err.Error())
+		}
+		replay.Moves = append(replay.Moves, ReplayedMove{PlayerId: playerId, Position: position})
+	}
+
+	replay.Winner = gameState.Winner()
+	return replay, nil
+}
@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+)
+
+func replayTestOptions(firstPlayerId uint8) *GameStateOptions {
+	return &GameStateOptions{
+		Player1Piece:  PIECE_X,
+		Player2Piece:  PIECE_O,
+		FirstPlayerId: firstPlayerId,
+	}
+}
+
+// Test that a valid game is replayed to its final position and winner.
+func TestReplayBoards(t *testing.T) {
+	// Player 1 takes the top row while Player 2 plays 4 and 5
+	boards := [][]byte{
+		packBoardBigEndian(0x0000, 0x0000),
+		packBoardBigEndian(0x0001, 0x0000),
+		packBoardBigEndian(0x0001, 0x0008),
+		packBoardBigEndian(0x0003, 0x0008),
+		packBoardBigEndian(0x0003, 0x0018),
+		packBoardBigEndian(0x0007, 0x0018),
+	}
+	replay, err := ReplayBoards(replayTestOptions(1), boards)
+	if err != nil {
+		t.Fatalf("Error replaying boards: %s", err)
+	}
+	if len(replay.Moves) != 5 || replay.Moves[4] != (ReplayedMove{PlayerId: 1, Position: 3}) {
+		t.Errorf("Unexpected moves: %v", replay.Moves)
+	}
+	if replay.GameState.TerminalState != TERM_WIN_1 || replay.Winner != 1 {
+		t.Errorf("Expected Player 1 to win, got terminal state %d and winner %d", replay.GameState.TerminalState, replay.Winner)
+	}
+
+	// Nothing may follow the winning move
+	boards = append(boards, packBoardBigEndian(0x0007, 0x0118))
+	if _, err := ReplayBoards(replayTestOptions(1), boards); err == nil {
+		t.Errorf("Expected error for a move after the game ended")
+	}
+}
+
+// Test that the first invalid board is reported with the game up to it.
+func TestReplayBoards_InvalidBoards(t *testing.T) {
+	tests := map[string]struct {
+		firstPlayerId uint8
+		boards        [][]byte
+		move          int
+	}{
+		"not blank":    {1, [][]byte{packBoardBigEndian(0x0001, 0x0000)}, 0},
+		"out of turn":  {2, [][]byte{packBoardBigEndian(0, 0), packBoardBigEndian(0x0001, 0x0000)}, 1},
+		"two moves":    {1, [][]byte{packBoardBigEndian(0, 0), packBoardBigEndian(0x0001, 0x0002)}, 1},
+		"taken square": {1, [][]byte{packBoardBigEndian(0, 0), packBoardBigEndian(0x0001, 0), packBoardBigEndian(0x0001, 0x0001)}, 2},
+	}
+	for name, test := range tests {
+		replay, err := ReplayBoards(replayTestOptions(test.firstPlayerId), test.boards)
+		var replayErr *ReplayError
+		if !errors.As(err, &replayErr) {
+			t.Errorf("%s: expected a ReplayError, got %v", name, err)
+			continue
+		}
+		if replayErr.Move != test.move {
+			t.Errorf("%s: expected move %d to be invalid, got move %d", name, test.move, replayErr.Move)
+		}
+		if len(replay.Moves) != max(test.move-1, 0) {
+			t.Errorf("%s: expected %d valid moves, got %d", name, max(test.move-1, 0), len(replay.Moves))
+		}
+	}
+}
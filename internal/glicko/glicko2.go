@@ -0,0 +1,119 @@
+package glicko
+
+import (
+	"math"
+)
+
+// Glicko-2 ratings, following Glickman's "Example of the Glicko-2 system" (http://www.glicko.net/glicko/glicko2.pdf).
+// Ratings are kept on the Glicko scale and converted to the Glicko-2 scale for updates.
+
+const (
+	DEFAULT_RATING           = 1500.0
+	DEFAULT_RATING_DEVIATION = 350.0
+	DEFAULT_VOLATILITY       = 0.06
+	TAU                      = 0.5      // Constrains the change in volatility over time
+	SCALE                    = 173.7178 // Converts between the Glicko and Glicko-2 scales
+	CONVERGENCE_TOLERANCE    = 0.000001
+)
+
+// Scores of a result
+const (
+	SCORE_LOSS = 0.0
+	SCORE_DRAW = 0.5
+	SCORE_WIN  = 1.0
+)
+
+type Rating struct {
+	Rating     float64
+	Deviation  float64
+	Volatility float64
+}
+
+// A game against an opponent, with the opponent's rating before the rating period
+type Result struct {
+	Opponent Rating
+	Score    float64 // SCORE_LOSS, SCORE_DRAW or SCORE_WIN
+}
+
+// Returns the rating of a new player
+func NewRating() Rating {
+	return Rating{Rating: DEFAULT_RATING, Deviation: DEFAULT_RATING_DEVIATION, Volatility: DEFAULT_VOLATILITY}
+}
+
+// Reduces the impact of an opponent's rating by its deviation
+func g(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
+
+// Expected score against an opponent
+func expectedScore(mu float64, opponentMu float64, opponentPhi float64) float64 {
+	return 1 / (1 + math.Exp(-g(opponentPhi)*(mu-opponentMu)))
+}
+
+// Returns the rating after a rating period with the results. Without results, only the deviation grows.
+func Update(r Rating, results []Result) Rating {
+	mu := (r.Rating - DEFAULT_RATING) / SCALE
+	phi := r.Deviation / SCALE
+	if len(results) == 0 {
+		phi = math.Sqrt(phi*phi + r.Volatility*r.Volatility)
+		return Rating{Rating: r.Rating, Deviation: phi * SCALE, Volatility: r.Volatility}
+	}
+
+	// Estimated variance of the rating from the results, and the estimated improvement
+	var inverseV, improvement float64
+	for _, result := range results {
+		opponentMu := (result.Opponent.Rating - DEFAULT_RATING) / SCALE
+		opponentPhi := result.Opponent.Deviation / SCALE
+		gPhi := g(opponentPhi)
+		e := expectedScore(mu, opponentMu, opponentPhi)
+		inverseV += gPhi * gPhi * e * (1 - e)
+		improvement += gPhi * (result.Score - e)
+	}
+	v := 1 / inverseV
+	delta := v * improvement
+
+	volatility := newVolatility(phi, r.Volatility, v, delta)
+	phiStar := math.Sqrt(phi*phi + volatility*volatility)
+	newPhi := 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	newMu := mu + newPhi*newPhi*improvement
+	return Rating{
+		Rating:     newMu*SCALE + DEFAULT_RATING,
+		Deviation:  newPhi * SCALE,
+		Volatility: volatility,
+	}
+}
+
+// Finds the new volatility with the Illinois algorithm (step 5 of the paper)
+func newVolatility(phi float64, sigma float64, v float64, delta float64) float64 {
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-phi*phi-v-ex)/(2*d*d) - (x-a)/(TAU*TAU)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*TAU) < 0 {
+			k++
+		}
+		B = a - k*TAU
+	}
+
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > CONVERGENCE_TOLERANCE {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+	return math.Exp(A / 2)
+}
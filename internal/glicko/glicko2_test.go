@@ -0,0 +1,53 @@
+package glicko
+
+import (
+	"math"
+	"testing"
+)
+
+// The worked example from Glickman's paper
+func TestUpdate_PaperExample(t *testing.T) {
+	player := Rating{Rating: 1500, Deviation: 200, Volatility: 0.06}
+	results := []Result{
+		{Opponent: Rating{Rating: 1400, Deviation: 30}, Score: SCORE_WIN},
+		{Opponent: Rating{Rating: 1550, Deviation: 100}, Score: SCORE_LOSS},
+		{Opponent: Rating{Rating: 1700, Deviation: 300}, Score: SCORE_LOSS},
+	}
+	got := Update(player, results)
+	want := Rating{Rating: 1464.06, Deviation: 151.52, Volatility: 0.05999}
+	if math.Abs(got.Rating-want.Rating) > 0.01 || math.Abs(got.Deviation-want.Deviation) > 0.01 ||
+		math.Abs(got.Volatility-want.Volatility) > 0.00001 {
+		t.Errorf("Update() = %+v, want %+v", got, want)
+	}
+}
+
+func TestUpdate_WinnerGainsWhatLoserLoses(t *testing.T) {
+	a, b := NewRating(), NewRating()
+	newA := Update(a, []Result{{Opponent: b, Score: SCORE_WIN}})
+	newB := Update(b, []Result{{Opponent: a, Score: SCORE_LOSS}})
+	if newA.Rating <= a.Rating || newB.Rating >= b.Rating {
+		t.Fatalf("Expected the winner to gain and the loser to lose, got %v and %v", newA.Rating, newB.Rating)
+	}
+	if math.Abs((newA.Rating-a.Rating)+(newB.Rating-b.Rating)) > 1e-9 {
+		t.Errorf("Expected equal players to gain and lose the same, got %v and %v", newA.Rating, newB.Rating)
+	}
+	if newA.Deviation >= a.Deviation {
+		t.Errorf("Expected a game to reduce the deviation, got %v", newA.Deviation)
+	}
+
+	draw := Update(a, []Result{{Opponent: b, Score: SCORE_DRAW}})
+	if math.Abs(draw.Rating-a.Rating) > 1e-9 {
+		t.Errorf("Expected a draw between equal players to keep the rating, got %v", draw.Rating)
+	}
+}
+
+func TestUpdate_NoResultsGrowsDeviation(t *testing.T) {
+	r := Rating{Rating: 1600, Deviation: 50, Volatility: 0.06}
+	got := Update(r, nil)
+	if got.Rating != r.Rating || got.Volatility != r.Volatility {
+		t.Errorf("Expected only the deviation to change, got %+v", got)
+	}
+	if want := math.Sqrt(50*50 + math.Pow(0.06*SCALE, 2)); math.Abs(got.Deviation-want) > 1e-9 {
+		t.Errorf("Deviation = %v, want %v", got.Deviation, want)
+	}
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"t-cubed/internal/middleware"
+	"t-cubed/internal/service"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReqCredentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type ResAccount struct {
+	UUID      string    `json:"uuid"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ResSession struct {
+	Account   *ResAccount `json:"account"`
+	Token     string      `json:"token"` // Sent back as "Authorization: Bearer <token>"
+	ExpiresAt time.Time   `json:"expires_at"`
+}
+
+func newResAccount(account *service.Account) *ResAccount {
+	return &ResAccount{
+		UUID:      account.Uuid.String(),
+		Username:  account.Username,
+		CreatedAt: account.CreatedAt,
+	}
+}
+
+func newResSession(session *service.Session) *ResSession {
+	return &ResSession{
+		Account:   newResAccount(session.Account),
+		Token:     session.Token,
+		ExpiresAt: session.ExpiresAt,
+	}
+}
+
+// Creates an account and logs it in
+func (h *Handler) Register(c *gin.Context) {
+	req := ReqCredentials{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	session, err := h.accountService.Register(c.Request.Context(), req.Username, req.Password)
+	switch {
+	case errors.Is(err, service.ErrInvalidUsername), errors.Is(err, service.ErrInvalidPassword):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	case errors.Is(err, service.ErrUsernameTaken):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, newResSession(session))
+}
+
+func (h *Handler) Login(c *gin.Context) {
+	req := ReqCredentials{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	session, err := h.accountService.Login(c.Request.Context(), req.Username, req.Password)
+	if errors.Is(err, service.ErrInvalidCredentials) {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, newResSession(session))
+}
+
+// Ends the session the request was sent with
+func (h *Handler) Logout(c *gin.Context) {
+	if err := h.accountService.Logout(c.Request.Context(), middleware.GetSessionToken(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Returns the logged in account
+func (h *Handler) GetAccount(c *gin.Context) {
+	c.JSON(http.StatusOK, newResAccount(middleware.GetAccount(c)))
+}
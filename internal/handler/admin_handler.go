@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Reloads the neural network weights from disk
+func (h *Handler) ReloadWeights(c *gin.Context) {
+	if err := h.gameService.ReloadWeights(); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	_, etag := h.gameService.GetWeights()
+	c.JSON(http.StatusOK, gin.H{
+		"etag": etag,
+	})
+}
@@ -7,5 +7,11 @@ import (
 )
 
 func (h *Handler) GetWeights(c *gin.Context) {
-	c.JSON(http.StatusOK, h.gameService.GetWeights())
+	weights, etag := h.gameService.GetWeights()
+	c.Header("ETag", etag)
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.JSON(http.StatusOK, weights)
 }
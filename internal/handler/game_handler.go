@@ -2,9 +2,16 @@ package handler
 
 import (
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"t-cubed/internal/ai"
+	"t-cubed/internal/engine"
+	"t-cubed/internal/middleware"
 	"t-cubed/internal/service"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -19,6 +26,103 @@ type ResGame struct {
 	Player1Piece  string `json:"player_1_piece"`
 	Player2Piece  string `json:"player_2_piece"`
 	TerminalState int16  `json:"terminal_state"`
+	MoveSequence  int16  `json:"move_sequence"` // Last move played, sent back as expected_move_sequence or If-Match
+	FirstPlayerID int16  `json:"first_player_id"`
+	AIPlayerID    int16  `json:"ai_player_id"` // 0 in games between humans
+	Variant       string `json:"variant"`
+	ModelID       string `json:"model_id,omitempty"` // Checksum of the neural network that plays the game
+	TimeControl   int32  `json:"time_control_seconds"`
+	TimeIncrement int32  `json:"time_increment_seconds"`
+	CreatorUUID   string `json:"creator_uuid,omitempty"` // Accounts that created the game and hold its seats, empty if none
+	Player1UUID   string `json:"player_1_uuid,omitempty"`
+	Player2UUID   string `json:"player_2_uuid,omitempty"`
+	Rated         bool   `json:"rated"` // Whether finishing the game updates its players' ratings, false for imported games
+}
+
+// Returns the UUID as a string, or an empty string if it is nil
+func optionalUUID(uuid *uuid.UUID) string {
+	if uuid == nil {
+		return ""
+	}
+	return uuid.String()
+}
+
+func (h *Handler) newResGame(game *service.Game, moveEvent *service.MoveEvent) *ResGame {
+	return &ResGame{
+		UUID:          game.Uuid.String(),
+		Name:          game.Name,
+		GameType:      h.gameService.GetGameTypeLabel(game.GameTypeID),
+		BoardState:    hex.EncodeToString(moveEvent.PostMoveState),
+		NextPlayerID:  getNextPlayerID(moveEvent),
+		Player1Piece:  game.Player1Piece,
+		Player2Piece:  game.Player2Piece,
+		TerminalState: game.TerminalState,
+		MoveSequence:  moveEvent.MoveSequence,
+		FirstPlayerID: game.FirstPlayerID,
+		AIPlayerID:    game.AiPlayerID,
+		Variant:       game.Variant,
+		ModelID:       game.ModelID,
+		TimeControl:   game.TimeControlSeconds,
+		TimeIncrement: game.TimeIncrementSeconds,
+		CreatorUUID:   optionalUUID(game.CreatorUuid),
+		Player1UUID:   optionalUUID(game.Player1Uuid),
+		Player2UUID:   optionalUUID(game.Player2Uuid),
+		Rated:         game.Rated,
+	}
+}
+
+// The ETag of a game changes with every move
+func gameETag(moveEvent *service.MoveEvent) string {
+	return fmt.Sprintf(`"%d"`, moveEvent.MoveSequence)
+}
+
+// Returns the move sequence the client expects the game to be at, from the request's expected_move_sequence
+// or its If-Match header. Returns nil if the client did not send one.
+func parseExpectedMoveSequence(c *gin.Context, field string) (*int16, error) {
+	value := field
+	if value == "" {
+		value = strings.TrimPrefix(strings.TrimSpace(c.GetHeader("If-Match")), "W/")
+		if value == "" || value == "*" {
+			return nil, nil
+		}
+		unquoted, err := strconv.Unquote(value)
+		if err != nil {
+			return nil, errors.New("invalid If-Match header")
+		}
+		value = unquoted
+	}
+	parsed, err := strconv.ParseInt(value, 10, 16)
+	if err != nil {
+		return nil, errors.New("invalid expected move sequence")
+	}
+	expected := int16(parsed)
+	return &expected, nil
+}
+
+// Responds to a failed move. A stale move, or one that conflicts with the game's current state, e.g. because another
+// move was played first, gets 409 Conflict with the game's current state.
+func (h *Handler) respondMoveError(c *gin.Context, err error) {
+	var staleErr *service.StaleGameError
+	if errors.As(err, &staleErr) {
+		h.respondMoveConflict(c, err, staleErr.Game, staleErr.MoveEvent)
+		return
+	}
+	var conflictErr *service.MoveConflictError
+	if errors.As(err, &conflictErr) {
+		h.respondMoveConflict(c, err, conflictErr.Game, conflictErr.MoveEvent)
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error": err.Error(),
+	})
+}
+
+func (h *Handler) respondMoveConflict(c *gin.Context, err error, game *service.Game, moveEvent *service.MoveEvent) {
+	c.Header("ETag", gameETag(moveEvent))
+	c.JSON(http.StatusConflict, gin.H{
+		"error": err.Error(),
+		"game":  h.newResGame(game, moveEvent),
+	})
 }
 
 func getNextPlayerID(moveEvent *service.MoveEvent) int16 {
@@ -44,25 +148,28 @@ func (h *Handler) GetGame(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, ResGame{
-		UUID:          game.Uuid.String(),
-		Name:          game.Name,
-		GameType:      h.gameService.GetGameTypeLabel(game.GameTypeID),
-		BoardState:    hex.EncodeToString(moveEvent.PostMoveState),
-		NextPlayerID:  getNextPlayerID(moveEvent),
-		Player1Piece:  game.Player1Piece,
-		Player2Piece:  game.Player2Piece,
-		TerminalState: game.TerminalState,
-	})
+	c.Header("ETag", gameETag(moveEvent))
+	c.JSON(http.StatusOK, h.newResGame(game, moveEvent))
 }
 
 type ReqCreateGame struct {
-	Name         string `json:"name"`
-	GameType     string `json:"game_type"`
-	Player1Piece string `json:"player_1_piece"`
-	Player2Piece string `json:"player_2_piece"`
-	NextPlayerID string `json:"next_player_id"`
-	AIPlayerID   string `json:"ai_player_id"`
+	Name          string `json:"name"`
+	GameType      string `json:"game_type"`
+	Player1Piece  string `json:"player_1_piece"`
+	Player2Piece  string `json:"player_2_piece"`
+	NextPlayerID  string `json:"next_player_id"`         // Optional: the player who moves first, 1 by default
+	AIPlayerID    string `json:"ai_player_id"`           // Optional: 2 by default, empty or 0 in games between humans
+	Variant       string `json:"variant"`                // Optional: "standard" by default
+	TimeControl   string `json:"time_control_seconds"`   // Optional: clock for each player, untimed by default
+	TimeIncrement string `json:"time_increment_seconds"` // Optional: seconds added to a player's clock after each move
+}
+
+// Parses an optional integer field of a request, which is 0 if it was not sent
+func parseOptionalInt(value string, bitSize int) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(value, 10, bitSize)
 }
 
 func (h *Handler) CreateGame(c *gin.Context) {
@@ -75,7 +182,35 @@ func (h *Handler) CreateGame(c *gin.Context) {
 		return
 	}
 
-	game, moveEvent, err := h.gameService.CreateGame(c.Request.Context(), req.Name, req.GameType, req.Player1Piece, req.Player2Piece)
+	config := service.GameConfig{
+		Player1Piece: req.Player1Piece,
+		Player2Piece: req.Player2Piece,
+		Variant:      req.Variant,
+		CreatorUUID:  middleware.GetAccountUUID(c),
+	}
+	fields := []struct {
+		name    string
+		value   string
+		bitSize int
+		set     func(int64)
+	}{
+		{"next_player_id", req.NextPlayerID, 16, func(v int64) { config.FirstPlayerID = int16(v) }},
+		{"ai_player_id", req.AIPlayerID, 16, func(v int64) { config.AIPlayerID = int16(v) }},
+		{"time_control_seconds", req.TimeControl, 32, func(v int64) { config.TimeControlSeconds = int32(v) }},
+		{"time_increment_seconds", req.TimeIncrement, 32, func(v int64) { config.TimeIncrementSeconds = int32(v) }},
+	}
+	for _, field := range fields {
+		parsed, err := parseOptionalInt(field.value, field.bitSize)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "invalid " + field.name,
+			})
+			return
+		}
+		field.set(parsed)
+	}
+
+	game, moveEvent, err := h.gameService.CreateGame(c.Request.Context(), req.Name, req.GameType, config)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
@@ -83,27 +218,23 @@ func (h *Handler) CreateGame(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, ResGame{
-		UUID:          game.Uuid.String(),
-		Name:          game.Name,
-		GameType:      h.gameService.GetGameTypeLabel(game.GameTypeID),
-		BoardState:    hex.EncodeToString(moveEvent.PostMoveState),
-		NextPlayerID:  getNextPlayerID(moveEvent),
-		Player1Piece:  game.Player1Piece,
-		Player2Piece:  game.Player2Piece,
-		TerminalState: game.TerminalState,
-	})
+	c.Header("ETag", gameETag(moveEvent))
+	c.JSON(http.StatusOK, h.newResGame(game, moveEvent))
 }
 
 type ReqNNMove struct {
-	PlayerID string `json:"player_id"`
-	Position string `json:"position"`
+	PlayerID             string `json:"player_id"`
+	Position             string `json:"position"`
+	ExpectedMoveSequence string `json:"expected_move_sequence"` // Optional: the game's move_sequence when the move was chosen
+	Attribution          string `json:"attribution"`            // Optional: "gradient_x_input" or "integrated_gradients"
 }
 
 type ResNNMove struct {
 	Game        *ResGame             `json:"game"`
 	Trace       *service.NNMoveTrace `json:"trace"`
 	RankedMoves []int                `json:"ranked_moves"`
+	Attribution *ai.Attribution      `json:"attribution,omitempty"`
+	Value       *float64             `json:"value,omitempty"` // Expected outcome for the AI in [-1, 1], only for networks with a value head
 }
 
 // Plays Neural Network move
@@ -127,10 +258,16 @@ func (h *Handler) PlayNNMove(c *gin.Context) {
 		return
 	}
 	playerID := int16(parsedPlayerID)
-	if playerID != 1 {
+	if playerID != 1 && playerID != 2 {
 		c.JSON(http.StatusBadRequest, gin.H{})
 		return
 	}
+	if !middleware.HoldsSeat(c, playerID) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "not seated as this player",
+		})
+		return
+	}
 
 	parsedPosition, err := strconv.ParseInt(req.Position, 10, 16)
 	if err != nil {
@@ -139,39 +276,57 @@ func (h *Handler) PlayNNMove(c *gin.Context) {
 	}
 	position := uint8(parsedPosition)
 
-	result, moveEvent, err := h.gameService.PlayNNMove(c.Request.Context(), uuid, playerID, position)
+	attribution, err := ai.ParseAttributionMethod(req.Attribution)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(http.StatusBadRequest, gin.H{
 			"error": err.Error(),
 		})
 		return
 	}
 
+	// Optional: ?trace=detailed also records pre-activations, logits and contributions in the AI's trace
+	traceLevel, err := ai.ParseTraceLevel(c.Query("trace"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	expectedMoveSequence, err := parseExpectedMoveSequence(c, req.ExpectedMoveSequence)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	result, moveEvent, err := h.gameService.PlayNNMove(c.Request.Context(), uuid, playerID, position, attribution, traceLevel, expectedMoveSequence)
+	if err != nil {
+		h.respondMoveError(c, err)
+		return
+	}
+
 	response := ResNNMove{
-		Game: &ResGame{
-			UUID:          result.Game.Uuid.String(),
-			Name:          result.Game.Name,
-			GameType:      h.gameService.GetGameTypeLabel(result.Game.GameTypeID),
-			BoardState:    hex.EncodeToString(moveEvent.PostMoveState),
-			NextPlayerID:  getNextPlayerID(moveEvent),
-			Player1Piece:  result.Game.Player1Piece,
-			Player2Piece:  result.Game.Player2Piece,
-			TerminalState: result.Game.TerminalState,
-		},
+		Game:        h.newResGame(result.Game, moveEvent),
 		Trace:       nil,
 		RankedMoves: result.RankedMoves,
+		Attribution: result.Attribution,
+		Value:       result.Value,
 	}
 
 	if result.Trace != nil {
 		response.Trace = result.Trace
 	}
 
+	c.Header("ETag", gameETag(moveEvent))
 	c.JSON(http.StatusOK, response)
 }
 
 type ReqMMMove struct {
-	PlayerID string `json:"player_id"`
-	Position string `json:"position"`
+	PlayerID             string `json:"player_id"`
+	Position             string `json:"position"`
+	ExpectedMoveSequence string `json:"expected_move_sequence"` // Optional: the game's move_sequence when the move was chosen
 }
 
 type ResMMMove struct {
@@ -199,10 +354,16 @@ func (h *Handler) PlayMMMove(c *gin.Context) {
 		return
 	}
 	playerID := int16(parsedPlayerID)
-	if playerID != 1 {
+	if playerID != 1 && playerID != 2 {
 		c.JSON(http.StatusBadRequest, gin.H{})
 		return
 	}
+	if !middleware.HoldsSeat(c, playerID) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "not seated as this player",
+		})
+		return
+	}
 
 	parsedPosition, err := strconv.ParseInt(req.Position, 10, 16)
 	if err != nil {
@@ -211,39 +372,62 @@ func (h *Handler) PlayMMMove(c *gin.Context) {
 	}
 	position := uint8(parsedPosition)
 
-	result, moveEvent, err := h.gameService.PlayMMMove(c.Request.Context(), uuid, playerID, position)
+	expectedMoveSequence, err := parseExpectedMoveSequence(c, req.ExpectedMoveSequence)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(http.StatusBadRequest, gin.H{
 			"error": err.Error(),
 		})
 		return
 	}
 
+	result, moveEvent, err := h.gameService.PlayMMMove(c.Request.Context(), uuid, playerID, position, expectedMoveSequence)
+	if err != nil {
+		h.respondMoveError(c, err)
+		return
+	}
+
 	response := ResMMMove{
-		Game: &ResGame{
-			UUID:          result.Uuid.String(),
-			Name:          result.Name,
-			GameType:      h.gameService.GetGameTypeLabel(result.GameTypeID),
-			BoardState:    hex.EncodeToString(moveEvent.PostMoveState),
-			NextPlayerID:  getNextPlayerID(moveEvent),
-			Player1Piece:  result.Player1Piece,
-			Player2Piece:  result.Player2Piece,
-			TerminalState: result.TerminalState,
-		},
+		Game: h.newResGame(result, moveEvent),
 	}
 
+	c.Header("ETag", gameETag(moveEvent))
 	c.JSON(http.StatusOK, response)
 }
 
 type ResMoveEvent struct {
-	MoveSequence  int16  `json:"move_sequence"`
-	PlayerID      int16  `json:"player_id"`
-	PostMoveState string `json:"post_move_state"`
+	MoveSequence  int16     `json:"move_sequence"`
+	PlayerID      int16     `json:"player_id"`
+	PostMoveState string    `json:"post_move_state"`
+	Position      *int16    `json:"position"`             // Square played (1-9), null for the blank board
+	Coordinate    string    `json:"coordinate,omitempty"` // Square played in algebraic notation (a1-c3)
+	ReceivedAt    time.Time `json:"received_at"`
+	ThinkTimeMs   *int32    `json:"think_time_ms,omitempty"` // Only for AI moves
+}
+
+func newResMoveEvent(moveEvent *service.MoveEvent) *ResMoveEvent {
+	res := &ResMoveEvent{
+		MoveSequence:  moveEvent.MoveSequence,
+		PlayerID:      moveEvent.PlayerID,
+		PostMoveState: hex.EncodeToString(moveEvent.PostMoveState),
+		ReceivedAt:    moveEvent.ReceivedAt,
+	}
+	if moveEvent.Position.Valid {
+		position := moveEvent.Position.Int16
+		res.Position = &position
+		res.Coordinate, _ = engine.PositionToCoordinate(uint8(position))
+	}
+	if moveEvent.ThinkTimeMs.Valid {
+		thinkTimeMs := moveEvent.ThinkTimeMs.Int32
+		res.ThinkTimeMs = &thinkTimeMs
+	}
+	return res
 }
 
 type ResMoveEventWithTrace struct {
-	MoveEvent *ResMoveEvent        `json:"move_event"`
-	Trace     *service.NNMoveTrace `json:"trace"`
+	MoveEvent          *ResMoveEvent        `json:"move_event"`
+	Trace              *service.NNMoveTrace `json:"trace"`
+	Attribution        *ai.Attribution      `json:"attribution,omitempty"`
+	AttributionSkipped string               `json:"attribution_skipped,omitempty"` // e.g. "model_not_loaded" when the model that made the move is gone
 }
 
 func (h *Handler) GetMoveHistory(c *gin.Context) {
@@ -254,7 +438,16 @@ func (h *Handler) GetMoveHistory(c *gin.Context) {
 		return
 	}
 
-	moveEvents, err := h.gameService.GetMoveHistory(c.Request.Context(), uuid)
+	// Optional: ?attribution=gradient_x_input or ?attribution=integrated_gradients
+	attribution, err := ai.ParseAttributionMethod(c.Query("attribution"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	moveEvents, err := h.gameService.GetMoveHistory(c.Request.Context(), uuid, attribution)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
@@ -264,13 +457,106 @@ func (h *Handler) GetMoveHistory(c *gin.Context) {
 	var resMoveEvents []ResMoveEventWithTrace
 	for _, moveEvent := range moveEvents {
 		resMoveEvents = append(resMoveEvents, ResMoveEventWithTrace{
-			MoveEvent: &ResMoveEvent{
-				MoveSequence:  moveEvent.MoveEvent.MoveSequence,
-				PlayerID:      moveEvent.MoveEvent.PlayerID,
-				PostMoveState: hex.EncodeToString(moveEvent.MoveEvent.PostMoveState),
-			},
-			Trace: moveEvent.Trace,
+			MoveEvent:          newResMoveEvent(moveEvent.MoveEvent),
+			Trace:              moveEvent.Trace,
+			Attribution:        moveEvent.Attribution,
+			AttributionSkipped: moveEvent.AttributionSkipped,
 		})
 	}
 	c.JSON(http.StatusOK, resMoveEvents)
 }
+
+// Returns the game as a game record, see engine.GameRecord
+func (h *Handler) ExportGame(c *gin.Context) {
+	uuidParam := c.Param("uuid")
+	uuid, err := uuid.Parse(uuidParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{})
+		return
+	}
+
+	record, err := h.gameService.ExportGame(c.Request.Context(), uuid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.tgn"`, uuid))
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(record))
+}
+
+type ReqImportGame struct {
+	Record string `json:"record"` // Game record, as returned by the export endpoint
+}
+
+// Creates a game from a game record
+func (h *Handler) ImportGame(c *gin.Context) {
+	req := ReqImportGame{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	game, moveEvent, err := h.gameService.ImportGame(c.Request.Context(), req.Record, middleware.GetAccountUUID(c))
+	if errors.Is(err, service.ErrInvalidGameRecord) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.Header("ETag", gameETag(moveEvent))
+	c.JSON(http.StatusOK, h.newResGame(game, moveEvent))
+}
+
+type ResJoinGame struct {
+	Game     *ResGame `json:"game"`
+	PlayerID int16    `json:"player_id"` // The player the account is seated as
+}
+
+// Seats the logged in account as the free human player of a game
+func (h *Handler) JoinGame(c *gin.Context) {
+	uuidParam := c.Param("uuid")
+	uuid, err := uuid.Parse(uuidParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{})
+		return
+	}
+
+	account := middleware.GetAccount(c)
+	game, moveEvent, playerID, err := h.gameService.JoinGame(c.Request.Context(), uuid, account.Uuid)
+	switch {
+	case errors.Is(err, service.ErrGameNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	case errors.Is(err, service.ErrNoFreeSeat), errors.Is(err, service.ErrOpenGame):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.Header("ETag", gameETag(moveEvent))
+	c.JSON(http.StatusOK, ResJoinGame{
+		Game:     h.newResGame(game, moveEvent),
+		PlayerID: playerID,
+	})
+}
@@ -2,16 +2,20 @@ package handler
 
 import (
 	"t-cubed/internal/service"
-
-	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Handler struct {
-	gameService *service.GameService
+	gameService       *service.GameService
+	accountService    *service.AccountService
+	ratingService     *service.RatingService
+	statisticsService *service.StatisticsService
 }
 
-func NewHandler(db *pgxpool.Pool) *Handler {
+func NewHandler(gameService *service.GameService, accountService *service.AccountService, ratingService *service.RatingService, statisticsService *service.StatisticsService) *Handler {
 	return &Handler{
-		gameService: service.NewGameService(db),
+		gameService:       gameService,
+		accountService:    accountService,
+		ratingService:     ratingService,
+		statisticsService: statisticsService,
 	}
 }
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"t-cubed/internal/service"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ResRating struct {
+	UUID            string  `json:"uuid"`
+	Rank            int     `json:"rank,omitempty"` // Position on the leaderboard, only in leaderboards
+	Kind            string  `json:"kind"`           // "account" or "ai"
+	Name            string  `json:"name"`           // Username, or the AI's identity, e.g. "minimax" or "neural_network:<model checksum>"
+	AccountUUID     string  `json:"account_uuid,omitempty"`
+	Rating          float64 `json:"rating"`
+	RatingDeviation float64 `json:"rating_deviation"`
+	Volatility      float64 `json:"volatility"`
+	Games           int32   `json:"games"`
+	Wins            int32   `json:"wins"`
+	Losses          int32   `json:"losses"`
+	Draws           int32   `json:"draws"`
+}
+
+func newResRating(player *service.RatedPlayer) *ResRating {
+	kind := service.RATING_KIND_AI
+	if player.Rating.AccountUuid != nil {
+		kind = service.RATING_KIND_ACCOUNT
+	}
+	return &ResRating{
+		UUID:            player.Rating.Uuid.String(),
+		Kind:            kind,
+		Name:            player.Name,
+		AccountUUID:     optionalUUID(player.Rating.AccountUuid),
+		Rating:          player.Rating.Rating,
+		RatingDeviation: player.Rating.RatingDeviation,
+		Volatility:      player.Rating.Volatility,
+		Games:           player.Rating.Games,
+		Wins:            player.Rating.Wins,
+		Losses:          player.Rating.Losses,
+		Draws:           player.Rating.Draws,
+	}
+}
+
+// Returns the leaderboard.
+// Optional query parameters: ?kind=all|account|ai, ?limit= (50 by default, at most 200) and ?offset=
+func (h *Handler) GetLeaderboard(c *gin.Context) {
+	kind := c.DefaultQuery("kind", service.RATING_KIND_ALL)
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", strconv.Itoa(service.LEADERBOARD_DEFAULT_LIMIT)), 10, 32)
+	if err != nil || limit < 1 || limit > service.LEADERBOARD_MAX_LIMIT {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid limit",
+		})
+		return
+	}
+	offset, err := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 32)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid offset",
+		})
+		return
+	}
+
+	players, err := h.ratingService.Leaderboard(c.Request.Context(), kind, int32(limit), int32(offset))
+	if errors.Is(err, service.ErrInvalidRatingKind) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	resRatings := []*ResRating{}
+	for i := range players {
+		resRating := newResRating(&players[i])
+		resRating.Rank = int(offset) + i + 1
+		resRatings = append(resRatings, resRating)
+	}
+	c.JSON(http.StatusOK, resRatings)
+}
+
+type ResRatedGame struct {
+	GameUUID           string    `json:"game_uuid"`
+	OpponentRatingUUID string    `json:"opponent_rating_uuid"`
+	OpponentName       string    `json:"opponent_name"`
+	Score              float64   `json:"score"`  // 1 for a win, 0.5 for a draw and 0 for a loss
+	Rating             float64   `json:"rating"` // Rating after the game
+	RatingDeviation    float64   `json:"rating_deviation"`
+	RatingChange       float64   `json:"rating_change"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+type ResRatingHistory struct {
+	Rating *ResRating     `json:"rating"`
+	Games  []ResRatedGame `json:"games"`
+}
+
+// Returns a rating and its rated games, oldest first
+func (h *Handler) GetRatingHistory(c *gin.Context) {
+	uuidParam := c.Param("uuid")
+	uuid, err := uuid.Parse(uuidParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{})
+		return
+	}
+
+	player, games, err := h.ratingService.GetRatingHistory(c.Request.Context(), uuid)
+	if errors.Is(err, service.ErrRatingNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response := ResRatingHistory{
+		Rating: newResRating(player),
+		Games:  []ResRatedGame{},
+	}
+	for _, game := range games {
+		response.Games = append(response.Games, ResRatedGame{
+			GameUUID:           game.History.GameUuid.String(),
+			OpponentRatingUUID: game.History.OpponentRatingUuid.String(),
+			OpponentName:       game.OpponentName,
+			Score:              game.History.Score,
+			Rating:             game.History.Rating,
+			RatingDeviation:    game.History.RatingDeviation,
+			RatingChange:       game.History.RatingChange,
+			CreatedAt:          game.History.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"net/http"
+	"t-cubed/internal/engine"
+	"t-cubed/internal/service"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Returns count as a share of total, or 0 if total is 0
+func rate(count int64, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(count) / float64(total)
+}
+
+type ResGameOutcomes struct {
+	GameType       string  `json:"game_type"`
+	ModelID        string  `json:"model_id,omitempty"` // Neural network games are split by model
+	Games          int64   `json:"games"`
+	Player1Wins    int64   `json:"player_1_wins"`
+	Player2Wins    int64   `json:"player_2_wins"`
+	Draws          int64   `json:"draws"`
+	HumanWins      int64   `json:"human_wins"` // Only counted in games against the AI
+	AIWins         int64   `json:"ai_wins"`
+	Player1WinRate float64 `json:"player_1_win_rate"`
+	Player2WinRate float64 `json:"player_2_win_rate"`
+	DrawRate       float64 `json:"draw_rate"`
+	HumanWinRate   float64 `json:"human_win_rate"`
+	AIWinRate      float64 `json:"ai_win_rate"`
+	AverageMoves   float64 `json:"average_moves"`
+}
+
+// Returns the results and average length of finished games per game type and neural network model
+func (h *Handler) GetGameOutcomes(c *gin.Context) {
+	outcomes, err := h.statisticsService.GameOutcomes(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response := []ResGameOutcomes{}
+	for _, outcome := range outcomes {
+		response = append(response, ResGameOutcomes{
+			GameType:       outcome.GameType,
+			ModelID:        outcome.ModelID,
+			Games:          outcome.Games,
+			Player1Wins:    outcome.Player1Wins,
+			Player2Wins:    outcome.Player2Wins,
+			Draws:          outcome.Draws,
+			HumanWins:      outcome.HumanWins,
+			AIWins:         outcome.AiWins,
+			Player1WinRate: rate(outcome.Player1Wins, outcome.Games),
+			Player2WinRate: rate(outcome.Player2Wins, outcome.Games),
+			DrawRate:       rate(outcome.Draws, outcome.Games),
+			HumanWinRate:   rate(outcome.HumanWins, outcome.Games),
+			AIWinRate:      rate(outcome.AiWins, outcome.Games),
+			AverageMoves:   outcome.AverageMoves,
+		})
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+type ResHumanResults struct {
+	GameType     string  `json:"game_type"`
+	Games        int64   `json:"games"`
+	HumanWins    int64   `json:"human_wins"`
+	Draws        int64   `json:"draws"`
+	AIWins       int64   `json:"ai_wins"`
+	HumanWinRate float64 `json:"human_win_rate"`
+	DrawRate     float64 `json:"draw_rate"`
+	AIWinRate    float64 `json:"ai_win_rate"`
+}
+
+// Returns how often humans beat the neural network compared to minimax
+func (h *Handler) GetHumanResultsAgainstAI(c *gin.Context) {
+	results, err := h.statisticsService.HumanResultsAgainstAI(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response := []ResHumanResults{}
+	for _, result := range results {
+		response = append(response, ResHumanResults{
+			GameType:     result.GameType,
+			Games:        result.Games,
+			HumanWins:    result.HumanWins,
+			Draws:        result.Draws,
+			AIWins:       result.AiWins,
+			HumanWinRate: rate(result.HumanWins, result.Games),
+			DrawRate:     rate(result.Draws, result.Games),
+			AIWinRate:    rate(result.AiWins, result.Games),
+		})
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+type ResOpeningSquare struct {
+	GameType   string  `json:"game_type"`
+	Position   int16   `json:"position"`
+	Coordinate string  `json:"coordinate"`
+	Games      int64   `json:"games"`
+	Share      float64 `json:"share"` // Share of the game type's openings
+}
+
+// Returns the squares humans opened finished games with per game type, most common first
+func (h *Handler) GetOpeningSquares(c *gin.Context) {
+	openings, err := h.statisticsService.OpeningSquares(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	totals := map[string]int64{}
+	for _, opening := range openings {
+		totals[opening.GameType] += opening.Games
+	}
+	response := []ResOpeningSquare{}
+	for _, opening := range openings {
+		coordinate, _ := engine.PositionToCoordinate(uint8(opening.Position.Int16))
+		response = append(response, ResOpeningSquare{
+			GameType:   opening.GameType,
+			Position:   opening.Position.Int16,
+			Coordinate: coordinate,
+			Games:      opening.Games,
+			Share:      rate(opening.Games, totals[opening.GameType]),
+		})
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+type ResSquareHeatmap struct {
+	GameType    string                `json:"game_type,omitempty"`
+	Human       service.SquareHeatmap `json:"human"` // Moves per [move number - 1][position - 1]
+	AI          service.SquareHeatmap `json:"ai"`
+	RefreshedAt *time.Time            `json:"refreshed_at"` // The heatmap is refreshed periodically, so it can lag behind
+}
+
+// Returns the heatmap of square usage by move number in finished games.
+// Optional query parameter: ?game_type=neural_network|minimax|humans, all game types by default
+func (h *Handler) GetSquareHeatmap(c *gin.Context) {
+	gameType := c.Query("game_type")
+	switch gameType {
+	case "", service.GAME_TYPE_NN, service.GAME_TYPE_MINIMAX, service.GAME_TYPE_HUMANS:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid game type",
+		})
+		return
+	}
+
+	usage, err := h.statisticsService.SquareUsage(c.Request.Context(), gameType)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, ResSquareHeatmap{
+		GameType:    gameType,
+		Human:       usage.Human,
+		AI:          usage.AI,
+		RefreshedAt: usage.RefreshedAt,
+	})
+}
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"t-cubed/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	ACCOUNT_CONTEXT_KEY         = "account"
+	SESSION_TOKEN_CONTEXT_KEY   = "session_token"
+	SEAT_PLAYER_IDS_CONTEXT_KEY = "seat_player_ids"
+)
+
+// Logs in requests sent with a session token as a bearer token. Requests without one continue anonymously, and
+// requests with an invalid or expired one are rejected.
+func NewAuth(accounts *service.AccountService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			return
+		}
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "Authorization must be a bearer token",
+			})
+			return
+		}
+
+		account, err := accounts.Authenticate(c.Request.Context(), token)
+		if errors.Is(err, service.ErrInvalidSession) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.Set(ACCOUNT_CONTEXT_KEY, account)
+		c.Set(SESSION_TOKEN_CONTEXT_KEY, token)
+	}
+}
+
+// Returns the account logged in by NewAuth, or nil for anonymous requests
+func GetAccount(c *gin.Context) *service.Account {
+	account, _ := c.Get(ACCOUNT_CONTEXT_KEY)
+	if account == nil {
+		return nil
+	}
+	return account.(*service.Account)
+}
+
+// Returns the account's UUID, or nil for anonymous requests
+func GetAccountUUID(c *gin.Context) *uuid.UUID {
+	account := GetAccount(c)
+	if account == nil {
+		return nil
+	}
+	return &account.Uuid
+}
+
+func GetSessionToken(c *gin.Context) string {
+	return c.GetString(SESSION_TOKEN_CONTEXT_KEY)
+}
+
+// Rejects requests that are not logged in. Must go after NewAuth.
+func NewRequireAccount() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if GetAccount(c) == nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "login required",
+			})
+			return
+		}
+	}
+}
+
+// Only lets the accounts seated in the :uuid game through. Games without seat owners are open to anyone.
+// Must go after NewAuth. Handlers check the player ID of a move with HoldsSeat.
+func NewGameParticipant(games *service.GameService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		gameUuid, err := uuid.Parse(c.Param("uuid"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": "invalid game UUID",
+			})
+			return
+		}
+
+		seats, err := games.GetGameSeats(c.Request.Context(), gameUuid)
+		if errors.Is(err, service.ErrGameNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if !seats.Owned() {
+			return
+		}
+
+		account := GetAccount(c)
+		if account == nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "login required to move in this game",
+			})
+			return
+		}
+		playerIDs := seats.PlayerIDs(account.Uuid)
+		if len(playerIDs) == 0 {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "not a player in this game",
+			})
+			return
+		}
+		c.Set(SEAT_PLAYER_IDS_CONTEXT_KEY, playerIDs)
+	}
+}
+
+// Reports whether the request may move as playerID in the game checked by NewGameParticipant
+func HoldsSeat(c *gin.Context, playerID int16) bool {
+	playerIDs, ok := c.Get(SEAT_PLAYER_IDS_CONTEXT_KEY)
+	if !ok {
+		return true
+	}
+	return slices.Contains(playerIDs.([]int16), playerID)
+}
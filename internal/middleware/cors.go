@@ -10,8 +10,8 @@ func NewCors(origins []string) gin.HandlerFunc {
   return cors.New(cors.Config{
     AllowOrigins:     origins,
     AllowMethods:     []string{"GET", "POST", "PUT"},
-    AllowHeaders:     []string{"Origin", "Content-Type"},
-    ExposeHeaders:    []string{"Content-Length"},
+    AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "If-Match", "Idempotency-Key"},
+    ExposeHeaders:    []string{"Content-Length", "Content-Disposition", "ETag", "Idempotent-Replayed"},
     AllowCredentials: true,
     MaxAge: 12 * time.Hour,
   })
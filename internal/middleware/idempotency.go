@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"net/http"
+	"t-cubed/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	IDEMPOTENCY_KEY_HEADER     = "Idempotency-Key"
+	IDEMPOTENT_REPLAYED_HEADER = "Idempotent-Replayed"
+)
+
+// Response headers that are saved and replayed with the body
+var IDEMPOTENT_RESPONSE_HEADERS = []string{"Content-Type", "ETag"}
+
+// Copies the response body while it is written
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Honours the Idempotency-Key header: the first request with a key runs and its response is saved, and retries
+// with the same key and body get the saved response. Server errors are not saved, so those requests can be retried.
+func NewIdempotency(idempotency *service.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IDEMPOTENCY_KEY_HEADER)
+		if key == "" {
+			return
+		}
+		if len(key) > service.IDEMPOTENCY_KEY_MAX_LENGTH {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": "Idempotency-Key is too long",
+			})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		hash := sha256.Sum256(body)
+		route := c.Request.Method + " " + c.Request.URL.Path
+
+		stored, err := idempotency.Begin(c.Request.Context(), key, route, hash[:])
+		switch {
+		case errors.Is(err, service.ErrIdempotencyKeyInProgress):
+			c.Header("Retry-After", "1")
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+				"error": err.Error(),
+			})
+			return
+		case errors.Is(err, service.ErrIdempotencyKeyReused):
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+				"error": err.Error(),
+			})
+			return
+		case err != nil:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		case stored != nil:
+			for name, value := range stored.Headers {
+				c.Header(name, value)
+			}
+			c.Header(IDEMPOTENT_REPLAYED_HEADER, "true")
+			c.Status(stored.StatusCode)
+			c.Writer.Write(stored.Body)
+			c.Abort()
+			return
+		}
+
+		// Release the key if the handler panics, so the request can be retried.
+		// A background context is used because the request's context may already be canceled.
+		completed := false
+		defer func() {
+			if !completed {
+				idempotency.Release(context.Background(), key, route)
+			}
+		}()
+
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		status := writer.Status()
+		if status >= http.StatusInternalServerError {
+			return
+		}
+		response := &service.StoredResponse{
+			StatusCode: status,
+			Headers:    map[string]string{},
+			Body:       writer.body.Bytes(),
+		}
+		for _, name := range IDEMPOTENT_RESPONSE_HEADERS {
+			if value := writer.Header().Get(name); value != "" {
+				response.Headers[name] = value
+			}
+		}
+		// If the response cannot be saved, the key is released rather than left in progress until it expires
+		completed = idempotency.Complete(context.Background(), key, route, response) == nil
+	}
+}
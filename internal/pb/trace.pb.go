@@ -21,14 +21,23 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
-// For the float64 activations from the neural network forward pass
+// A recorded neural network forward pass.
+// Version 1 traces only set the fixed layer1-layer5 fields and have no version.
+// Version 2 traces set version and one Layer per network layer, so any depth is supported.
 type Trace struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Layer1        []float64              `protobuf:"fixed64,1,rep,packed,name=layer1,proto3" json:"layer1,omitempty"` // 18 activations
-	Layer2        []float64              `protobuf:"fixed64,2,rep,packed,name=layer2,proto3" json:"layer2,omitempty"` // 32
-	Layer3        []float64              `protobuf:"fixed64,3,rep,packed,name=layer3,proto3" json:"layer3,omitempty"` // 32
-	Layer4        []float64              `protobuf:"fixed64,4,rep,packed,name=layer4,proto3" json:"layer4,omitempty"` // 32
-	Layer5        []float64              `protobuf:"fixed64,5,rep,packed,name=layer5,proto3" json:"layer5,omitempty"` // 9
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Deprecated: Marked as deprecated in proto/trace.proto.
+	Layer1 []float64 `protobuf:"fixed64,1,rep,packed,name=layer1,proto3" json:"layer1,omitempty"` // Version 1: 18 activations
+	// Deprecated: Marked as deprecated in proto/trace.proto.
+	Layer2 []float64 `protobuf:"fixed64,2,rep,packed,name=layer2,proto3" json:"layer2,omitempty"` // Version 1: 32
+	// Deprecated: Marked as deprecated in proto/trace.proto.
+	Layer3 []float64 `protobuf:"fixed64,3,rep,packed,name=layer3,proto3" json:"layer3,omitempty"` // Version 1: 32
+	// Deprecated: Marked as deprecated in proto/trace.proto.
+	Layer4 []float64 `protobuf:"fixed64,4,rep,packed,name=layer4,proto3" json:"layer4,omitempty"` // Version 1: 32
+	// Deprecated: Marked as deprecated in proto/trace.proto.
+	Layer5        []float64 `protobuf:"fixed64,5,rep,packed,name=layer5,proto3" json:"layer5,omitempty"` // Version 1: 9
+	Version       uint32    `protobuf:"varint,6,opt,name=version,proto3" json:"version,omitempty"`       // Schema version, unset (0) for version 1 traces
+	Layers        []*Layer  `protobuf:"bytes,7,rep,name=layers,proto3" json:"layers,omitempty"`          // Input layer first, output layer last
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -63,6 +72,7 @@ func (*Trace) Descriptor() ([]byte, []int) {
 	return file_proto_trace_proto_rawDescGZIP(), []int{0}
 }
 
+// Deprecated: Marked as deprecated in proto/trace.proto.
 func (x *Trace) GetLayer1() []float64 {
 	if x != nil {
 		return x.Layer1
@@ -70,6 +80,7 @@ func (x *Trace) GetLayer1() []float64 {
 	return nil
 }
 
+// Deprecated: Marked as deprecated in proto/trace.proto.
 func (x *Trace) GetLayer2() []float64 {
 	if x != nil {
 		return x.Layer2
@@ -77,6 +88,7 @@ func (x *Trace) GetLayer2() []float64 {
 	return nil
 }
 
+// Deprecated: Marked as deprecated in proto/trace.proto.
 func (x *Trace) GetLayer3() []float64 {
 	if x != nil {
 		return x.Layer3
@@ -84,6 +96,7 @@ func (x *Trace) GetLayer3() []float64 {
 	return nil
 }
 
+// Deprecated: Marked as deprecated in proto/trace.proto.
 func (x *Trace) GetLayer4() []float64 {
 	if x != nil {
 		return x.Layer4
@@ -91,6 +104,7 @@ func (x *Trace) GetLayer4() []float64 {
 	return nil
 }
 
+// Deprecated: Marked as deprecated in proto/trace.proto.
 func (x *Trace) GetLayer5() []float64 {
 	if x != nil {
 		return x.Layer5
@@ -98,17 +112,109 @@ func (x *Trace) GetLayer5() []float64 {
 	return nil
 }
 
+func (x *Trace) GetVersion() uint32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *Trace) GetLayers() []*Layer {
+	if x != nil {
+		return x.Layers
+	}
+	return nil
+}
+
+// The values recorded for a single layer of the forward pass
+type Layer struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Name           string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`                                                    // e.g. "input", "hidden_1", "output"
+	Activation     string                 `protobuf:"bytes,2,opt,name=activation,proto3" json:"activation,omitempty"`                                        // Activation function applied to the layer, e.g. "relu"
+	Values         []float64              `protobuf:"fixed64,3,rep,packed,name=values,proto3" json:"values,omitempty"`                                       // Post-activation values
+	PreActivations []float64              `protobuf:"fixed64,4,rep,packed,name=pre_activations,json=preActivations,proto3" json:"pre_activations,omitempty"` // Pre-activation values, empty if not recorded
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Layer) Reset() {
+	*x = Layer{}
+	mi := &file_proto_trace_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Layer) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Layer) ProtoMessage() {}
+
+func (x *Layer) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_trace_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Layer.ProtoReflect.Descriptor instead.
+func (*Layer) Descriptor() ([]byte, []int) {
+	return file_proto_trace_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Layer) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Layer) GetActivation() string {
+	if x != nil {
+		return x.Activation
+	}
+	return ""
+}
+
+func (x *Layer) GetValues() []float64 {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+func (x *Layer) GetPreActivations() []float64 {
+	if x != nil {
+		return x.PreActivations
+	}
+	return nil
+}
+
 var File_proto_trace_proto protoreflect.FileDescriptor
 
 const file_proto_trace_proto_rawDesc = "" +
 	"\n" +
-	"\x11proto/trace.proto\x12\ttcubed.pb\"\x7f\n" +
-	"\x05Trace\x12\x16\n" +
-	"\x06layer1\x18\x01 \x03(\x01R\x06layer1\x12\x16\n" +
-	"\x06layer2\x18\x02 \x03(\x01R\x06layer2\x12\x16\n" +
-	"\x06layer3\x18\x03 \x03(\x01R\x06layer3\x12\x16\n" +
-	"\x06layer4\x18\x04 \x03(\x01R\x06layer4\x12\x16\n" +
-	"\x06layer5\x18\x05 \x03(\x01R\x06layer5B\x11Z\x0f/internal/pb;pbb\x06proto3"
+	"\x11proto/trace.proto\x12\ttcubed.pb\"\xd7\x01\n" +
+	"\x05Trace\x12\x1a\n" +
+	"\x06layer1\x18\x01 \x03(\x01B\x02\x18\x01R\x06layer1\x12\x1a\n" +
+	"\x06layer2\x18\x02 \x03(\x01B\x02\x18\x01R\x06layer2\x12\x1a\n" +
+	"\x06layer3\x18\x03 \x03(\x01B\x02\x18\x01R\x06layer3\x12\x1a\n" +
+	"\x06layer4\x18\x04 \x03(\x01B\x02\x18\x01R\x06layer4\x12\x1a\n" +
+	"\x06layer5\x18\x05 \x03(\x01B\x02\x18\x01R\x06layer5\x12\x18\n" +
+	"\aversion\x18\x06 \x01(\rR\aversion\x12(\n" +
+	"\x06layers\x18\a \x03(\v2\x10.tcubed.pb.LayerR\x06layers\"|\n" +
+	"\x05Layer\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1e\n" +
+	"\n" +
+	"activation\x18\x02 \x01(\tR\n" +
+	"activation\x12\x16\n" +
+	"\x06values\x18\x03 \x03(\x01R\x06values\x12'\n" +
+	"\x0fpre_activations\x18\x04 \x03(\x01R\x0epreActivationsB\x11Z\x0f/internal/pb;pbb\x06proto3"
 
 var (
 	file_proto_trace_proto_rawDescOnce sync.Once
@@ -122,16 +228,18 @@ func file_proto_trace_proto_rawDescGZIP() []byte {
 	return file_proto_trace_proto_rawDescData
 }
 
-var file_proto_trace_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_proto_trace_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
 var file_proto_trace_proto_goTypes = []any{
 	(*Trace)(nil), // 0: tcubed.pb.Trace
+	(*Layer)(nil), // 1: tcubed.pb.Layer
 }
 var file_proto_trace_proto_depIdxs = []int32{
-	0, // [0:0] is the sub-list for method output_type
-	0, // [0:0] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	1, // 0: tcubed.pb.Trace.layers:type_name -> tcubed.pb.Layer
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
 }
 
 func init() { file_proto_trace_proto_init() }
@@ -145,7 +253,7 @@ func file_proto_trace_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_trace_proto_rawDesc), len(file_proto_trace_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   1,
+			NumMessages:   2,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
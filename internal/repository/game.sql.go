@@ -12,17 +12,30 @@ import (
 )
 
 const createGame = `-- name: CreateGame :one
-INSERT INTO game (uuid, name, game_type_id, ai_player_id, player_1_piece, player_2_piece)
-VALUES (uuid_generate_v4(), $1, $2, $3, $4, $5)
-RETURNING uuid, created_at, updated_at, name, game_type_id, player_1_piece, player_2_piece, ai_player_id, terminal_state
+INSERT INTO game (
+    uuid, name, game_type_id, ai_player_id, player_1_piece, player_2_piece,
+    first_player_id, variant, model_id, time_control_seconds, time_increment_seconds,
+    creator_uuid, player_1_uuid, player_2_uuid, rated
+)
+VALUES (uuid_generate_v4(), $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+RETURNING uuid, created_at, updated_at, name, game_type_id, player_1_piece, player_2_piece, ai_player_id, terminal_state, first_player_id, variant, model_id, time_control_seconds, time_increment_seconds, creator_uuid, player_1_uuid, player_2_uuid, rated
 `
 
 type CreateGameParams struct {
-	Name         string
-	GameTypeID   int32
-	AiPlayerID   int16
-	Player1Piece string
-	Player2Piece string
+	Name                 string
+	GameTypeID           int32
+	AiPlayerID           int16
+	Player1Piece         string
+	Player2Piece         string
+	FirstPlayerID        int16
+	Variant              string
+	ModelID              string
+	TimeControlSeconds   int32
+	TimeIncrementSeconds int32
+	CreatorUuid          *uuid.UUID
+	Player1Uuid          *uuid.UUID
+	Player2Uuid          *uuid.UUID
+	Rated                bool
 }
 
 func (q *Queries) CreateGame(ctx context.Context, arg CreateGameParams) (Game, error) {
@@ -32,6 +45,15 @@ func (q *Queries) CreateGame(ctx context.Context, arg CreateGameParams) (Game, e
 		arg.AiPlayerID,
 		arg.Player1Piece,
 		arg.Player2Piece,
+		arg.FirstPlayerID,
+		arg.Variant,
+		arg.ModelID,
+		arg.TimeControlSeconds,
+		arg.TimeIncrementSeconds,
+		arg.CreatorUuid,
+		arg.Player1Uuid,
+		arg.Player2Uuid,
+		arg.Rated,
 	)
 	var i Game
 	err := row.Scan(
@@ -44,6 +66,15 @@ func (q *Queries) CreateGame(ctx context.Context, arg CreateGameParams) (Game, e
 		&i.Player2Piece,
 		&i.AiPlayerID,
 		&i.TerminalState,
+		&i.FirstPlayerID,
+		&i.Variant,
+		&i.ModelID,
+		&i.TimeControlSeconds,
+		&i.TimeIncrementSeconds,
+		&i.CreatorUuid,
+		&i.Player1Uuid,
+		&i.Player2Uuid,
+		&i.Rated,
 	)
 	return i, err
 }
@@ -59,7 +90,7 @@ func (q *Queries) DeleteGame(ctx context.Context, argUuid uuid.UUID) error {
 }
 
 const getGameByUUID = `-- name: GetGameByUUID :one
-SELECT g.uuid, g.created_at, g.updated_at, g.name, g.game_type_id, g.player_1_piece, g.player_2_piece, g.ai_player_id, g.terminal_state, me.uuid, me.game_uuid, me.trace_uuid, me.move_sequence, me.player_id, me.post_move_state, me.created_at, me.updated_at
+SELECT g.uuid, g.created_at, g.updated_at, g.name, g.game_type_id, g.player_1_piece, g.player_2_piece, g.ai_player_id, g.terminal_state, g.first_player_id, g.variant, g.model_id, g.time_control_seconds, g.time_increment_seconds, g.creator_uuid, g.player_1_uuid, g.player_2_uuid, g.rated, me.uuid, me.game_uuid, me.trace_uuid, me.move_sequence, me.player_id, me.post_move_state, me.created_at, me.updated_at, me.position, me.received_at, me.think_time_ms
 FROM game g
 LEFT JOIN move_event me
   ON me.uuid = (
@@ -90,6 +121,73 @@ func (q *Queries) GetGameByUUID(ctx context.Context, argUuid uuid.UUID) (GetGame
 		&i.Game.Player2Piece,
 		&i.Game.AiPlayerID,
 		&i.Game.TerminalState,
+		&i.Game.FirstPlayerID,
+		&i.Game.Variant,
+		&i.Game.ModelID,
+		&i.Game.TimeControlSeconds,
+		&i.Game.TimeIncrementSeconds,
+		&i.Game.CreatorUuid,
+		&i.Game.Player1Uuid,
+		&i.Game.Player2Uuid,
+		&i.Game.Rated,
+		&i.MoveEvent.Uuid,
+		&i.MoveEvent.GameUuid,
+		&i.MoveEvent.TraceUuid,
+		&i.MoveEvent.MoveSequence,
+		&i.MoveEvent.PlayerID,
+		&i.MoveEvent.PostMoveState,
+		&i.MoveEvent.CreatedAt,
+		&i.MoveEvent.UpdatedAt,
+		&i.MoveEvent.Position,
+		&i.MoveEvent.ReceivedAt,
+		&i.MoveEvent.ThinkTimeMs,
+	)
+	return i, err
+}
+
+const getGameByUUIDForUpdate = `-- name: GetGameByUUIDForUpdate :one
+SELECT g.uuid, g.created_at, g.updated_at, g.name, g.game_type_id, g.player_1_piece, g.player_2_piece, g.ai_player_id, g.terminal_state, g.first_player_id, g.variant, g.model_id, g.time_control_seconds, g.time_increment_seconds, g.creator_uuid, g.player_1_uuid, g.player_2_uuid, g.rated, me.uuid, me.game_uuid, me.trace_uuid, me.move_sequence, me.player_id, me.post_move_state, me.created_at, me.updated_at, me.position, me.received_at, me.think_time_ms
+FROM game g
+LEFT JOIN move_event me
+  ON me.uuid = (
+      SELECT uuid
+      FROM move_event
+      WHERE game_uuid = g.uuid
+      ORDER BY move_sequence DESC
+      LIMIT 1
+  )
+WHERE g.uuid = $1
+FOR UPDATE OF g
+`
+
+type GetGameByUUIDForUpdateRow struct {
+	Game      Game
+	MoveEvent MoveEvent
+}
+
+// Locks the game row until the end of the transaction, so moves on a game are played one at a time
+func (q *Queries) GetGameByUUIDForUpdate(ctx context.Context, argUuid uuid.UUID) (GetGameByUUIDForUpdateRow, error) {
+	row := q.db.QueryRow(ctx, getGameByUUIDForUpdate, argUuid)
+	var i GetGameByUUIDForUpdateRow
+	err := row.Scan(
+		&i.Game.Uuid,
+		&i.Game.CreatedAt,
+		&i.Game.UpdatedAt,
+		&i.Game.Name,
+		&i.Game.GameTypeID,
+		&i.Game.Player1Piece,
+		&i.Game.Player2Piece,
+		&i.Game.AiPlayerID,
+		&i.Game.TerminalState,
+		&i.Game.FirstPlayerID,
+		&i.Game.Variant,
+		&i.Game.ModelID,
+		&i.Game.TimeControlSeconds,
+		&i.Game.TimeIncrementSeconds,
+		&i.Game.CreatorUuid,
+		&i.Game.Player1Uuid,
+		&i.Game.Player2Uuid,
+		&i.Game.Rated,
 		&i.MoveEvent.Uuid,
 		&i.MoveEvent.GameUuid,
 		&i.MoveEvent.TraceUuid,
@@ -98,15 +196,60 @@ func (q *Queries) GetGameByUUID(ctx context.Context, argUuid uuid.UUID) (GetGame
 		&i.MoveEvent.PostMoveState,
 		&i.MoveEvent.CreatedAt,
 		&i.MoveEvent.UpdatedAt,
+		&i.MoveEvent.Position,
+		&i.MoveEvent.ReceivedAt,
+		&i.MoveEvent.ThinkTimeMs,
 	)
 	return i, err
 }
 
+const getGameSeats = `-- name: GetGameSeats :one
+SELECT player_1_uuid, player_2_uuid FROM game
+WHERE uuid = $1
+`
+
+type GetGameSeatsRow struct {
+	Player1Uuid *uuid.UUID
+	Player2Uuid *uuid.UUID
+}
+
+func (q *Queries) GetGameSeats(ctx context.Context, argUuid uuid.UUID) (GetGameSeatsRow, error) {
+	row := q.db.QueryRow(ctx, getGameSeats, argUuid)
+	var i GetGameSeatsRow
+	err := row.Scan(&i.Player1Uuid, &i.Player2Uuid)
+	return i, err
+}
+
+const listGameUUIDs = `-- name: ListGameUUIDs :many
+SELECT uuid FROM game
+ORDER BY created_at
+`
+
+func (q *Queries) ListGameUUIDs(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := q.db.Query(ctx, listGameUUIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var uuid uuid.UUID
+		if err := rows.Scan(&uuid); err != nil {
+			return nil, err
+		}
+		items = append(items, uuid)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateGame = `-- name: UpdateGame :one
 UPDATE game
 SET name = $1, terminal_state = $2
 WHERE uuid = $3
-RETURNING uuid, created_at, updated_at, name, game_type_id, player_1_piece, player_2_piece, ai_player_id, terminal_state
+RETURNING uuid, created_at, updated_at, name, game_type_id, player_1_piece, player_2_piece, ai_player_id, terminal_state, first_player_id, variant, model_id, time_control_seconds, time_increment_seconds, creator_uuid, player_1_uuid, player_2_uuid, rated
 `
 
 type UpdateGameParams struct {
@@ -128,6 +271,92 @@ func (q *Queries) UpdateGame(ctx context.Context, arg UpdateGameParams) (Game, e
 		&i.Player2Piece,
 		&i.AiPlayerID,
 		&i.TerminalState,
+		&i.FirstPlayerID,
+		&i.Variant,
+		&i.ModelID,
+		&i.TimeControlSeconds,
+		&i.TimeIncrementSeconds,
+		&i.CreatorUuid,
+		&i.Player1Uuid,
+		&i.Player2Uuid,
+		&i.Rated,
+	)
+	return i, err
+}
+
+const updateGameModel = `-- name: UpdateGameModel :one
+UPDATE game
+SET model_id = $1
+WHERE uuid = $2
+RETURNING uuid, created_at, updated_at, name, game_type_id, player_1_piece, player_2_piece, ai_player_id, terminal_state, first_player_id, variant, model_id, time_control_seconds, time_increment_seconds, creator_uuid, player_1_uuid, player_2_uuid, rated
+`
+
+type UpdateGameModelParams struct {
+	ModelID string
+	Uuid    uuid.UUID
+}
+
+func (q *Queries) UpdateGameModel(ctx context.Context, arg UpdateGameModelParams) (Game, error) {
+	row := q.db.QueryRow(ctx, updateGameModel, arg.ModelID, arg.Uuid)
+	var i Game
+	err := row.Scan(
+		&i.Uuid,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.GameTypeID,
+		&i.Player1Piece,
+		&i.Player2Piece,
+		&i.AiPlayerID,
+		&i.TerminalState,
+		&i.FirstPlayerID,
+		&i.Variant,
+		&i.ModelID,
+		&i.TimeControlSeconds,
+		&i.TimeIncrementSeconds,
+		&i.CreatorUuid,
+		&i.Player1Uuid,
+		&i.Player2Uuid,
+		&i.Rated,
+	)
+	return i, err
+}
+
+const updateGameSeats = `-- name: UpdateGameSeats :one
+UPDATE game
+SET player_1_uuid = $1, player_2_uuid = $2
+WHERE uuid = $3
+RETURNING uuid, created_at, updated_at, name, game_type_id, player_1_piece, player_2_piece, ai_player_id, terminal_state, first_player_id, variant, model_id, time_control_seconds, time_increment_seconds, creator_uuid, player_1_uuid, player_2_uuid, rated
+`
+
+type UpdateGameSeatsParams struct {
+	Player1Uuid *uuid.UUID
+	Player2Uuid *uuid.UUID
+	Uuid        uuid.UUID
+}
+
+func (q *Queries) UpdateGameSeats(ctx context.Context, arg UpdateGameSeatsParams) (Game, error) {
+	row := q.db.QueryRow(ctx, updateGameSeats, arg.Player1Uuid, arg.Player2Uuid, arg.Uuid)
+	var i Game
+	err := row.Scan(
+		&i.Uuid,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.GameTypeID,
+		&i.Player1Piece,
+		&i.Player2Piece,
+		&i.AiPlayerID,
+		&i.TerminalState,
+		&i.FirstPlayerID,
+		&i.Variant,
+		&i.ModelID,
+		&i.TimeControlSeconds,
+		&i.TimeIncrementSeconds,
+		&i.CreatorUuid,
+		&i.Player1Uuid,
+		&i.Player2Uuid,
+		&i.Rated,
 	)
 	return i, err
 }
@@ -8,18 +8,43 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type Account struct {
+	Uuid         uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Username     string
+	PasswordHash []byte
+}
+
+type AccountSession struct {
+	TokenHash   []byte
+	AccountUuid uuid.UUID
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
 type Game struct {
-	Uuid          uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	GameTypeID    int32
-	Player1Piece  string
-	Player2Piece  string
-	AiPlayerID    int16
-	TerminalState int16
+	Uuid                 uuid.UUID
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+	Name                 string
+	GameTypeID           int32
+	Player1Piece         string
+	Player2Piece         string
+	AiPlayerID           int16
+	TerminalState        int16
+	FirstPlayerID        int16
+	Variant              string
+	ModelID              string
+	TimeControlSeconds   int32
+	TimeIncrementSeconds int32
+	CreatorUuid          *uuid.UUID
+	Player1Uuid          *uuid.UUID
+	Player2Uuid          *uuid.UUID
+	Rated                bool
 }
 
 type GameType struct {
@@ -29,6 +54,18 @@ type GameType struct {
 	Label     string
 }
 
+type IdempotencyKey struct {
+	Key             string
+	Route           string
+	RequestHash     []byte
+	StatusCode      int16
+	ResponseHeaders []byte
+	ResponseBody    []byte
+	CreatedAt       time.Time
+	ExpiresAt       time.Time
+	LockedUntil     time.Time
+}
+
 type MoveEvent struct {
 	Uuid          uuid.UUID
 	GameUuid      uuid.UUID
@@ -38,6 +75,45 @@ type MoveEvent struct {
 	PostMoveState []byte
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
+	Position      pgtype.Int2
+	ReceivedAt    time.Time
+	ThinkTimeMs   pgtype.Int4
+}
+
+type Rating struct {
+	Uuid            uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	AccountUuid     *uuid.UUID
+	AiIdentity      pgtype.Text
+	Rating          float64
+	RatingDeviation float64
+	Volatility      float64
+	Games           int32
+	Wins            int32
+	Losses          int32
+	Draws           int32
+}
+
+type RatingHistory struct {
+	Uuid               uuid.UUID
+	RatingUuid         uuid.UUID
+	GameUuid           uuid.UUID
+	OpponentRatingUuid uuid.UUID
+	Score              float64
+	Rating             float64
+	RatingDeviation    float64
+	Volatility         float64
+	RatingChange       float64
+	CreatedAt          time.Time
+}
+
+type SquareUsage struct {
+	GameType   string
+	MoveNumber int16
+	Position   int16
+	AiMove     bool
+	Moves      int64
 }
 
 type TraceCache struct {
@@ -46,4 +122,6 @@ type TraceCache struct {
 	Trace                []byte
 	CreatedAt            time.Time
 	UpdatedAt            time.Time
+	SchemaVersion        int16
+	ModelID              string
 }
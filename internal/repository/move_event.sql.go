@@ -10,12 +10,13 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const createMoveEvent = `-- name: CreateMoveEvent :one
-INSERT INTO move_event (uuid, game_uuid, trace_uuid, move_sequence, player_id, post_move_state)
-VALUES (uuid_generate_v4(), $1, $2, $3, $4, $5)
-RETURNING uuid, game_uuid, trace_uuid, move_sequence, player_id, post_move_state, created_at, updated_at
+INSERT INTO move_event (uuid, game_uuid, trace_uuid, move_sequence, player_id, post_move_state, position, received_at, think_time_ms)
+VALUES (uuid_generate_v4(), $1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING uuid, game_uuid, trace_uuid, move_sequence, player_id, post_move_state, created_at, updated_at, position, received_at, think_time_ms
 `
 
 type CreateMoveEventParams struct {
@@ -24,6 +25,9 @@ type CreateMoveEventParams struct {
 	MoveSequence  int16
 	PlayerID      int16
 	PostMoveState []byte
+	Position      pgtype.Int2
+	ReceivedAt    time.Time
+	ThinkTimeMs   pgtype.Int4
 }
 
 func (q *Queries) CreateMoveEvent(ctx context.Context, arg CreateMoveEventParams) (MoveEvent, error) {
@@ -33,6 +37,9 @@ func (q *Queries) CreateMoveEvent(ctx context.Context, arg CreateMoveEventParams
 		arg.MoveSequence,
 		arg.PlayerID,
 		arg.PostMoveState,
+		arg.Position,
+		arg.ReceivedAt,
+		arg.ThinkTimeMs,
 	)
 	var i MoveEvent
 	err := row.Scan(
@@ -44,13 +51,35 @@ func (q *Queries) CreateMoveEvent(ctx context.Context, arg CreateMoveEventParams
 		&i.PostMoveState,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Position,
+		&i.ReceivedAt,
+		&i.ThinkTimeMs,
 	)
 	return i, err
 }
 
+const deleteMoveEventsAfter = `-- name: DeleteMoveEventsAfter :execrows
+DELETE FROM move_event
+WHERE game_uuid = $1 AND move_sequence > $2
+`
+
+type DeleteMoveEventsAfterParams struct {
+	GameUuid     uuid.UUID
+	MoveSequence int16
+}
+
+func (q *Queries) DeleteMoveEventsAfter(ctx context.Context, arg DeleteMoveEventsAfterParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteMoveEventsAfter, arg.GameUuid, arg.MoveSequence)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const listGameMoveEvents = `-- name: ListGameMoveEvents :many
-SELECT uuid, game_uuid, trace_uuid, move_sequence, player_id, post_move_state, created_at, updated_at FROM move_event
+SELECT uuid, game_uuid, trace_uuid, move_sequence, player_id, post_move_state, created_at, updated_at, position, received_at, think_time_ms FROM move_event
 WHERE game_uuid = $1
+ORDER BY move_sequence
 `
 
 func (q *Queries) ListGameMoveEvents(ctx context.Context, gameUuid uuid.UUID) ([]MoveEvent, error) {
@@ -71,6 +100,9 @@ func (q *Queries) ListGameMoveEvents(ctx context.Context, gameUuid uuid.UUID) ([
 			&i.PostMoveState,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Position,
+			&i.ReceivedAt,
+			&i.ThinkTimeMs,
 		); err != nil {
 			return nil, err
 		}
@@ -83,7 +115,7 @@ func (q *Queries) ListGameMoveEvents(ctx context.Context, gameUuid uuid.UUID) ([
 }
 
 const listGameMoveEventsWithTrace = `-- name: ListGameMoveEventsWithTrace :many
-SELECT move_event.uuid, game_uuid, trace_uuid, move_sequence, player_id, post_move_state, move_event.created_at, move_event.updated_at, trace_cache.uuid, pre_post_move_state_hash, trace, trace_cache.created_at, trace_cache.updated_at FROM move_event
+SELECT move_event.uuid, game_uuid, trace_uuid, move_sequence, player_id, post_move_state, move_event.created_at, move_event.updated_at, position, received_at, think_time_ms, trace_cache.uuid, pre_post_move_state_hash, trace, trace_cache.created_at, trace_cache.updated_at, schema_version, model_id FROM move_event
 LEFT JOIN trace_cache ON trace_cache.uuid = move_event.trace_uuid
 WHERE move_event.game_uuid = $1
 ORDER BY move_event.move_sequence
@@ -98,11 +130,16 @@ type ListGameMoveEventsWithTraceRow struct {
 	PostMoveState        []byte
 	CreatedAt            time.Time
 	UpdatedAt            time.Time
+	Position             pgtype.Int2
+	ReceivedAt           time.Time
+	ThinkTimeMs          pgtype.Int4
 	Uuid_2               *uuid.UUID
 	PrePostMoveStateHash []byte
 	Trace                []byte
 	CreatedAt_2          *time.Time
 	UpdatedAt_2          *time.Time
+	SchemaVersion        pgtype.Int2
+	ModelID              pgtype.Text
 }
 
 func (q *Queries) ListGameMoveEventsWithTrace(ctx context.Context, gameUuid uuid.UUID) ([]ListGameMoveEventsWithTraceRow, error) {
@@ -123,11 +160,16 @@ func (q *Queries) ListGameMoveEventsWithTrace(ctx context.Context, gameUuid uuid
 			&i.PostMoveState,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Position,
+			&i.ReceivedAt,
+			&i.ThinkTimeMs,
 			&i.Uuid_2,
 			&i.PrePostMoveStateHash,
 			&i.Trace,
 			&i.CreatedAt_2,
 			&i.UpdatedAt_2,
+			&i.SchemaVersion,
+			&i.ModelID,
 		); err != nil {
 			return nil, err
 		}
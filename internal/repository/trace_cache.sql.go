@@ -12,18 +12,25 @@ import (
 )
 
 const createTraceCache = `-- name: CreateTraceCache :one
-INSERT INTO trace_cache (uuid, pre_post_move_state_hash, trace)
-VALUES (uuid_generate_v4(), $1, $2)
-RETURNING uuid, pre_post_move_state_hash, trace, created_at, updated_at
+INSERT INTO trace_cache (uuid, pre_post_move_state_hash, trace, schema_version, model_id)
+VALUES (uuid_generate_v4(), $1, $2, $3, $4)
+RETURNING uuid, pre_post_move_state_hash, trace, created_at, updated_at, schema_version, model_id
 `
 
 type CreateTraceCacheParams struct {
 	PrePostMoveStateHash []byte
 	Trace                []byte
+	SchemaVersion        int16
+	ModelID              string
 }
 
 func (q *Queries) CreateTraceCache(ctx context.Context, arg CreateTraceCacheParams) (TraceCache, error) {
-	row := q.db.QueryRow(ctx, createTraceCache, arg.PrePostMoveStateHash, arg.Trace)
+	row := q.db.QueryRow(ctx, createTraceCache,
+		arg.PrePostMoveStateHash,
+		arg.Trace,
+		arg.SchemaVersion,
+		arg.ModelID,
+	)
 	var i TraceCache
 	err := row.Scan(
 		&i.Uuid,
@@ -31,12 +38,14 @@ func (q *Queries) CreateTraceCache(ctx context.Context, arg CreateTraceCachePara
 		&i.Trace,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.SchemaVersion,
+		&i.ModelID,
 	)
 	return i, err
 }
 
 const getTraceCache = `-- name: GetTraceCache :one
-SELECT uuid, pre_post_move_state_hash, trace, created_at, updated_at FROM trace_cache
+SELECT uuid, pre_post_move_state_hash, trace, created_at, updated_at, schema_version, model_id FROM trace_cache
 WHERE uuid = $1
 `
 
@@ -49,12 +58,14 @@ func (q *Queries) GetTraceCache(ctx context.Context, argUuid uuid.UUID) (TraceCa
 		&i.Trace,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.SchemaVersion,
+		&i.ModelID,
 	)
 	return i, err
 }
 
 const getTraceCacheByHash = `-- name: GetTraceCacheByHash :one
-SELECT uuid, pre_post_move_state_hash, trace, created_at, updated_at FROM trace_cache
+SELECT uuid, pre_post_move_state_hash, trace, created_at, updated_at, schema_version, model_id FROM trace_cache
 WHERE pre_post_move_state_hash = $1
 `
 
@@ -67,12 +78,14 @@ func (q *Queries) GetTraceCacheByHash(ctx context.Context, prePostMoveStateHash
 		&i.Trace,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.SchemaVersion,
+		&i.ModelID,
 	)
 	return i, err
 }
 
 const getTraceCaches = `-- name: GetTraceCaches :many
-SELECT uuid, pre_post_move_state_hash, trace, created_at, updated_at FROM trace_cache
+SELECT uuid, pre_post_move_state_hash, trace, created_at, updated_at, schema_version, model_id FROM trace_cache
 `
 
 func (q *Queries) GetTraceCaches(ctx context.Context) ([]TraceCache, error) {
@@ -90,6 +103,42 @@ func (q *Queries) GetTraceCaches(ctx context.Context) ([]TraceCache, error) {
 			&i.Trace,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.SchemaVersion,
+			&i.ModelID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTraceCachesBelowSchemaVersion = `-- name: ListTraceCachesBelowSchemaVersion :many
+SELECT uuid, pre_post_move_state_hash, trace, created_at, updated_at, schema_version, model_id FROM trace_cache
+WHERE schema_version < $1
+ORDER BY created_at
+`
+
+func (q *Queries) ListTraceCachesBelowSchemaVersion(ctx context.Context, schemaVersion int16) ([]TraceCache, error) {
+	rows, err := q.db.Query(ctx, listTraceCachesBelowSchemaVersion, schemaVersion)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TraceCache
+	for rows.Next() {
+		var i TraceCache
+		if err := rows.Scan(
+			&i.Uuid,
+			&i.PrePostMoveStateHash,
+			&i.Trace,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.SchemaVersion,
+			&i.ModelID,
 		); err != nil {
 			return nil, err
 		}
@@ -100,3 +149,31 @@ func (q *Queries) GetTraceCaches(ctx context.Context) ([]TraceCache, error) {
 	}
 	return items, nil
 }
+
+const updateTraceCache = `-- name: UpdateTraceCache :one
+UPDATE trace_cache
+SET trace = $1, schema_version = $2
+WHERE uuid = $3
+RETURNING uuid, pre_post_move_state_hash, trace, created_at, updated_at, schema_version, model_id
+`
+
+type UpdateTraceCacheParams struct {
+	Trace         []byte
+	SchemaVersion int16
+	Uuid          uuid.UUID
+}
+
+func (q *Queries) UpdateTraceCache(ctx context.Context, arg UpdateTraceCacheParams) (TraceCache, error) {
+	row := q.db.QueryRow(ctx, updateTraceCache, arg.Trace, arg.SchemaVersion, arg.Uuid)
+	var i TraceCache
+	err := row.Scan(
+		&i.Uuid,
+		&i.PrePostMoveStateHash,
+		&i.Trace,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SchemaVersion,
+		&i.ModelID,
+	)
+	return i, err
+}
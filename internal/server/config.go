@@ -6,6 +6,9 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
+
+	"t-cubed/internal/service"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -18,6 +21,11 @@ type Config struct {
 	PORT string
 	CORS_ORIGINS []string
 	GIN_MODE string
+	ADMIN_TOKEN string
+	NN_WEIGHTS_WATCH_INTERVAL time.Duration
+	IDEMPOTENCY_KEY_TTL time.Duration
+	SESSION_TTL time.Duration
+	STATISTICS_REFRESH_INTERVAL time.Duration
 	DB *pgxpool.Pool
 }
 
@@ -51,6 +59,50 @@ func newConfig(port int) *Config {
 	} else {
 		slog.Info("Found GIN_MODE environment variable.", "value", GIN_MODE)
 	}
+	ADMIN_TOKEN := os.Getenv("ADMIN_TOKEN")
+	if ADMIN_TOKEN == "" {
+		slog.Warn("No ADMIN_TOKEN environment variable found. Admin routes are disabled.")
+	}
+	NN_WEIGHTS_WATCH_INTERVAL := time.Duration(0)
+	tmpWatchInterval := os.Getenv("NN_WEIGHTS_WATCH_INTERVAL")
+	if tmpWatchInterval != "" {
+		NN_WEIGHTS_WATCH_INTERVAL, err = time.ParseDuration(tmpWatchInterval)
+		if err != nil || NN_WEIGHTS_WATCH_INTERVAL <= 0 {
+			slog.Error("Invalid NN_WEIGHTS_WATCH_INTERVAL environment variable. Exiting...", "value", tmpWatchInterval)
+			panic(1)
+		}
+		slog.Info("Found NN_WEIGHTS_WATCH_INTERVAL environment variable.", "value", tmpWatchInterval)
+	}
+	IDEMPOTENCY_KEY_TTL := service.IDEMPOTENCY_KEY_TTL
+	tmpIdempotencyKeyTTL := os.Getenv("IDEMPOTENCY_KEY_TTL")
+	if tmpIdempotencyKeyTTL != "" {
+		IDEMPOTENCY_KEY_TTL, err = time.ParseDuration(tmpIdempotencyKeyTTL)
+		if err != nil || IDEMPOTENCY_KEY_TTL <= 0 {
+			slog.Error("Invalid IDEMPOTENCY_KEY_TTL environment variable. Exiting...", "value", tmpIdempotencyKeyTTL)
+			panic(1)
+		}
+		slog.Info("Found IDEMPOTENCY_KEY_TTL environment variable.", "value", tmpIdempotencyKeyTTL)
+	}
+	SESSION_TTL := service.SESSION_TTL
+	tmpSessionTTL := os.Getenv("SESSION_TTL")
+	if tmpSessionTTL != "" {
+		SESSION_TTL, err = time.ParseDuration(tmpSessionTTL)
+		if err != nil || SESSION_TTL <= 0 {
+			slog.Error("Invalid SESSION_TTL environment variable. Exiting...", "value", tmpSessionTTL)
+			panic(1)
+		}
+		slog.Info("Found SESSION_TTL environment variable.", "value", tmpSessionTTL)
+	}
+	STATISTICS_REFRESH_INTERVAL := service.STATISTICS_REFRESH_INTERVAL
+	tmpStatisticsRefreshInterval := os.Getenv("STATISTICS_REFRESH_INTERVAL")
+	if tmpStatisticsRefreshInterval != "" {
+		STATISTICS_REFRESH_INTERVAL, err = time.ParseDuration(tmpStatisticsRefreshInterval)
+		if err != nil || STATISTICS_REFRESH_INTERVAL <= 0 {
+			slog.Error("Invalid STATISTICS_REFRESH_INTERVAL environment variable. Exiting...", "value", tmpStatisticsRefreshInterval)
+			panic(1)
+		}
+		slog.Info("Found STATISTICS_REFRESH_INTERVAL environment variable.", "value", tmpStatisticsRefreshInterval)
+	}
 	DATABASE_URL := os.Getenv("DATABASE_URL")
 	if DATABASE_URL == "" {
 		slog.Error("No DATABASE_URL environment variable found. Exiting...")
@@ -82,6 +134,11 @@ func newConfig(port int) *Config {
 		PORT: PORT,
 		CORS_ORIGINS: CORS_ORIGINS,
 		GIN_MODE: GIN_MODE,
+		ADMIN_TOKEN: ADMIN_TOKEN,
+		NN_WEIGHTS_WATCH_INTERVAL: NN_WEIGHTS_WATCH_INTERVAL,
+		IDEMPOTENCY_KEY_TTL: IDEMPOTENCY_KEY_TTL,
+		SESSION_TTL: SESSION_TTL,
+		STATISTICS_REFRESH_INTERVAL: STATISTICS_REFRESH_INTERVAL,
 		DB: pool,
 	}
 }
@@ -1,11 +1,13 @@
 package server
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"os"
 	"t-cubed/internal/handler"
 	"t-cubed/internal/middleware"
+	"t-cubed/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,7 +16,8 @@ const (
 	INDEX_HTML = "./static/index.html"
 )
 
-func newRouter(config *Config) *gin.Engine {
+// The context controls background work started for the router, such as watching the weights file
+func newRouter(ctx context.Context, config *Config) *gin.Engine {
 	// Set up server and routes
 	gin.SetMode(config.GIN_MODE)
 	gin.DefaultWriter = io.MultiWriter(os.Stdout, config.routerLogFile)
@@ -23,13 +26,29 @@ func newRouter(config *Config) *gin.Engine {
 	engine.SetTrustedProxies(nil)
 	engine.TrustedPlatform = gin.PlatformFlyIO
 
-	handler := handler.NewHandler(config.DB)
-	applyRoutes(config, engine, handler)
+	gameService := service.NewGameService(config.DB)
+	if config.NN_WEIGHTS_WATCH_INTERVAL > 0 {
+		go gameService.WatchWeights(ctx, config.NN_WEIGHTS_WATCH_INTERVAL)
+	}
+
+	idempotencyService := service.NewIdempotencyService(config.DB, config.IDEMPOTENCY_KEY_TTL)
+	go idempotencyService.CleanupExpired(ctx, service.IDEMPOTENCY_KEY_CLEANUP_INTERVAL)
+
+	accountService := service.NewAccountService(config.DB, config.SESSION_TTL)
+	go accountService.CleanupExpired(ctx, service.SESSION_CLEANUP_INTERVAL)
+
+	ratingService := service.NewRatingService(config.DB)
+
+	statisticsService := service.NewStatisticsService(config.DB)
+	go statisticsService.RefreshPeriodically(ctx, config.STATISTICS_REFRESH_INTERVAL)
+
+	handler := handler.NewHandler(gameService, accountService, ratingService, statisticsService)
+	applyRoutes(config, engine, handler, gameService, idempotencyService, accountService)
 
 	return engine
 }
 
-func applyRoutes(config *Config, engine *gin.Engine, handler *handler.Handler) {
+func applyRoutes(config *Config, engine *gin.Engine, handler *handler.Handler, gameService *service.GameService, idempotencyService *service.IdempotencyService, accountService *service.AccountService) {
 	// Middleware for all routes
 	engine.Use(
 		middleware.NewRequestID(),
@@ -67,12 +86,35 @@ func applyRoutes(config *Config, engine *gin.Engine, handler *handler.Handler) {
 
 	// API
 	{
-		apiV1 := engine.Group("/api/v1")
+		// Requests with a session token are logged in, others are anonymous
+		apiV1 := engine.Group("/api/v1", middleware.NewAuth(accountService))
+		idempotency := middleware.NewIdempotency(idempotencyService)
+		requireAccount := middleware.NewRequireAccount()
+		participant := middleware.NewGameParticipant(gameService)
+		apiV1.POST("/auth/register", handler.Register)
+		apiV1.POST("/auth/login", handler.Login)
+		apiV1.POST("/auth/logout", requireAccount, handler.Logout)
+		apiV1.GET("/auth/me", requireAccount, handler.GetAccount)
 		apiV1.GET("/data/nn/weights", handler.GetWeights)
-		apiV1.POST("/game", handler.CreateGame)
+		apiV1.POST("/game", idempotency, handler.CreateGame)
 		apiV1.GET("/game/:uuid", handler.GetGame)
-		apiV1.POST("/game/:uuid/nn", handler.PlayNNMove)
-		apiV1.POST("/game/:uuid/mm", handler.PlayMMMove)
+		apiV1.POST("/game/:uuid/join", requireAccount, handler.JoinGame)
+		apiV1.POST("/game/:uuid/nn", participant, idempotency, handler.PlayNNMove)
+		apiV1.POST("/game/:uuid/mm", participant, idempotency, handler.PlayMMMove)
 		apiV1.GET("/game/:uuid/history", handler.GetMoveHistory)
+		apiV1.GET("/game/:uuid/export", handler.ExportGame)
+		apiV1.POST("/game/import", idempotency, handler.ImportGame)
+		apiV1.GET("/ratings", handler.GetLeaderboard)
+		apiV1.GET("/ratings/:uuid/history", handler.GetRatingHistory)
+		apiV1.GET("/statistics/outcomes", handler.GetGameOutcomes)
+		apiV1.GET("/statistics/human-vs-ai", handler.GetHumanResultsAgainstAI)
+		apiV1.GET("/statistics/openings", handler.GetOpeningSquares)
+		apiV1.GET("/statistics/heatmap", handler.GetSquareHeatmap)
+	}
+
+	// Admin API
+	{
+		adminV1 := engine.Group("/api/v1/admin", middleware.NewAdminAuth(config.ADMIN_TOKEN))
+		adminV1.POST("/nn/reload", handler.ReloadWeights)
 	}
 }
@@ -15,7 +15,10 @@ func RunServer(port int) {
 	config := newConfig(port)
 	defer config.Cleanup()
 
-	router := newRouter(config)
+	routerCtx, cancelRouter := context.WithCancel(context.Background())
+	defer cancelRouter()
+
+	router := newRouter(routerCtx, config)
 	srv := &http.Server{
 		Addr:    ":" + config.PORT,
 		Handler: router,
@@ -25,7 +28,7 @@ func RunServer(port int) {
 	go func() {
 		slog.Info("Starting server...")
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			slog.Error("listen: %s\n", err)
+			slog.Error("listen", "error", err)
 		}
 	}()
 
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"t-cubed/internal/repository"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	SESSION_TTL              = 30 * 24 * time.Hour
+	SESSION_CLEANUP_INTERVAL = time.Hour
+	SESSION_TOKEN_BYTES      = 32
+	USERNAME_MIN_LENGTH      = 3
+	USERNAME_MAX_LENGTH      = 32
+	PASSWORD_MIN_LENGTH      = 8
+	PASSWORD_MAX_LENGTH      = 72 // bcrypt only uses the first 72 bytes
+)
+
+var (
+	ErrInvalidUsername    = errors.New("usernames must be 3 to 32 letters, digits, '_' or '-'")
+	ErrInvalidPassword    = errors.New("passwords must be 8 to 72 bytes long")
+	ErrUsernameTaken      = errors.New("username is already taken")
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrInvalidSession     = errors.New("invalid or expired session")
+)
+
+type Account = repository.Account
+
+// Registers accounts and logs them in with session tokens. Only the SHA256 of a token is stored, so tokens cannot be
+// recovered from the database.
+type AccountService struct {
+	repo *repository.Queries
+	ttl  time.Duration
+}
+
+// A logged in account and its bearer token
+type Session struct {
+	Account   *Account
+	Token     string
+	ExpiresAt time.Time
+}
+
+func NewAccountService(db *pgxpool.Pool, ttl time.Duration) *AccountService {
+	return &AccountService{
+		repo: repository.New(db),
+		ttl:  ttl,
+	}
+}
+
+func isValidUsername(username string) bool {
+	if len(username) < USERNAME_MIN_LENGTH || len(username) > USERNAME_MAX_LENGTH {
+		return false
+	}
+	for _, c := range username {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+func isValidPassword(password string) bool {
+	return utf8.RuneCountInString(password) >= PASSWORD_MIN_LENGTH && len(password) <= PASSWORD_MAX_LENGTH
+}
+
+// Hash compared against when a username does not exist, so logins take as long whether or not it does
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("not a password"), bcrypt.DefaultCost)
+	return hash
+})
+
+func hashSessionToken(token string) []byte {
+	hash := sha256.Sum256([]byte(token))
+	return hash[:]
+}
+
+// Creates an account and logs it in
+func (s *AccountService) Register(ctx context.Context, username string, password string) (*Session, error) {
+	if !isValidUsername(username) {
+		return nil, ErrInvalidUsername
+	}
+	if !isValidPassword(password) {
+		return nil, ErrInvalidPassword
+	}
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		slog.Error("Could not hash password", "error", err)
+		return nil, err
+	}
+
+	account, err := s.repo.CreateAccount(ctx, repository.CreateAccountParams{
+		Username:     username,
+		PasswordHash: passwordHash,
+	})
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return nil, ErrUsernameTaken
+	}
+	if err != nil {
+		slog.Error("Could not create account", "error", err)
+		return nil, err
+	}
+	return s.newSession(ctx, &account)
+}
+
+// Checks an account's password and starts a session
+func (s *AccountService) Login(ctx context.Context, username string, password string) (*Session, error) {
+	account, err := s.repo.GetAccountByUsername(ctx, username)
+	if errors.Is(err, pgx.ErrNoRows) {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		slog.Error("Could not get account", "error", err)
+		return nil, err
+	}
+	if bcrypt.CompareHashAndPassword(account.PasswordHash, []byte(password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return s.newSession(ctx, &account)
+}
+
+func (s *AccountService) newSession(ctx context.Context, account *Account) (*Session, error) {
+	tokenBytes := make([]byte, SESSION_TOKEN_BYTES)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(tokenBytes)
+
+	session, err := s.repo.CreateAccountSession(ctx, repository.CreateAccountSessionParams{
+		TokenHash:   hashSessionToken(token),
+		AccountUuid: account.Uuid,
+		ExpiresAt:   time.Now().Add(s.ttl),
+	})
+	if err != nil {
+		slog.Error("Could not create session", "error", err)
+		return nil, err
+	}
+	return &Session{Account: account, Token: token, ExpiresAt: session.ExpiresAt}, nil
+}
+
+// Returns the account logged in with a session token
+func (s *AccountService) Authenticate(ctx context.Context, token string) (*Account, error) {
+	row, err := s.repo.GetAccountBySessionToken(ctx, hashSessionToken(token))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInvalidSession
+	}
+	if err != nil {
+		slog.Error("Could not get session", "error", err)
+		return nil, err
+	}
+	return &row.Account, nil
+}
+
+// Ends the session of a token
+func (s *AccountService) Logout(ctx context.Context, token string) error {
+	err := s.repo.DeleteAccountSession(ctx, hashSessionToken(token))
+	if err != nil {
+		slog.Error("Could not delete session", "error", err)
+	}
+	return err
+}
+
+// Deletes expired sessions every interval until ctx is done
+func (s *AccountService) CleanupExpired(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := s.repo.DeleteExpiredAccountSessions(ctx)
+			if err != nil {
+				slog.Warn("Could not delete expired sessions", "error", err)
+				continue
+			}
+			if deleted > 0 {
+				slog.Info("Deleted expired sessions", "count", deleted)
+			}
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Registers an account with a unique username that is deleted when the test ends
+func createTestAccount(t *testing.T, s *AccountService) *Session {
+	t.Helper()
+	ctx := context.Background()
+	username := "test-" + strings.ReplaceAll(uuid.NewString(), "-", "")[:16]
+	session, err := s.Register(ctx, username, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := s.repo.DeleteAccount(ctx, session.Account.Uuid); err != nil {
+			t.Error(err)
+		}
+	})
+	return session
+}
+
+func TestAccountService_RejectsInvalidUsernamesAndPasswords(t *testing.T) {
+	s := &AccountService{}
+	usernames := []string{"", "ab", strings.Repeat("a", USERNAME_MAX_LENGTH+1), "with space", "émile"}
+	for _, username := range usernames {
+		if _, err := s.Register(context.Background(), username, "correct horse"); !errors.Is(err, ErrInvalidUsername) {
+			t.Errorf("Expected username %q to be rejected, got %v", username, err)
+		}
+	}
+	passwords := []string{"", "short", strings.Repeat("a", PASSWORD_MAX_LENGTH+1)}
+	for _, password := range passwords {
+		if _, err := s.Register(context.Background(), "player_1", password); !errors.Is(err, ErrInvalidPassword) {
+			t.Errorf("Expected password %q to be rejected, got %v", password, err)
+		}
+	}
+}
+
+func TestAccountService_RegisterLoginLogout(t *testing.T) {
+	s := NewAccountService(newTestPool(t), time.Hour)
+	ctx := context.Background()
+	registered := createTestAccount(t, s)
+	username := registered.Account.Username
+
+	if _, err := s.Register(ctx, strings.ToUpper(username), "another password"); !errors.Is(err, ErrUsernameTaken) {
+		t.Errorf("Expected ErrUsernameTaken for a username that differs in case, got %v", err)
+	}
+	if _, err := s.Login(ctx, username, "wrong password"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials for a wrong password, got %v", err)
+	}
+	if _, err := s.Login(ctx, username+"-missing", "correct horse"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials for a missing account, got %v", err)
+	}
+
+	session, err := s.Login(ctx, strings.ToUpper(username), "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.Token == registered.Token {
+		t.Error("Expected each login to get a new session token")
+	}
+	account, err := s.Authenticate(ctx, session.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Uuid != registered.Account.Uuid {
+		t.Errorf("Expected the session to belong to %s, got %s", registered.Account.Uuid, account.Uuid)
+	}
+
+	if err := s.Logout(ctx, session.Token); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Authenticate(ctx, session.Token); !errors.Is(err, ErrInvalidSession) {
+		t.Errorf("Expected ErrInvalidSession after logging out, got %v", err)
+	}
+	if _, err := s.Authenticate(ctx, registered.Token); err != nil {
+		t.Errorf("Expected other sessions to stay logged in, got %v", err)
+	}
+}
+
+func TestAccountService_ExpiredSession(t *testing.T) {
+	s := NewAccountService(newTestPool(t), time.Millisecond)
+	session := createTestAccount(t, s)
+	time.Sleep(10 * time.Millisecond)
+	if _, err := s.Authenticate(context.Background(), session.Token); !errors.Is(err, ErrInvalidSession) {
+		t.Errorf("Expected ErrInvalidSession for an expired session, got %v", err)
+	}
+}
+
+func TestGameSeats_PlayerIDs(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	seats := &GameSeats{Player1: &a, Player2: &a}
+	if got := seats.PlayerIDs(a); !reflect.DeepEqual(got, []int16{1, 2}) {
+		t.Errorf("Expected both seats, got %v", got)
+	}
+	if got := seats.PlayerIDs(b); len(got) != 0 {
+		t.Errorf("Expected no seats, got %v", got)
+	}
+	if (&GameSeats{}).Owned() {
+		t.Error("Expected a game without seat owners to be open")
+	}
+}
+
+func TestCreateAndJoinGame_SeatsAccounts(t *testing.T) {
+	s := newTestGameService(t)
+	accounts := NewAccountService(s.db, time.Hour)
+	ctx := context.Background()
+	creator := createTestAccount(t, accounts).Account
+	opponent := createTestAccount(t, accounts).Account
+	other := createTestAccount(t, accounts).Account
+
+	// The creator takes the human's seat against the AI
+	config := GameConfig{Player1Piece: "X", Player2Piece: "O", AIPlayerID: 1, CreatorUUID: &creator.Uuid}
+	aiGame := createTestGameWithConfig(t, s, GAME_TYPE_MINIMAX, config)
+	if aiGame.Player1Uuid != nil || aiGame.Player2Uuid == nil || *aiGame.Player2Uuid != creator.Uuid {
+		t.Errorf("Expected the creator to be seated as Player 2, got %v and %v", aiGame.Player1Uuid, aiGame.Player2Uuid)
+	}
+	if _, _, _, err := s.JoinGame(ctx, aiGame.Uuid, other.Uuid); !errors.Is(err, ErrNoFreeSeat) {
+		t.Errorf("Expected ErrNoFreeSeat in a game against the AI, got %v", err)
+	}
+
+	config = GameConfig{Player1Piece: "X", Player2Piece: "O", CreatorUUID: &creator.Uuid}
+	humansGame := createTestGameWithConfig(t, s, GAME_TYPE_HUMANS, config)
+	game, _, playerID, err := s.JoinGame(ctx, humansGame.Uuid, opponent.Uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if playerID != 2 || game.Player2Uuid == nil || *game.Player2Uuid != opponent.Uuid {
+		t.Errorf("Expected the opponent to be seated as Player 2, got player %d", playerID)
+	}
+	if _, _, playerID, err := s.JoinGame(ctx, humansGame.Uuid, creator.Uuid); err != nil || playerID != 1 {
+		t.Errorf("Expected the creator to keep Player 1's seat, got player %d, %v", playerID, err)
+	}
+	if _, _, _, err := s.JoinGame(ctx, humansGame.Uuid, other.Uuid); !errors.Is(err, ErrNoFreeSeat) {
+		t.Errorf("Expected ErrNoFreeSeat in a full game, got %v", err)
+	}
+
+	openGame := createTestGame(t, s, GAME_TYPE_HUMANS)
+	if _, _, _, err := s.JoinGame(ctx, openGame.Uuid, other.Uuid); !errors.Is(err, ErrOpenGame) {
+		t.Errorf("Expected ErrOpenGame for a game created without an account, got %v", err)
+	}
+	seats, err := s.GetGameSeats(ctx, openGame.Uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seats.Owned() {
+		t.Error("Expected a game created without an account to be open")
+	}
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"t-cubed/internal/engine"
+	"t-cubed/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Game record tags written by the service, besides the ones the engine reads
+const (
+	TAG_NAME         = "Name"
+	TAG_DATE         = "Date"
+	TAG_GAME_TYPE    = "GameType"
+	TAG_PLAYER_1     = "Player1"
+	TAG_PLAYER_2     = "Player2"
+	TAG_AI_PLAYER    = "AIPlayer"
+	TAG_MODEL        = "Model"       // Informational, imported neural network games are played with the current model
+	TAG_TIME_CONTROL = "TimeControl" // Seconds per player and increment, e.g. "300+5", or "-" for untimed games
+)
+
+const RECORD_DATE_FORMAT = "2006.01.02"
+
+// Returned for game records that cannot be imported
+var ErrInvalidGameRecord = errors.New("invalid game record")
+
+// Returns the name of a player in a game record
+func recordPlayerName(gameTypeLabel string, aiPlayerID int16, playerID int16) string {
+	if playerID != aiPlayerID {
+		return "Human"
+	}
+	switch gameTypeLabel {
+	case GAME_TYPE_NN:
+		return "Neural network"
+	case GAME_TYPE_MINIMAX:
+		return "Minimax"
+	default:
+		return "AI"
+	}
+}
+
+// Writes a game and its moves in the game record notation
+func (s *GameService) ExportGame(ctx context.Context, uuid uuid.UUID) (string, error) {
+	// The moves are the ones replayed when the game was verified, from the same snapshot as the game
+	game, _, verification, err := s.getVerifiedGame(ctx, uuid)
+	if err != nil {
+		return "", err
+	}
+
+	gameTypeLabel := s.GetGameTypeLabel(game.GameTypeID)
+	timeControl := "-"
+	if game.TimeControlSeconds > 0 {
+		timeControl = fmt.Sprintf("%d+%d", game.TimeControlSeconds, game.TimeIncrementSeconds)
+	}
+	record := &engine.GameRecord{
+		Tags: []engine.RecordTag{
+			{Name: TAG_NAME, Value: game.Name},
+			{Name: TAG_DATE, Value: game.CreatedAt.UTC().Format(RECORD_DATE_FORMAT)},
+			{Name: TAG_GAME_TYPE, Value: gameTypeLabel},
+			{Name: TAG_PLAYER_1, Value: recordPlayerName(gameTypeLabel, game.AiPlayerID, 1)},
+			{Name: TAG_PLAYER_2, Value: recordPlayerName(gameTypeLabel, game.AiPlayerID, 2)},
+			{Name: engine.TAG_PLAYER_1_PIECE, Value: game.Player1Piece},
+			{Name: engine.TAG_PLAYER_2_PIECE, Value: game.Player2Piece},
+			{Name: engine.TAG_FIRST_PLAYER, Value: strconv.Itoa(int(game.FirstPlayerID))},
+			{Name: TAG_AI_PLAYER, Value: strconv.Itoa(int(game.AiPlayerID))},
+			{Name: engine.TAG_VARIANT, Value: game.Variant},
+			{Name: TAG_MODEL, Value: game.ModelID},
+			{Name: TAG_TIME_CONTROL, Value: timeControl},
+		},
+		Result: engine.ResultForTerminalState(uint8(game.TerminalState)),
+	}
+	record.SetTag(engine.TAG_RESULT, record.Result)
+
+	for _, move := range verification.Replay.Moves {
+		record.Moves = append(record.Moves, move.Position)
+	}
+	return engine.FormatGameRecord(record)
+}
+
+// Creates a game with the moves of a game record, after replaying them with the engine.
+// If the game is in progress and waiting for the AI, the AI moves. Errors in the record wrap ErrInvalidGameRecord.
+// creatorUUID is the account importing the game, nil for anonymous games.
+func (s *GameService) ImportGame(ctx context.Context, text string, creatorUUID *uuid.UUID) (*Game, *MoveEvent, error) {
+	receivedAt := time.Now()
+	record, err := engine.ParseGameRecord(text)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidGameRecord, err)
+	}
+	boards, err := record.Boards()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidGameRecord, err)
+	}
+	options, _ := record.Options()
+
+	config := GameConfig{
+		Player1Piece:  string(options.Player1Piece),
+		Player2Piece:  string(options.Player2Piece),
+		FirstPlayerID: int16(options.FirstPlayerId),
+		Variant:       options.Variant,
+		CreatorUUID:   creatorUUID,
+	}
+	if aiPlayer := record.Tag(TAG_AI_PLAYER); aiPlayer != "" {
+		aiPlayerID, err := strconv.ParseInt(aiPlayer, 10, 16)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: invalid AI player", ErrInvalidGameRecord)
+		}
+		config.AIPlayerID = int16(aiPlayerID)
+	}
+	if timeControl := record.Tag(TAG_TIME_CONTROL); timeControl != "" && timeControl != "-" {
+		seconds, increment, _ := strings.Cut(timeControl, "+")
+		parsedSeconds, err := strconv.ParseInt(seconds, 10, 32)
+		parsedIncrement, incrementErr := parseOptionalInt32(increment)
+		if err != nil || incrementErr != nil {
+			return nil, nil, fmt.Errorf("%w: invalid time control", ErrInvalidGameRecord)
+		}
+		config.TimeControlSeconds = int32(parsedSeconds)
+		config.TimeIncrementSeconds = parsedIncrement
+	}
+	gameTypeLabel := record.Tag(TAG_GAME_TYPE)
+	// The record's model tag cannot be trusted, and the game continues with the current model anyway
+	modelID := ""
+	if gameTypeLabel == GAME_TYPE_NN {
+		modelID = s.currentModel().checksum
+	}
+	createGameParams, err := s.newCreateGameParams(record.Tag(TAG_NAME), gameTypeLabel, config, modelID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidGameRecord, err)
+	}
+	// Imported games were not played on this server, and could be imported a move away from a win, so they are
+	// never rated, even when they are finished here
+	createGameParams.Rated = false
+
+	var game Game
+	var move MoveEvent
+	var traceHash []byte
+	err = inTx(ctx, s.db, func(repo *repository.Queries) error {
+		var err error
+		game, err = repo.CreateGame(ctx, createGameParams)
+		if err != nil {
+			slog.Error("Could not create game", "error", err)
+			return err
+		}
+
+		// The blank board is saved for the player opposite the first player, then the players alternate
+		playerID := getNextPlayerID(game.FirstPlayerID)
+		for i, board := range boards {
+			createMoveEventParams := repository.CreateMoveEventParams{
+				GameUuid:      game.Uuid,
+				MoveSequence:  int16(i),
+				PlayerID:      playerID,
+				PostMoveState: board,
+				ReceivedAt:    receivedAt,
+			}
+			if i > 0 {
+				createMoveEventParams.Position = pgtype.Int2{Int16: int16(record.Moves[i-1]), Valid: true}
+			}
+			move, err = repo.CreateMoveEvent(ctx, createMoveEventParams)
+			if err != nil {
+				slog.Error("Could not create move event", "uuid", game.Uuid, "error", err)
+				return err
+			}
+			playerID = getNextPlayerID(playerID)
+		}
+
+		gameState, err := restoreGameState(&game, &move)
+		if err != nil {
+			return err
+		}
+		game, err = repo.UpdateGame(ctx, repository.UpdateGameParams{
+			Name:          game.Name,
+			TerminalState: int16(gameState.TerminalState),
+			Uuid:          game.Uuid,
+		})
+		if err != nil {
+			slog.Warn("Failed to write updated game state to database", "uuid", game.Uuid, "error", err)
+			return err
+		}
+
+		traceHash, err = s.playAIMoveIfDue(ctx, repo, gameTypeLabel, &game, &move)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if traceHash != nil && move.TraceUuid != nil {
+		s.cacheTrace(traceHash, *move.TraceUuid)
+	}
+	return &game, &move, nil
+}
+
+// Parses an optional 32-bit integer, which is 0 if it is empty
+func parseOptionalInt32(value string) (int32, error) {
+	if value == "" {
+		return 0, nil
+	}
+	parsed, err := strconv.ParseInt(value, 10, 32)
+	return int32(parsed), err
+}
@@ -7,17 +7,22 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
-	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"t-cubed/internal/ai"
 	"t-cubed/internal/engine"
-	"t-cubed/internal/pb"
 	"t-cubed/internal/repository"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"google.golang.org/protobuf/proto"
 )
 
 const (
@@ -26,12 +31,30 @@ const (
 	GAME_TYPE_HUMANS  = "humans"
 )
 
+const (
+	TX_MAX_ATTEMPTS = 3
+	TX_RETRY_DELAY  = 20 * time.Millisecond // Multiplied by the attempt number
+)
+
+// Postgres errors after which a transaction can succeed if it is run again
+var RETRYABLE_PG_ERROR_CODES = map[string]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+	"23505": true, // unique_violation, e.g. two games adding the same trace
+}
+
 type GameService struct {
+	db                   *pgxpool.Pool
 	repo                 *repository.Queries
-	neuralNet            *ai.Network
-	weights              json.RawMessage
+	weightsFile          string
+	model                atomic.Pointer[nnModel]
+	models               map[string]*nnModel  // Checksum -> recently used models, so games keep their model
+	modelsByUse          []string             // Checksums of models, least recently used first
+	modelsMu             sync.Mutex           // Guards models and modelsByUse
+	reloadMu             sync.Mutex           // Serializes reloads of the neural network
 	cachedGameTypesMap   map[string]int32     // Label -> ID
-	cachedTraceHachesMap map[string]uuid.UUID // Hash of pre+post game state  -> UUID
+	cachedTraceHachesMap map[string]uuid.UUID // Hash of model checksum+trace level+pre+post game state -> UUID
+	traceHashesMu        sync.RWMutex         // Guards cachedTraceHachesMap, which concurrent moves read and add to
 }
 
 type Game = repository.Game
@@ -39,20 +62,59 @@ type MoveEvent = repository.MoveEvent
 type GameType = repository.GameType
 type NNMoveTrace = ai.ForwardTrace
 
-type MoveEventWithTrace struct {
-	MoveEvent *MoveEvent
-	Trace     *NNMoveTrace
+var (
+	ErrGameNotFound = errors.New("game not found")
+	ErrNoFreeSeat   = errors.New("game has no free seat")
+	ErrOpenGame     = errors.New("game was created without an account and is open to anyone")
+)
+
+// Returned when a move was sent for a move sequence the game has already moved past
+type StaleGameError struct {
+	ExpectedMoveSequence int16
+	Game                 *Game
+	MoveEvent            *MoveEvent // The game's current last move
 }
 
-func NewGameService(db *pgxpool.Pool) *GameService {
-	neuralNetWeightsFile := "data/weights.json"
-	weightBytes, err := os.ReadFile(neuralNetWeightsFile)
-	if err != nil {
-		slog.Error("Could not read neural network weights file", "error", err)
-		panic(1)
+func (e *StaleGameError) Error() string {
+	return fmt.Sprintf("game is at move %d, not the expected move %d", e.MoveEvent.MoveSequence, e.ExpectedMoveSequence)
+}
+
+// Returned when a move cannot be played on the game's current state, e.g. because another move was played first
+type MoveConflictError struct {
+	Reason    string
+	Game      *Game
+	MoveEvent *MoveEvent // The game's current last move
+}
+
+func (e *MoveConflictError) Error() string {
+	return e.Reason
+}
+
+// Returns a *MoveConflictError with the game's current state if err is a unique violation that was still raised when
+// the move's transaction ran out of attempts, i.e. other moves on the game kept winning the race. Otherwise returns err.
+func (s *GameService) moveConflict(ctx context.Context, uuid uuid.UUID, err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "23505" {
+		return err
+	}
+	game, moveEvent, getErr := s.GetGame(ctx, uuid)
+	if getErr != nil {
+		return err
 	}
-	weights := json.RawMessage(weightBytes)
+	return &MoveConflictError{Reason: "another move was played on the game at the same time", Game: game, MoveEvent: moveEvent}
+}
+
+type MoveEventWithTrace struct {
+	MoveEvent          *MoveEvent
+	Trace              *NNMoveTrace
+	Attribution        *ai.Attribution
+	AttributionSkipped string // Why the move was not attributed although attribution was requested, see ATTRIBUTION_SKIPPED_*
+}
+
+// The model that made the move is not loaded, or was not recorded for older traces, so the move cannot be attributed
+const ATTRIBUTION_SKIPPED_MODEL_NOT_LOADED = "model_not_loaded"
 
+func NewGameService(db *pgxpool.Pool) *GameService {
 	repo := repository.New(db)
 
 	ctx := context.Background()
@@ -64,24 +126,75 @@ func NewGameService(db *pgxpool.Pool) *GameService {
 	}
 	slog.Info("Games types cached", "game_types", cachedGameTypesMap)
 
-	neuralNet, err := ai.LoadNetwork(neuralNetWeightsFile)
+	model, err := loadNNModel(NN_WEIGHTS_FILE)
 	if err != nil {
 		slog.Error("Could not load neural network", "error", err)
 		panic(1)
 	}
-	slog.Info("Loaded neural network", "weights_file", neuralNetWeightsFile)
+	slog.Info("Loaded neural network", "weights_file", NN_WEIGHTS_FILE, "checksum", model.checksum)
 
-	return &GameService{
+	s := &GameService{
+		db:                   db,
 		repo:                 repo,
-		neuralNet:            neuralNet,
-		weights:              weights,
+		weightsFile:          NN_WEIGHTS_FILE,
 		cachedGameTypesMap:   cachedGameTypesMap,
 		cachedTraceHachesMap: nil,
 	}
+	s.serveModel(model)
+	return s
+}
+
+// Returns the raw weights of the served neural network and an ETag that changes with the model
+func (s *GameService) GetWeights() (json.RawMessage, string) {
+	model := s.currentModel()
+	return model.weights, model.etag
+}
+
+// Runs fn in a transaction, committing if it returns nil and rolling back otherwise.
+// The transaction is run again, up to TX_MAX_ATTEMPTS times, if it failed on a conflict with another transaction.
+func inTx(ctx context.Context, db *pgxpool.Pool, fn func(repo *repository.Queries) error) error {
+	var err error
+	for attempt := 1; attempt <= TX_MAX_ATTEMPTS; attempt++ {
+		err = runTx(ctx, db, pgx.TxOptions{}, fn)
+		if err == nil || !isRetryable(err) || attempt == TX_MAX_ATTEMPTS {
+			break
+		}
+		slog.Warn("Retrying transaction", "attempt", attempt, "error", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * TX_RETRY_DELAY):
+		}
+	}
+	return err
+}
+
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && RETRYABLE_PG_ERROR_CODES[pgErr.Code]
+}
+
+// Runs fn in a read-only REPEATABLE READ transaction, so all of its reads see the same snapshot of the database
+func inSnapshot(ctx context.Context, db *pgxpool.Pool, fn func(repo *repository.Queries) error) error {
+	return runTx(ctx, db, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}, fn)
 }
 
-func (s *GameService) GetWeights() json.RawMessage {
-	return s.weights
+func runTx(ctx context.Context, db *pgxpool.Pool, txOptions pgx.TxOptions, fn func(repo *repository.Queries) error) error {
+	tx, err := db.BeginTx(ctx, txOptions)
+	if err != nil {
+		slog.Error("Could not begin transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback(ctx) // No-op once committed
+
+	if err := fn(repository.New(tx)); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		slog.Error("Could not commit transaction", "error", err)
+		return err
+	}
+	return nil
 }
 
 // Returns a map of the game type labels to their IDs for caching
@@ -103,60 +216,73 @@ func (s *GameService) GetGameTypeLabel(gameTypeID int32) string {
 	return "unknown"
 }
 
-// Singleton pattern: Returns a map of the trace hashes to their UUIDs for caching
-func (s *GameService) getTraceHashesMap(ctx context.Context) map[string]uuid.UUID {
-	if s.cachedTraceHachesMap != nil {
-		return s.cachedTraceHachesMap
+// Singleton pattern: Loads the map of the trace hashes to their UUIDs for caching, if it is not loaded yet
+func (s *GameService) loadTraceHashesMap(ctx context.Context) {
+	s.traceHashesMu.RLock()
+	loaded := s.cachedTraceHachesMap != nil
+	s.traceHashesMu.RUnlock()
+	if loaded {
+		return
 	}
 
-	traceHashesMap := make(map[string]uuid.UUID)
 	traces, err := s.repo.GetTraceCaches(ctx)
 	if err != nil {
 		slog.Error("Could not get trace caches", "error", err)
-		return traceHashesMap
+		return
 	}
+	traceHashesMap := make(map[string]uuid.UUID)
 	for _, trace := range traces {
 		traceHashesMap[hex.EncodeToString(trace.PrePostMoveStateHash)] = trace.Uuid
 	}
-	s.cachedTraceHachesMap = traceHashesMap
-	slog.Info("Loaded trace hashes cache", "size", len(traceHashesMap))
-	return traceHashesMap
+
+	s.traceHashesMu.Lock()
+	defer s.traceHashesMu.Unlock()
+	// Another request may have loaded it in the meantime
+	if s.cachedTraceHachesMap == nil {
+		s.cachedTraceHachesMap = traceHashesMap
+		slog.Info("Loaded trace hashes cache", "size", len(traceHashesMap))
+	}
 }
 
-func getCombinedStatesHash(preMoveState []byte, postMoveState []byte) []byte {
-	combinedStates := append(preMoveState, postMoveState...)
-	hash := sha256.Sum256(combinedStates)
-	return hash[:]
+// Returns the key a trace is cached under. The same move by different models, or recorded at different levels,
+// has different traces.
+func getCombinedStatesHash(modelID string, level ai.TraceLevel, preMoveState []byte, postMoveState []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(modelID))
+	h.Write([]byte{byte(level)})
+	h.Write(preMoveState)
+	h.Write(postMoveState)
+	return h.Sum(nil)
 }
 
 // Returns the UUID of the trace (if it exists) for the given pre-post move state hash
 func (s *GameService) GetTraceUUID(ctx context.Context, prePostMoveStateHash []byte) (*uuid.UUID, error) {
-	traceHashesMap := s.getTraceHashesMap(ctx)
-	uuid, ok := traceHashesMap[hex.EncodeToString(prePostMoveStateHash)]
+	s.loadTraceHashesMap(ctx)
+	s.traceHashesMu.RLock()
+	uuid, ok := s.cachedTraceHachesMap[hex.EncodeToString(prePostMoveStateHash)]
+	s.traceHashesMu.RUnlock()
 	if !ok {
 		return nil, errors.New("trace not found")
 	}
 	return &uuid, nil
 }
 
-// Adds a trace to the database (if needed), and returns the UUID of the trace
-func (s *GameService) AddTrace(ctx context.Context, preMoveState []byte, postMoveState []byte, trace *ai.ForwardTrace) (*uuid.UUID, error) {
-	combinedStatesHash := getCombinedStatesHash(preMoveState, postMoveState)
+// Adds a trace of a move by the model with checksum modelID to the database (if needed), and returns the UUID of the trace.
+// New traces are not cached until cacheTrace is called, so a rolled back trace is never reused.
+func (s *GameService) AddTrace(ctx context.Context, repo *repository.Queries, modelID string, preMoveState []byte, postMoveState []byte, trace *ai.ForwardTrace) (*uuid.UUID, error) {
+	combinedStatesHash := getCombinedStatesHash(modelID, trace.Level, preMoveState, postMoveState)
 	// Check if the trace already exists, and if so, return the UUID
 	traceUuid, err := s.GetTraceUUID(ctx, combinedStatesHash)
 	if err == nil {
 		slog.Warn("Trace already exists", "hash", hex.EncodeToString(combinedStatesHash))
 		return traceUuid, nil
 	}
-
-	traceProto := &pb.Trace{
-		Layer1: trace.LayerOutputs[0],
-		Layer2: trace.LayerOutputs[1],
-		Layer3: trace.LayerOutputs[2],
-		Layer4: trace.LayerOutputs[3],
-		Layer5: trace.LayerOutputs[4],
+	// Another request may have added it since the cache was loaded
+	if traceCache, err := repo.GetTraceCacheByHash(ctx, combinedStatesHash); err == nil {
+		return &traceCache.Uuid, nil
 	}
-	traceBytes, err := proto.Marshal(traceProto)
+
+	traceBytes, err := marshalTrace(trace)
 	if err != nil {
 		slog.Error("Could not marshal trace proto", "error", err)
 		return nil, err
@@ -165,85 +291,349 @@ func (s *GameService) AddTrace(ctx context.Context, preMoveState []byte, postMov
 	createTraceParams := repository.CreateTraceCacheParams{
 		PrePostMoveStateHash: combinedStatesHash,
 		Trace:                traceBytes,
+		SchemaVersion:        TRACE_SCHEMA_VERSION,
+		ModelID:              modelID,
 	}
 
-	traceCache, err := s.repo.CreateTraceCache(ctx, createTraceParams)
+	traceCache, err := repo.CreateTraceCache(ctx, createTraceParams)
 	if err != nil {
 		slog.Error("Could not create trace cache", "error", err)
 		return nil, err
 	}
 
-	s.cachedTraceHachesMap[hex.EncodeToString(combinedStatesHash)] = traceCache.Uuid
-
 	return &traceCache.Uuid, nil
 }
 
-func (s *GameService) CreateGame(ctx context.Context, name string, gameTypeLabel string, player1Piece string, player2Piece string) (*Game, *MoveEvent, error) {
-	if !isValidGamePice(player1Piece) {
-		return nil, nil, errors.New("invalid player 1 piece")
+// Caches the UUID of a committed trace
+func (s *GameService) cacheTrace(prePostMoveStateHash []byte, traceUuid uuid.UUID) {
+	s.traceHashesMu.Lock()
+	defer s.traceHashesMu.Unlock()
+	if s.cachedTraceHachesMap != nil {
+		s.cachedTraceHachesMap[hex.EncodeToString(prePostMoveStateHash)] = traceUuid
+	}
+}
+
+// Settings chosen when a game is created. They are saved with the game, and every move rebuilds the game from them.
+type GameConfig struct {
+	Player1Piece         string
+	Player2Piece         string
+	FirstPlayerID        int16 // Defaults to Player 1
+	AIPlayerID           int16 // Defaults to Player 2, and must be 0 in games between humans
+	Variant              string
+	TimeControlSeconds   int32      // Clock for each player, 0 for untimed games
+	TimeIncrementSeconds int32      // Added to a player's clock after each of their moves
+	CreatorUUID          *uuid.UUID // Account creating the game, nil for anonymous games
+}
+
+// Checks a config for a game of the given type and fills in its defaults.
+// Returns the parameters to create the game with.
+func (s *GameService) newCreateGameParams(name string, gameTypeLabel string, config GameConfig, modelID string) (repository.CreateGameParams, error) {
+	if !isValidGamePice(config.Player1Piece) {
+		return repository.CreateGameParams{}, errors.New("invalid player 1 piece")
 	}
-	if !isValidGamePice(player2Piece) {
-		return nil, nil, errors.New("invalid player 2 piece")
+	if !isValidGamePice(config.Player2Piece) {
+		return repository.CreateGameParams{}, errors.New("invalid player 2 piece")
 	}
-	if player1Piece == player2Piece {
-		return nil, nil, errors.New("player 1 and player 2 cannot be the same piece")
+	if config.Player1Piece == config.Player2Piece {
+		return repository.CreateGameParams{}, errors.New("player 1 and player 2 cannot be the same piece")
 	}
 	gameTypeID, ok := s.cachedGameTypesMap[gameTypeLabel]
 	if !ok {
-		return nil, nil, errors.New("invalid game type")
+		return repository.CreateGameParams{}, errors.New("invalid game type")
+	}
+
+	if config.FirstPlayerID == 0 {
+		config.FirstPlayerID = 1
+	}
+	if !isValidPlayerID(config.FirstPlayerID) {
+		return repository.CreateGameParams{}, errors.New("invalid first player ID")
+	}
+	if gameTypeLabel == GAME_TYPE_HUMANS {
+		if config.AIPlayerID != 0 {
+			return repository.CreateGameParams{}, errors.New("games between humans cannot have an AI player")
+		}
+	} else {
+		if config.AIPlayerID == 0 {
+			config.AIPlayerID = 2
+		}
+		if !isValidPlayerID(config.AIPlayerID) {
+			return repository.CreateGameParams{}, errors.New("invalid AI player ID")
+		}
+	}
+	if config.Variant == "" {
+		config.Variant = engine.VARIANT_STANDARD
+	}
+	if config.Variant != engine.VARIANT_STANDARD {
+		return repository.CreateGameParams{}, errors.New("unsupported variant")
+	}
+	if config.TimeControlSeconds < 0 || config.TimeIncrementSeconds < 0 {
+		return repository.CreateGameParams{}, errors.New("time control cannot be negative")
 	}
+	if config.TimeControlSeconds == 0 && config.TimeIncrementSeconds != 0 {
+		return repository.CreateGameParams{}, errors.New("time increment requires a time control")
+	}
+
+	// The creator takes the human's seat in games against the AI, and Player 1's seat in games between humans
+	var player1UUID, player2UUID *uuid.UUID
+	if config.CreatorUUID != nil {
+		if config.AIPlayerID == 1 {
+			player2UUID = config.CreatorUUID
+		} else {
+			player1UUID = config.CreatorUUID
+		}
+	}
+
+	return repository.CreateGameParams{
+		Name:                 name,
+		GameTypeID:           gameTypeID,
+		AiPlayerID:           config.AIPlayerID,
+		Player1Piece:         config.Player1Piece,
+		Player2Piece:         config.Player2Piece,
+		FirstPlayerID:        config.FirstPlayerID,
+		Variant:              config.Variant,
+		ModelID:              modelID,
+		TimeControlSeconds:   config.TimeControlSeconds,
+		TimeIncrementSeconds: config.TimeIncrementSeconds,
+		CreatorUuid:          config.CreatorUUID,
+		Player1Uuid:          player1UUID,
+		Player2Uuid:          player2UUID,
+		Rated:                true,
+	}, nil
+}
+
+func (s *GameService) CreateGame(ctx context.Context, name string, gameTypeLabel string, config GameConfig) (*Game, *MoveEvent, error) {
+	receivedAt := time.Now()
 
-	createGameParams := repository.CreateGameParams{
-		Name:         name,
-		GameTypeID:   gameTypeID,
-		AiPlayerID:   2,
-		Player1Piece: player1Piece,
-		Player2Piece: player2Piece,
+	// NN games remember the model they were started with, and play every AI move with it
+	modelID := ""
+	if gameTypeLabel == GAME_TYPE_NN {
+		modelID = s.currentModel().checksum
 	}
 
-	game, err := s.repo.CreateGame(ctx, createGameParams)
+	createGameParams, err := s.newCreateGameParams(name, gameTypeLabel, config, modelID)
 	if err != nil {
-		slog.Error("Could not create game", "error", err)
 		return nil, nil, err
 	}
 
-	// Create a blank move event to prepare the game for the first move
-	initialMoveEventparams := repository.CreateMoveEventParams{
-		GameUuid:      game.Uuid,
-		MoveSequence:  0,
-		PlayerID:      2, // Must be opposite of the first player's ID so that first player will be next
-		PostMoveState: bytes.Repeat([]byte{0}, 4),
-	}
+	var game Game
+	var move MoveEvent
+	var traceHash []byte
+	err = inTx(ctx, s.db, func(repo *repository.Queries) error {
+		var err error
+		game, err = repo.CreateGame(ctx, createGameParams)
+		if err != nil {
+			slog.Error("Could not create game", "error", err)
+			return err
+		}
+
+		// Create a blank move event to prepare the game for the first move
+		initialMoveEventparams := repository.CreateMoveEventParams{
+			GameUuid:      game.Uuid,
+			MoveSequence:  0,
+			PlayerID:      getNextPlayerID(game.FirstPlayerID), // Opposite of the first player's ID so that first player will be next
+			PostMoveState: bytes.Repeat([]byte{0}, 4),
+			ReceivedAt:    receivedAt,
+		}
+
+		move, err = repo.CreateMoveEvent(ctx, initialMoveEventparams)
+		if err != nil {
+			slog.Error("Could not create first move event", "error", err)
+			return err
+		}
 
-	move, err := s.repo.CreateMoveEvent(ctx, initialMoveEventparams)
+		// The AI opens the game if it moves first
+		traceHash, err = s.playAIMoveIfDue(ctx, repo, gameTypeLabel, &game, &move)
+		return err
+	})
 	if err != nil {
-		slog.Error("Could not create first move event", "error", err)
 		return nil, nil, err
 	}
+	if traceHash != nil && move.TraceUuid != nil {
+		s.cacheTrace(traceHash, *move.TraceUuid)
+	}
 
 	return &game, &move, nil
 }
 
+// Plays the AI's move after moveEvent if the game is in progress and waiting for the AI, with repo's transaction.
+// game and moveEvent are updated in place. Also returns the hash of the neural network move's trace, if any.
+func (s *GameService) playAIMoveIfDue(ctx context.Context, repo *repository.Queries, gameTypeLabel string, game *Game, moveEvent *MoveEvent) ([]byte, error) {
+	if game.AiPlayerID == 0 {
+		return nil, nil
+	}
+	gameState, err := restoreGameState(game, moveEvent)
+	if err != nil {
+		slog.Error("Could not create game state", "uuid", game.Uuid, "error", err)
+		return nil, err
+	}
+	if gameState.IsTerminal() || int16(gameState.GetCurrentPlayerId()) != game.AiPlayerID {
+		return nil, nil
+	}
+
+	switch gameTypeLabel {
+	case GAME_TYPE_NN:
+		model, err := s.gameModel(ctx, repo, game)
+		if err != nil {
+			return nil, err
+		}
+		_, traceHash, err := s.playNNReply(ctx, repo, model, game, gameState, moveEvent, ai.ATTRIBUTION_NONE, ai.TRACE_LEVEL_OUTPUTS)
+		return traceHash, err
+	case GAME_TYPE_MINIMAX:
+		return nil, s.playMMReply(ctx, repo, game, gameState, moveEvent)
+	}
+	return nil, nil
+}
+
 func isValidGamePice(piece string) bool {
 	return piece == string(engine.PIECE_O) || piece == string(engine.PIECE_X)
 }
 
+// Returns the engine options for the game's config
+func gameStateOptions(game *Game) *engine.GameStateOptions {
+	return &engine.GameStateOptions{
+		Player1Piece:  pieceToByte(game.Player1Piece),
+		Player2Piece:  pieceToByte(game.Player2Piece),
+		FirstPlayerId: uint8(game.FirstPlayerID),
+		Variant:       game.Variant,
+	}
+}
+
+// Rebuilds the engine's game state from the game's config and the board after its last move
+func restoreGameState(game *Game, lastMoveEvent *MoveEvent) (*engine.GameState, error) {
+	return engine.NewGameStateFromBytes(gameStateOptions(game), lastMoveEvent.PostMoveState)
+}
+
+// Returns a game and its last move. The game's moves are replayed first, and a *CorruptGameError is returned
+// if they do not add up to its board and terminal state.
 func (s *GameService) GetGame(ctx context.Context, uuid uuid.UUID) (*Game, *MoveEvent, error) {
-	gameData, err := s.repo.GetGameByUUID(ctx, uuid)
+	game, moveEvent, _, err := s.getVerifiedGame(ctx, uuid)
+	return game, moveEvent, err
+}
+
+// Returns a game, its last move event and the verification of its move events
+func (s *GameService) getVerifiedGame(ctx context.Context, uuid uuid.UUID) (*Game, *MoveEvent, *GameVerification, error) {
+	// The game and its move events are read from one snapshot, so a move saved in between is not seen as corruption
+	var game *Game
+	var moveEvent *MoveEvent
+	var verification *GameVerification
+	err := inSnapshot(ctx, s.db, func(repo *repository.Queries) error {
+		gameData, err := repo.GetGameByUUID(ctx, uuid)
+		if err != nil {
+			slog.Error("Could not get game", "error", err)
+			return err
+		}
+		game = &gameData.Game
+		moveEvent = &gameData.MoveEvent
+
+		verification, err = verifyGame(ctx, repo, game)
+		return err
+	})
 	if err != nil {
-		slog.Error("Could not get game", "error", err)
-		return nil, nil, err
+		return nil, nil, nil, err
+	}
+	if !verification.OK() {
+		slog.Error("Game failed verification", "uuid", game.Uuid, "problems", verification.Problems)
+		return nil, nil, nil, &CorruptGameError{Verification: verification}
 	}
-	game := &gameData.Game
-	moveEvent := &gameData.MoveEvent
 
-	return game, moveEvent, nil
+	return game, moveEvent, verification, nil
+}
+
+// Accounts seated as each player of a game. Seats without an owner are nil.
+type GameSeats struct {
+	Player1 *uuid.UUID
+	Player2 *uuid.UUID
+}
+
+// Games created without an account have no owners, and anyone can move in them
+func (g *GameSeats) Owned() bool {
+	return g.Player1 != nil || g.Player2 != nil
+}
+
+// Returns the IDs of the players the account is seated as
+func (g *GameSeats) PlayerIDs(accountUUID uuid.UUID) []int16 {
+	var playerIDs []int16
+	if g.Player1 != nil && *g.Player1 == accountUUID {
+		playerIDs = append(playerIDs, 1)
+	}
+	if g.Player2 != nil && *g.Player2 == accountUUID {
+		playerIDs = append(playerIDs, 2)
+	}
+	return playerIDs
+}
+
+func (s *GameService) GetGameSeats(ctx context.Context, uuid uuid.UUID) (*GameSeats, error) {
+	seats, err := s.repo.GetGameSeats(ctx, uuid)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrGameNotFound
+	}
+	if err != nil {
+		slog.Error("Could not get game seats", "uuid", uuid, "error", err)
+		return nil, err
+	}
+	return &GameSeats{Player1: seats.Player1Uuid, Player2: seats.Player2Uuid}, nil
+}
+
+// Seats an account as the free human player of a game created by another account.
+// Returns the game, its last move and the account's player ID. Joining a game the account is seated in is a no-op.
+func (s *GameService) JoinGame(ctx context.Context, uuid uuid.UUID, accountUUID uuid.UUID) (*Game, *MoveEvent, int16, error) {
+	var game *Game
+	var moveEvent *MoveEvent
+	var playerID int16
+	err := inTx(ctx, s.db, func(repo *repository.Queries) error {
+		gameData, err := repo.GetGameByUUIDForUpdate(ctx, uuid)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrGameNotFound
+		}
+		if err != nil {
+			slog.Error("Could not get game from DB", "error", err)
+			return err
+		}
+		game = &gameData.Game
+		moveEvent = &gameData.MoveEvent
+
+		seats := &GameSeats{Player1: game.Player1Uuid, Player2: game.Player2Uuid}
+		if playerIDs := seats.PlayerIDs(accountUUID); len(playerIDs) > 0 {
+			playerID = playerIDs[0]
+			return nil
+		}
+		if !seats.Owned() {
+			return ErrOpenGame
+		}
+		switch {
+		case game.AiPlayerID != 1 && seats.Player1 == nil:
+			playerID, seats.Player1 = 1, &accountUUID
+		case game.AiPlayerID != 2 && seats.Player2 == nil:
+			playerID, seats.Player2 = 2, &accountUUID
+		default:
+			return ErrNoFreeSeat
+		}
+
+		updatedGame, err := repo.UpdateGameSeats(ctx, repository.UpdateGameSeatsParams{
+			Player1Uuid: seats.Player1,
+			Player2Uuid: seats.Player2,
+			Uuid:        game.Uuid,
+		})
+		if err != nil {
+			slog.Error("Could not update game seats", "uuid", game.Uuid, "error", err)
+			return err
+		}
+		game = &updatedGame
+		return nil
+	})
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return game, moveEvent, playerID, nil
 }
 
 type NNMoveResult struct {
 	Game        *Game            `json:"game"`
+	MoveEvent   *MoveEvent       `json:"-"`
 	Trace       *ai.ForwardTrace `json:"trace"`
 	RankedMoves []int            `json:"ranked_moves"`
+	Attribution *ai.Attribution  `json:"attribution"`
+	Value       *float64         `json:"value"` // Value head's expected outcome for the AI before its move, nil without a value head
 }
 
 func getNextPlayerID(playerID int16) int16 {
@@ -254,103 +644,164 @@ func getNextPlayerID(playerID int16) int16 {
 }
 
 // Plays a Neural Network move
-func (s *GameService) PlayNNMove(ctx context.Context, uuid uuid.UUID, playerID int16, position uint8) (*NNMoveResult, *MoveEvent, error) {
-	gameData, err := s.repo.GetGameByUUID(ctx, uuid)
+// If attribution is not ATTRIBUTION_NONE, the AI's move is attributed to the board's input features.
+// traceLevel chooses how much of the AI's forward pass is recorded in its trace.
+// The human's move and the AI's reply are saved in one transaction, with the game row locked.
+// If expectedMoveSequence is not nil and the game's last move is a different one, a *StaleGameError is returned.
+func (s *GameService) PlayNNMove(ctx context.Context, uuid uuid.UUID, playerID int16, position uint8, attribution ai.AttributionMethod, traceLevel ai.TraceLevel, expectedMoveSequence *int16) (*NNMoveResult, *MoveEvent, error) {
+	receivedAt := time.Now()
+
+	var result *NNMoveResult
+	var moveEvent *MoveEvent
+	var traceHash []byte
+	err := inTx(ctx, s.db, func(repo *repository.Queries) error {
+		var err error
+		result, moveEvent, traceHash, err = s.playNNMove(ctx, repo, uuid, playerID, position, attribution, traceLevel, expectedMoveSequence, receivedAt)
+		return err
+	})
 	if err != nil {
-		slog.Error("Could not get game from DB", "error", err)
-		return nil, nil, err
+		return nil, nil, s.moveConflict(ctx, uuid, err)
 	}
-	game := &gameData.Game
-	moveEvent := &gameData.MoveEvent
-	nextPlayerID := getNextPlayerID(moveEvent.PlayerID)
-	lastBoardState := moveEvent.PostMoveState
+	if traceHash != nil && moveEvent.TraceUuid != nil {
+		s.cacheTrace(traceHash, *moveEvent.TraceUuid)
+	}
+	return result, moveEvent, nil
+}
 
-	if !isValidPlayerID(playerID) {
-		return nil, nil, errors.New("invalid player ID")
+// Plays a Neural Network move with repo's transaction. Also returns the hash of the AI move's trace, if any.
+func (s *GameService) playNNMove(ctx context.Context, repo *repository.Queries, uuid uuid.UUID, playerID int16, position uint8, attribution ai.AttributionMethod, traceLevel ai.TraceLevel, expectedMoveSequence *int16, receivedAt time.Time) (*NNMoveResult, *MoveEvent, []byte, error) {
+	game, gameState, moveEvent, err := s.playHumanMove(ctx, repo, GAME_TYPE_NN, uuid, playerID, position, expectedMoveSequence, receivedAt)
+	if err != nil {
+		return nil, nil, nil, err
 	}
-	if !isValidPosition(position) {
-		return nil, nil, errors.New("invalid position")
+
+	// If the game is over, return the game without a neural network trace
+	if gameState.IsTerminal() {
+		return &NNMoveResult{
+				Game:      game,
+				MoveEvent: moveEvent,
+				Trace:     nil,
+			},
+			moveEvent,
+			nil,
+			nil
 	}
 
-	if game.TerminalState != engine.TERM_NOT {
-		return nil, nil, errors.New("cannot play move on a finished game")
+	// Otherwise, play the AI (neural network) move with the game's model and respond
+	model, err := s.gameModel(ctx, repo, game)
+	if err != nil {
+		return nil, nil, nil, err
 	}
-	if game.GameTypeID != s.cachedGameTypesMap[GAME_TYPE_NN] {
-		return nil, nil, errors.New("game type must be neural network")
+	result, traceHash, err := s.playNNReply(ctx, repo, model, game, gameState, moveEvent, attribution, traceLevel)
+	if err != nil {
+		return nil, nil, nil, err
 	}
-	if playerID != 1 {
-		return nil, nil, errors.New("human player ID must be 1")
+	return result, result.MoveEvent, traceHash, nil
+}
+
+// Plays Minimax move
+// The human's move and the AI's reply are saved in one transaction, with the game row locked.
+// If expectedMoveSequence is not nil and the game's last move is a different one, a *StaleGameError is returned.
+func (s *GameService) PlayMMMove(ctx context.Context, uuid uuid.UUID, playerID int16, position uint8, expectedMoveSequence *int16) (*Game, *MoveEvent, error) {
+	receivedAt := time.Now()
+	var game *Game
+	var moveEvent *MoveEvent
+	err := inTx(ctx, s.db, func(repo *repository.Queries) error {
+		var err error
+		game, moveEvent, err = s.playMMMove(ctx, repo, uuid, playerID, position, expectedMoveSequence, receivedAt)
+		return err
+	})
+	if err != nil {
+		return nil, nil, s.moveConflict(ctx, uuid, err)
 	}
-	if playerID != nextPlayerID {
-		return nil, nil, errors.New("player ID does not match next player ID")
+	return game, moveEvent, nil
+}
+
+// Plays Minimax move with repo's transaction
+func (s *GameService) playMMMove(ctx context.Context, repo *repository.Queries, uuid uuid.UUID, playerID int16, position uint8, expectedMoveSequence *int16, receivedAt time.Time) (*Game, *MoveEvent, error) {
+	game, gameState, moveEvent, err := s.playHumanMove(ctx, repo, GAME_TYPE_MINIMAX, uuid, playerID, position, expectedMoveSequence, receivedAt)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	gameStateOptions := &engine.GameStateOptions{
-		Player1Piece:  pieceToByte(game.Player1Piece),
-		Player2Piece:  pieceToByte(game.Player2Piece),
-		FirstPlayerId: uint8(nextPlayerID),
+	// If the game is over, return the game without the AI's move
+	if gameState.IsTerminal() {
+		return game, moveEvent, nil
 	}
-	gameState, err := engine.NewGameState(gameStateOptions)
-	if err != nil {
-		slog.Error("Could not create game state", "uuid", game.Uuid, "error", err)
+
+	// Otherwise, play the AI (minimax) move and respond
+	if err := s.playMMReply(ctx, repo, game, gameState, moveEvent); err != nil {
 		return nil, nil, err
 	}
-	// Set the board state based on persisted data
-	p1Board, p2Board := engine.UnpackBoard(lastBoardState)
-	gameState.Board.P1Board = p1Board
-	gameState.Board.P2Board = p2Board
+	return game, moveEvent, nil
+}
 
-	// Play the move, update the game state, and respond if the game is over
-	ok, err := gameState.Move(position)
+// Locks a game of the given type and plays the human's move on it with repo's transaction.
+// receivedAt is when the server received the move. Returns the updated game, its game state after the move and the
+// move's event.
+func (s *GameService) playHumanMove(ctx context.Context, repo *repository.Queries, gameTypeLabel string, uuid uuid.UUID, playerID int16, position uint8, expectedMoveSequence *int16, receivedAt time.Time) (*Game, *engine.GameState, *MoveEvent, error) {
+	gameData, err := repo.GetGameByUUIDForUpdate(ctx, uuid)
 	if err != nil {
-		slog.Error("Could not play move", "uuid", game.Uuid, "error", err)
-		return nil, nil, err
+		slog.Error("Could not get game from DB", "error", err)
+		return nil, nil, nil, err
 	}
-	if !ok {
-		slog.Warn("Could not play move", "uuid", game.Uuid, "position", position)
-		return nil, nil, errors.New("invalid move")
+	game := &gameData.Game
+	moveEvent := &gameData.MoveEvent
+	if expectedMoveSequence != nil && *expectedMoveSequence != moveEvent.MoveSequence {
+		return nil, nil, nil, &StaleGameError{ExpectedMoveSequence: *expectedMoveSequence, Game: game, MoveEvent: moveEvent}
 	}
 
-	updateGameParams := repository.UpdateGameParams{
-		Name:          game.Name,
-		TerminalState: int16(gameState.TerminalState),
-		Uuid:          game.Uuid,
+	if !isValidPlayerID(playerID) {
+		return nil, nil, nil, errors.New("invalid player ID")
+	}
+	if !isValidPosition(position) {
+		return nil, nil, nil, errors.New("invalid position")
 	}
 
-	*game, err = s.repo.UpdateGame(ctx, updateGameParams)
-	if err != nil {
-		slog.Warn("Failed to write updated game state to database", "uuid", uuid, "error", err)
-		return nil, nil, err
+	if game.TerminalState != engine.TERM_NOT {
+		return nil, nil, nil, &MoveConflictError{Reason: "cannot play move on a finished game", Game: game, MoveEvent: moveEvent}
+	}
+	if game.GameTypeID != s.cachedGameTypesMap[gameTypeLabel] {
+		return nil, nil, nil, fmt.Errorf("game type must be %s", strings.ReplaceAll(gameTypeLabel, "_", " "))
+	}
+	if playerID == game.AiPlayerID {
+		return nil, nil, nil, fmt.Errorf("human player ID must be %d", getNextPlayerID(game.AiPlayerID))
 	}
 
-	createMoveEventParams := repository.CreateMoveEventParams{
-		GameUuid:      game.Uuid,
-		MoveSequence:  moveEvent.MoveSequence + 1,
-		PlayerID:      nextPlayerID,
-		PostMoveState: gameState.GetBoardAsByteArray(),
+	gameState, err := restoreGameState(game, moveEvent)
+	if err != nil {
+		slog.Error("Could not create game state", "uuid", game.Uuid, "error", err)
+		return nil, nil, nil, err
+	}
+	if playerID != int16(gameState.GetCurrentPlayerId()) {
+		return nil, nil, nil, &MoveConflictError{Reason: "player ID does not match next player ID", Game: game, MoveEvent: moveEvent}
 	}
 
-	*moveEvent, err = s.repo.CreateMoveEvent(ctx, createMoveEventParams)
+	// Play the move and save it
+	ok, err := gameState.Move(position)
 	if err != nil {
-		slog.Error("Could not create move event", "uuid", game.Uuid, "error", err)
-		return nil, nil, err
+		slog.Error("Could not play move", "uuid", game.Uuid, "error", err)
+		return nil, nil, nil, err
+	}
+	if !ok {
+		slog.Warn("Could not play move", "uuid", game.Uuid, "position", position)
+		return nil, nil, nil, errors.New("invalid move")
 	}
 
-	// If the game is over, return the game without a neural network trace
-	if gameState.IsTerminal() {
-		return &NNMoveResult{
-				Game:  game,
-				Trace: nil,
-			},
-			moveEvent,
-			nil
+	if err := s.saveMove(ctx, repo, game, gameState, moveEvent, playerID, position, nil, moveTiming{receivedAt: receivedAt}); err != nil {
+		return nil, nil, nil, err
 	}
+	return game, gameState, moveEvent, nil
+}
 
-	// Otherwise, play the AI (neural network) move and respond
-	nextPlayerID = getNextPlayerID(moveEvent.PlayerID)
-	input := gameState.GetBoardAsNetworkInput()
-	trace := new(ai.ForwardTrace)
-	output, err := s.neuralNet.Forward(input, trace)
+// Plays the neural network's move for the game's AI player after moveEvent, with repo's transaction.
+// Also returns the hash of the move's trace.
+func (s *GameService) playNNReply(ctx context.Context, repo *repository.Queries, model *nnModel, game *Game, gameState *engine.GameState, moveEvent *MoveEvent, attribution ai.AttributionMethod, traceLevel ai.TraceLevel) (*NNMoveResult, []byte, error) {
+	startedAt := time.Now()
+	input := gameState.GetBoardAsNetworkInputFor(uint8(game.AiPlayerID))
+	trace := ai.NewForwardTrace(traceLevel, 0)
+	output := make([]float64, engine.NETWORK_OUTPUT_LEN)
+	value, err := model.evaluate(input, output, trace)
 	if err != nil {
 		panic(err)
 	}
@@ -367,194 +818,133 @@ func (s *GameService) PlayNNMove(ctx context.Context, uuid uuid.UUID, playerID i
 	}
 
 	// Try the positions in the sorted order until one works
-	moveSuccess := false
+	preMoveState := moveEvent.PostMoveState
+	aiPosition := uint8(0)
 	for _, position := range positions {
 		ok, _ := gameState.Move(uint8(position))
 		if ok {
-			moveSuccess = true
+			aiPosition = uint8(position)
 			break
 		}
 	}
-	if !moveSuccess {
+	if aiPosition == 0 {
 		return nil, nil, errors.New("no valid move for AI found")
 	}
+	timing := moveTiming{receivedAt: startedAt, thinkTime: time.Since(startedAt)}
 
-	updateGameParams = repository.UpdateGameParams{
-		Name:          game.Name,
-		TerminalState: int16(gameState.TerminalState),
-		Uuid:          game.Uuid,
-	}
-	*game, err = s.repo.UpdateGame(ctx, updateGameParams)
-	if err != nil {
-		slog.Warn("Failed to write updated game state to database", "uuid", uuid, "error", err)
-		return nil, nil, err
+	var moveAttribution *ai.Attribution
+	if attribution != ai.ATTRIBUTION_NONE {
+		moveAttribution, err = model.network.Attribute(input, aiPosition, attribution)
+		if err != nil {
+			slog.Error("Could not attribute AI move", "uuid", game.Uuid, "error", err)
+			return nil, nil, err
+		}
 	}
 
 	// PostMoveState is the last move (pre-move) and gameState is the post-move state
-	traceUuid, err := s.AddTrace(ctx, moveEvent.PostMoveState, gameState.GetBoardAsByteArray(), trace)
+	traceHash := getCombinedStatesHash(model.checksum, traceLevel, preMoveState, gameState.GetBoardAsByteArray())
+	traceUuid, err := s.AddTrace(ctx, repo, model.checksum, preMoveState, gameState.GetBoardAsByteArray(), trace)
 	if err != nil {
 		slog.Error("Could not add trace to database", "uuid", game.Uuid, "error", err)
 		return nil, nil, err
 	}
 
-	createMoveEventParams = repository.CreateMoveEventParams{
-		GameUuid:      game.Uuid,
-		TraceUuid:     traceUuid,
-		MoveSequence:  moveEvent.MoveSequence + 1,
-		PlayerID:      nextPlayerID,
-		PostMoveState: gameState.GetBoardAsByteArray(),
-	}
-
-	*moveEvent, err = s.repo.CreateMoveEvent(ctx, createMoveEventParams)
-	if err != nil {
-		slog.Error("Could not create move event", "uuid", game.Uuid, "error", err)
+	if err := s.saveMove(ctx, repo, game, gameState, moveEvent, game.AiPlayerID, aiPosition, traceUuid, timing); err != nil {
 		return nil, nil, err
 	}
 
 	return &NNMoveResult{
 			Game:        game,
+			MoveEvent:   moveEvent,
 			Trace:       trace,
 			RankedMoves: positions,
+			Attribution: moveAttribution,
+			Value:       value,
 		},
-		moveEvent,
+		traceHash,
 		nil
 }
 
-// Plays Minimax move
-func (s *GameService) PlayMMMove(ctx context.Context, uuid uuid.UUID, playerID int16, position uint8) (*Game, *MoveEvent, error) {
-	gameData, err := s.repo.GetGameByUUID(ctx, uuid)
-	if err != nil {
-		slog.Error("Could not get game from DB", "error", err)
-		return nil, nil, err
-	}
-	game := &gameData.Game
-	moveEvent := &gameData.MoveEvent
-	nextPlayerID := getNextPlayerID(moveEvent.PlayerID)
-	lastBoardState := moveEvent.PostMoveState
-
-	if !isValidPlayerID(playerID) {
-		return nil, nil, errors.New("invalid player ID")
-	}
-	if !isValidPosition(position) {
-		return nil, nil, errors.New("invalid position")
-	}
-
-	if game.TerminalState != engine.TERM_NOT {
-		return nil, nil, errors.New("cannot play move on a finished game")
-	}
-	if game.GameTypeID != s.cachedGameTypesMap[GAME_TYPE_MINIMAX] {
-		return nil, nil, errors.New("game type must be minimax")
-	}
-	if playerID != 1 {
-		return nil, nil, errors.New("human player ID must be 1")
-	}
-	if playerID != nextPlayerID {
-		return nil, nil, errors.New("player ID does not match next player ID")
-	}
-
-	gameStateOptions := &engine.GameStateOptions{
-		Player1Piece:  pieceToByte(game.Player1Piece),
-		Player2Piece:  pieceToByte(game.Player2Piece),
-		FirstPlayerId: uint8(nextPlayerID),
-	}
-	gameState, err := engine.NewGameState(gameStateOptions)
-	if err != nil {
-		slog.Error("Could not create game state", "uuid", game.Uuid, "error", err)
-		return nil, nil, err
-	}
-	// Set the board state based on persisted data
-	p1Board, p2Board := engine.UnpackBoard(lastBoardState)
-	gameState.Board.P1Board = p1Board
-	gameState.Board.P2Board = p2Board
-
-	// Play the move, update the game state, and respond if the game is over
-	ok, err := gameState.Move(position)
+// Plays minimax's move for the game's AI player after moveEvent, with repo's transaction
+func (s *GameService) playMMReply(ctx context.Context, repo *repository.Queries, game *Game, gameState *engine.GameState, moveEvent *MoveEvent) error {
+	startedAt := time.Now()
+	bestMove := ai.BestMoveFor(gameState.Board, uint8(game.AiPlayerID))
+	timing := moveTiming{receivedAt: startedAt, thinkTime: time.Since(startedAt)}
+	ok, err := gameState.Move(bestMove)
 	if err != nil {
 		slog.Error("Could not play move", "uuid", game.Uuid, "error", err)
-		return nil, nil, err
+		return err
 	}
 	if !ok {
-		slog.Warn("Could not play move", "uuid", game.Uuid, "position", position)
-		return nil, nil, errors.New("invalid move")
+		slog.Warn("Could not play move", "uuid", game.Uuid, "position", bestMove)
+		return errors.New("invalid move")
 	}
 
+	return s.saveMove(ctx, repo, game, gameState, moveEvent, game.AiPlayerID, bestMove, nil, timing)
+}
+
+// When the server received a move, or started choosing it for the AI, and how long the AI took to choose it
+type moveTiming struct {
+	receivedAt time.Time
+	thinkTime  time.Duration // 0 for human moves
+}
+
+// Saves the move at position that led to gameState as the event after moveEvent, with repo's transaction.
+// game and moveEvent are updated in place. If the move finishes the game, its players' ratings are updated.
+func (s *GameService) saveMove(ctx context.Context, repo *repository.Queries, game *Game, gameState *engine.GameState, moveEvent *MoveEvent, playerID int16, position uint8, traceUuid *uuid.UUID, timing moveTiming) error {
+	previousTerminalState := game.TerminalState
 	updateGameParams := repository.UpdateGameParams{
 		Name:          game.Name,
 		TerminalState: int16(gameState.TerminalState),
 		Uuid:          game.Uuid,
 	}
-	*game, err = s.repo.UpdateGame(ctx, updateGameParams)
+	updatedGame, err := repo.UpdateGame(ctx, updateGameParams)
 	if err != nil {
-		slog.Warn("Failed to write updated game state to database", "uuid", uuid, "error", err)
-		return nil, nil, err
+		slog.Warn("Failed to write updated game state to database", "uuid", game.Uuid, "error", err)
+		return err
+	}
+	*game = updatedGame
+	if previousTerminalState == engine.TERM_NOT && game.TerminalState != engine.TERM_NOT {
+		if err := rateGame(ctx, repo, s.GetGameTypeLabel(game.GameTypeID), game); err != nil {
+			return err
+		}
 	}
 
 	createMoveEventParams := repository.CreateMoveEventParams{
 		GameUuid:      game.Uuid,
+		TraceUuid:     traceUuid,
 		MoveSequence:  moveEvent.MoveSequence + 1,
-		PlayerID:      nextPlayerID,
+		PlayerID:      playerID,
 		PostMoveState: gameState.GetBoardAsByteArray(),
+		Position:      pgtype.Int2{Int16: int16(position), Valid: true},
+		ReceivedAt:    timing.receivedAt,
 	}
-
-	*moveEvent, err = s.repo.CreateMoveEvent(ctx, createMoveEventParams)
-	if err != nil {
-		slog.Error("Could not create move event", "uuid", game.Uuid, "error", err)
-		return nil, nil, err
+	if playerID == game.AiPlayerID {
+		createMoveEventParams.ThinkTimeMs = pgtype.Int4{Int32: int32(timing.thinkTime.Milliseconds()), Valid: true}
 	}
-
-	// If the game is over, return the game without a neural network trace
-	if gameState.IsTerminal() {
-		return game, moveEvent, nil
-	}
-
-	// Otherwise, play the AI (minimax) move and respond
-	nextPlayerID = getNextPlayerID(moveEvent.PlayerID)
-	bestMove := ai.BestMove(gameState.Board)
-	ok, err = gameState.Move(bestMove)
-	if err != nil {
-		slog.Error("Could not play move", "uuid", game.Uuid, "error", err)
-		return nil, nil, err
-	}
-	if !ok {
-		slog.Warn("Could not play move", "uuid", game.Uuid, "position", position)
-		return nil, nil, errors.New("invalid move")
-	}
-
-	updateGameParams = repository.UpdateGameParams{
-		Name:          game.Name,
-		TerminalState: int16(gameState.TerminalState),
-		Uuid:          game.Uuid,
-	}
-	*game, err = s.repo.UpdateGame(ctx, updateGameParams)
-	if err != nil {
-		slog.Warn("Failed to write updated game state to database", "uuid", uuid, "error", err)
-		return nil, nil, err
-	}
-
-	createMoveEventParams = repository.CreateMoveEventParams{
-		GameUuid:      game.Uuid,
-		MoveSequence:  moveEvent.MoveSequence + 1,
-		PlayerID:      nextPlayerID,
-		PostMoveState: gameState.GetBoardAsByteArray(),
-	}
-
-	*moveEvent, err = s.repo.CreateMoveEvent(ctx, createMoveEventParams)
+	createdMoveEvent, err := repo.CreateMoveEvent(ctx, createMoveEventParams)
 	if err != nil {
 		slog.Error("Could not create move event", "uuid", game.Uuid, "error", err)
-		return nil, nil, err
+		return err
 	}
-
-	return game, moveEvent, nil
+	*moveEvent = createdMoveEvent
+	return nil
 }
 
-func (s *GameService) GetMoveHistory(ctx context.Context, uuid uuid.UUID) ([]MoveEventWithTrace, error) {
+// Returns the moves of a game in order. If attribution is not ATTRIBUTION_NONE, neural network moves are
+// attributed to their input features using the model that made them, or marked as skipped if it is not loaded.
+func (s *GameService) GetMoveHistory(ctx context.Context, uuid uuid.UUID, attribution ai.AttributionMethod) ([]MoveEventWithTrace, error) {
 	moveEventRows, err := s.repo.ListGameMoveEventsWithTrace(ctx, uuid)
 	if err != nil {
 		slog.Error("Could not get game from DB", "error", err)
 		return nil, err
 	}
 	var moveEvents []MoveEventWithTrace
+	var preMoveState []byte
 	for _, moveEventRow := range moveEventRows {
+		previousState := preMoveState
+		preMoveState = moveEventRow.PostMoveState
+
 		moveEvent := &MoveEvent{
 			Uuid:          moveEventRow.Uuid,
 			GameUuid:      moveEventRow.GameUuid,
@@ -564,6 +954,9 @@ func (s *GameService) GetMoveHistory(ctx context.Context, uuid uuid.UUID) ([]Mov
 			PostMoveState: moveEventRow.PostMoveState,
 			CreatedAt:     moveEventRow.CreatedAt,
 			UpdatedAt:     moveEventRow.UpdatedAt,
+			Position:      moveEventRow.Position,
+			ReceivedAt:    moveEventRow.ReceivedAt,
+			ThinkTimeMs:   moveEventRow.ThinkTimeMs,
 		}
 		if moveEventRow.TraceUuid == nil {
 			moveEvents = append(moveEvents, MoveEventWithTrace{
@@ -574,29 +967,51 @@ func (s *GameService) GetMoveHistory(ctx context.Context, uuid uuid.UUID) ([]Mov
 		}
 
 		// If there is a trace UUID, transform the protobuf into the trace struct
-		var traceMessage pb.Trace
-		err := proto.Unmarshal(moveEventRow.Trace, &traceMessage)
+		trace, err := unmarshalTrace(moveEventRow.Trace)
 		if err != nil {
 			slog.Error("Could not unmarshal trace message", "error", err)
 			return nil, err
 		}
-		trace := &NNMoveTrace{
-			LayerOutputs: [][]float64{
-				traceMessage.GetLayer1(),
-				traceMessage.GetLayer2(),
-				traceMessage.GetLayer3(),
-				traceMessage.GetLayer4(),
-				traceMessage.GetLayer5(),
-			},
+
+		var moveAttribution *ai.Attribution
+		var attributionSkipped string
+		if attribution != ai.ATTRIBUTION_NONE && len(trace.LayerOutputs) > 0 {
+			// Attributing with another model would explain a choice that model did not make
+			model := s.loadedModel(moveEventRow.ModelID.String)
+			if model == nil {
+				attributionSkipped = ATTRIBUTION_SKIPPED_MODEL_NOT_LOADED
+			} else {
+				position, err := movePosition(moveEvent, previousState)
+				if err != nil {
+					slog.Error("Could not derive move position", "uuid", moveEventRow.Uuid, "error", err)
+					return nil, err
+				}
+				moveAttribution, err = model.network.Attribute(trace.LayerOutputs[0], position, attribution)
+				if err != nil {
+					slog.Error("Could not attribute AI move", "uuid", moveEventRow.Uuid, "error", err)
+					return nil, err
+				}
+			}
 		}
+
 		moveEvents = append(moveEvents, MoveEventWithTrace{
-			MoveEvent: moveEvent,
-			Trace:     trace,
+			MoveEvent:          moveEvent,
+			Trace:              trace,
+			Attribution:        moveAttribution,
+			AttributionSkipped: attributionSkipped,
 		})
 	}
 	return moveEvents, nil
 }
 
+// Returns the square played in moveEvent. Events saved before positions were stored derive it from the boards.
+func movePosition(moveEvent *MoveEvent, preMoveState []byte) (uint8, error) {
+	if moveEvent.Position.Valid {
+		return uint8(moveEvent.Position.Int16), nil
+	}
+	return engine.MovePosition(preMoveState, moveEvent.PostMoveState)
+}
+
 // Utility function to convert a piece string to a byte
 func pieceToByte(piece string) byte {
 	switch piece {
@@ -0,0 +1,672 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"t-cubed/internal/ai"
+	"t-cubed/internal/engine"
+	"t-cubed/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Connects to the migrated database at DATABASE_URL, or skips the test without one
+func newTestPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("DATABASE_URL is not set")
+	}
+
+	pool, err := pgxpool.New(context.Background(), databaseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+// Returns a service backed by the test database
+func newTestGameService(t *testing.T) *GameService {
+	t.Helper()
+	ctx := context.Background()
+	pool := newTestPool(t)
+
+	repo := repository.New(pool)
+	gameTypes, err := repo.GetGameTypes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	weightsFile := filepath.Join("..", "..", NN_WEIGHTS_FILE)
+	model, err := loadNNModel(weightsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &GameService{
+		db:                 pool,
+		repo:               repo,
+		weightsFile:        weightsFile,
+		cachedGameTypesMap: gameTypesFromRepo(&gameTypes),
+	}
+	s.serveModel(model)
+	return s
+}
+
+// Creates a game that is deleted with its moves when the test ends
+func createTestGame(t *testing.T, s *GameService, gameType string) *Game {
+	t.Helper()
+	return createTestGameWithConfig(t, s, gameType, GameConfig{Player1Piece: "X", Player2Piece: "O"})
+}
+
+func createTestGameWithConfig(t *testing.T, s *GameService, gameType string, config GameConfig) *Game {
+	t.Helper()
+	ctx := context.Background()
+	game, _, err := s.CreateGame(ctx, t.Name(), gameType, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, err := s.db.Exec(ctx, "DELETE FROM move_event WHERE game_uuid = $1", game.Uuid); err != nil {
+			t.Error(err)
+		}
+		if err := s.repo.DeleteGame(ctx, game.Uuid); err != nil {
+			t.Error(err)
+		}
+	})
+	return game
+}
+
+// Makes inserting a game's move event fail, as if the database failed midway through a play.
+// Returns a function that removes the failure again.
+func failMoveEventInsert(t *testing.T, s *GameService, gameUuid uuid.UUID, moveSequence int16) func() {
+	t.Helper()
+	ctx := context.Background()
+	name := "fail_move_event_" + strings.ReplaceAll(gameUuid.String(), "-", "")
+	statements := []string{
+		fmt.Sprintf(`CREATE FUNCTION %s() RETURNS TRIGGER AS $$
+BEGIN
+	IF NEW.game_uuid = '%s' AND NEW.move_sequence = %d THEN
+		RAISE EXCEPTION 'injected failure';
+	END IF;
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql`, name, gameUuid, moveSequence),
+		fmt.Sprintf("CREATE TRIGGER %s BEFORE INSERT ON move_event FOR EACH ROW EXECUTE PROCEDURE %s()", name, name),
+	}
+	for _, statement := range statements {
+		if _, err := s.db.Exec(ctx, statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var once sync.Once
+	remove := func() {
+		once.Do(func() {
+			if _, err := s.db.Exec(ctx, fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON move_event", name)); err != nil {
+				t.Error(err)
+			}
+			if _, err := s.db.Exec(ctx, fmt.Sprintf("DROP FUNCTION IF EXISTS %s()", name)); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	t.Cleanup(remove)
+	return remove
+}
+
+// Checks that the game is still waiting for Player 1's first move
+func assertGameUntouched(t *testing.T, s *GameService, gameUuid uuid.UUID) {
+	t.Helper()
+	game, moveEvent, err := s.GetGame(context.Background(), gameUuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.TerminalState != engine.TERM_NOT {
+		t.Errorf("Expected the game to be in progress, got terminal state %d", game.TerminalState)
+	}
+	if moveEvent.MoveSequence != 0 {
+		t.Errorf("Expected only the initial move event, got move %d", moveEvent.MoveSequence)
+	}
+}
+
+func TestPlayMMMove_RollsBackWhenAIReplyFails(t *testing.T) {
+	s := newTestGameService(t)
+	ctx := context.Background()
+	game := createTestGame(t, s, GAME_TYPE_MINIMAX)
+	removeFailure := failMoveEventInsert(t, s, game.Uuid, 2)
+
+	if _, _, err := s.PlayMMMove(ctx, game.Uuid, 1, 5, nil); err == nil {
+		t.Fatal("Expected the move to fail")
+	}
+	assertGameUntouched(t, s, game.Uuid)
+
+	// The same move can be played once the database works again
+	removeFailure()
+	_, moveEvent, err := s.PlayMMMove(ctx, game.Uuid, 1, 5, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moveEvent.MoveSequence != 2 || moveEvent.PlayerID != 2 {
+		t.Errorf("Expected the AI's reply as move 2, got move %d by player %d", moveEvent.MoveSequence, moveEvent.PlayerID)
+	}
+}
+
+func TestPlayNNMove_RollsBackWhenAIReplyFails(t *testing.T) {
+	s := newTestGameService(t)
+	ctx := context.Background()
+	game := createTestGame(t, s, GAME_TYPE_NN)
+	removeFailure := failMoveEventInsert(t, s, game.Uuid, 2)
+
+	if _, _, err := s.PlayNNMove(ctx, game.Uuid, 1, 5, ai.ATTRIBUTION_NONE, ai.TRACE_LEVEL_OUTPUTS, nil); err == nil {
+		t.Fatal("Expected the move to fail")
+	}
+	assertGameUntouched(t, s, game.Uuid)
+
+	// Retrying creates the trace again instead of reusing the rolled back one
+	removeFailure()
+	_, moveEvent, err := s.PlayNNMove(ctx, game.Uuid, 1, 5, ai.ATTRIBUTION_NONE, ai.TRACE_LEVEL_OUTPUTS, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moveEvent.MoveSequence != 2 || moveEvent.TraceUuid == nil {
+		t.Errorf("Expected the AI's reply with a trace as move 2, got move %d with trace %v", moveEvent.MoveSequence, moveEvent.TraceUuid)
+	}
+}
+
+func TestPlayNNMove_TraceLevel(t *testing.T) {
+	s := newTestGameService(t)
+	ctx := context.Background()
+
+	var traceUuids []uuid.UUID
+	for _, level := range []ai.TraceLevel{ai.TRACE_LEVEL_OUTPUTS, ai.TRACE_LEVEL_DETAILED} {
+		game := createTestGame(t, s, GAME_TYPE_NN)
+		result, moveEvent, err := s.PlayNNMove(ctx, game.Uuid, 1, 5, ai.ATTRIBUTION_NONE, level, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if detailed := result.Trace.Logits != nil; detailed != (level == ai.TRACE_LEVEL_DETAILED) {
+			t.Errorf("Expected logits only in a detailed trace, got logits %v at level %d", result.Trace.Logits, level)
+		}
+		traceUuids = append(traceUuids, *moveEvent.TraceUuid)
+	}
+	// The same move is traced again at another level instead of reusing the cached trace
+	if traceUuids[0] == traceUuids[1] {
+		t.Error("Expected the outputs and detailed traces to be stored separately")
+	}
+}
+
+// Writes a copy of the served weights with one output bias changed, so it is a different model
+func writeChangedWeights(t *testing.T, s *GameService) string {
+	t.Helper()
+	network, err := ai.ParseNetwork(s.currentModel().weights)
+	if err != nil {
+		t.Fatal(err)
+	}
+	network.Layers[len(network.Layers)-1].Biases[0] += 1
+	weightsFile := filepath.Join(t.TempDir(), "weights.json")
+	if err := ai.SaveNetwork(weightsFile, network); err != nil {
+		t.Fatal(err)
+	}
+	return weightsFile
+}
+
+func TestPlayNNMove_KeepsGameModelAcrossReload(t *testing.T) {
+	s := newTestGameService(t)
+	ctx := context.Background()
+	game := createTestGame(t, s, GAME_TYPE_NN)
+	startModel := s.currentModel()
+
+	s.weightsFile = writeChangedWeights(t, s)
+	if err := s.ReloadWeights(); err != nil {
+		t.Fatal(err)
+	}
+	if s.currentModel().checksum == startModel.checksum {
+		t.Fatal("Expected the reload to serve a different model")
+	}
+
+	// The reply is chosen by the model the game was started with
+	result, _, err := s.PlayNNMove(ctx, game.Uuid, 1, 5, ai.ATTRIBUTION_NONE, ai.TRACE_LEVEL_OUTPUTS, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Game.ModelID != startModel.checksum {
+		t.Errorf("Expected the game to keep model %s, got %s", startModel.checksum, result.Game.ModelID)
+	}
+	want := ai.NewForwardTrace(ai.TRACE_LEVEL_OUTPUTS, 0)
+	if _, err := startModel.network.Forward(result.Trace.LayerOutputs[0], want); err != nil {
+		t.Fatal(err)
+	}
+	logits := result.Trace.LayerOutputs[len(result.Trace.LayerOutputs)-1]
+	wantLogits := want.LayerOutputs[len(want.LayerOutputs)-1]
+	for i := range wantLogits {
+		if math.Abs(logits[i]-wantLogits[i]) > 1e-9 {
+			t.Fatalf("Expected the reply to be traced with the game's model, got logits %v, want %v", logits, wantLogits)
+		}
+	}
+
+	// New games are started with the reloaded model
+	newGame := createTestGame(t, s, GAME_TYPE_NN)
+	if newGame.ModelID != s.currentModel().checksum {
+		t.Errorf("Expected a new game to use the reloaded model, got %s", newGame.ModelID)
+	}
+
+	// A game whose model is no longer loaded, as after a restart, switches to the current model
+	s.unloadModel(startModel.checksum)
+	result, _, err = s.PlayNNMove(ctx, game.Uuid, 1, firstFreePosition(t, result.MoveEvent), ai.ATTRIBUTION_NONE, ai.TRACE_LEVEL_OUTPUTS, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Game.ModelID != s.currentModel().checksum {
+		t.Errorf("Expected the game to switch to model %s, got %s", s.currentModel().checksum, result.Game.ModelID)
+	}
+}
+
+func TestGetMoveHistory_AttributesWithTheMoveModel(t *testing.T) {
+	s := newTestGameService(t)
+	ctx := context.Background()
+	game := createTestGame(t, s, GAME_TYPE_NN)
+	startModel := s.currentModel()
+	result, _, err := s.PlayNNMove(ctx, game.Uuid, 1, 5, ai.ATTRIBUTION_NONE, ai.TRACE_LEVEL_OUTPUTS, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := startModel.network.Attribute(result.Trace.LayerOutputs[0], uint8(result.MoveEvent.Position.Int16), ai.ATTRIBUTION_GRADIENT_X_INPUT)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.weightsFile = writeChangedWeights(t, s)
+	if err := s.ReloadWeights(); err != nil {
+		t.Fatal(err)
+	}
+	moveEvents, err := s.GetMoveHistory(ctx, game.Uuid, ai.ATTRIBUTION_GRADIENT_X_INPUT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply := moveEvents[len(moveEvents)-1]
+	if reply.Attribution == nil || !slices.Equal(reply.Attribution.Scores, want.Scores) {
+		t.Errorf("Expected the reply to be attributed with the model that made it, got %v", reply.Attribution)
+	}
+
+	// Without the model, the move is marked instead of being attributed with the current one
+	s.unloadModel(startModel.checksum)
+	moveEvents, err = s.GetMoveHistory(ctx, game.Uuid, ai.ATTRIBUTION_GRADIENT_X_INPUT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply = moveEvents[len(moveEvents)-1]
+	if reply.Attribution != nil || reply.AttributionSkipped != ATTRIBUTION_SKIPPED_MODEL_NOT_LOADED {
+		t.Errorf("Expected the reply's attribution to be skipped, got %v (%q)", reply.Attribution, reply.AttributionSkipped)
+	}
+}
+
+// Returns the first square that is free after moveEvent
+func firstFreePosition(t *testing.T, moveEvent *MoveEvent) uint8 {
+	t.Helper()
+	p1Board, p2Board := engine.UnpackBoard(moveEvent.PostMoveState)
+	for position := uint8(1); position <= 9; position++ {
+		if (p1Board|p2Board)&(1<<(position-1)) == 0 {
+			return position
+		}
+	}
+	t.Fatal("Expected a free square")
+	return 0
+}
+
+func TestPlayMMMove_RollsBackWhenHumanMoveFails(t *testing.T) {
+	s := newTestGameService(t)
+	game := createTestGame(t, s, GAME_TYPE_MINIMAX)
+	failMoveEventInsert(t, s, game.Uuid, 1)
+
+	if _, _, err := s.PlayMMMove(context.Background(), game.Uuid, 1, 5, nil); err == nil {
+		t.Fatal("Expected the move to fail")
+	}
+	assertGameUntouched(t, s, game.Uuid)
+}
+
+func TestPlayMMMove_ConcurrentMovesArePlayedInTurn(t *testing.T) {
+	s := newTestGameService(t)
+	ctx := context.Background()
+	game := createTestGame(t, s, GAME_TYPE_MINIMAX)
+
+	// Minimax must answer a corner with the center, so both corners stay available whichever move runs first
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i, position := range []uint8{1, 9} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, errs[i] = s.PlayMMMove(ctx, game.Uuid, 1, position, nil)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	moveEvents, err := s.repo.ListGameMoveEvents(ctx, game.Uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.SortFunc(moveEvents, func(a, b repository.MoveEvent) int {
+		return int(a.MoveSequence) - int(b.MoveSequence)
+	})
+	if len(moveEvents) != 5 {
+		t.Fatalf("Expected the initial event and two moves with their replies, got %d events", len(moveEvents))
+	}
+	for i := 1; i < len(moveEvents); i++ {
+		if moveEvents[i].MoveSequence != int16(i) {
+			t.Errorf("Expected move %d, got move %d", i, moveEvents[i].MoveSequence)
+		}
+		if _, err := engine.MovePosition(moveEvents[i-1].PostMoveState, moveEvents[i].PostMoveState); err != nil {
+			t.Errorf("Move %d does not follow from the previous board: %v", i, err)
+		}
+	}
+}
+
+func TestPlayNNMove_ParallelGamesShareTraces(t *testing.T) {
+	s := newTestGameService(t)
+	ctx := context.Background()
+	games := make([]*Game, 8)
+	for i := range games {
+		games[i] = createTestGame(t, s, GAME_TYPE_NN)
+	}
+
+	// The same move on every game gets the same reply, so the games read and add the same trace at once
+	var wg sync.WaitGroup
+	moveEvents := make([]*MoveEvent, len(games))
+	errs := make([]error, len(games))
+	for i, game := range games {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, moveEvents[i], errs[i] = s.PlayNNMove(ctx, game.Uuid, 1, 5, ai.ATTRIBUTION_NONE, ai.TRACE_LEVEL_OUTPUTS, nil)
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if moveEvents[i].TraceUuid == nil || *moveEvents[i].TraceUuid != *moveEvents[0].TraceUuid {
+			t.Errorf("Expected every game to share one trace, got %v and %v", moveEvents[i].TraceUuid, moveEvents[0].TraceUuid)
+		}
+	}
+}
+
+func TestGetCombinedStatesHash_DependsOnModel(t *testing.T) {
+	preMoveState := []byte{0, 0, 1, 0}
+	postMoveState := []byte{2, 0, 1, 0}
+	hash := getCombinedStatesHash("model-a", ai.TRACE_LEVEL_OUTPUTS, preMoveState, postMoveState)
+	if !slices.Equal(hash, getCombinedStatesHash("model-a", ai.TRACE_LEVEL_OUTPUTS, preMoveState, postMoveState)) {
+		t.Error("Expected the same move by the same model to have the same hash")
+	}
+	if slices.Equal(hash, getCombinedStatesHash("model-b", ai.TRACE_LEVEL_OUTPUTS, preMoveState, postMoveState)) {
+		t.Error("Expected the same move by different models to have different hashes")
+	}
+	if slices.Equal(hash, getCombinedStatesHash("model-a", ai.TRACE_LEVEL_DETAILED, preMoveState, postMoveState)) {
+		t.Error("Expected the same move traced at different levels to have different hashes")
+	}
+	if !slices.Equal(preMoveState, []byte{0, 0, 1, 0}) {
+		t.Errorf("Expected the pre-move state to be left unchanged, got %v", preMoveState)
+	}
+}
+
+func TestTraceHashesMap_ConcurrentAccess(t *testing.T) {
+	// An already loaded cache, so the service does not need a database
+	s := &GameService{cachedTraceHachesMap: make(map[string]uuid.UUID)}
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			hash := []byte{byte(i)}
+			traceUuid := uuid.New()
+			s.cacheTrace(hash, traceUuid)
+			got, err := s.GetTraceUUID(ctx, hash)
+			if err != nil || *got != traceUuid {
+				t.Errorf("Expected trace %s, got %v (%v)", traceUuid, got, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestPlayMMMove_StaleMoveSequenceIsRejected(t *testing.T) {
+	s := newTestGameService(t)
+	ctx := context.Background()
+	game := createTestGame(t, s, GAME_TYPE_MINIMAX)
+
+	// Both requests were sent for the blank board, so only the first one to lock the game may play
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	expected := int16(0)
+	for i, position := range []uint8{1, 9} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, errs[i] = s.PlayMMMove(ctx, game.Uuid, 1, position, &expected)
+		}()
+	}
+	wg.Wait()
+
+	if (errs[0] == nil) == (errs[1] == nil) {
+		t.Fatalf("Expected exactly one move to succeed, got errors %v and %v", errs[0], errs[1])
+	}
+	var staleErr *StaleGameError
+	if !errors.As(errors.Join(errs...), &staleErr) {
+		t.Fatalf("Expected a StaleGameError, got %v", errors.Join(errs...))
+	}
+	if staleErr.MoveEvent.MoveSequence != 2 || staleErr.ExpectedMoveSequence != 0 {
+		t.Errorf("Expected the stale move to report the game at move 2, got move %d", staleErr.MoveEvent.MoveSequence)
+	}
+
+	// Sending the current move sequence plays the move
+	expected = staleErr.MoveEvent.MoveSequence
+	position := uint8(9)
+	if errs[1] == nil {
+		position = 1
+	}
+	if _, _, err := s.PlayMMMove(ctx, game.Uuid, 1, position, &expected); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPlayMMMove_LostRaceIsAConflict(t *testing.T) {
+	s := newTestGameService(t)
+	ctx := context.Background()
+	game := createTestGame(t, s, GAME_TYPE_MINIMAX)
+
+	// Neither request says which move it was chosen for, so the losers find it is not their turn anymore
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i, position := range []uint8{1, 3, 7, 9} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, errs[i] = s.PlayMMMove(ctx, game.Uuid, 1, position, nil)
+		}()
+	}
+	wg.Wait()
+
+	played := 0
+	for _, err := range errs {
+		var conflictErr *MoveConflictError
+		switch {
+		case err == nil:
+			played++
+		case !errors.As(err, &conflictErr):
+			t.Errorf("Expected a MoveConflictError, got %v", err)
+		case conflictErr.MoveEvent.MoveSequence != 2:
+			t.Errorf("Expected the conflict to report the game at move 2, got move %d", conflictErr.MoveEvent.MoveSequence)
+		}
+	}
+	if played != 1 {
+		t.Fatalf("Expected exactly one move to succeed, got %d", played)
+	}
+}
+
+func TestPlayMMMove_FinishedGameIsAConflict(t *testing.T) {
+	s := newTestGameService(t)
+	ctx := context.Background()
+	game := playOutMMGame(t, s, createTestGame(t, s, GAME_TYPE_MINIMAX))
+
+	_, _, err := s.PlayMMMove(ctx, game.Uuid, 1, 1, nil)
+	var conflictErr *MoveConflictError
+	if !errors.As(err, &conflictErr) {
+		t.Fatalf("Expected a MoveConflictError, got %v", err)
+	}
+	if conflictErr.Game.TerminalState != game.TerminalState {
+		t.Errorf("Expected the conflict to report the finished game, got terminal state %d", conflictErr.Game.TerminalState)
+	}
+}
+
+func TestCreateGame_AIMovesFirst(t *testing.T) {
+	s := newTestGameService(t)
+	ctx := context.Background()
+
+	for _, gameType := range []string{GAME_TYPE_MINIMAX, GAME_TYPE_NN} {
+		game := createTestGameWithConfig(t, s, gameType, GameConfig{
+			Player1Piece:  "X",
+			Player2Piece:  "O",
+			FirstPlayerID: 2,
+			AIPlayerID:    2,
+		})
+		game, moveEvent, err := s.GetGame(ctx, game.Uuid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if game.FirstPlayerID != 2 || game.Variant != engine.VARIANT_STANDARD {
+			t.Errorf("Expected the config to be saved, got first player %d and variant %q", game.FirstPlayerID, game.Variant)
+		}
+		if (game.ModelID != "") != (gameType == GAME_TYPE_NN) {
+			t.Errorf("Expected a model ID only for neural network games, got %q", game.ModelID)
+		}
+		if moveEvent.MoveSequence != 1 || moveEvent.PlayerID != 2 {
+			t.Fatalf("Expected the AI's opening move, got move %d by player %d", moveEvent.MoveSequence, moveEvent.PlayerID)
+		}
+
+		// The human plays Player 1 and moves second
+		position := uint8(1)
+		if _, p2Board := engine.UnpackBoard(moveEvent.PostMoveState); p2Board&1 != 0 {
+			position = 9
+		}
+		if _, _, err := s.PlayMMMove(ctx, game.Uuid, 2, position, nil); err == nil {
+			t.Error("Expected the AI player's ID to be rejected")
+		}
+		var played *MoveEvent
+		if gameType == GAME_TYPE_NN {
+			_, played, err = s.PlayNNMove(ctx, game.Uuid, 1, position, ai.ATTRIBUTION_NONE, ai.TRACE_LEVEL_OUTPUTS, nil)
+		} else {
+			_, played, err = s.PlayMMMove(ctx, game.Uuid, 1, position, nil)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if played.MoveSequence != 3 || played.PlayerID != 2 {
+			t.Errorf("Expected the AI's reply as move 3, got move %d by player %d", played.MoveSequence, played.PlayerID)
+		}
+		if !played.Position.Valid || !played.ThinkTimeMs.Valid {
+			t.Errorf("Expected the AI's reply to store its position and think time, got %v and %v", played.Position, played.ThinkTimeMs)
+		}
+	}
+}
+
+func TestCreateGame_RejectsInvalidConfig(t *testing.T) {
+	s := newTestGameService(t)
+	configs := map[string]GameConfig{
+		"first player":        {Player1Piece: "X", Player2Piece: "O", FirstPlayerID: 3},
+		"variant":             {Player1Piece: "X", Player2Piece: "O", Variant: "4x4"},
+		"negative clock":      {Player1Piece: "X", Player2Piece: "O", TimeControlSeconds: -1},
+		"increment unclocked": {Player1Piece: "X", Player2Piece: "O", TimeIncrementSeconds: 5},
+		"AI in a humans game": {Player1Piece: "X", Player2Piece: "O", AIPlayerID: 2},
+	}
+	for name, config := range configs {
+		gameType := GAME_TYPE_MINIMAX
+		if name == "AI in a humans game" {
+			gameType = GAME_TYPE_HUMANS
+		}
+		if _, _, err := s.CreateGame(context.Background(), t.Name(), gameType, config); err == nil {
+			t.Errorf("Expected an invalid %s to be rejected", name)
+		}
+	}
+}
+
+// Imports a game that is deleted with its moves when the test ends
+func importTestGame(t *testing.T, s *GameService, record string, creatorUUID *uuid.UUID) *Game {
+	t.Helper()
+	ctx := context.Background()
+	game, _, err := s.ImportGame(ctx, record, creatorUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, err := s.db.Exec(ctx, "DELETE FROM move_event WHERE game_uuid = $1", game.Uuid); err != nil {
+			t.Error(err)
+		}
+		if err := s.repo.DeleteGame(ctx, game.Uuid); err != nil {
+			t.Error(err)
+		}
+	})
+	return game
+}
+
+func TestExportImportGame_RoundTrip(t *testing.T) {
+	s := newTestGameService(t)
+	ctx := context.Background()
+	game := createTestGame(t, s, GAME_TYPE_MINIMAX)
+	// Minimax answers a corner with the center, so the opposite corner stays available
+	for _, position := range []uint8{1, 9} {
+		if _, _, err := s.PlayMMMove(ctx, game.Uuid, 1, position, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	exported, err := s.ExportGame(ctx, game.Uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported := importTestGame(t, s, exported, nil)
+
+	reexported, err := s.ExportGame(ctx, imported.Uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reexported != exported {
+		t.Errorf("Expected the imported game to export the same record, got\n%s\nand\n%s", exported, reexported)
+	}
+}
+
+func TestImportGame_IgnoresModelTag(t *testing.T) {
+	s := newTestGameService(t)
+	for gameType, want := range map[string]string{GAME_TYPE_NN: s.currentModel().checksum, GAME_TYPE_MINIMAX: ""} {
+		record := fmt.Sprintf("[GameType %q]\n[Model \"forged\"]\n\n1. b2 *\n", gameType)
+		game := importTestGame(t, s, record, nil)
+		if game.ModelID != want {
+			t.Errorf("Expected %s game to have model ID %q, got %q", gameType, want, game.ModelID)
+		}
+	}
+}
+
+func TestImportGame_RejectsIllegalMoves(t *testing.T) {
+	s := newTestGameService(t)
+	record := "[GameType \"minimax\"]\n\n1. b2 b2 *\n"
+	if _, _, err := s.ImportGame(context.Background(), record, nil); !errors.Is(err, ErrInvalidGameRecord) {
+		t.Errorf("Expected ErrInvalidGameRecord, got %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"t-cubed/internal/repository"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	IDEMPOTENCY_KEY_TTL              = 24 * time.Hour
+	IDEMPOTENCY_KEY_LOCK_TIMEOUT     = time.Minute // After which a request still in progress loses its key
+	IDEMPOTENCY_KEY_MAX_LENGTH       = 255
+	IDEMPOTENCY_KEY_CLEANUP_INTERVAL = time.Hour
+)
+
+var (
+	ErrIdempotencyKeyInProgress = errors.New("a request with this Idempotency-Key is still in progress")
+	ErrIdempotencyKeyReused     = errors.New("Idempotency-Key was already used for a different request")
+)
+
+// Stores responses to requests sent with an Idempotency-Key, so retries are answered without running them again
+type IdempotencyService struct {
+	repo        *repository.Queries
+	ttl         time.Duration
+	lockTimeout time.Duration
+}
+
+// A response saved for replaying
+type StoredResponse struct {
+	StatusCode int
+	Headers    map[string]string
+	Body       []byte
+}
+
+func NewIdempotencyService(db *pgxpool.Pool, ttl time.Duration) *IdempotencyService {
+	return &IdempotencyService{
+		repo:        repository.New(db),
+		ttl:         ttl,
+		lockTimeout: IDEMPOTENCY_KEY_LOCK_TIMEOUT,
+	}
+}
+
+// Claims key for a request to route. Returns nil if the caller should run the request and then Complete or
+// Release the key, or the stored response if the request already ran. A key whose request has not finished within
+// the lock timeout, e.g. because the server crashed, is taken over.
+func (s *IdempotencyService) Begin(ctx context.Context, key string, route string, requestHash []byte) (*StoredResponse, error) {
+	// The existing key can expire or be released between the two queries, so try again once
+	for range 2 {
+		now := time.Now()
+		_, err := s.repo.ReserveIdempotencyKey(ctx, repository.ReserveIdempotencyKeyParams{
+			Key:         key,
+			Route:       route,
+			RequestHash: requestHash,
+			ExpiresAt:   now.Add(s.ttl),
+			LockedUntil: now.Add(s.lockTimeout),
+		})
+		if err == nil {
+			return nil, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			slog.Error("Could not reserve idempotency key", "error", err)
+			return nil, err
+		}
+
+		existing, err := s.repo.GetIdempotencyKey(ctx, repository.GetIdempotencyKeyParams{Key: key, Route: route})
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			slog.Error("Could not get idempotency key", "error", err)
+			return nil, err
+		}
+
+		if !bytes.Equal(existing.RequestHash, requestHash) {
+			return nil, ErrIdempotencyKeyReused
+		}
+		if existing.StatusCode == 0 {
+			return nil, ErrIdempotencyKeyInProgress
+		}
+		response := &StoredResponse{
+			StatusCode: int(existing.StatusCode),
+			Body:       existing.ResponseBody,
+		}
+		if existing.ResponseHeaders != nil {
+			if err := json.Unmarshal(existing.ResponseHeaders, &response.Headers); err != nil {
+				return nil, err
+			}
+		}
+		return response, nil
+	}
+	return nil, ErrIdempotencyKeyInProgress
+}
+
+// Saves the response of a request claimed with Begin
+func (s *IdempotencyService) Complete(ctx context.Context, key string, route string, response *StoredResponse) error {
+	headers, err := json.Marshal(response.Headers)
+	if err != nil {
+		return err
+	}
+	err = s.repo.CompleteIdempotencyKey(ctx, repository.CompleteIdempotencyKeyParams{
+		StatusCode:      int16(response.StatusCode),
+		ResponseHeaders: headers,
+		ResponseBody:    response.Body,
+		Key:             key,
+		Route:           route,
+	})
+	if err != nil {
+		slog.Error("Could not save idempotent response", "error", err)
+	}
+	return err
+}
+
+// Frees a key claimed with Begin without saving a response, so the request can be retried
+func (s *IdempotencyService) Release(ctx context.Context, key string, route string) error {
+	err := s.repo.DeleteIdempotencyKey(ctx, repository.DeleteIdempotencyKeyParams{Key: key, Route: route})
+	if err != nil {
+		slog.Error("Could not release idempotency key", "error", err)
+	}
+	return err
+}
+
+// Deletes expired keys every interval until ctx is done
+func (s *IdempotencyService) CleanupExpired(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := s.repo.DeleteExpiredIdempotencyKeys(ctx)
+			if err != nil {
+				slog.Warn("Could not delete expired idempotency keys", "error", err)
+				continue
+			}
+			if deleted > 0 {
+				slog.Info("Deleted expired idempotency keys", "count", deleted)
+			}
+		}
+	}
+}
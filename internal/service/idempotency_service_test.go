@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"t-cubed/internal/repository"
+)
+
+// Returns a service and a route that is cleaned up when the test ends
+func newTestIdempotencyService(t *testing.T, ttl time.Duration) (*IdempotencyService, string) {
+	t.Helper()
+	s := NewIdempotencyService(newTestPool(t), ttl)
+	route := "POST /test/" + t.Name()
+	t.Cleanup(func() {
+		for _, key := range []string{"key-1", "key-2"} {
+			s.repo.DeleteIdempotencyKey(context.Background(), repository.DeleteIdempotencyKeyParams{Key: key, Route: route})
+		}
+	})
+	return s, route
+}
+
+func TestIdempotencyService_ReplaysCompletedResponse(t *testing.T) {
+	s, route := newTestIdempotencyService(t, time.Hour)
+	ctx := context.Background()
+	hash := []byte("request")
+
+	stored, err := s.Begin(ctx, "key-1", route, hash)
+	if err != nil || stored != nil {
+		t.Fatalf("Expected the first request to run, got %v, %v", stored, err)
+	}
+	if _, err := s.Begin(ctx, "key-1", route, hash); !errors.Is(err, ErrIdempotencyKeyInProgress) {
+		t.Fatalf("Expected ErrIdempotencyKeyInProgress while the request runs, got %v", err)
+	}
+
+	response := &StoredResponse{
+		StatusCode: 200,
+		Headers:    map[string]string{"ETag": `"1"`},
+		Body:       []byte(`{"ok":true}`),
+	}
+	if err := s.Complete(ctx, "key-1", route, response); err != nil {
+		t.Fatal(err)
+	}
+	stored, err = s.Begin(ctx, "key-1", route, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(stored, response) {
+		t.Errorf("Expected the stored response %+v, got %+v", response, stored)
+	}
+
+	// Keys are scoped to their route
+	stored, err = s.Begin(ctx, "key-1", route+"/other", hash)
+	if err != nil || stored != nil {
+		t.Fatalf("Expected the key to be unused on another route, got %v, %v", stored, err)
+	}
+	s.Release(ctx, "key-1", route+"/other")
+}
+
+func TestIdempotencyService_RejectsReusedKey(t *testing.T) {
+	s, route := newTestIdempotencyService(t, time.Hour)
+	ctx := context.Background()
+
+	if _, err := s.Begin(ctx, "key-1", route, []byte("request")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Begin(ctx, "key-1", route, []byte("other request")); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Errorf("Expected ErrIdempotencyKeyReused, got %v", err)
+	}
+}
+
+func TestIdempotencyService_ReleasedKeyRunsAgain(t *testing.T) {
+	s, route := newTestIdempotencyService(t, time.Hour)
+	ctx := context.Background()
+	hash := []byte("request")
+
+	if _, err := s.Begin(ctx, "key-1", route, hash); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Release(ctx, "key-1", route); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := s.Begin(ctx, "key-1", route, hash)
+	if err != nil || stored != nil {
+		t.Errorf("Expected a released key to run again, got %v, %v", stored, err)
+	}
+}
+
+func TestIdempotencyService_ExpiredKeyRunsAgain(t *testing.T) {
+	s, route := newTestIdempotencyService(t, time.Millisecond)
+	ctx := context.Background()
+
+	if _, err := s.Begin(ctx, "key-1", route, []byte("request")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Complete(ctx, "key-1", route, &StoredResponse{StatusCode: 200}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Begin(ctx, "key-2", route, []byte("request")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	// An expired key can be reused, even for a different request
+	stored, err := s.Begin(ctx, "key-1", route, []byte("other request"))
+	if err != nil || stored != nil {
+		t.Fatalf("Expected an expired key to run again, got %v, %v", stored, err)
+	}
+
+	deleted, err := s.repo.DeleteExpiredIdempotencyKeys(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted < 1 {
+		t.Errorf("Expected the expired key-2 to be deleted")
+	}
+	if _, err := s.repo.GetIdempotencyKey(ctx, repository.GetIdempotencyKeyParams{Key: "key-2", Route: route}); err == nil {
+		t.Errorf("Expected key-2 to be deleted")
+	}
+}
+
+func TestIdempotencyService_StuckKeyIsTakenOver(t *testing.T) {
+	s, route := newTestIdempotencyService(t, time.Hour)
+	s.lockTimeout = time.Millisecond
+	ctx := context.Background()
+	hash := []byte("request")
+
+	// key-1's request never finishes, key-2's does
+	if _, err := s.Begin(ctx, "key-1", route, hash); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Begin(ctx, "key-2", route, hash); err != nil {
+		t.Fatal(err)
+	}
+	response := &StoredResponse{StatusCode: 200, Body: []byte(`{"ok":true}`)}
+	if err := s.Complete(ctx, "key-2", route, response); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	stored, err := s.Begin(ctx, "key-1", route, hash)
+	if err != nil || stored != nil {
+		t.Fatalf("Expected a key stuck in progress to run again, got %v, %v", stored, err)
+	}
+	stored, err = s.Begin(ctx, "key-2", route, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored == nil || stored.StatusCode != 200 {
+		t.Errorf("Expected the completed key to keep its response past the lock timeout, got %+v", stored)
+	}
+}
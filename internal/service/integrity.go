@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"t-cubed/internal/engine"
+	"t-cubed/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Result of replaying a game's move events with the engine
+type GameVerification struct {
+	Game                  *Game
+	Replay                *engine.Replay // Game up to the last valid move event, nil if there is none
+	LastValidMoveSequence int16          // -1 if not even the blank board is valid
+	MoveEventCount        int
+	Problems              []string
+}
+
+func (v *GameVerification) OK() bool {
+	return len(v.Problems) == 0
+}
+
+// Returned when a game read from the database does not replay correctly
+type CorruptGameError struct {
+	Verification *GameVerification
+}
+
+func (e *CorruptGameError) Error() string {
+	return fmt.Sprintf("game %s is corrupted: %s", e.Verification.Game.Uuid, strings.Join(e.Verification.Problems, "; "))
+}
+
+// Replays a game's move events, which must be ordered by move sequence. Checks that the sequence has no gaps, that
+// each board follows from the previous one by a legal move of the recorded player and position, and that the game's
+// terminal state matches the last valid board.
+func VerifyGameEvents(game *Game, moveEvents []MoveEvent) *GameVerification {
+	v := &GameVerification{Game: game, LastValidMoveSequence: -1, MoveEventCount: len(moveEvents)}
+	problem := func(format string, args ...any) {
+		v.Problems = append(v.Problems, fmt.Sprintf(format, args...))
+	}
+	if !isValidGamePice(game.Player1Piece) || !isValidGamePice(game.Player2Piece) || !isValidPlayerID(game.FirstPlayerID) {
+		problem("invalid game config")
+		return v
+	}
+
+	// Only the events before the first problem are valid
+	valid := len(moveEvents)
+	for i, moveEvent := range moveEvents {
+		if moveEvent.MoveSequence != int16(i) {
+			problem("move %d is missing", i)
+			valid = i
+			break
+		}
+	}
+	boardStates := make([][]byte, valid)
+	for i := range boardStates {
+		boardStates[i] = moveEvents[i].PostMoveState
+	}
+	replay, err := engine.ReplayBoards(gameStateOptions(game), boardStates)
+	var replayErr *engine.ReplayError
+	switch {
+	case errors.As(err, &replayErr):
+		problem("%s", replayErr)
+		valid = replayErr.Move
+	case err != nil:
+		problem("%s", err)
+		return v
+	}
+
+	// The blank board is saved for the player opposite the first player, without a position
+	for i := 0; i < valid; i++ {
+		expected := getNextPlayerID(game.FirstPlayerID)
+		if i > 0 {
+			expected = int16(replay.Moves[i-1].PlayerId)
+		}
+		if moveEvents[i].PlayerID != expected {
+			problem("move %d is recorded for player %d but was played by player %d", i, moveEvents[i].PlayerID, expected)
+			valid = i
+			break
+		}
+		position := moveEvents[i].Position
+		if i == 0 && position.Valid {
+			problem("move 0 is recorded at position %d but is the blank board", position.Int16)
+			valid = i
+			break
+		}
+		if i > 0 && position.Valid && uint8(position.Int16) != replay.Moves[i-1].Position {
+			problem("move %d is recorded at position %d but was played at %d", i, position.Int16, replay.Moves[i-1].Position)
+			valid = i
+			break
+		}
+	}
+	if valid == 0 {
+		return v
+	}
+
+	// Replay again if a later event was invalid, so the replay stops at the last valid board
+	if valid < len(boardStates) {
+		replay, _ = engine.ReplayBoards(gameStateOptions(game), boardStates[:valid])
+	}
+	v.Replay = replay
+	v.LastValidMoveSequence = int16(valid - 1)
+	if terminalState := int16(replay.GameState.TerminalState); game.TerminalState != terminalState {
+		problem("terminal state is %d but the board's is %d", game.TerminalState, terminalState)
+	}
+	return v
+}
+
+// Loads and verifies a game's move events with repo
+func verifyGame(ctx context.Context, repo *repository.Queries, game *Game) (*GameVerification, error) {
+	moveEvents, err := repo.ListGameMoveEvents(ctx, game.Uuid)
+	if err != nil {
+		slog.Error("Could not get move events", "uuid", game.Uuid, "error", err)
+		return nil, err
+	}
+	return VerifyGameEvents(game, moveEvents), nil
+}
+
+// Checks games stored in the database and repairs corrupted ones
+type GameVerifier struct {
+	db   *pgxpool.Pool
+	repo *repository.Queries
+}
+
+func NewGameVerifier(db *pgxpool.Pool) *GameVerifier {
+	return &GameVerifier{
+		db:   db,
+		repo: repository.New(db),
+	}
+}
+
+// Returns the UUIDs of all games, oldest first
+func (v *GameVerifier) ListGames(ctx context.Context) ([]uuid.UUID, error) {
+	return v.repo.ListGameUUIDs(ctx)
+}
+
+func (v *GameVerifier) Verify(ctx context.Context, gameUuid uuid.UUID) (*GameVerification, error) {
+	var verification *GameVerification
+	err := inSnapshot(ctx, v.db, func(repo *repository.Queries) error {
+		gameData, err := repo.GetGameByUUID(ctx, gameUuid)
+		if err != nil {
+			return err
+		}
+		verification, err = verifyGame(ctx, repo, &gameData.Game)
+		return err
+	})
+	return verification, err
+}
+
+// Deletes the move events after the game's last valid one and sets its terminal state from the remaining board. If
+// the terminal state changes, the game's rating changes are undone, so it is rated again when it is finished. The game
+// is locked while it is verified and repaired. Returns the verification from before the repair.
+func (v *GameVerifier) Repair(ctx context.Context, gameUuid uuid.UUID) (*GameVerification, error) {
+	var verification *GameVerification
+	err := inTx(ctx, v.db, func(repo *repository.Queries) error {
+		gameData, err := repo.GetGameByUUIDForUpdate(ctx, gameUuid)
+		if err != nil {
+			return err
+		}
+		game := &gameData.Game
+		verification, err = verifyGame(ctx, repo, game)
+		if err != nil || verification.OK() {
+			return err
+		}
+		if verification.Replay == nil {
+			return errors.New("game has no valid moves to keep")
+		}
+
+		deleted, err := repo.DeleteMoveEventsAfter(ctx, repository.DeleteMoveEventsAfterParams{
+			GameUuid:     game.Uuid,
+			MoveSequence: verification.LastValidMoveSequence,
+		})
+		if err != nil {
+			return err
+		}
+		terminalState := int16(verification.Replay.GameState.TerminalState)
+		_, err = repo.UpdateGame(ctx, repository.UpdateGameParams{
+			Name:          game.Name,
+			TerminalState: terminalState,
+			Uuid:          game.Uuid,
+		})
+		if err != nil {
+			return err
+		}
+		var undoneRatings int64
+		if terminalState != game.TerminalState {
+			undoneRatings, err = repo.UndoGameRatings(ctx, game.Uuid)
+			if err != nil {
+				return err
+			}
+		}
+		slog.Info("Repaired game", "uuid", game.Uuid, "deleted_move_events", deleted, "undone_ratings", undoneRatings, "problems", verification.Problems)
+		return nil
+	})
+	return verification, err
+}
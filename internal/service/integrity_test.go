@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"t-cubed/internal/engine"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Returns move events for the boards, with player IDs alternating from the player opposite firstPlayerID
+func testMoveEvents(firstPlayerID int16, boards ...[]byte) []MoveEvent {
+	moveEvents := make([]MoveEvent, len(boards))
+	playerID := getNextPlayerID(firstPlayerID)
+	for i, board := range boards {
+		moveEvents[i] = MoveEvent{MoveSequence: int16(i), PlayerID: playerID, PostMoveState: board}
+		playerID = getNextPlayerID(playerID)
+	}
+	return moveEvents
+}
+
+func testGame(terminalState int16) *Game {
+	return &Game{Player1Piece: "X", Player2Piece: "O", FirstPlayerID: 1, Variant: engine.VARIANT_STANDARD, TerminalState: terminalState}
+}
+
+func TestVerifyGameEvents(t *testing.T) {
+	blank := []byte{0, 0, 0, 0}
+	p1Center := []byte{0, 0x10, 0, 0}
+	p2Corner := []byte{0, 0x10, 0, 0x01}
+
+	verification := VerifyGameEvents(testGame(engine.TERM_NOT), testMoveEvents(1, blank, p1Center, p2Corner))
+	if !verification.OK() || verification.LastValidMoveSequence != 2 {
+		t.Errorf("Expected a valid game up to move 2, got move %d with problems %v", verification.LastValidMoveSequence, verification.Problems)
+	}
+
+	// A game in progress cannot be recorded as won
+	verification = VerifyGameEvents(testGame(engine.TERM_WIN_1), testMoveEvents(1, blank, p1Center))
+	if verification.OK() {
+		t.Error("Expected the terminal state to be reported")
+	}
+
+	// Player 2's piece appears before Player 1 has moved
+	verification = VerifyGameEvents(testGame(engine.TERM_NOT), testMoveEvents(1, blank, p2Corner, p1Center))
+	if verification.OK() || verification.LastValidMoveSequence != 0 {
+		t.Errorf("Expected only the blank board to be valid, got move %d", verification.LastValidMoveSequence)
+	}
+
+	// The player ID saved with a move does not match the player who made it
+	moveEvents := testMoveEvents(1, blank, p1Center, p2Corner)
+	moveEvents[2].PlayerID = 1
+	verification = VerifyGameEvents(testGame(engine.TERM_NOT), moveEvents)
+	if verification.OK() || verification.LastValidMoveSequence != 1 {
+		t.Errorf("Expected moves up to 1 to be valid, got move %d", verification.LastValidMoveSequence)
+	}
+
+	// The stored position does not match the boards
+	moveEvents = testMoveEvents(1, blank, p1Center, p2Corner)
+	moveEvents[1].Position = pgtype.Int2{Int16: 5, Valid: true}
+	moveEvents[2].Position = pgtype.Int2{Int16: 3, Valid: true}
+	verification = VerifyGameEvents(testGame(engine.TERM_NOT), moveEvents)
+	if verification.OK() || verification.LastValidMoveSequence != 1 {
+		t.Errorf("Expected moves up to 1 to be valid, got move %d", verification.LastValidMoveSequence)
+	}
+
+	// A move sequence is missing
+	moveEvents = testMoveEvents(1, blank, p1Center, p2Corner)
+	moveEvents = append(moveEvents[:1], moveEvents[2:]...)
+	verification = VerifyGameEvents(testGame(engine.TERM_NOT), moveEvents)
+	if verification.OK() || verification.LastValidMoveSequence != 0 {
+		t.Errorf("Expected only the blank board to be valid, got move %d", verification.LastValidMoveSequence)
+	}
+}
+
+func TestGameVerifier_RepairsCorruptedGame(t *testing.T) {
+	s := newTestGameService(t)
+	ctx := context.Background()
+	game := createTestGame(t, s, GAME_TYPE_MINIMAX)
+	if _, _, err := s.PlayMMMove(ctx, game.Uuid, 1, 5, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Move 2 claims the square Player 1 already took
+	if _, err := s.db.Exec(ctx, "UPDATE move_event SET post_move_state = '\\x00100010'::bytea WHERE game_uuid = $1 AND move_sequence = 2", game.Uuid); err != nil {
+		t.Fatal(err)
+	}
+	var corruptErr *CorruptGameError
+	if _, _, err := s.GetGame(ctx, game.Uuid); !errors.As(err, &corruptErr) {
+		t.Fatalf("Expected a CorruptGameError, got %v", err)
+	}
+
+	verification, err := NewGameVerifier(s.db).Repair(ctx, game.Uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verification.LastValidMoveSequence != 1 {
+		t.Errorf("Expected moves up to 1 to be kept, got %d", verification.LastValidMoveSequence)
+	}
+	_, moveEvent, err := s.GetGame(ctx, game.Uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moveEvent.MoveSequence != 1 {
+		t.Errorf("Expected the game to be back at move 1, got move %d", moveEvent.MoveSequence)
+	}
+}
+
+func TestGetGame_ConcurrentMovesAreNotCorrupt(t *testing.T) {
+	s := newTestGameService(t)
+	ctx := context.Background()
+	game := createTestGame(t, s, GAME_TYPE_MINIMAX)
+
+	// Reads the game while it is played, until the game is over
+	done := make(chan struct{})
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(readErr)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if _, _, err := s.GetGame(ctx, game.Uuid); err != nil {
+				readErr <- err
+				return
+			}
+		}
+	}()
+	playOutMMGame(t, s, game)
+	close(done)
+	if err := <-readErr; err != nil {
+		t.Fatalf("Expected every read during the game to succeed, got %v", err)
+	}
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"t-cubed/internal/ai"
+	"t-cubed/internal/engine"
+	"t-cubed/internal/repository"
+)
+
+const (
+	NN_WEIGHTS_FILE      = "data/weights.json"
+	NN_MAX_LOADED_MODELS = 8 // Models kept loaded for the games started with them, including the served one
+)
+
+// An immutable snapshot of a loaded neural network and the raw weights it was built from.
+// A new snapshot is created on every reload, so readers holding an old one are unaffected.
+type nnModel struct {
+	network  *ai.Network
+	checksum string // network.Checksum(), computed once on load
+	weights  json.RawMessage
+	etag     string
+	modTime  time.Time
+
+	workspaces sync.Pool // *ai.Workspace for network, so moves do not allocate a forward pass each
+}
+
+// Reads, validates and builds a model snapshot from a weights file
+func loadNNModel(fpath string) (*nnModel, error) {
+	fpath = filepath.Clean(fpath)
+	info, err := os.Stat(fpath)
+	if err != nil {
+		return nil, err
+	}
+	weightBytes, err := os.ReadFile(fpath)
+	if err != nil {
+		return nil, err
+	}
+
+	network, err := ai.ParseNetwork(weightBytes)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateNNModel(network); err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256(weightBytes)
+	model := &nnModel{
+		network:  network,
+		checksum: network.Checksum(),
+		weights:  json.RawMessage(weightBytes),
+		etag:     `"` + hex.EncodeToString(hash[:]) + `"`,
+		modTime:  info.ModTime(),
+	}
+	model.workspaces.New = func() any {
+		return network.NewWorkspace()
+	}
+	return model, nil
+}
+
+// Runs the network on input with a pooled workspace, writing the move probabilities into policy.
+// Returns the value head's expected outcome, nil without a value head. The forward pass is recorded in trace,
+// and a detailed trace falls back to the network's own forward pass, which records the details.
+func (m *nnModel) evaluate(input []float64, policy []float64, trace *ai.ForwardTrace) (*float64, error) {
+	detailed := trace.Level >= ai.TRACE_LEVEL_DETAILED
+	hasValueHead := m.network.HasValueHead()
+	var workspace *ai.Workspace
+	if !detailed {
+		workspace = m.workspaces.Get().(*ai.Workspace)
+		defer m.workspaces.Put(workspace)
+	}
+
+	var output []float64
+	var value float64
+	var err error
+	switch {
+	case detailed && hasValueHead:
+		output, value, err = m.network.Evaluate(input, trace)
+	case detailed:
+		output, err = m.network.Forward(input, trace)
+	case hasValueHead:
+		output, value, err = workspace.Evaluate(input)
+	default:
+		output, err = workspace.Forward(input)
+	}
+	if err != nil {
+		return nil, err
+	}
+	copy(policy, output)
+	if !detailed {
+		workspace.TraceOutputs(input, trace)
+	}
+	if !hasValueHead {
+		return nil, nil
+	}
+	return &value, nil
+}
+
+// Checks the network against the engine's board encoding and runs a smoke evaluation
+// on an empty board so a broken network is rejected before it can be served
+func validateNNModel(network *ai.Network) error {
+	if err := network.ValidateForGame(); err != nil {
+		return err
+	}
+
+	// Smoke evaluation on an empty board
+	gameState, err := engine.NewGameState(&engine.GameStateOptions{
+		Player1Piece:  engine.PIECE_X,
+		Player2Piece:  engine.PIECE_O,
+		FirstPlayerId: 2,
+	})
+	if err != nil {
+		return err
+	}
+	output, err := network.Forward(gameState.GetBoardAsNetworkInput(), nil)
+	if err != nil {
+		return fmt.Errorf("smoke evaluation failed: %w", err)
+	}
+	sum := 0.0
+	for _, v := range output {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return errors.New("smoke evaluation produced a non-finite output")
+		}
+		sum += v
+	}
+	if math.Abs(sum-1) > 1e-6 {
+		return fmt.Errorf("smoke evaluation output does not sum to 1 (%f)", sum)
+	}
+	return nil
+}
+
+// Returns the model currently being served
+func (s *GameService) currentModel() *nnModel {
+	return s.model.Load()
+}
+
+// Serves model for new games, and keeps it loaded for the games started with it. Returns the previous model.
+// Only the NN_MAX_LOADED_MODELS most recently used models stay loaded. Games of an unloaded model switch to the
+// current one, see gameModel.
+func (s *GameService) serveModel(model *nnModel) *nnModel {
+	s.modelsMu.Lock()
+	if s.models == nil {
+		s.models = make(map[string]*nnModel)
+	}
+	s.models[model.checksum] = model
+	s.markModelUsed(model.checksum)
+	for len(s.modelsByUse) > NN_MAX_LOADED_MODELS {
+		slog.Info("Unloaded neural network", "checksum", s.modelsByUse[0])
+		delete(s.models, s.modelsByUse[0])
+		s.modelsByUse = s.modelsByUse[1:]
+	}
+	s.modelsMu.Unlock()
+	return s.model.Swap(model)
+}
+
+// Moves checksum to the end of modelsByUse. modelsMu must be held.
+func (s *GameService) markModelUsed(checksum string) {
+	s.modelsByUse = slices.DeleteFunc(s.modelsByUse, func(c string) bool { return c == checksum })
+	s.modelsByUse = append(s.modelsByUse, checksum)
+}
+
+// Returns the loaded model with the checksum, or nil if it is not loaded
+func (s *GameService) loadedModel(checksum string) *nnModel {
+	s.modelsMu.Lock()
+	defer s.modelsMu.Unlock()
+	model := s.models[checksum]
+	if model != nil {
+		s.markModelUsed(checksum)
+	}
+	return model
+}
+
+// Unloads the model with the checksum, as if it was evicted or the server restarted
+func (s *GameService) unloadModel(checksum string) {
+	s.modelsMu.Lock()
+	defer s.modelsMu.Unlock()
+	delete(s.models, checksum)
+	s.modelsByUse = slices.DeleteFunc(s.modelsByUse, func(c string) bool { return c == checksum })
+}
+
+// Returns the model the game was started with, with repo's transaction. A game whose model is no longer loaded,
+// e.g. after a restart, is switched to the current model, so its result is credited to the model that finishes it.
+func (s *GameService) gameModel(ctx context.Context, repo *repository.Queries, game *Game) (*nnModel, error) {
+	if model := s.loadedModel(game.ModelID); model != nil {
+		return model, nil
+	}
+
+	model := s.currentModel()
+	updatedGame, err := repo.UpdateGameModel(ctx, repository.UpdateGameModelParams{
+		ModelID: model.checksum,
+		Uuid:    game.Uuid,
+	})
+	if err != nil {
+		slog.Error("Could not update game model", "uuid", game.Uuid, "error", err)
+		return nil, err
+	}
+	slog.Warn("Game's model is not loaded, switched it to the current model", "uuid", game.Uuid, "model_id", game.ModelID, "checksum", model.checksum)
+	*game = updatedGame
+	return model, nil
+}
+
+// Reloads the neural network from the weights file and swaps it in atomically.
+// The current model keeps serving if the new weights fail validation. Games already started keep their model.
+func (s *GameService) ReloadWeights() error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	model, err := loadNNModel(s.weightsFile)
+	if err != nil {
+		slog.Error("Could not reload neural network", "weights_file", s.weightsFile, "error", err)
+		return err
+	}
+
+	previous := s.serveModel(model)
+	if previous != nil && previous.etag == model.etag {
+		slog.Info("Reloaded neural network (unchanged)", "weights_file", s.weightsFile, "checksum", model.checksum)
+		return nil
+	}
+	slog.Info("Reloaded neural network", "weights_file", s.weightsFile, "checksum", model.checksum)
+	return nil
+}
+
+// Polls the weights file for changes and reloads the network when it is modified.
+// Blocks until ctx is cancelled.
+func (s *GameService) WatchWeights(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	slog.Info("Watching neural network weights file", "weights_file", s.weightsFile, "interval", interval.String())
+
+	// Track the last seen modification time so a bad file is only attempted once
+	lastModTime := s.currentModel().modTime
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(s.weightsFile)
+			if err != nil {
+				slog.Warn("Could not stat neural network weights file", "weights_file", s.weightsFile, "error", err)
+				continue
+			}
+			if info.ModTime().Equal(lastModTime) {
+				continue
+			}
+			lastModTime = info.ModTime()
+			// Errors are logged by ReloadWeights, and the old model keeps serving
+			_ = s.ReloadWeights()
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"t-cubed/internal/ai"
+	"t-cubed/internal/engine"
+)
+
+func loadTestNNModel(tb testing.TB) *nnModel {
+	tb.Helper()
+	model, err := loadNNModel(filepath.Join("..", "..", NN_WEIGHTS_FILE))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return model
+}
+
+// Mid-game board from the AI's (Player 2's) point of view
+func testNetworkInput(tb testing.TB) []float64 {
+	tb.Helper()
+	gameState, err := engine.NewGameState(&engine.GameStateOptions{
+		Player1Piece:  engine.PIECE_X,
+		Player2Piece:  engine.PIECE_O,
+		FirstPlayerId: 1,
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	for _, position := range []uint8{5, 1, 9} {
+		if ok, err := gameState.Move(position); !ok || err != nil {
+			tb.Fatalf("Could not play %d: %v", position, err)
+		}
+	}
+	return gameState.GetBoardAsNetworkInputFor(2)
+}
+
+func TestNNModelEvaluate_MatchesNetwork(t *testing.T) {
+	model := loadTestNNModel(t)
+	input := testNetworkInput(t)
+
+	for _, level := range []ai.TraceLevel{ai.TRACE_LEVEL_OUTPUTS, ai.TRACE_LEVEL_DETAILED} {
+		want := ai.NewForwardTrace(level, 0)
+		wantPolicy, err := model.network.Forward(input, want)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		trace := ai.NewForwardTrace(level, 0)
+		policy := make([]float64, engine.NETWORK_OUTPUT_LEN)
+		value, err := model.evaluate(input, policy, trace)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (value != nil) != model.network.HasValueHead() {
+			t.Errorf("Expected a value only with a value head, got %v", value)
+		}
+		for i := range wantPolicy {
+			if diff := policy[i] - wantPolicy[i]; diff > 1e-12 || diff < -1e-12 {
+				t.Errorf("Level %d: policy[%d] = %v, want %v", level, i, policy[i], wantPolicy[i])
+			}
+		}
+		if len(trace.LayerOutputs) != len(want.LayerOutputs) || (trace.Logits != nil) != (want.Logits != nil) {
+			t.Errorf("Level %d: expected the trace to match the network's, got %d layers and logits %v", level, len(trace.LayerOutputs), trace.Logits)
+		}
+	}
+}
+
+// The server's path for choosing a neural network move, with traces at the default level.
+// The remaining allocations are the trace's copies of the layer outputs, which are saved with the move.
+func BenchmarkNNModelEvaluate(b *testing.B) {
+	model := loadTestNNModel(b)
+	input := testNetworkInput(b)
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		policy := make([]float64, engine.NETWORK_OUTPUT_LEN)
+		for pb.Next() {
+			if _, err := model.evaluate(input, policy, ai.NewForwardTrace(ai.TRACE_LEVEL_OUTPUTS, 0)); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+// The allocating forward pass the server used before workspaces were pooled, for comparison
+func BenchmarkNNModelEvaluate_Network(b *testing.B) {
+	model := loadTestNNModel(b)
+	input := testNetworkInput(b)
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := model.network.Forward(input, ai.NewForwardTrace(ai.TRACE_LEVEL_OUTPUTS, 0)); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+func TestServeModel_UnloadsLeastRecentlyUsedModel(t *testing.T) {
+	s := &GameService{}
+	for i := range NN_MAX_LOADED_MODELS {
+		s.serveModel(&nnModel{checksum: fmt.Sprint(i)})
+	}
+
+	// A game still playing the first model keeps it loaded, so the second one is unloaded
+	if s.loadedModel("0") == nil {
+		t.Fatal("Expected the first model to be loaded")
+	}
+	s.serveModel(&nnModel{checksum: "new"})
+	if s.loadedModel("1") != nil {
+		t.Error("Expected the least recently used model to be unloaded")
+	}
+	if s.loadedModel("0") == nil || s.loadedModel("new") == nil {
+		t.Error("Expected the recently used and the served model to stay loaded")
+	}
+	if len(s.models) != NN_MAX_LOADED_MODELS {
+		t.Errorf("Expected %d loaded models, got %d", NN_MAX_LOADED_MODELS, len(s.models))
+	}
+}
@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"t-cubed/internal/engine"
+	"t-cubed/internal/glicko"
+	"t-cubed/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Kinds of players on the leaderboard
+const (
+	RATING_KIND_ALL     = "all"
+	RATING_KIND_ACCOUNT = "account"
+	RATING_KIND_AI      = "ai"
+)
+
+const (
+	LEADERBOARD_DEFAULT_LIMIT = 50
+	LEADERBOARD_MAX_LIMIT     = 200
+)
+
+var (
+	ErrInvalidRatingKind = errors.New("rating kind must be all, account or ai")
+	ErrRatingNotFound    = errors.New("rating not found")
+)
+
+type Rating = repository.Rating
+type RatingHistory = repository.RatingHistory
+
+// A rating and the name its player is shown as: the account's username or the AI's identity
+type RatedPlayer struct {
+	Rating *Rating
+	Name   string
+}
+
+// A rated game of a player, with the player's rating after it
+type RatedGame struct {
+	History      *RatingHistory
+	OpponentName string
+}
+
+// Reads the Glicko-2 ratings of accounts and AI opponents. Ratings are updated by the game service as games finish.
+type RatingService struct {
+	repo *repository.Queries
+}
+
+func NewRatingService(db *pgxpool.Pool) *RatingService {
+	return &RatingService{
+		repo: repository.New(db),
+	}
+}
+
+func ratedPlayerName(username pgtype.Text, aiIdentity pgtype.Text) string {
+	if username.Valid {
+		return username.String
+	}
+	return aiIdentity.String
+}
+
+// Returns the players of a kind with at least one rated game, best first
+func (s *RatingService) Leaderboard(ctx context.Context, kind string, limit int32, offset int32) ([]RatedPlayer, error) {
+	if kind != RATING_KIND_ALL && kind != RATING_KIND_ACCOUNT && kind != RATING_KIND_AI {
+		return nil, ErrInvalidRatingKind
+	}
+	rows, err := s.repo.ListRatings(ctx, repository.ListRatingsParams{
+		Kind:      kind,
+		RowLimit:  limit,
+		RowOffset: offset,
+	})
+	if err != nil {
+		slog.Error("Could not list ratings", "error", err)
+		return nil, err
+	}
+	players := make([]RatedPlayer, len(rows))
+	for i := range rows {
+		players[i] = RatedPlayer{Rating: &rows[i].Rating, Name: ratedPlayerName(rows[i].Username, rows[i].Rating.AiIdentity)}
+	}
+	return players, nil
+}
+
+// Returns a rating and its rated games, oldest first
+func (s *RatingService) GetRatingHistory(ctx context.Context, ratingUuid uuid.UUID) (*RatedPlayer, []RatedGame, error) {
+	row, err := s.repo.GetRating(ctx, ratingUuid)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, ErrRatingNotFound
+	}
+	if err != nil {
+		slog.Error("Could not get rating", "uuid", ratingUuid, "error", err)
+		return nil, nil, err
+	}
+	historyRows, err := s.repo.ListRatingHistory(ctx, ratingUuid)
+	if err != nil {
+		slog.Error("Could not get rating history", "uuid", ratingUuid, "error", err)
+		return nil, nil, err
+	}
+
+	games := make([]RatedGame, len(historyRows))
+	for i := range historyRows {
+		games[i] = RatedGame{
+			History:      &historyRows[i].RatingHistory,
+			OpponentName: ratedPlayerName(historyRows[i].OpponentUsername, historyRows[i].OpponentAiIdentity),
+		}
+	}
+	return &RatedPlayer{Rating: &row.Rating, Name: ratedPlayerName(row.Username, row.Rating.AiIdentity)}, games, nil
+}
+
+// Returns the identity an AI opponent is rated as. Each neural network model is rated on its own.
+func aiIdentity(gameTypeLabel string, game *Game) string {
+	if gameTypeLabel == GAME_TYPE_NN && game.ModelID != "" {
+		return gameTypeLabel + ":" + game.ModelID
+	}
+	return gameTypeLabel
+}
+
+// Returns Player 1's and Player 2's scores for a finished game
+func ratingScores(terminalState int16) (float64, float64) {
+	switch terminalState {
+	case engine.TERM_WIN_1:
+		return glicko.SCORE_WIN, glicko.SCORE_LOSS
+	case engine.TERM_WIN_2:
+		return glicko.SCORE_LOSS, glicko.SCORE_WIN
+	default:
+		return glicko.SCORE_DRAW, glicko.SCORE_DRAW
+	}
+}
+
+// A player of a rated game: an account, or an AI identity if accountUuid is nil
+type ratedSeat struct {
+	accountUuid *uuid.UUID
+	aiIdentity  string
+}
+
+// Orders seats so their ratings are always locked in the same order
+func (r ratedSeat) lockKey() string {
+	if r.accountUuid != nil {
+		return "account:" + r.accountUuid.String()
+	}
+	return "ai:" + r.aiIdentity
+}
+
+func getOrCreateRating(ctx context.Context, repo *repository.Queries, seat ratedSeat) (Rating, error) {
+	if seat.accountUuid != nil {
+		return repo.GetOrCreateAccountRating(ctx, seat.accountUuid)
+	}
+	return repo.GetOrCreateAIRating(ctx, pgtype.Text{String: seat.aiIdentity, Valid: true})
+}
+
+// Updates the ratings of a finished game's players and records them in their rating history, with repo's
+// transaction. Unrated games, e.g. imported ones, games with an anonymous human player, and games with the same
+// account in both seats are not rated. A game that is already rated is not rated again.
+func rateGame(ctx context.Context, repo *repository.Queries, gameTypeLabel string, game *Game) error {
+	if !game.Rated || game.TerminalState == engine.TERM_NOT {
+		return nil
+	}
+	rated, err := repo.GameHasRatingHistory(ctx, game.Uuid)
+	if err != nil {
+		slog.Error("Could not check rating history", "uuid", game.Uuid, "error", err)
+		return err
+	}
+	if rated {
+		slog.Warn("Game is already rated", "uuid", game.Uuid)
+		return nil
+	}
+	var seats [2]ratedSeat
+	for i, accountUuid := range []*uuid.UUID{game.Player1Uuid, game.Player2Uuid} {
+		switch {
+		case int16(i+1) == game.AiPlayerID:
+			seats[i].aiIdentity = aiIdentity(gameTypeLabel, game)
+		case accountUuid != nil:
+			seats[i].accountUuid = accountUuid
+		default:
+			return nil
+		}
+	}
+	if seats[0].lockKey() == seats[1].lockKey() {
+		return nil
+	}
+
+	// Lock the ratings in a fixed order, so games finishing at the same time cannot deadlock
+	order := []int{0, 1}
+	if seats[1].lockKey() < seats[0].lockKey() {
+		order = []int{1, 0}
+	}
+	var ratings [2]Rating
+	for _, i := range order {
+		rating, err := getOrCreateRating(ctx, repo, seats[i])
+		if err != nil {
+			slog.Error("Could not get rating", "seat", seats[i].lockKey(), "error", err)
+			return err
+		}
+		ratings[i] = rating
+	}
+
+	score1, score2 := ratingScores(game.TerminalState)
+	scores := [2]float64{score1, score2}
+	for i, rating := range ratings {
+		opponent := ratings[1-i]
+		updated := glicko.Update(
+			glicko.Rating{Rating: rating.Rating, Deviation: rating.RatingDeviation, Volatility: rating.Volatility},
+			[]glicko.Result{{
+				Opponent: glicko.Rating{Rating: opponent.Rating, Deviation: opponent.RatingDeviation, Volatility: opponent.Volatility},
+				Score:    scores[i],
+			}},
+		)
+
+		updateRatingParams := repository.UpdateRatingParams{
+			Rating:          updated.Rating,
+			RatingDeviation: updated.Deviation,
+			Volatility:      updated.Volatility,
+			Games:           rating.Games + 1,
+			Wins:            rating.Wins,
+			Losses:          rating.Losses,
+			Draws:           rating.Draws,
+			Uuid:            rating.Uuid,
+		}
+		switch scores[i] {
+		case glicko.SCORE_WIN:
+			updateRatingParams.Wins++
+		case glicko.SCORE_LOSS:
+			updateRatingParams.Losses++
+		default:
+			updateRatingParams.Draws++
+		}
+		if _, err := repo.UpdateRating(ctx, updateRatingParams); err != nil {
+			slog.Error("Could not update rating", "uuid", rating.Uuid, "error", err)
+			return err
+		}
+
+		_, err = repo.CreateRatingHistory(ctx, repository.CreateRatingHistoryParams{
+			RatingUuid:         rating.Uuid,
+			GameUuid:           game.Uuid,
+			OpponentRatingUuid: opponent.Uuid,
+			Score:              scores[i],
+			Rating:             updated.Rating,
+			RatingDeviation:    updated.Deviation,
+			Volatility:         updated.Volatility,
+			RatingChange:       updated.Rating - rating.Rating,
+		})
+		if err != nil {
+			slog.Error("Could not create rating history", "uuid", rating.Uuid, "error", err)
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"t-cubed/internal/engine"
+	"t-cubed/internal/glicko"
+)
+
+func TestRatingScores(t *testing.T) {
+	tests := map[int16][2]float64{
+		engine.TERM_WIN_1: {glicko.SCORE_WIN, glicko.SCORE_LOSS},
+		engine.TERM_WIN_2: {glicko.SCORE_LOSS, glicko.SCORE_WIN},
+		engine.TERM_DRAW:  {glicko.SCORE_DRAW, glicko.SCORE_DRAW},
+	}
+	for terminalState, want := range tests {
+		if score1, score2 := ratingScores(terminalState); score1 != want[0] || score2 != want[1] {
+			t.Errorf("ratingScores(%d) = %v, %v, want %v", terminalState, score1, score2, want)
+		}
+	}
+}
+
+func TestAIIdentity(t *testing.T) {
+	game := &Game{ModelID: "abc123"}
+	if got := aiIdentity(GAME_TYPE_NN, game); got != "neural_network:abc123" {
+		t.Errorf("Expected each model to be rated on its own, got %q", got)
+	}
+	if got := aiIdentity(GAME_TYPE_MINIMAX, &Game{}); got != GAME_TYPE_MINIMAX {
+		t.Errorf("Expected minimax to be rated as %q, got %q", GAME_TYPE_MINIMAX, got)
+	}
+}
+
+// Plays the first free square for the human until the game is over
+func playOutMMGame(t *testing.T, s *GameService, game *Game) *Game {
+	t.Helper()
+	ctx := context.Background()
+	for game.TerminalState == engine.TERM_NOT {
+		played := false
+		for position := uint8(1); position <= 9 && !played; position++ {
+			updatedGame, _, err := s.PlayMMMove(ctx, game.Uuid, getNextPlayerID(game.AiPlayerID), position, nil)
+			if err == nil {
+				game, played = updatedGame, true
+			}
+		}
+		if !played {
+			t.Fatal("Could not play a move")
+		}
+	}
+	return game
+}
+
+func TestRateGame_FinishedGameUpdatesRatings(t *testing.T) {
+	s := newTestGameService(t)
+	accounts := NewAccountService(s.db, time.Hour)
+	ratings := NewRatingService(s.db)
+	ctx := context.Background()
+	account := createTestAccount(t, accounts).Account
+
+	config := GameConfig{Player1Piece: "X", Player2Piece: "O", CreatorUUID: &account.Uuid}
+	game := playOutMMGame(t, s, createTestGameWithConfig(t, s, GAME_TYPE_MINIMAX, config))
+
+	accountRating, err := s.repo.GetOrCreateAccountRating(ctx, &account.Uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	player, games, err := ratings.GetRatingHistory(ctx, accountRating.Uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if player.Name != account.Username || player.Rating.Games != 1 {
+		t.Fatalf("Expected one rated game for %s, got %+v", account.Username, player)
+	}
+	if len(games) != 1 || games[0].History.GameUuid != game.Uuid || games[0].OpponentName != GAME_TYPE_MINIMAX {
+		t.Fatalf("Expected the game against minimax in the rating history, got %+v", games)
+	}
+	// Minimax never loses
+	score1, _ := ratingScores(game.TerminalState)
+	if games[0].History.Score != score1 || score1 == glicko.SCORE_WIN {
+		t.Errorf("Expected a loss or draw, got score %v", games[0].History.Score)
+	}
+	if player.Rating.RatingDeviation >= glicko.DEFAULT_RATING_DEVIATION {
+		t.Errorf("Expected the rated game to reduce the deviation, got %v", player.Rating.RatingDeviation)
+	}
+
+	leaderboard, err := ratings.Leaderboard(ctx, RATING_KIND_AI, LEADERBOARD_MAX_LIMIT, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, entry := range leaderboard {
+		found = found || entry.Name == GAME_TYPE_MINIMAX
+		if entry.Rating.AccountUuid != nil {
+			t.Errorf("Expected only AI ratings, got account %s", entry.Name)
+		}
+	}
+	if !found {
+		t.Error("Expected minimax on the AI leaderboard")
+	}
+}
+
+func TestRateGame_ImportedGameIsNotRated(t *testing.T) {
+	s := newTestGameService(t)
+	accounts := NewAccountService(s.db, time.Hour)
+	ctx := context.Background()
+	account := createTestAccount(t, accounts).Account
+
+	// Minimax answers a corner with the center, so the opposite corner stays available
+	config := GameConfig{Player1Piece: "X", Player2Piece: "O", CreatorUUID: &account.Uuid}
+	game := createTestGameWithConfig(t, s, GAME_TYPE_MINIMAX, config)
+	for _, position := range []uint8{1, 9} {
+		if _, _, err := s.PlayMMMove(ctx, game.Uuid, 1, position, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	record, err := s.ExportGame(ctx, game.Uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The importing account is seated and finishes the game on this server
+	imported := importTestGame(t, s, record, &account.Uuid)
+	if imported.Rated || imported.Player1Uuid == nil || *imported.Player1Uuid != account.Uuid {
+		t.Fatalf("Expected an unrated game with the account seated, got rated %v and seat %v", imported.Rated, imported.Player1Uuid)
+	}
+	imported = playOutMMGame(t, s, imported)
+
+	var count int
+	if err := s.db.QueryRow(ctx, "SELECT count(*) FROM rating_history WHERE game_uuid = $1", imported.Uuid).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("Expected no rating history for an imported game, got %d rows", count)
+	}
+}
+
+func TestRateGame_AnonymousGameIsNotRated(t *testing.T) {
+	s := newTestGameService(t)
+	ctx := context.Background()
+	game := playOutMMGame(t, s, createTestGame(t, s, GAME_TYPE_MINIMAX))
+
+	var count int
+	if err := s.db.QueryRow(ctx, "SELECT count(*) FROM rating_history WHERE game_uuid = $1", game.Uuid).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("Expected no rating history for an anonymous game, got %d rows", count)
+	}
+}
+
+func TestGameVerifier_RepairUndoesRatings(t *testing.T) {
+	s := newTestGameService(t)
+	accounts := NewAccountService(s.db, time.Hour)
+	ctx := context.Background()
+	account := createTestAccount(t, accounts).Account
+
+	config := GameConfig{Player1Piece: "X", Player2Piece: "O", CreatorUUID: &account.Uuid}
+	game := playOutMMGame(t, s, createTestGameWithConfig(t, s, GAME_TYPE_MINIMAX, config))
+
+	// Move 3, the human's second move, removes every piece, so the repair goes back to minimax's answer
+	if _, err := s.db.Exec(ctx, "UPDATE move_event SET post_move_state = '\\x00000000'::bytea WHERE game_uuid = $1 AND move_sequence = 3", game.Uuid); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewGameVerifier(s.db).Repair(ctx, game.Uuid); err != nil {
+		t.Fatal(err)
+	}
+	accountRating, err := s.repo.GetOrCreateAccountRating(ctx, &account.Uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accountRating.Games != 0 || accountRating.Rating != glicko.DEFAULT_RATING {
+		t.Errorf("Expected the repair to undo the rated game, got %d games and rating %v", accountRating.Games, accountRating.Rating)
+	}
+
+	// Finishing the repaired game rates it again
+	game, _, err = s.GetGame(ctx, game.Uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.TerminalState != engine.TERM_NOT {
+		t.Fatalf("Expected the repaired game to be unfinished, got terminal state %d", game.TerminalState)
+	}
+	playOutMMGame(t, s, game)
+	accountRating, err = s.repo.GetOrCreateAccountRating(ctx, &account.Uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accountRating.Games != 1 {
+		t.Errorf("Expected the finished game to be rated once, got %d games", accountRating.Games)
+	}
+}
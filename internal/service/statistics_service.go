@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"t-cubed/internal/repository"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const STATISTICS_REFRESH_INTERVAL = 10 * time.Minute
+
+type GameOutcomes = repository.ListGameOutcomesRow
+type HumanResultsAgainstAI = repository.ListHumanResultsAgainstAIRow
+type OpeningSquare = repository.ListOpeningSquaresRow
+
+// Moves played on each square at each move number, indexed by [move number - 1][position - 1]
+type SquareHeatmap [9][9]int64
+
+// Square usage of finished games, split by who played the moves
+type SquareUsage struct {
+	Human       SquareHeatmap
+	AI          SquareHeatmap
+	RefreshedAt *time.Time // When the square usage view was last refreshed by this server, nil if it has not been yet
+}
+
+// Aggregates statistics over finished games. The square usage heatmap is read from a materialized view,
+// which is refreshed periodically with RefreshPeriodically.
+type StatisticsService struct {
+	repo        *repository.Queries
+	refreshedAt atomic.Pointer[time.Time]
+}
+
+func NewStatisticsService(db *pgxpool.Pool) *StatisticsService {
+	return &StatisticsService{
+		repo: repository.New(db),
+	}
+}
+
+// Returns the results and average length of finished games per game type and neural network model
+func (s *StatisticsService) GameOutcomes(ctx context.Context) ([]GameOutcomes, error) {
+	outcomes, err := s.repo.ListGameOutcomes(ctx)
+	if err != nil {
+		slog.Error("Could not get game outcomes", "error", err)
+	}
+	return outcomes, err
+}
+
+// Returns how often humans beat, draw against and lose to each kind of AI
+func (s *StatisticsService) HumanResultsAgainstAI(ctx context.Context) ([]HumanResultsAgainstAI, error) {
+	results, err := s.repo.ListHumanResultsAgainstAI(ctx)
+	if err != nil {
+		slog.Error("Could not get human results against the AI", "error", err)
+	}
+	return results, err
+}
+
+// Returns the squares humans opened finished games with per game type, most common first
+func (s *StatisticsService) OpeningSquares(ctx context.Context) ([]OpeningSquare, error) {
+	openings, err := s.repo.ListOpeningSquares(ctx)
+	if err != nil {
+		slog.Error("Could not get opening squares", "error", err)
+	}
+	return openings, err
+}
+
+// Returns the square usage heatmaps of a game type, or of all game types if gameTypeLabel is empty
+func (s *StatisticsService) SquareUsage(ctx context.Context, gameTypeLabel string) (*SquareUsage, error) {
+	rows, err := s.repo.ListSquareUsage(ctx, gameTypeLabel)
+	if err != nil {
+		slog.Error("Could not get square usage", "error", err)
+		return nil, err
+	}
+	usage := newSquareUsage(rows)
+	usage.RefreshedAt = s.refreshedAt.Load()
+	return usage, nil
+}
+
+func newSquareUsage(rows []repository.ListSquareUsageRow) *SquareUsage {
+	usage := &SquareUsage{}
+	for _, row := range rows {
+		if row.MoveNumber < 1 || row.MoveNumber > 9 || !isValidPosition(uint8(row.Position)) {
+			continue
+		}
+		heatmap := &usage.Human
+		if row.AiMove {
+			heatmap = &usage.AI
+		}
+		heatmap[row.MoveNumber-1][row.Position-1] += row.Moves
+	}
+	return usage
+}
+
+// Refreshes the square usage view now and then every interval until ctx is done
+func (s *StatisticsService) RefreshPeriodically(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.repo.RefreshSquareUsage(ctx); err != nil {
+			slog.Warn("Could not refresh square usage", "error", err)
+		} else {
+			refreshedAt := time.Now()
+			s.refreshedAt.Store(&refreshedAt)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"t-cubed/internal/repository"
+)
+
+func TestNewSquareUsage(t *testing.T) {
+	usage := newSquareUsage([]repository.ListSquareUsageRow{
+		{MoveNumber: 1, Position: 5, Moves: 3},
+		{MoveNumber: 2, Position: 1, AiMove: true, Moves: 2},
+		{MoveNumber: 9, Position: 9, Moves: 1},
+		{MoveNumber: 0, Position: 1, Moves: 7}, // The blank board is not a move
+	})
+	if usage.Human[0][4] != 3 || usage.AI[1][0] != 2 || usage.Human[8][8] != 1 {
+		t.Errorf("Unexpected heatmaps %v and %v", usage.Human, usage.AI)
+	}
+	var total int64
+	for _, heatmap := range []SquareHeatmap{usage.Human, usage.AI} {
+		for _, moves := range heatmap {
+			for _, count := range moves {
+				total += count
+			}
+		}
+	}
+	if total != 6 {
+		t.Errorf("Expected 6 moves in the heatmaps, got %d", total)
+	}
+}
+
+func TestStatisticsService_CountsFinishedGames(t *testing.T) {
+	s := newTestGameService(t)
+	statistics := NewStatisticsService(s.db)
+	ctx := context.Background()
+
+	before, err := statistics.GameOutcomes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The human opens at position 1
+	playOutMMGame(t, s, createTestGame(t, s, GAME_TYPE_MINIMAX))
+	if err := statistics.repo.RefreshSquareUsage(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := statistics.GameOutcomes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	minimaxGames := func(outcomes []GameOutcomes) int64 {
+		for _, outcome := range outcomes {
+			if outcome.GameType == GAME_TYPE_MINIMAX {
+				return outcome.Games
+			}
+		}
+		return 0
+	}
+	if minimaxGames(after) != minimaxGames(before)+1 {
+		t.Errorf("Expected one more finished minimax game, got %d and %d", minimaxGames(before), minimaxGames(after))
+	}
+
+	usage, err := statistics.SquareUsage(ctx, GAME_TYPE_MINIMAX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Human[0][0] == 0 {
+		t.Error("Expected the opening at position 1 in the human heatmap")
+	}
+
+	openings, err := statistics.OpeningSquares(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, opening := range openings {
+		found = found || opening.GameType == GAME_TYPE_MINIMAX && opening.Position.Int16 == 1
+	}
+	if !found {
+		t.Error("Expected position 1 among the minimax openings")
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"t-cubed/internal/ai"
+	"t-cubed/internal/pb"
+	"t-cubed/internal/repository"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	TRACE_SCHEMA_VERSION_LEGACY = 1 // Fixed layer1-layer5 fields. Their messages have no version, so it reads as 0.
+	TRACE_SCHEMA_VERSION        = 2 // Repeated Layer messages
+)
+
+// Returns the name of the layer at index i of a trace with numLayers entries (including the input layer)
+func traceLayerName(i int, numLayers int) string {
+	switch i {
+	case 0:
+		return "input"
+	case numLayers - 1:
+		return "output"
+	default:
+		return fmt.Sprintf("hidden_%d", i)
+	}
+}
+
+// Converts a forward trace of any depth into the current protobuf schema
+func traceToProto(trace *ai.ForwardTrace) *pb.Trace {
+	numLayers := len(trace.LayerOutputs)
+	message := &pb.Trace{
+		Version: TRACE_SCHEMA_VERSION,
+		Layers:  make([]*pb.Layer, numLayers),
+	}
+	for i, values := range trace.LayerOutputs {
+		layer := &pb.Layer{
+			Name:   traceLayerName(i, numLayers),
+			Values: values,
+		}
+		if i < len(trace.Activations) {
+			layer.Activation = trace.Activations[i]
+		}
+		if i < len(trace.PreActivations) {
+			layer.PreActivations = trace.PreActivations[i]
+		}
+		message.Layers[i] = layer
+	}
+	return message
+}
+
+// Converts a protobuf trace of any supported schema version into a forward trace
+func traceFromProto(message *pb.Trace) (*ai.ForwardTrace, error) {
+	switch message.GetVersion() {
+	case 0, TRACE_SCHEMA_VERSION_LEGACY:
+		return legacyTraceFromProto(message), nil
+	case TRACE_SCHEMA_VERSION:
+		layers := message.GetLayers()
+		trace := &ai.ForwardTrace{
+			LayerOutputs: make([][]float64, len(layers)),
+			Activations:  make([]string, len(layers)),
+		}
+		detailed := false
+		for i, layer := range layers {
+			trace.LayerOutputs[i] = layer.GetValues()
+			trace.Activations[i] = layer.GetActivation()
+			detailed = detailed || len(layer.GetPreActivations()) > 0
+		}
+		// Contributions are not stored, but pre-activations and logits are
+		if detailed {
+			trace.Level = ai.TRACE_LEVEL_DETAILED
+			trace.PreActivations = make([][]float64, len(layers))
+			for i, layer := range layers {
+				trace.PreActivations[i] = layer.GetPreActivations()
+			}
+			if len(layers) > 0 {
+				trace.Logits = layers[len(layers)-1].GetValues()
+			}
+		}
+		return trace, nil
+	default:
+		return nil, fmt.Errorf("unsupported trace schema version %d", message.GetVersion())
+	}
+}
+
+// TRACE_SCHEMA_VERSION_LEGACY traces were recorded by the 18-32-32-32-9 network, with ReLU hidden layers and identity output
+func legacyTraceFromProto(message *pb.Trace) *ai.ForwardTrace {
+	return &ai.ForwardTrace{
+		LayerOutputs: [][]float64{
+			message.GetLayer1(),
+			message.GetLayer2(),
+			message.GetLayer3(),
+			message.GetLayer4(),
+			message.GetLayer5(),
+		},
+		Activations: []string{
+			ai.ACTIVATION_NONE,
+			ai.ACTIVATION_RELU,
+			ai.ACTIVATION_RELU,
+			ai.ACTIVATION_RELU,
+			ai.ACTIVATION_IDENTITY,
+		},
+	}
+}
+
+// Serializes a forward trace for storage in trace_cache
+func marshalTrace(trace *ai.ForwardTrace) ([]byte, error) {
+	return proto.Marshal(traceToProto(trace))
+}
+
+// Deserializes a forward trace stored in trace_cache
+func unmarshalTrace(data []byte) (*ai.ForwardTrace, error) {
+	var message pb.Trace
+	if err := proto.Unmarshal(data, &message); err != nil {
+		return nil, err
+	}
+	return traceFromProto(&message)
+}
+
+// Rewrites trace_cache rows stored with an older schema version to the current one.
+// Returns the number of migrated rows.
+func MigrateTraces(ctx context.Context, db *pgxpool.Pool) (int, error) {
+	repo := repository.New(db)
+	traceCaches, err := repo.ListTraceCachesBelowSchemaVersion(ctx, TRACE_SCHEMA_VERSION)
+	if err != nil {
+		return 0, err
+	}
+
+	migrated := 0
+	for _, traceCache := range traceCaches {
+		trace, err := unmarshalTrace(traceCache.Trace)
+		if err != nil {
+			return migrated, fmt.Errorf("trace %s: %w", traceCache.Uuid, err)
+		}
+		traceBytes, err := marshalTrace(trace)
+		if err != nil {
+			return migrated, fmt.Errorf("trace %s: %w", traceCache.Uuid, err)
+		}
+		_, err = repo.UpdateTraceCache(ctx, repository.UpdateTraceCacheParams{
+			Trace:         traceBytes,
+			SchemaVersion: TRACE_SCHEMA_VERSION,
+			Uuid:          traceCache.Uuid,
+		})
+		if err != nil {
+			return migrated, fmt.Errorf("trace %s: %w", traceCache.Uuid, err)
+		}
+		migrated++
+	}
+	slog.Info("Migrated trace caches", "count", migrated, "schema_version", TRACE_SCHEMA_VERSION)
+	return migrated, nil
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"t-cubed/internal/ai"
+	"t-cubed/internal/pb"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func TestTraceRoundTrip_ArbitraryDepth(t *testing.T) {
+	for _, neurons := range [][]int{{18, 9}, {18, 32, 9}, {18, 32, 32, 32, 9}, {18, 16, 16, 16, 16, 16, 16, 9}} {
+		n, err := ai.NewNetwork(neurons...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		input := make([]float64, 18)
+		input[0], input[13] = 1, 1
+
+		trace := new(ai.ForwardTrace)
+		if _, err := n.Forward(input, trace); err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := marshalTrace(trace)
+		if err != nil {
+			t.Fatalf("%v: marshalTrace returned error: %v", neurons, err)
+		}
+		got, err := unmarshalTrace(data)
+		if err != nil {
+			t.Fatalf("%v: unmarshalTrace returned error: %v", neurons, err)
+		}
+		if !reflect.DeepEqual(got, trace) {
+			t.Fatalf("%v: trace changed after round trip:\ngot  %v\nwant %v", neurons, got, trace)
+		}
+	}
+}
+
+func TestTraceToProto_LayerNames(t *testing.T) {
+	trace := &ai.ForwardTrace{
+		LayerOutputs: [][]float64{{1}, {2}, {3}, {4}},
+		Activations:  []string{ai.ACTIVATION_NONE, ai.ACTIVATION_RELU, ai.ACTIVATION_RELU, ai.ACTIVATION_IDENTITY},
+	}
+	message := traceToProto(trace)
+	if message.GetVersion() != TRACE_SCHEMA_VERSION {
+		t.Fatalf("expected version %d, got %d", TRACE_SCHEMA_VERSION, message.GetVersion())
+	}
+	wantNames := []string{"input", "hidden_1", "hidden_2", "output"}
+	for i, layer := range message.GetLayers() {
+		if layer.GetName() != wantNames[i] {
+			t.Fatalf("layer %d: expected name %q, got %q", i, wantNames[i], layer.GetName())
+		}
+		if layer.GetActivation() != trace.Activations[i] {
+			t.Fatalf("layer %d: expected activation %q, got %q", i, trace.Activations[i], layer.GetActivation())
+		}
+	}
+}
+
+func TestUnmarshalTrace_Legacy(t *testing.T) {
+	legacy := &pb.Trace{
+		Layer1: []float64{1, 0},
+		Layer2: []float64{0.5},
+		Layer3: []float64{0.25},
+		Layer4: []float64{0.125},
+		Layer5: []float64{2, -2},
+	}
+	data, err := proto.Marshal(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trace, err := unmarshalTrace(data)
+	if err != nil {
+		t.Fatalf("unmarshalTrace returned error: %v", err)
+	}
+	want := [][]float64{{1, 0}, {0.5}, {0.25}, {0.125}, {2, -2}}
+	if !reflect.DeepEqual(trace.LayerOutputs, want) {
+		t.Fatalf("expected %v, got %v", want, trace.LayerOutputs)
+	}
+
+	// Migrating the legacy trace must preserve its values
+	migrated, err := marshalTrace(trace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trace2, err := unmarshalTrace(migrated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(trace2, trace) {
+		t.Fatalf("legacy trace changed after migration: got %v want %v", trace2, trace)
+	}
+
+	// A legacy trace that states its version reads the same
+	legacy.Version = TRACE_SCHEMA_VERSION_LEGACY
+	data, err = proto.Marshal(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trace3, err := unmarshalTrace(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(trace3, trace) {
+		t.Fatalf("expected the versioned legacy trace %v, got %v", trace, trace3)
+	}
+}
+
+func TestUnmarshalTrace_UnsupportedVersion(t *testing.T) {
+	data, err := proto.Marshal(&pb.Trace{Version: TRACE_SCHEMA_VERSION + 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := unmarshalTrace(data); err == nil {
+		t.Fatalf("expected error for unsupported schema version")
+	}
+}
+
+func TestTraceRoundTrip_Detailed(t *testing.T) {
+	n, err := ai.NewNetwork(18, 16, 16, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := make([]float64, 18)
+	input[4] = 1
+
+	trace := ai.NewForwardTrace(ai.TRACE_LEVEL_DETAILED, 0)
+	if _, err := n.Forward(input, trace); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := marshalTrace(trace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := unmarshalTrace(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Contributions are derived from the weights and are not stored
+	trace.Contributions = nil
+	if !reflect.DeepEqual(got, trace) {
+		t.Fatalf("detailed trace changed after round trip:\ngot  %+v\nwant %+v", got, trace)
+	}
+}